@@ -20,6 +20,8 @@ require (
 	github.com/sirupsen/logrus v1.9.3
 	go.uber.org/dig v1.19.0
 	golang.org/x/crypto v0.37.0
+	golang.org/x/net v0.38.0
+	golang.org/x/sync v0.16.0
 	golang.org/x/text v0.28.0
 	gorm.io/datatypes v1.2.1
 	gorm.io/driver/mysql v1.6.0
@@ -59,8 +61,6 @@ require (
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	golang.org/x/arch v0.16.0 // indirect
-	golang.org/x/net v0.38.0 // indirect
-	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
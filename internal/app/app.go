@@ -16,6 +16,7 @@ import (
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
+	"gpt-load/internal/tracing"
 	"gpt-load/internal/types"
 	"gpt-load/internal/version"
 
@@ -34,6 +35,7 @@ type App struct {
 	logCleanupService *services.LogCleanupService
 	requestLogService *services.RequestLogService
 	cronChecker       *keypool.CronChecker
+	expiryChecker     *keypool.ExpiryChecker
 	keyPoolProvider   *keypool.KeyProvider
 	proxyServer       *proxy.ProxyServer
 	storage           store.Store
@@ -51,6 +53,7 @@ type AppParams struct {
 	LogCleanupService *services.LogCleanupService
 	RequestLogService *services.RequestLogService
 	CronChecker       *keypool.CronChecker
+	ExpiryChecker     *keypool.ExpiryChecker
 	KeyPoolProvider   *keypool.KeyProvider
 	ProxyServer       *proxy.ProxyServer
 	Storage           store.Store
@@ -67,6 +70,7 @@ func NewApp(params AppParams) *App {
 		logCleanupService: params.LogCleanupService,
 		requestLogService: params.RequestLogService,
 		cronChecker:       params.CronChecker,
+		expiryChecker:     params.ExpiryChecker,
 		keyPoolProvider:   params.KeyPoolProvider,
 		proxyServer:       params.ProxyServer,
 		storage:           params.Storage,
@@ -126,6 +130,7 @@ func (a *App) Start() error {
 		a.requestLogService.Start()
 		a.logCleanupService.Start()
 		a.cronChecker.Start()
+		a.expiryChecker.Start()
 	} else {
 		logrus.Info("Starting as Slave Node.")
 		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
@@ -133,6 +138,7 @@ func (a *App) Start() error {
 
 	// 显示配置并启动所有后台服务
 	a.configManager.DisplayServerConfig()
+	tracing.Configure(a.configManager.GetTracingConfig())
 
 	a.groupManager.Initialize()
 
@@ -185,11 +191,13 @@ func (a *App) Stop(ctx context.Context) {
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
 		a.settingsManager.Stop,
+		tracing.Shutdown,
 	}
 
 	if serverConfig.IsMaster {
 		stoppableServices = append(stoppableServices,
 			a.cronChecker.Stop,
+			a.expiryChecker.Stop,
 			a.logCleanupService.Stop,
 			a.requestLogService.Stop,
 		)
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -90,6 +91,7 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 	// Build final URL with path and query parameters
 	finalURL := *upstreamURL
 	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + endpointURL.Path
+	ch.withPathPrefix(&finalURL)
 	finalURL.RawQuery = endpointURL.RawQuery
 	reqURL := finalURL.String()
 
@@ -120,7 +122,9 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
-	resp, err := ch.HTTPClient.Do(req)
+	upstreamStart := time.Now()
+	resp, err := ch.ClientForKey(apiKey, false).Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
 	if err != nil {
 		return false, fmt.Errorf("failed to send validation request: %w", err)
 	}
@@ -0,0 +1,456 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// anthropicDefaultMaxTokens is the max_tokens of translated requests that do not set one; Anthropic requires it.
+const anthropicDefaultMaxTokens = 4096
+
+// anthropicFinishReasons maps Anthropic stop reasons to OpenAI finish reasons.
+var anthropicFinishReasons = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"pause_turn":    "stop",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+	"refusal":       "content_filter",
+}
+
+// TranslateRequest converts OpenAI chat completion requests to the Messages API when the group's
+// client_api_format is "openai". Other requests, such as native Messages calls, are left as they are.
+func (ch *AnthropicChannel) TranslateRequest(req *http.Request, body []byte, group *models.Group) ([]byte, ResponseTranslator, error) {
+	if group.EffectiveConfig.ClientAPIFormat != ClientAPIFormatOpenAI || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return body, nil, nil
+	}
+
+	translated, err := openAIToAnthropicRequest(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.Path = strings.TrimSuffix(req.URL.Path, "/chat/completions") + "/messages"
+	req.URL.RawPath = ""
+
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group":    group.Name,
+		"new_path": req.URL.Path,
+	}).Debug("Translated OpenAI chat request to Anthropic Messages")
+
+	return translated, &anthropicToOpenAI{created: time.Now().Unix(), toolIndexes: make(map[int]int)}, nil
+}
+
+// openAIToAnthropicRequest converts an OpenAI chat completion request body to a Messages API body.
+func openAIToAnthropicRequest(body []byte) ([]byte, error) {
+	var in map[string]any
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid chat completion request: %w", err)
+	}
+	rawMessages, _ := in["messages"].([]any)
+	if len(rawMessages) == 0 {
+		return nil, fmt.Errorf("chat completion request has no messages")
+	}
+
+	out := map[string]any{"model": in["model"]}
+	var system []any
+	var messages []map[string]any
+	appendBlocks := func(role string, blocks []any) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Anthropic expects alternating roles, so consecutive messages of a role are merged.
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]any), blocks...)
+			return
+		}
+		messages = append(messages, map[string]any{"role": role, "content": blocks})
+	}
+
+	for _, item := range rawMessages {
+		message, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch message["role"] {
+		case "system", "developer":
+			if text := textOfContent(message["content"]); text != "" {
+				system = append(system, map[string]any{"type": "text", "text": text})
+			}
+		case "user":
+			appendBlocks("user", anthropicContentBlocks(message["content"]))
+		case "assistant":
+			blocks := anthropicContentBlocks(message["content"])
+			toolCalls, _ := message["tool_calls"].([]any)
+			for _, call := range toolCalls {
+				if block := anthropicToolUseBlock(call); block != nil {
+					blocks = append(blocks, block)
+				}
+			}
+			appendBlocks("assistant", blocks)
+		case "tool":
+			result := map[string]any{"type": "tool_result", "tool_use_id": message["tool_call_id"]}
+			if content, ok := message["content"].(string); ok {
+				result["content"] = content
+			} else {
+				result["content"] = anthropicContentBlocks(message["content"])
+			}
+			appendBlocks("user", []any{result})
+		}
+	}
+	out["messages"] = messages
+	if len(system) > 0 {
+		out["system"] = system
+	}
+
+	out["max_tokens"] = anthropicDefaultMaxTokens
+	for _, field := range []string{"max_tokens", "max_completion_tokens"} {
+		if value, ok := in[field].(float64); ok && value > 0 {
+			out["max_tokens"] = int(value)
+		}
+	}
+	if temperature, ok := in["temperature"].(float64); ok {
+		// OpenAI allows temperatures up to 2, Anthropic up to 1.
+		out["temperature"] = min(temperature, 1)
+	}
+	if topP, ok := in["top_p"].(float64); ok {
+		out["top_p"] = topP
+	}
+	switch stop := in["stop"].(type) {
+	case string:
+		out["stop_sequences"] = []string{stop}
+	case []any:
+		out["stop_sequences"] = stop
+	}
+	if stream, ok := in["stream"].(bool); ok {
+		out["stream"] = stream
+	}
+	if user, ok := in["user"].(string); ok && user != "" {
+		out["metadata"] = map[string]any{"user_id": user}
+	}
+
+	if tools, ok := in["tools"].([]any); ok && len(tools) > 0 {
+		var anthropicTools []any
+		for _, item := range tools {
+			tool, _ := item.(map[string]any)
+			function, _ := tool["function"].(map[string]any)
+			if function == nil {
+				continue
+			}
+			schema := function["parameters"]
+			if schema == nil {
+				schema = map[string]any{"type": "object"}
+			}
+			anthropicTool := map[string]any{"name": function["name"], "input_schema": schema}
+			if description, ok := function["description"].(string); ok {
+				anthropicTool["description"] = description
+			}
+			anthropicTools = append(anthropicTools, anthropicTool)
+		}
+		out["tools"] = anthropicTools
+		if choice := anthropicToolChoice(in["tool_choice"]); choice != nil {
+			if parallel, ok := in["parallel_tool_calls"].(bool); ok && !parallel {
+				choice["disable_parallel_tool_use"] = true
+			}
+			out["tool_choice"] = choice
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// anthropicContentBlocks converts OpenAI message content, a string or a list of parts, to Anthropic content
+// blocks. Text and image parts are converted; other parts have no Anthropic counterpart and are dropped.
+func anthropicContentBlocks(content any) []any {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []any{map[string]any{"type": "text", "text": v}}
+	case []any:
+		var blocks []any
+		for _, item := range v {
+			part, _ := item.(map[string]any)
+			switch part["type"] {
+			case "text":
+				if text, ok := part["text"].(string); ok && text != "" {
+					blocks = append(blocks, map[string]any{"type": "text", "text": text})
+				}
+			case "image_url":
+				imageURL, _ := part["image_url"].(map[string]any)
+				if url, ok := imageURL["url"].(string); ok {
+					blocks = append(blocks, anthropicImageBlock(url))
+				}
+			}
+		}
+		return blocks
+	default:
+		return nil
+	}
+}
+
+// anthropicImageBlock converts an image URL, possibly a base64 data URL, to an image block.
+func anthropicImageBlock(url string) map[string]any {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if mediaType, data, ok := strings.Cut(rest, ";base64,"); ok {
+			return map[string]any{
+				"type":   "image",
+				"source": map[string]any{"type": "base64", "media_type": mediaType, "data": data},
+			}
+		}
+	}
+	return map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": url}}
+}
+
+// anthropicToolUseBlock converts an OpenAI assistant tool call to a tool_use block.
+func anthropicToolUseBlock(call any) map[string]any {
+	toolCall, _ := call.(map[string]any)
+	function, _ := toolCall["function"].(map[string]any)
+	if function == nil {
+		return nil
+	}
+	input := map[string]any{}
+	if arguments, ok := function["arguments"].(string); ok && arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+			input = map[string]any{}
+		}
+	}
+	return map[string]any{"type": "tool_use", "id": toolCall["id"], "name": function["name"], "input": input}
+}
+
+// anthropicToolChoice converts an OpenAI tool_choice, returning nil when none is set.
+func anthropicToolChoice(choice any) map[string]any {
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "none":
+			return map[string]any{"type": "none"}
+		case "required":
+			return map[string]any{"type": "any"}
+		default:
+			return map[string]any{"type": "auto"}
+		}
+	case map[string]any:
+		if function, ok := v["function"].(map[string]any); ok {
+			return map[string]any{"type": "tool", "name": function["name"]}
+		}
+	}
+	return nil
+}
+
+// anthropicToOpenAI converts Messages API responses of a translated request to chat completion responses.
+// It keeps the state of one streamed response.
+type anthropicToOpenAI struct {
+	id      string
+	model   string
+	created int64
+
+	// toolIndexes maps the content block index of a tool_use block to its tool call index.
+	toolIndexes      map[int]int
+	promptTokens     int
+	completionTokens int
+	finishReason     string
+	finished         bool
+}
+
+// TranslateBody converts a Messages API response to a chat completion.
+func (t *anthropicToOpenAI) TranslateBody(body []byte) ([]byte, error) {
+	var in struct {
+		ID         string           `json:"id"`
+		Model      string           `json:"model"`
+		Content    []map[string]any `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid Anthropic response: %w", err)
+	}
+
+	var texts, thinking []string
+	var toolCalls []any
+	for _, block := range in.Content {
+		switch block["type"] {
+		case "text":
+			if text, ok := block["text"].(string); ok {
+				texts = append(texts, text)
+			}
+		case "thinking":
+			if text, ok := block["thinking"].(string); ok {
+				thinking = append(thinking, text)
+			}
+		case "tool_use":
+			arguments, _ := json.Marshal(block["input"])
+			toolCalls = append(toolCalls, map[string]any{
+				"id":       block["id"],
+				"type":     "function",
+				"function": map[string]any{"name": block["name"], "arguments": string(arguments)},
+			})
+		}
+	}
+
+	message := map[string]any{"role": "assistant", "content": nil}
+	if len(texts) > 0 {
+		message["content"] = strings.Join(texts, "")
+	}
+	if len(thinking) > 0 {
+		message["reasoning_content"] = strings.Join(thinking, "")
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	return json.Marshal(map[string]any{
+		"id":      in.ID,
+		"object":  "chat.completion",
+		"created": t.created,
+		"model":   in.Model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       message,
+			"finish_reason": openAIFinishReason(in.StopReason),
+		}},
+		"usage": openAIUsage(in.Usage.InputTokens, in.Usage.OutputTokens),
+	})
+}
+
+// TranslateEvent converts a Messages API stream event to chat completion chunks.
+func (t *anthropicToOpenAI) TranslateEvent(data []byte) ([]byte, error) {
+	var event struct {
+		Type         string         `json:"type"`
+		Index        int            `json:"index"`
+		Message      map[string]any `json:"message"`
+		ContentBlock map[string]any `json:"content_block"`
+		Delta        map[string]any `json:"delta"`
+		Usage        map[string]any `json:"usage"`
+		Error        map[string]any `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid Anthropic stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		t.id, _ = event.Message["id"].(string)
+		t.model, _ = event.Message["model"].(string)
+		if usage, ok := event.Message["usage"].(map[string]any); ok {
+			t.promptTokens = intField(usage, "input_tokens")
+		}
+		return t.chunk(map[string]any{"role": "assistant", "content": ""}), nil
+
+	case "content_block_start":
+		switch event.ContentBlock["type"] {
+		case "tool_use":
+			index := len(t.toolIndexes)
+			t.toolIndexes[event.Index] = index
+			return t.chunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index":    index,
+				"id":       event.ContentBlock["id"],
+				"type":     "function",
+				"function": map[string]any{"name": event.ContentBlock["name"], "arguments": ""},
+			}}}), nil
+		case "text":
+			if text, _ := event.ContentBlock["text"].(string); text != "" {
+				return t.chunk(map[string]any{"content": text}), nil
+			}
+		}
+
+	case "content_block_delta":
+		switch event.Delta["type"] {
+		case "text_delta":
+			return t.chunk(map[string]any{"content": event.Delta["text"]}), nil
+		case "thinking_delta":
+			return t.chunk(map[string]any{"reasoning_content": event.Delta["thinking"]}), nil
+		case "input_json_delta":
+			return t.chunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index":    t.toolIndexes[event.Index],
+				"function": map[string]any{"arguments": event.Delta["partial_json"]},
+			}}}), nil
+		}
+
+	case "message_delta":
+		if reason, ok := event.Delta["stop_reason"].(string); ok {
+			t.finishReason = openAIFinishReason(reason)
+		}
+		if event.Usage != nil {
+			if tokens := intField(event.Usage, "output_tokens"); tokens > 0 {
+				t.completionTokens = tokens
+			}
+			if tokens := intField(event.Usage, "input_tokens"); tokens > 0 {
+				t.promptTokens = tokens
+			}
+		}
+
+	case "message_stop":
+		return t.finish(), nil
+
+	case "error":
+		return sseData(map[string]any{"error": event.Error}), nil
+	}
+	return nil, nil
+}
+
+// FinishStream ends the chunk stream with the final chunk, if not sent yet, and the "[DONE]" marker.
+func (t *anthropicToOpenAI) FinishStream() []byte {
+	return append(t.finish(), "data: [DONE]\n\n"...)
+}
+
+// finish returns the chunk carrying the finish reason and usage, once.
+func (t *anthropicToOpenAI) finish() []byte {
+	if t.finished {
+		return nil
+	}
+	t.finished = true
+	if t.finishReason == "" {
+		t.finishReason = "stop"
+	}
+	return sseData(map[string]any{
+		"id":      t.id,
+		"object":  "chat.completion.chunk",
+		"created": t.created,
+		"model":   t.model,
+		"choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": t.finishReason}},
+		"usage":   openAIUsage(t.promptTokens, t.completionTokens),
+	})
+}
+
+func (t *anthropicToOpenAI) chunk(delta map[string]any) []byte {
+	return sseData(map[string]any{
+		"id":      t.id,
+		"object":  "chat.completion.chunk",
+		"created": t.created,
+		"model":   t.model,
+		"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": nil}},
+	})
+}
+
+func openAIFinishReason(stopReason string) string {
+	if reason, ok := anthropicFinishReasons[stopReason]; ok {
+		return reason
+	}
+	return "stop"
+}
+
+func openAIUsage(promptTokens, completionTokens int) map[string]any {
+	return map[string]any{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+}
+
+// intField reads a JSON number field as an int.
+func intField(payload map[string]any, field string) int {
+	value, _ := payload[field].(float64)
+	return int(value)
+}
@@ -0,0 +1,166 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	Register("azure_openai", newAzureOpenAIChannel)
+}
+
+// AzureOpenAIChannel proxies OpenAI-style requests to an Azure OpenAI resource. Azure routes by deployment
+// name instead of model, so the model of the request, after redirection, names the deployment:
+// "/v1/chat/completions" with model "gpt-4o" is sent to "/openai/deployments/gpt-4o/chat/completions".
+// Model redirect rules map OpenAI model IDs to deployment names where they differ.
+type AzureOpenAIChannel struct {
+	*OpenAIChannel
+}
+
+func newAzureOpenAIChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("azure_openai", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureOpenAIChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// ModifyRequest sets the api-key header and the api-version query parameter, unless the client sent one.
+func (ch *AzureOpenAIChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
+	req.Header.Set("api-key", apiKey.KeyValue)
+
+	query := req.URL.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", group.EffectiveConfig.AzureAPIVersion)
+		req.URL.RawQuery = query.Encode()
+	}
+	return nil
+}
+
+// ApplyModelRedirect applies the group's redirect rules to the model, then routes the request to the
+// deployment it names.
+func (ch *AzureOpenAIChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	finalBodyBytes, err := ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := ch.ExtractModel(&gin.Context{Request: req}, finalBodyBytes)
+	if deployment == "" {
+		if boundary, ok := multipartBoundary(req); ok {
+			deployment, _ = readMultipartField(finalBodyBytes, boundary, "model")
+		}
+	}
+
+	originalPath := req.URL.Path
+	ch.setDeploymentPath(req.URL, deployment)
+	if req.URL.Path != originalPath {
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+			"group":         group.Name,
+			"deployment":    deployment,
+			"original_path": originalPath,
+			"new_path":      req.URL.Path,
+		}).Debug("Request routed to Azure deployment")
+	}
+
+	return finalBodyBytes, nil
+}
+
+// setDeploymentPath rewrites an OpenAI path below the upstream base path to its Azure form. Paths already
+// starting with "/openai" are left alone, and requests without a model, such as model listings, keep
+// their path below "/openai".
+func (ch *AzureOpenAIChannel) setDeploymentPath(u *url.URL, deployment string) {
+	base := ch.upstreamBasePath(u.Path)
+	rest := strings.TrimPrefix(u.Path, base)
+	if rest == "/openai" || strings.HasPrefix(rest, "/openai/") {
+		return
+	}
+	if rest == "/v1" || strings.HasPrefix(rest, "/v1/") {
+		rest = strings.TrimPrefix(rest, "/v1")
+	}
+
+	if deployment == "" {
+		u.Path = base + "/openai" + rest
+	} else {
+		u.Path = base + "/openai/deployments/" + deployment + rest
+	}
+	u.RawPath = ""
+}
+
+// ValidateKey checks the key with a minimal chat completion on the deployment of the test model.
+func (ch *AzureOpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	deployment := ch.TestModel
+	if target, ok := group.ModelRedirectMap[deployment]; ok {
+		deployment = target
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + "/openai/deployments/" + deployment + "/chat/completions"
+	ch.withPathPrefix(&finalURL)
+	finalURL.RawQuery = url.Values{"api-version": {group.EffectiveConfig.AzureAPIVersion}}.Encode()
+
+	payload := gin.H{
+		"messages": []gin.H{
+			{"role": "user", "content": "hi"},
+		},
+		"max_tokens": 1,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", finalURL.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("api-key", apiKey.KeyValue)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	upstreamStart := time.Now()
+	resp, err := ch.ClientForKey(apiKey, false).Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
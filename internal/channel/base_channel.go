@@ -4,24 +4,31 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"net/http"
 	"net/url"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 )
 
-// UpstreamInfo holds the information for a single upstream server, including its weight.
+// UpstreamInfo holds the information for a single upstream server, including its weight and health.
 type UpstreamInfo struct {
 	URL           *url.URL
 	Weight        int
 	CurrentWeight int
+
+	failures       int
+	unhealthyUntil time.Time
 }
 
 // BaseChannel provides common functionality for channel proxies.
@@ -32,17 +39,32 @@ type BaseChannel struct {
 	StreamClient       *http.Client
 	TestModel          string
 	ValidationEndpoint string
+	Signer             RequestSigner
 	upstreamLock       sync.Mutex
 
+	// Client configurations, used to derive per-key clients with a different proxy.
+	clientManager *httpclient.HTTPClientManager
+	clientConfig  httpclient.Config
+	streamConfig  httpclient.Config
+
+	// Normalized upstream path prefix ("/llm"), prepended to the path of every outgoing request.
+	pathPrefix string
+
 	// Cached fields from the group for stale check
 	channelType         string
 	groupUpstreams      datatypes.JSON
 	effectiveConfig     *types.SystemSettings
 	modelRedirectRules  datatypes.JSONMap
 	modelRedirectStrict bool
+
+	// Sorted source models of the redirect rules. The channel is rebuilt when the rules change,
+	// so this is computed once per channel instead of on every model list call.
+	configuredModelsOnce sync.Once
+	configuredModels     []string
 }
 
-// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm.
+// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm. Upstreams in
+// cooldown after consecutive failures are skipped, unless all of them are.
 func (b *BaseChannel) getUpstreamURL() *url.URL {
 	b.upstreamLock.Lock()
 	defer b.upstreamLock.Unlock()
@@ -54,11 +76,17 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 		return b.Upstreams[0].URL
 	}
 
+	now := time.Now()
+	healthy := slices.ContainsFunc(b.Upstreams, func(up UpstreamInfo) bool { return up.available(now) })
+
 	totalWeight := 0
 	var best *UpstreamInfo
 
 	for i := range b.Upstreams {
 		up := &b.Upstreams[i]
+		if healthy && !up.available(now) {
+			continue
+		}
 		totalWeight += up.Weight
 		up.CurrentWeight += up.Weight
 
@@ -88,6 +116,7 @@ func (b *BaseChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (
 	requestPath = strings.TrimPrefix(requestPath, proxyPrefix)
 
 	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + requestPath
+	b.withPathPrefix(&finalURL)
 
 	finalURL.RawQuery = originalURL.RawQuery
 
@@ -131,12 +160,34 @@ func (b *BaseChannel) GetStreamClient() *http.Client {
 	return b.StreamClient
 }
 
+// ClientForKey returns the client for a key. Keys with their own proxy URL get a client for that proxy,
+// shared through the client manager with all other keys using the same proxy.
+func (b *BaseChannel) ClientForKey(apiKey *models.APIKey, isStream bool) *http.Client {
+	if apiKey == nil || apiKey.ProxyURL == "" || b.clientManager == nil {
+		if isStream {
+			return b.StreamClient
+		}
+		return b.HTTPClient
+	}
+
+	config := b.clientConfig
+	if isStream {
+		config = b.streamConfig
+	}
+	config.ProxyURL = apiKey.ProxyURL
+	return b.clientManager.GetClient(&config)
+}
+
 // ApplyModelRedirect applies model redirection based on the group's redirect rules.
 func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
 	if len(group.ModelRedirectMap) == 0 || len(bodyBytes) == 0 {
 		return bodyBytes, nil
 	}
 
+	if boundary, ok := multipartBoundary(req); ok {
+		return b.applyMultipartModelRedirect(req, bodyBytes, boundary, group)
+	}
+
 	var requestData map[string]any
 	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
 		return bodyBytes, nil
@@ -153,11 +204,16 @@ func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, gr
 	}
 
 	// Direct match without any prefix processing
-	if targetModel, found := group.ModelRedirectMap[model]; found {
+	if targetModel, found := redirectTarget(req, group, model); found {
+		// Identity redirects only allow the model in strict mode, leave the body untouched.
+		if targetModel == model {
+			return bodyBytes, nil
+		}
+
 		requestData["model"] = targetModel
 
 		// Log the redirection for audit
-		logrus.WithFields(logrus.Fields{
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 			"group":          group.Name,
 			"original_model": model,
 			"target_model":   targetModel,
@@ -178,7 +234,7 @@ func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, gr
 func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error) {
 	var response map[string]any
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
-		logrus.WithError(err).Debug("Failed to parse model list response, returning empty")
+		utils.LoggerFromContext(req.Context()).WithError(err).Debug("Failed to parse model list response, returning empty")
 		return nil, err
 	}
 
@@ -193,13 +249,14 @@ func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, gr
 	}
 
 	// Build configured source models list (common logic for both modes)
-	configuredModels := buildConfiguredModels(group.ModelRedirectMap)
+	configuredModels := buildConfiguredModels(b.configuredModelIDs(group))
 
 	// Strict mode: return only configured models (whitelist)
 	if group.ModelRedirectStrict {
 		response["data"] = configuredModels
+		annotateModelCapabilities(response, group)
 
-		logrus.WithFields(logrus.Fields{
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 			"group":       group.Name,
 			"model_count": len(configuredModels),
 			"strict_mode": true,
@@ -211,8 +268,9 @@ func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, gr
 	// Non-strict mode: merge upstream + configured models (upstream priority)
 	merged := mergeModelLists(upstreamModels, configuredModels)
 	response["data"] = merged
+	annotateModelCapabilities(response, group)
 
-	logrus.WithFields(logrus.Fields{
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 		"group":            group.Name,
 		"upstream_count":   len(upstreamModels),
 		"configured_count": len(configuredModels),
@@ -223,14 +281,61 @@ func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, gr
 	return response, nil
 }
 
-// buildConfiguredModels builds a list of models from redirect rules
-func buildConfiguredModels(redirectMap map[string]string) []any {
-	if len(redirectMap) == 0 {
+// upstreamBasePath returns the longest upstream base path, upstream path prefix included, that prefixes an
+// upstream request path.
+func (b *BaseChannel) upstreamBasePath(path string) string {
+	longest := ""
+	for _, up := range b.Upstreams {
+		base := b.pathPrefix + strings.TrimRight(up.URL.Path, "/")
+		if base == "" || len(base) <= len(longest) {
+			continue
+		}
+		if path == base || strings.HasPrefix(path, base+"/") {
+			longest = base
+		}
+	}
+	return longest
+}
+
+// isOpenAICompatiblePath reports whether the upstream request path contains the given consecutive
+// path segments (e.g. "v1beta", "openai"). Whole segments are compared, and matches lying inside
+// a reverse-proxy prefix of the upstream base path are ignored unless the base path ends with them.
+func (b *BaseChannel) isOpenAICompatiblePath(path string, segments ...string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	baseCount := 0
+	if base := strings.Trim(b.upstreamBasePath(path), "/"); base != "" {
+		baseCount = len(strings.Split(base, "/"))
+	}
+	for i := 0; i+len(segments) <= len(parts); i++ {
+		if i+len(segments) >= baseCount && slices.Equal(parts[i:i+len(segments)], segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// configuredModelIDs returns the sorted source models of the group's redirect rules.
+func (b *BaseChannel) configuredModelIDs(group *models.Group) []string {
+	b.configuredModelsOnce.Do(func() {
+		ids := make([]string, 0, len(group.ModelRedirectMap))
+		for sourceModel := range group.ModelRedirectMap {
+			ids = append(ids, sourceModel)
+		}
+		sort.Strings(ids)
+		b.configuredModels = ids
+	})
+	return b.configuredModels
+}
+
+// buildConfiguredModels builds a list of models from redirect rules.
+// Fresh model objects are built on every call since the response may be annotated in place.
+func buildConfiguredModels(modelIDs []string) []any {
+	if len(modelIDs) == 0 {
 		return []any{}
 	}
 
-	models := make([]any, 0, len(redirectMap))
-	for sourceModel := range redirectMap {
+	models := make([]any, 0, len(modelIDs))
+	for _, sourceModel := range modelIDs {
 		models = append(models, map[string]any{
 			"id":       sourceModel,
 			"object":   "model",
@@ -244,7 +349,7 @@ func buildConfiguredModels(redirectMap map[string]string) []any {
 // mergeModelLists merges upstream and configured model lists
 func mergeModelLists(upstream []any, configured []any) []any {
 	// Create set of upstream model IDs
-	upstreamIDs := make(map[string]bool)
+	upstreamIDs := make(map[string]bool, len(upstream))
 	for _, item := range upstream {
 		if modelObj, ok := item.(map[string]any); ok {
 			if modelID, ok := modelObj["id"].(string); ok {
@@ -254,7 +359,7 @@ func mergeModelLists(upstream []any, configured []any) []any {
 	}
 
 	// Start with all upstream models
-	result := make([]any, len(upstream))
+	result := make([]any, len(upstream), len(upstream)+len(configured))
 	copy(result, upstream)
 
 	// Add configured models that don't exist in upstream
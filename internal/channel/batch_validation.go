@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+// KeyValidationResult holds the outcome of validating one key in a batch.
+type KeyValidationResult struct {
+	APIKey  *models.APIKey
+	IsValid bool
+	Err     error
+	Timings *ValidationTimings
+}
+
+// BatchKeyValidator is implemented by channels that can validate many keys concurrently.
+// Results are returned in the order of the input keys.
+type BatchKeyValidator interface {
+	ValidateKeys(ctx context.Context, keys []*models.APIKey, group *models.Group) []KeyValidationResult
+}
+
+// validateKeysConcurrently runs validate for each key through a pool of at most concurrency workers.
+// Each key gets its own timeout; keys not started before ctx is cancelled are reported with ctx's error.
+func validateKeysConcurrently(
+	ctx context.Context,
+	keys []*models.APIKey,
+	group *models.Group,
+	concurrency int,
+	validate func(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error),
+) []KeyValidationResult {
+	results := make([]KeyValidationResult, len(keys))
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	timeout := time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds) * time.Second
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(keys)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				keyCtx, timings := WithValidationTimings(ctx)
+				keyCtx, cancel := context.WithTimeout(keyCtx, timeout)
+				isValid, err := validate(keyCtx, keys[i], group)
+				cancel()
+				results[i] = KeyValidationResult{APIKey: keys[i], IsValid: isValid, Err: err, Timings: timings}
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(keys); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(keys); i++ {
+		results[i] = KeyValidationResult{APIKey: keys[i], Err: ctx.Err()}
+	}
+	return results
+}
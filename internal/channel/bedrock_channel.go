@@ -0,0 +1,214 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// bedrockSigningService is the SigV4 service name of the Bedrock runtime API.
+	bedrockSigningService = "bedrock"
+	bedrockDefaultRegion  = "us-east-1"
+)
+
+func init() {
+	Register("bedrock", newBedrockChannel)
+}
+
+// BedrockChannel proxies the AWS Bedrock runtime API (/model/{modelId}/invoke, /invoke-with-response-stream,
+// /converse and /converse-stream). Keys hold AWS credentials and every request is signed with SigV4.
+type BedrockChannel struct {
+	*BaseChannel
+}
+
+func newBedrockChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("bedrock", group)
+	if err != nil {
+		return nil, err
+	}
+	base.Signer = &SigV4Signer{Service: bedrockSigningService, DefaultRegion: bedrockDefaultRegion}
+
+	return &BedrockChannel{
+		BaseChannel: base,
+	}, nil
+}
+
+// BuildUpstreamURL keeps the request path escaped, since model ARNs in it contain escaped slashes.
+func (ch *BedrockChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error) {
+	base := ch.getUpstreamURL()
+	if base == nil {
+		return "", fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *base
+	finalURL.RawPath = strings.TrimRight(base.EscapedPath(), "/") + strings.TrimPrefix(originalURL.EscapedPath(), "/proxy/"+groupName)
+	path, err := url.PathUnescape(finalURL.RawPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid request path: %w", err)
+	}
+	finalURL.Path = path
+	ch.withPathPrefix(&finalURL)
+	finalURL.RawQuery = originalURL.RawQuery
+
+	return finalURL.String(), nil
+}
+
+// ModifyRequest validates the key's credentials; the request itself is signed by the proxy once its headers are final.
+func (ch *BedrockChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
+	if _, err := parseAWSCredentials(apiKey.KeyValue); err != nil {
+		return err
+	}
+	if req.Header.Get("Content-Type") == "" && req.ContentLength > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return nil
+}
+
+// IsStreamRequest checks for the streaming actions of the Bedrock runtime API.
+func (ch *BedrockChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
+	path := c.Request.URL.Path
+	return strings.HasSuffix(path, "/invoke-with-response-stream") || strings.HasSuffix(path, "/converse-stream")
+}
+
+// ExtractModel returns the model ID or ARN of the /model/{modelId}/... path segment.
+func (ch *BedrockChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	model, _, _ := bedrockPathModel(c.Request.URL)
+	return model
+}
+
+// ApplyModelRedirect rewrites the model of the request path. Redirect targets may be model IDs, inference
+// profile IDs or full ARNs such as "arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-5-sonnet-20241022-v2:0".
+func (ch *BedrockChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if len(group.ModelRedirectMap) == 0 {
+		return bodyBytes, nil
+	}
+	model, index, ok := bedrockPathModel(req.URL)
+	if !ok {
+		return bodyBytes, nil
+	}
+
+	targetModel, found := redirectTarget(req, group, model)
+	if !found {
+		if group.ModelRedirectStrict {
+			return nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+		}
+		return bodyBytes, nil
+	}
+	// Identity redirects only allow the model in strict mode, leave the path untouched.
+	if targetModel == model {
+		return bodyBytes, nil
+	}
+
+	originalPath := req.URL.Path
+	setBedrockPathModel(req.URL, index, targetModel)
+
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group":          group.Name,
+		"original_model": model,
+		"target_model":   targetModel,
+		"channel":        "bedrock",
+		"original_path":  originalPath,
+		"new_path":       req.URL.Path,
+	}).Debug("Model redirected")
+
+	return bodyBytes, nil
+}
+
+// ValidateKey checks the key's credentials with a minimal Converse request to the test model.
+func (ch *BedrockChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.RawPath = strings.TrimRight(upstreamURL.EscapedPath(), "/") + "/model/" + url.PathEscape(ch.TestModel) + "/converse"
+	finalURL.Path = strings.TrimRight(upstreamURL.Path, "/") + "/model/" + ch.TestModel + "/converse"
+	ch.withPathPrefix(&finalURL)
+
+	payload := gin.H{
+		"messages": []gin.H{
+			{"role": "user", "content": []gin.H{{"text": "hi"}}},
+		},
+		"inferenceConfig": gin.H{"maxTokens": 1},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", finalURL.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+	if err := ch.SignRequest(req, apiKey, group); err != nil {
+		return false, err
+	}
+
+	upstreamStart := time.Now()
+	resp, err := ch.ClientForKey(apiKey, false).Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
+
+// bedrockPathModel returns the unescaped model of a /model/{modelId}/... path and the index of its segment.
+// ARNs contain slashes, so the segment is read from the escaped path.
+func bedrockPathModel(u *url.URL) (string, int, bool) {
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if segment == "model" && i+1 < len(segments) && segments[i+1] != "" {
+			model, err := url.PathUnescape(segments[i+1])
+			if err != nil {
+				return "", 0, false
+			}
+			return model, i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// setBedrockPathModel replaces the model segment at index with an escaped model ID or ARN.
+func setBedrockPathModel(u *url.URL, index int, model string) {
+	segments := strings.Split(u.EscapedPath(), "/")
+	segments[index] = url.PathEscape(model)
+	rawPath := strings.Join(segments, "/")
+	if path, err := url.PathUnescape(rawPath); err == nil {
+		u.Path = path
+		u.RawPath = rawPath
+	}
+}
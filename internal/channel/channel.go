@@ -5,6 +5,7 @@ import (
 	"gpt-load/internal/models"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,6 +24,9 @@ type ChannelProxy interface {
 	// GetStreamClient returns the client for streaming requests.
 	GetStreamClient() *http.Client
 
+	// ClientForKey returns the client to use for a key, honoring the key's own proxy URL.
+	ClientForKey(apiKey *models.APIKey, isStream bool) *http.Client
+
 	// ModifyRequest allows the channel to add specific headers or modify the request
 	ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error
 
@@ -40,4 +44,57 @@ type ChannelProxy interface {
 
 	// TransformModelList transforms the model list response based on redirect rules.
 	TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error)
+
+	// ParseRetryAfter returns how long a rate-limited key should rest, as reported by the upstream, or 0 if unknown.
+	ParseRetryAfter(resp *http.Response) time.Duration
+}
+
+// WebSocketProxy is implemented by channels that relay WebSocket connections, such as bidirectional streaming APIs.
+type WebSocketProxy interface {
+	// SupportsWebSocket reports whether upgrade requests for the given proxy path can be relayed.
+	SupportsWebSocket(path string) bool
+
+	// ModifyWebSocketRequest prepares the upgrade request sent upstream, e.g. rewriting its path and adding credentials.
+	ModifyWebSocketRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error
+}
+
+// FormatTranslator is implemented by channels that accept requests in another API format than their
+// upstream's, as selected by the group's client_api_format.
+type FormatTranslator interface {
+	// TranslateRequest converts a client request to the upstream format, rewriting the request path and
+	// returning the new body. It returns a nil ResponseTranslator if the request is left as it is.
+	TranslateRequest(req *http.Request, body []byte, group *models.Group) ([]byte, ResponseTranslator, error)
+}
+
+// ResponseTranslator converts the successful response of a translated request back to the client format.
+type ResponseTranslator interface {
+	// TranslateBody converts a complete response body.
+	TranslateBody(body []byte) ([]byte, error)
+
+	// TranslateEvent converts the data of one upstream SSE event into the SSE text sent to the client,
+	// which may be empty.
+	TranslateEvent(data []byte) ([]byte, error)
+
+	// FinishStream returns the SSE text that ends the client stream.
+	FinishStream() []byte
+}
+
+// UpstreamHealthReporter is implemented by channels that steer requests away from failing upstreams.
+type UpstreamHealthReporter interface {
+	// ReportUpstreamResult records the outcome of a request sent to a URL built by BuildUpstreamURL.
+	ReportUpstreamResult(upstreamURL string, healthy bool)
+}
+
+// UpstreamResponseObserver is implemented by channels that adapt to failed upstream responses,
+// e.g. by steering later requests of the same key elsewhere.
+type UpstreamResponseObserver interface {
+	ObserveUpstreamResponse(req *http.Request, apiKey *models.APIKey, group *models.Group, resp *http.Response)
+}
+
+// EndpointFallback is implemented by channels that can serve a request through another endpoint of the
+// same upstream when the first one rejects it as unsupported, e.g. because the model lacks the method.
+type EndpointFallback interface {
+	// FallbackRequest returns the request that retries a failed one, built from the body sent, and the
+	// translator of its response. It returns a nil request if the failure does not call for a fallback.
+	FallbackRequest(req *http.Request, body []byte, group *models.Group, statusCode int, errorBody []byte) (*http.Request, ResponseTranslator, error)
 }
@@ -0,0 +1,46 @@
+package channel
+
+import (
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"mime"
+	"strings"
+)
+
+// defaultAllowedContentTypes lists the request content types accepted by channels that restrict them by default.
+var defaultAllowedContentTypes = map[string][]string{
+	"vertex_gemini": {"application/json", "multipart/*"},
+	"bedrock":       {"application/json"},
+}
+
+// AllowedContentTypes returns the request content types accepted for a group, or nil when any type is allowed.
+func AllowedContentTypes(group *models.Group) []string {
+	if configured := utils.SplitAndTrim(group.EffectiveConfig.AllowedContentTypes, ","); len(configured) > 0 {
+		return configured
+	}
+	return defaultAllowedContentTypes[group.ChannelType]
+}
+
+// IsContentTypeAllowed checks a Content-Type header value against an allow list.
+// Entries may use a subtype wildcard such as "multipart/*".
+func IsContentTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(entry, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
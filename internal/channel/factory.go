@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
+	"gpt-load/internal/store"
 	"gpt-load/internal/utils"
 	"net/url"
 	"sync"
@@ -43,15 +45,24 @@ func GetChannels() []string {
 type Factory struct {
 	settingsManager *config.SystemSettingsManager
 	clientManager   *httpclient.HTTPClientManager
+	store           store.Store
+	encryptionSvc   encryption.Service
 	channelCache    map[uint]ChannelProxy
 	cacheLock       sync.Mutex
 }
 
 // NewFactory creates a new channel factory.
-func NewFactory(settingsManager *config.SystemSettingsManager, clientManager *httpclient.HTTPClientManager) *Factory {
+func NewFactory(
+	settingsManager *config.SystemSettingsManager,
+	clientManager *httpclient.HTTPClientManager,
+	store store.Store,
+	encryptionSvc encryption.Service,
+) *Factory {
 	return &Factory{
 		settingsManager: settingsManager,
 		clientManager:   clientManager,
+		store:           store,
+		encryptionSvc:   encryptionSvc,
 		channelCache:    make(map[uint]ChannelProxy),
 	}
 }
@@ -109,10 +120,19 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		upstreamInfos = append(upstreamInfos, UpstreamInfo{URL: u, Weight: def.Weight})
 	}
 
+	tlsMinVersion, err := httpclient.ParseTLSVersion(group.EffectiveConfig.TLSMinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings for %s channel: %w", name, err)
+	}
+	cipherSuites, err := httpclient.ParseCipherSuites(group.EffectiveConfig.TLSCipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings for %s channel: %w", name, err)
+	}
+
 	// Base configuration for regular requests, derived from the group's effective settings.
 	clientConfig := &httpclient.Config{
 		ConnectTimeout:        time.Duration(group.EffectiveConfig.ConnectTimeout) * time.Second,
-		RequestTimeout:        time.Duration(group.EffectiveConfig.RequestTimeout) * time.Second,
+		RequestTimeout:        time.Duration(max(group.EffectiveConfig.RequestTimeout, group.EffectiveConfig.MaxRequestTimeout)) * time.Second,
 		IdleConnTimeout:       time.Duration(group.EffectiveConfig.IdleConnTimeout) * time.Second,
 		MaxIdleConns:          group.EffectiveConfig.MaxIdleConns,
 		MaxIdleConnsPerHost:   group.EffectiveConfig.MaxIdleConnsPerHost,
@@ -122,8 +142,11 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		WriteBufferSize:       32 * 1024,
 		ReadBufferSize:        32 * 1024,
 		ForceAttemptHTTP2:     true,
-		TLSHandshakeTimeout:   15 * time.Second,
+		TLSHandshakeTimeout:   time.Duration(group.EffectiveConfig.TLSHandshakeTimeout) * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
+		TLSMinVersion:         tlsMinVersion,
+		CipherSuites:          cipherSuites,
+		PriorKnowledgeHTTP2:   group.EffectiveConfig.ForceHTTP2,
 	}
 
 	// Create a dedicated configuration for streaming requests.
@@ -152,5 +175,9 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		effectiveConfig:     &group.EffectiveConfig,
 		modelRedirectRules:  group.ModelRedirectRules,
 		modelRedirectStrict: group.ModelRedirectStrict,
+		clientManager:       f.clientManager,
+		clientConfig:        *clientConfig,
+		streamConfig:        streamConfig,
+		pathPrefix:          normalizeUpstreamPathPrefix(group.EffectiveConfig.UpstreamPathPrefix),
 	}, nil
 }
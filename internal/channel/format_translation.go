@@ -0,0 +1,42 @@
+package channel
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Client API formats
+const (
+	ClientAPIFormatNative = "native"
+	ClientAPIFormatOpenAI = "openai"
+	ClientAPIFormatGemini = "gemini"
+)
+
+// sseData frames a payload as an SSE "data:" event.
+func sseData(payload any) []byte {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return append(append([]byte("data: "), data...), '\n', '\n')
+}
+
+// textOfContent joins the text of an OpenAI message content, which is a string or a list of parts.
+func textOfContent(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		var texts []string
+		for _, item := range v {
+			if part, ok := item.(map[string]any); ok && part["type"] == "text" {
+				if text, ok := part["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	default:
+		return ""
+	}
+}
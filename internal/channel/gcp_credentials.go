@@ -0,0 +1,27 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// gcpAdminTokenTimeout bounds the token exchange of administrative GCP calls.
+const gcpAdminTokenTimeout = 30 * time.Second
+
+// MintServiceAccountAccessToken mints a cloud-platform access token for a GCP service account JSON, for
+// administrative calls outside of proxying such as key imports. It returns the token and the account email.
+func MintServiceAccountAccessToken(ctx context.Context, client *http.Client, keyJSON string) (string, string, error) {
+	sa, err := parseGCPServiceAccount(keyJSON)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Minting does not depend on channel state, so no group channel is needed.
+	token, _, err := (&VertexGeminiChannel{}).mintAccessTokenFromServiceAccount(ctx, client, sa, []string{vertexOAuthScope}, "", gcpAdminTokenTimeout)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to mint access token for %s: %w", sa.ClientEmail, err)
+	}
+	return token, sa.ClientEmail, nil
+}
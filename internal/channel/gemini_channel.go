@@ -10,8 +10,8 @@ import (
 	"gpt-load/internal/utils"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -38,7 +38,7 @@ func newGeminiChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
 
 // ModifyRequest adds the API key as a query parameter for Gemini requests.
 func (ch *GeminiChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
-	if strings.Contains(req.URL.Path, "v1beta/openai") {
+	if ch.isOpenAICompatiblePath(req.URL.Path, "v1beta", "openai") {
 		req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
 	} else {
 		q := req.URL.Query()
@@ -105,11 +105,9 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	}
 
 	// Safely join the path segments
-	reqURL, err := url.JoinPath(upstreamURL.String(), "v1beta", "models", ch.TestModel+":generateContent")
-	if err != nil {
-		return false, fmt.Errorf("failed to create gemini validation path: %w", err)
-	}
-	reqURL += "?key=" + apiKey.KeyValue
+	finalURL := upstreamURL.JoinPath("v1beta", "models", ch.TestModel+":generateContent")
+	ch.withPathPrefix(finalURL)
+	reqURL := finalURL.String() + "?key=" + apiKey.KeyValue
 
 	payload := gin.H{
 		"contents": []gin.H{
@@ -138,7 +136,9 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
-	resp, err := ch.HTTPClient.Do(req)
+	upstreamStart := time.Now()
+	resp, err := ch.ClientForKey(apiKey, false).Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
 	if err != nil {
 		return false, fmt.Errorf("failed to send validation request: %w", err)
 	}
@@ -167,7 +167,7 @@ func (ch *GeminiChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte,
 		return bodyBytes, nil
 	}
 
-	if strings.Contains(req.URL.Path, "v1beta/openai") {
+	if ch.isOpenAICompatiblePath(req.URL.Path, "v1beta", "openai") {
 		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
 	}
 
@@ -184,7 +184,12 @@ func (ch *GeminiChannel) applyNativeFormatRedirect(req *http.Request, bodyBytes
 			modelPart := parts[i+1]
 			originalModel := strings.Split(modelPart, ":")[0]
 
-			if targetModel, found := group.ModelRedirectMap[originalModel]; found {
+			if targetModel, found := redirectTarget(req, group, originalModel); found {
+				// Identity redirects only allow the model in strict mode, leave the path untouched.
+				if targetModel == originalModel {
+					return bodyBytes, nil
+				}
+
 				suffix := ""
 				if colonIndex := strings.Index(modelPart, ":"); colonIndex != -1 {
 					suffix = modelPart[colonIndex:]
@@ -192,7 +197,7 @@ func (ch *GeminiChannel) applyNativeFormatRedirect(req *http.Request, bodyBytes
 				parts[i+1] = targetModel + suffix
 				req.URL.Path = strings.Join(parts, "/")
 
-				logrus.WithFields(logrus.Fields{
+				utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 					"group":          group.Name,
 					"original_model": originalModel,
 					"target_model":   targetModel,
@@ -218,12 +223,14 @@ func (ch *GeminiChannel) applyNativeFormatRedirect(req *http.Request, bodyBytes
 func (ch *GeminiChannel) TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error) {
 	var response map[string]any
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
-		logrus.WithError(err).Debug("Failed to parse model list response, returning empty")
+		utils.LoggerFromContext(req.Context()).WithError(err).Debug("Failed to parse model list response, returning empty")
 		return nil, err
 	}
 
 	if modelsInterface, hasModels := response["models"]; hasModels {
-		return ch.transformGeminiNativeFormat(req, response, modelsInterface, group), nil
+		result := ch.transformGeminiNativeFormat(req, response, modelsInterface, group)
+		annotateModelCapabilities(result, group)
+		return result, nil
 	}
 
 	if _, hasData := response["data"]; hasData {
@@ -240,14 +247,14 @@ func (ch *GeminiChannel) transformGeminiNativeFormat(req *http.Request, response
 		return response
 	}
 
-	configuredModels := buildConfiguredGeminiModels(group.ModelRedirectMap)
+	configuredModels := buildConfiguredGeminiModels(ch.configuredModelIDs(group))
 
-	// Strict mode: return only configured models (whitelist)
+	// Strict mode: return only configured models (whitelist), whatever page was requested, as a single page
 	if group.ModelRedirectStrict {
 		response["models"] = configuredModels
 		delete(response, "nextPageToken")
 
-		logrus.WithFields(logrus.Fields{
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 			"group":       group.Name,
 			"model_count": len(configuredModels),
 			"strict_mode": true,
@@ -257,11 +264,11 @@ func (ch *GeminiChannel) transformGeminiNativeFormat(req *http.Request, response
 		return response
 	}
 
-	// Non-strict mode: merge upstream + configured models (upstream priority)
+	// Non-strict mode: merge upstream + configured models on the first page
 	var merged []any
 	if isFirstPage(req) {
 		merged = mergeGeminiModelLists(upstreamModels, configuredModels)
-		logrus.WithFields(logrus.Fields{
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 			"group":            group.Name,
 			"upstream_count":   len(upstreamModels),
 			"configured_count": len(configuredModels),
@@ -271,8 +278,9 @@ func (ch *GeminiChannel) transformGeminiNativeFormat(req *http.Request, response
 			"page":             "first",
 		}).Debug("Model list merged (non-strict mode - first page)")
 	} else {
-		merged = upstreamModels
-		logrus.WithFields(logrus.Fields{
+		// Configured models were listed on the first page already.
+		merged = excludeGeminiModels(upstreamModels, configuredModels)
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 			"group":          group.Name,
 			"upstream_count": len(upstreamModels),
 			"strict_mode":    false,
@@ -286,13 +294,13 @@ func (ch *GeminiChannel) transformGeminiNativeFormat(req *http.Request, response
 }
 
 // buildConfiguredGeminiModels builds a list of models from redirect rules for Gemini format
-func buildConfiguredGeminiModels(redirectMap map[string]string) []any {
-	if len(redirectMap) == 0 {
+func buildConfiguredGeminiModels(modelIDs []string) []any {
+	if len(modelIDs) == 0 {
 		return []any{}
 	}
 
-	models := make([]any, 0, len(redirectMap))
-	for sourceModel := range redirectMap {
+	models := make([]any, 0, len(modelIDs))
+	for _, sourceModel := range modelIDs {
 		modelName := sourceModel
 		if !strings.HasPrefix(sourceModel, "models/") {
 			modelName = "models/" + sourceModel
@@ -307,35 +315,77 @@ func buildConfiguredGeminiModels(redirectMap map[string]string) []any {
 	return models
 }
 
-// mergeGeminiModelLists merges upstream and configured model lists for Gemini format
+// geminiModelID returns the model id of a Gemini model list entry, without the "models/" prefix.
+func geminiModelID(item any) (string, bool) {
+	modelObj, ok := item.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	modelName, ok := modelObj["name"].(string)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimPrefix(modelName, "models/"), true
+}
+
+// mergeGeminiModelLists merges upstream and configured model lists for Gemini format. Each model id
+// appears once: upstream models keep their order, a configured model replaces the upstream entry of the
+// same id in place, and configured models missing upstream follow in their configured order.
 func mergeGeminiModelLists(upstream []any, configured []any) []any {
-	upstreamNames := make(map[string]bool)
-	for _, item := range upstream {
-		if modelObj, ok := item.(map[string]any); ok {
-			if modelName, ok := modelObj["name"].(string); ok {
-				upstreamNames[modelName] = true
-				cleanName := strings.TrimPrefix(modelName, "models/")
-				upstreamNames[cleanName] = true
+	configuredByID := make(map[string]any, len(configured))
+	for _, item := range configured {
+		if id, ok := geminiModelID(item); ok {
+			if _, exists := configuredByID[id]; !exists {
+				configuredByID[id] = item
 			}
 		}
 	}
 
-	// Start with all upstream models
-	result := make([]any, len(upstream))
-	copy(result, upstream)
+	result := make([]any, 0, len(upstream)+len(configured))
+	seen := make(map[string]bool, len(upstream)+len(configured))
+	for _, item := range upstream {
+		id, ok := geminiModelID(item)
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if configuredItem, ok := configuredByID[id]; ok {
+			item = configuredItem
+		}
+		result = append(result, item)
+	}
 
-	// Add configured models that don't exist in upstream
 	for _, item := range configured {
-		if modelObj, ok := item.(map[string]any); ok {
-			if modelName, ok := modelObj["name"].(string); ok {
-				cleanName := strings.TrimPrefix(modelName, "models/")
-				if !upstreamNames[modelName] && !upstreamNames[cleanName] {
-					result = append(result, item)
-				}
-			}
+		if id, ok := geminiModelID(item); ok && !seen[id] {
+			seen[id] = true
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// excludeGeminiModels drops the models of a later page of a paginated list that are configured, since the
+// first page lists every configured model. Each model then appears once across all pages.
+func excludeGeminiModels(upstream []any, configured []any) []any {
+	configuredIDs := make(map[string]bool, len(configured))
+	for _, item := range configured {
+		if id, ok := geminiModelID(item); ok {
+			configuredIDs[id] = true
 		}
 	}
 
+	result := make([]any, 0, len(upstream))
+	for _, item := range upstream {
+		if id, ok := geminiModelID(item); ok && configuredIDs[id] {
+			continue
+		}
+		result = append(result, item)
+	}
 	return result
 }
 
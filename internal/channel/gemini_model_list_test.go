@@ -0,0 +1,226 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+// geminiModelItems builds Gemini model list entries, tagging each with its source list.
+func geminiModelItems(source string, ids ...string) []any {
+	items := make([]any, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, map[string]any{"name": "models/" + id, "source": source})
+	}
+	return items
+}
+
+// describeGeminiModels returns "id@source" for each entry of a model list.
+func describeGeminiModels(items []any) []string {
+	described := make([]string, 0, len(items))
+	for _, item := range items {
+		id, ok := geminiModelID(item)
+		if !ok {
+			described = append(described, "?")
+			continue
+		}
+		described = append(described, id+"@"+item.(map[string]any)["source"].(string))
+	}
+	return described
+}
+
+func TestMergeGeminiModelLists(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   []any
+		configured []any
+		want       []string
+	}{
+		{
+			name:     "no configured models",
+			upstream: geminiModelItems("up", "a", "b"),
+			want:     []string{"a@up", "b@up"},
+		},
+		{
+			name:       "configured model replaces upstream entry in place",
+			upstream:   geminiModelItems("up", "a", "b", "c"),
+			configured: geminiModelItems("cfg", "b"),
+			want:       []string{"a@up", "b@cfg", "c@up"},
+		},
+		{
+			name:       "missing configured models follow in configured order",
+			upstream:   geminiModelItems("up", "a"),
+			configured: geminiModelItems("cfg", "z", "y"),
+			want:       []string{"a@up", "z@cfg", "y@cfg"},
+		},
+		{
+			name:       "duplicates listed once",
+			upstream:   geminiModelItems("up", "a", "a", "b"),
+			configured: append(geminiModelItems("cfg", "c", "c"), geminiModelItems("cfg2", "a")...),
+			want:       []string{"a@cfg2", "b@up", "c@cfg"},
+		},
+		{
+			name:       "first configured entry of an id wins",
+			upstream:   geminiModelItems("up", "a"),
+			configured: append(geminiModelItems("cfg", "a"), geminiModelItems("cfg2", "a")...),
+			want:       []string{"a@cfg"},
+		},
+		{
+			name:       "empty upstream",
+			configured: geminiModelItems("cfg", "a", "b"),
+			want:       []string{"a@cfg", "b@cfg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeGeminiModels(mergeGeminiModelLists(tt.upstream, tt.configured))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mergeGeminiModelLists = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeGeminiModelListsKeepsEntriesWithoutName(t *testing.T) {
+	upstream := append(geminiModelItems("up", "a"), map[string]any{"displayName": "unnamed"}, "not a model")
+	got := mergeGeminiModelLists(upstream, geminiModelItems("cfg", "b"))
+	if len(got) != 4 {
+		t.Fatalf("mergeGeminiModelLists kept %d entries, want 4: %v", len(got), got)
+	}
+	if want := []string{"a@up", "?", "?", "b@cfg"}; !slices.Equal(describeGeminiModels(got), want) {
+		t.Errorf("mergeGeminiModelLists = %v, want %v", describeGeminiModels(got), want)
+	}
+}
+
+func TestExcludeGeminiModels(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   []any
+		configured []any
+		want       []string
+	}{
+		{
+			name:     "no configured models",
+			upstream: geminiModelItems("up", "a", "b"),
+			want:     []string{"a@up", "b@up"},
+		},
+		{
+			name:       "configured models dropped",
+			upstream:   geminiModelItems("up", "a", "b", "c"),
+			configured: geminiModelItems("cfg", "c", "a"),
+			want:       []string{"b@up"},
+		},
+		{
+			name:       "configured models missing upstream ignored",
+			upstream:   geminiModelItems("up", "a"),
+			configured: geminiModelItems("cfg", "z"),
+			want:       []string{"a@up"},
+		},
+		{
+			name:       "all dropped",
+			upstream:   geminiModelItems("up", "a"),
+			configured: geminiModelItems("cfg", "a"),
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeGeminiModels(excludeGeminiModels(tt.upstream, tt.configured))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("excludeGeminiModels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// largeModelCatalog returns a redirect map of n configured models and an upstream Gemini model list of n
+// models, half of which are also configured.
+func largeModelCatalog(n int) (map[string]string, []byte) {
+	redirects := make(map[string]string, n)
+	upstream := make([]any, 0, n)
+	for i := range n {
+		redirects[fmt.Sprintf("configured-%05d", i)] = "gemini-2.0-flash"
+		id := fmt.Sprintf("upstream-%05d", i)
+		if i%2 == 0 {
+			id = fmt.Sprintf("configured-%05d", i)
+		}
+		upstream = append(upstream, map[string]any{"name": "models/" + id, "supportedGenerationMethods": []string{"generateContent"}})
+	}
+	body, _ := json.Marshal(map[string]any{"models": upstream})
+	return redirects, body
+}
+
+// mergeGeminiModelListsNested is the previous merge, which scanned the configured models for every upstream model.
+func mergeGeminiModelListsNested(upstream []any, configured []any) []any {
+	var result []any
+	for _, item := range upstream {
+		id, _ := geminiModelID(item)
+		for _, configuredItem := range configured {
+			if configuredID, _ := geminiModelID(configuredItem); configuredID == id {
+				item = configuredItem
+				break
+			}
+		}
+		result = append(result, item)
+	}
+	for _, configuredItem := range configured {
+		configuredID, _ := geminiModelID(configuredItem)
+		found := false
+		for _, item := range upstream {
+			if id, _ := geminiModelID(item); id == configuredID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, configuredItem)
+		}
+	}
+	return result
+}
+
+func BenchmarkMergeGeminiModelLists(b *testing.B) {
+	redirects, body := largeModelCatalog(5000)
+	var list struct {
+		Models []any `json:"models"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		b.Fatal(err)
+	}
+	ids := make([]string, 0, len(redirects))
+	for id := range redirects {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	configured := buildConfiguredGeminiModels(ids)
+
+	b.Run("indexed", func(b *testing.B) {
+		for range b.N {
+			mergeGeminiModelLists(list.Models, configured)
+		}
+	})
+	b.Run("nested", func(b *testing.B) {
+		for range b.N {
+			mergeGeminiModelListsNested(list.Models, configured)
+		}
+	})
+}
+
+func BenchmarkTransformModelListLargeRedirectMap(b *testing.B) {
+	redirects, body := largeModelCatalog(5000)
+	group := &models.Group{Name: "g", ModelRedirectMap: redirects}
+	ch := &GeminiChannel{BaseChannel: &BaseChannel{}}
+
+	b.ResetTimer()
+	for range b.N {
+		req := httptest.NewRequest(http.MethodGet, "/v1beta/models", nil)
+		if _, err := ch.TransformModelList(req, body, group); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+)
+
+// vertexIAMKeyURI reads the metadata of a service account key through the IAM API.
+const vertexIAMKeyURI = "https://iam.googleapis.com/v1/projects/-/serviceAccounts/%s/keys/%s"
+
+// KeyExpiryResolver is implemented by channels that can look up when a key expires upstream.
+type KeyExpiryResolver interface {
+	// ResolveKeyExpiry returns the expiry of the key, or nil if it does not expire.
+	ResolveKeyExpiry(ctx context.Context, apiKey *models.APIKey, group *models.Group) (*time.Time, error)
+}
+
+// ResolveKeyExpiry reads the validBeforeTime of the key's service accounts from the IAM API, which the
+// accounts may read for their own keys when granted iam.serviceAccountKeys.get. Of several accounts the
+// earliest expiry counts. Keys without expiry report a validBeforeTime in the year 9999.
+func (ch *VertexGeminiChannel) ResolveKeyExpiry(ctx context.Context, apiKey *models.APIKey, group *models.Group) (*time.Time, error) {
+	accounts, err := parseGCPServiceAccounts(apiKey.KeyValue)
+	if err != nil {
+		return nil, err
+	}
+
+	client := ch.ClientForKey(apiKey, false)
+	var earliest *time.Time
+	for _, sa := range accounts {
+		if sa.PrivateKeyID == "" {
+			continue
+		}
+		accessToken, err := ch.getOrMintAccessToken(ctx, client, sa, group)
+		if err != nil {
+			return nil, err
+		}
+		expiry, err := ch.serviceAccountKeyExpiry(ctx, client, sa, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		if expiry != nil && (earliest == nil || expiry.Before(*earliest)) {
+			earliest = expiry
+		}
+	}
+	return earliest, nil
+}
+
+func (ch *VertexGeminiChannel) serviceAccountKeyExpiry(ctx context.Context, client *http.Client, sa gcpServiceAccount, accessToken string) (*time.Time, error) {
+	reqURL := fmt.Sprintf(vertexIAMKeyURI, url.PathEscape(sa.ClientEmail), url.PathEscape(sa.PrivateKeyID))
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key metadata request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key metadata of %s: %w", sa.ClientEmail, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key metadata of %s: %w", sa.ClientEmail, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to read key metadata of %s: [status %d] %s", sa.ClientEmail, resp.StatusCode, app_errors.ParseUpstreamError(bodyBytes))
+	}
+
+	var metadata struct {
+		ValidBeforeTime time.Time `json:"validBeforeTime"`
+	}
+	if err := json.Unmarshal(bodyBytes, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse key metadata of %s: %w", sa.ClientEmail, err)
+	}
+	if metadata.ValidBeforeTime.IsZero() || metadata.ValidBeforeTime.Year() >= 9999 {
+		return nil, nil
+	}
+	return &metadata.ValidBeforeTime, nil
+}
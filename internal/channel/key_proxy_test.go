@@ -0,0 +1,83 @@
+package channel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+)
+
+// newTestProxy starts a forward proxy that answers every request itself with its name, recording the requested URLs.
+func newTestProxy(t *testing.T, name string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requested []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.String())
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &requested
+}
+
+func TestClientForKeyEgressesThroughTheKeysProxy(t *testing.T) {
+	proxyA, requestedA := newTestProxy(t, "proxy-a")
+	proxyB, requestedB := newTestProxy(t, "proxy-b")
+
+	groupClient := &http.Client{}
+	b := &BaseChannel{
+		HTTPClient:    groupClient,
+		StreamClient:  groupClient,
+		clientManager: httpclient.NewHTTPClientManager(),
+		clientConfig:  httpclient.Config{ConnectTimeout: time.Second, RequestTimeout: 5 * time.Second},
+		streamConfig:  httpclient.Config{ConnectTimeout: time.Second},
+	}
+	keyA := &models.APIKey{ID: 1, ProxyURL: proxyA.URL}
+	keyB := &models.APIKey{ID: 2, ProxyURL: proxyB.URL}
+	keyA2 := &models.APIKey{ID: 3, ProxyURL: proxyA.URL}
+
+	clientA, clientB := b.ClientForKey(keyA, false), b.ClientForKey(keyB, false)
+	if clientA == clientB {
+		t.Fatal("keys with different proxies share a client")
+	}
+	if b.ClientForKey(keyA2, false) != clientA {
+		t.Error("keys with the same proxy got different clients")
+	}
+	if b.ClientForKey(keyA, true) == clientA {
+		t.Error("stream and non-stream requests of a key share a client")
+	}
+	if b.ClientForKey(&models.APIKey{ID: 4}, false) != groupClient || b.ClientForKey(nil, false) != groupClient {
+		t.Error("keys without a proxy do not use the group's client")
+	}
+
+	for _, tt := range []struct {
+		client *http.Client
+		want   string
+	}{
+		{clientA, "proxy-a"},
+		{clientB, "proxy-b"},
+		{b.ClientForKey(keyA, true), "proxy-a"},
+	} {
+		resp, err := tt.client.Get("http://upstream.example.com/v1/models")
+		if err != nil {
+			t.Fatalf("request through %s: %v", tt.want, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want {
+			t.Errorf("request egressed through %s, want %s", body, tt.want)
+		}
+	}
+
+	if len(*requestedA) != 2 || len(*requestedB) != 1 {
+		t.Errorf("proxy A saw %v, proxy B saw %v; want 2 and 1 requests", *requestedA, *requestedB)
+	}
+	for _, requested := range append(append([]string{}, *requestedA...), *requestedB...) {
+		if requested != "http://upstream.example.com/v1/models" {
+			t.Errorf("proxy was asked for %q, want the upstream URL", requested)
+		}
+	}
+}
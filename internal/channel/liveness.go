@@ -0,0 +1,13 @@
+package channel
+
+import (
+	"context"
+
+	"gpt-load/internal/models"
+)
+
+// LivenessChecker is implemented by channels that can verify a key is usable without a full
+// upstream round-trip. Channels without it are probed through ValidateKey.
+type LivenessChecker interface {
+	Liveness(ctx context.Context, apiKey *models.APIKey, group *models.Group) error
+}
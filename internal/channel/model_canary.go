@@ -0,0 +1,57 @@
+package channel
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redirectTarget returns the model a request for model is sent as, and whether a redirect rule covers the
+// model. Canary rules decide per request; see canaryTarget.
+func redirectTarget(req *http.Request, group *models.Group, model string) (string, bool) {
+	if canary, ok := group.ModelRedirectCanaries[model]; ok {
+		return canaryTarget(req, group, model, canary), true
+	}
+	target, found := group.ModelRedirectMap[model]
+	return target, found
+}
+
+// canaryTarget sends the canary percentage of a model's requests to the canary target. Requests of a session
+// identified by the group's session_affinity_header always take the same side; other requests are split at
+// random. Every decision is logged.
+func canaryTarget(req *http.Request, group *models.Group, model string, canary models.ModelRedirectCanary) string {
+	sessionID := ""
+	if header := group.EffectiveConfig.SessionAffinityHeader; header != "" {
+		sessionID = strings.TrimSpace(req.Header.Get(header))
+	}
+
+	var bucket int
+	if sessionID != "" {
+		hash := fnv.New32a()
+		hash.Write([]byte(model + "\x00" + sessionID))
+		bucket = int(hash.Sum32() % 100)
+	} else {
+		bucket = rand.Intn(100)
+	}
+
+	target := model
+	if bucket < canary.Percentage {
+		target = canary.Target
+	}
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group":          group.Name,
+		"original_model": model,
+		"canary_model":   canary.Target,
+		"percentage":     canary.Percentage,
+		"bucket":         bucket,
+		"sticky":         sessionID != "",
+		"canary":         target != model,
+	}).Info("Model canary decision")
+	return target
+}
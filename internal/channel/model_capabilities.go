@@ -0,0 +1,49 @@
+package channel
+
+import (
+	"gpt-load/internal/models"
+	"strings"
+)
+
+// annotateModelCapabilities attaches configured capability metadata to the models of a transformed model list.
+// Both OpenAI ("data" with "id") and Gemini ("models" with "name") shapes are supported.
+func annotateModelCapabilities(response map[string]any, group *models.Group) {
+	if response == nil || len(group.ModelCapabilityMap) == 0 {
+		return
+	}
+
+	annotate := func(list any, idField string) {
+		items, ok := list.([]any)
+		if !ok {
+			return
+		}
+		for _, item := range items {
+			modelObj, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			modelID, ok := modelObj[idField].(string)
+			if !ok {
+				continue
+			}
+			if capability, found := group.ModelCapabilityMap[normalizeModelName(modelID)]; found {
+				modelObj["capabilities"] = capability
+			}
+		}
+	}
+
+	if data, ok := response["data"]; ok {
+		annotate(data, "id")
+	}
+	if list, ok := response["models"]; ok {
+		annotate(list, "name")
+	}
+}
+
+// normalizeModelName strips resource prefixes such as "models/" or "publishers/google/models/" from a model name.
+func normalizeModelName(name string) string {
+	if idx := strings.LastIndex(name, "models/"); idx != -1 {
+		return name[idx+len("models/"):]
+	}
+	return name
+}
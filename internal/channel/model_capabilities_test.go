@@ -0,0 +1,114 @@
+package channel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestTransformModelListCarriesCapabilities(t *testing.T) {
+	capabilities := map[string]models.ModelCapability{
+		"gemini-2.0-flash": {Streaming: boolPtr(true), Tools: boolPtr(true), Vision: boolPtr(true), ContextWindow: 1048576},
+		"gemini-1.0-pro":   {Streaming: boolPtr(true), Vision: boolPtr(false)},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		listKey  string
+		idField  string
+		wantCaps map[string]string
+	}{
+		{
+			name:    "Gemini shape",
+			path:    "/v1beta/models",
+			body:    `{"models":[{"name":"models/gemini-2.0-flash"},{"name":"models/gemini-1.0-pro"},{"name":"models/text-embedding-004"}]}`,
+			listKey: "models",
+			idField: "name",
+			wantCaps: map[string]string{
+				"models/gemini-2.0-flash":   `{"streaming":true,"tools":true,"vision":true,"context_window":1048576}`,
+				"models/gemini-1.0-pro":     `{"streaming":true,"vision":false}`,
+				"models/text-embedding-004": "",
+			},
+		},
+		{
+			name:    "OpenAI shape",
+			path:    "/v1beta/openai/models",
+			body:    `{"object":"list","data":[{"id":"gemini-2.0-flash","object":"model"},{"id":"text-embedding-004","object":"model"}]}`,
+			listKey: "data",
+			idField: "id",
+			wantCaps: map[string]string{
+				"gemini-2.0-flash":   `{"streaming":true,"tools":true,"vision":true,"context_window":1048576}`,
+				"text-embedding-004": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &GeminiChannel{BaseChannel: &BaseChannel{}}
+			group := &models.Group{Name: "g", ModelCapabilityMap: capabilities}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			result, err := ch.TransformModelList(req, []byte(tt.body), group)
+			if err != nil {
+				t.Fatalf("TransformModelList: %v", err)
+			}
+			// Clients see the list as JSON.
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var list struct {
+				Models []map[string]json.RawMessage `json:"models"`
+				Data   []map[string]json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(encoded, &list); err != nil {
+				t.Fatalf("unmarshal %s: %v", encoded, err)
+			}
+			entries := list.Models
+			if tt.listKey == "data" {
+				entries = list.Data
+			}
+
+			seen := 0
+			for _, entry := range entries {
+				var id string
+				if err := json.Unmarshal(entry[tt.idField], &id); err != nil {
+					t.Fatalf("model entry %v has no %s", entry, tt.idField)
+				}
+				want, ok := tt.wantCaps[id]
+				if !ok {
+					continue
+				}
+				seen++
+				got := string(entry["capabilities"])
+				if got != want {
+					t.Errorf("%s capabilities = %s, want %s", id, got, want)
+				}
+			}
+			if seen != len(tt.wantCaps) {
+				t.Errorf("found %d of %d expected models in %s", seen, len(tt.wantCaps), encoded)
+			}
+		})
+	}
+}
+
+func TestTransformModelListWithoutCapabilities(t *testing.T) {
+	ch := &GeminiChannel{BaseChannel: &BaseChannel{}}
+	req := httptest.NewRequest(http.MethodGet, "/v1beta/models", nil)
+	result, err := ch.TransformModelList(req, []byte(`{"models":[{"name":"models/gemini-2.0-flash"}]}`), &models.Group{Name: "g"})
+	if err != nil {
+		t.Fatalf("TransformModelList: %v", err)
+	}
+	for _, item := range result["models"].([]any) {
+		if _, ok := item.(map[string]any)["capabilities"]; ok {
+			t.Errorf("model %v annotated without configured capabilities", item)
+		}
+	}
+}
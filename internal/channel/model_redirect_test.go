@@ -0,0 +1,88 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestIdentityRedirectIsSilentNoOp(t *testing.T) {
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() {
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+		logrus.SetLevel(level)
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		model    string
+		wantPath string
+		wantBody string
+	}{
+		{
+			name:     "Gemini native path",
+			path:     "/v1beta/models/gemini-2.0-flash:generateContent",
+			body:     `{"contents":[]}`,
+			model:    "gemini-2.0-flash",
+			wantPath: "/v1beta/models/gemini-2.0-flash:generateContent",
+			wantBody: `{"contents":[]}`,
+		},
+		{
+			name:     "OpenAI-compatible body",
+			path:     "/v1beta/openai/chat/completions",
+			body:     `{"model": "gemini-2.0-flash", "messages": []}`,
+			model:    "gemini-2.0-flash",
+			wantPath: "/v1beta/openai/chat/completions",
+			wantBody: `{"model": "gemini-2.0-flash", "messages": []}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &GeminiChannel{BaseChannel: &BaseChannel{}}
+			for _, strict := range []bool{false, true} {
+				hook.Reset()
+				group := &models.Group{
+					Name:                "g",
+					ModelRedirectMap:    map[string]string{tt.model: tt.model},
+					ModelRedirectStrict: strict,
+				}
+				req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+
+				body, err := ch.ApplyModelRedirect(req, []byte(tt.body), group)
+				if err != nil {
+					t.Fatalf("strict=%v: ApplyModelRedirect: %v", strict, err)
+				}
+				if req.URL.Path != tt.wantPath || string(body) != tt.wantBody {
+					t.Errorf("strict=%v: request rewritten to %s %s", strict, req.URL.Path, body)
+				}
+				for _, entry := range hook.AllEntries() {
+					if entry.Message == "Model redirected" {
+						t.Errorf("strict=%v: identity mapping logged %q with %v", strict, entry.Message, entry.Data)
+					}
+				}
+			}
+		})
+	}
+
+	// A real redirect is still logged, so the absence above is meaningful.
+	hook.Reset()
+	ch := &GeminiChannel{BaseChannel: &BaseChannel{}}
+	group := &models.Group{Name: "g", ModelRedirectMap: map[string]string{"gemini-pro": "gemini-2.0-flash"}}
+	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-pro:generateContent", nil)
+	if _, err := ch.ApplyModelRedirect(req, []byte(`{}`), group); err != nil {
+		t.Fatalf("ApplyModelRedirect: %v", err)
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Message != "Model redirected" {
+		t.Errorf("redirect to another model not logged, last entry %v", entry)
+	}
+}
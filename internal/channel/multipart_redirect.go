@@ -0,0 +1,109 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// multipartBoundary returns the boundary of a multipart/form-data request.
+func multipartBoundary(req *http.Request) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// applyMultipartModelRedirect applies redirect rules to the "model" field of a multipart/form-data body.
+// The body is re-encoded with the original boundary so the request's Content-Type stays valid.
+func (b *BaseChannel) applyMultipartModelRedirect(req *http.Request, bodyBytes []byte, boundary string, group *models.Group) ([]byte, error) {
+	model, err := readMultipartField(bodyBytes, boundary, "model")
+	if err != nil || model == "" {
+		return bodyBytes, nil
+	}
+
+	targetModel, found := redirectTarget(req, group, model)
+	if !found {
+		if group.ModelRedirectStrict {
+			return nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+		}
+		return bodyBytes, nil
+	}
+	if targetModel == model {
+		return bodyBytes, nil
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return bodyBytes, nil
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(bodyBytes), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		if part.FormName() == "model" && part.FileName() == "" {
+			_, err = io.WriteString(dst, targetModel)
+		} else {
+			_, err = io.Copy(dst, part)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write multipart body: %w", err)
+	}
+
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group":          group.Name,
+		"original_model": model,
+		"target_model":   targetModel,
+		"channel":        "multipart_form",
+	}).Debug("Model redirected")
+
+	return buf.Bytes(), nil
+}
+
+// readMultipartField returns the value of a non-file form field, or "" if it is absent.
+func readMultipartField(bodyBytes []byte, boundary string, name string) (string, error) {
+	reader := multipart.NewReader(bytes.NewReader(bodyBytes), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if part.FormName() != name || part.FileName() != "" {
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -51,6 +52,10 @@ func (ch *OpenAIChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool
 		return true
 	}
 
+	if strings.HasSuffix(c.Request.URL.Path, ":streamGenerateContent") {
+		return true
+	}
+
 	type streamPayload struct {
 		Stream bool `json:"stream"`
 	}
@@ -67,9 +72,13 @@ func (ch *OpenAIChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 		Model string `json:"model"`
 	}
 	var p modelPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
+	if err := json.Unmarshal(bodyBytes, &p); err == nil && p.Model != "" {
 		return p.Model
 	}
+	// Gemini requests translated for this channel carry the model in the path.
+	if model, _, _, ok := geminiPathModel(c.Request.URL.Path); ok {
+		return model
+	}
 	return ""
 }
 
@@ -89,6 +98,7 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	// Build final URL with path and query parameters
 	finalURL := *upstreamURL
 	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + endpointURL.Path
+	ch.withPathPrefix(&finalURL)
 	finalURL.RawQuery = endpointURL.RawQuery
 	reqURL := finalURL.String()
 
@@ -117,7 +127,9 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
-	resp, err := ch.HTTPClient.Do(req)
+	upstreamStart := time.Now()
+	resp, err := ch.ClientForKey(apiKey, false).Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
 	if err != nil {
 		return false, fmt.Errorf("failed to send validation request: %w", err)
 	}
@@ -0,0 +1,475 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// geminiFinishReasons maps OpenAI finish reasons to Gemini finish reasons.
+var geminiFinishReasons = map[string]string{
+	"stop":           "STOP",
+	"tool_calls":     "STOP",
+	"function_call":  "STOP",
+	"length":         "MAX_TOKENS",
+	"content_filter": "SAFETY",
+}
+
+// geminiPathModel returns the model and method of a Gemini native path such as
+// "/v1beta/models/gemini-2.0-flash:generateContent", and the path before the API version segment.
+func geminiPathModel(path string) (model, method, base string, ok bool) {
+	index := strings.LastIndex(path, "/models/")
+	if index < 0 {
+		return "", "", "", false
+	}
+	model, method, ok = strings.Cut(path[index+len("/models/"):], ":")
+	if !ok || model == "" || strings.Contains(model, "/") {
+		return "", "", "", false
+	}
+	base = path[:index]
+	if slash := strings.LastIndex(base, "/"); slash >= 0 {
+		base = base[:slash]
+	}
+	return model, method, base, true
+}
+
+// TranslateRequest converts Gemini generateContent and streamGenerateContent requests to chat completions
+// when the group's client_api_format is "gemini". The model of the path goes through the redirect rules.
+// Other requests are left as they are.
+func (ch *OpenAIChannel) TranslateRequest(req *http.Request, body []byte, group *models.Group) ([]byte, ResponseTranslator, error) {
+	if group.EffectiveConfig.ClientAPIFormat != ClientAPIFormatGemini {
+		return body, nil, nil
+	}
+	model, method, base, ok := geminiPathModel(req.URL.Path)
+	if !ok || (method != "generateContent" && method != "streamGenerateContent") {
+		return body, nil, nil
+	}
+
+	if target, found := redirectTarget(req, group, model); found {
+		model = target
+	} else if group.ModelRedirectStrict {
+		return nil, nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+
+	logger := utils.LoggerFromContext(req.Context()).WithField("group", group.Name)
+	translated, err := geminiToOpenAIRequest(body, model, method == "streamGenerateContent", logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.Path = base + "/v1/chat/completions"
+	req.URL.RawPath = ""
+	query := req.URL.Query()
+	query.Del("alt")
+	query.Del("key")
+	req.URL.RawQuery = query.Encode()
+
+	logger.WithFields(logrus.Fields{"model": model, "new_path": req.URL.Path}).Debug("Translated Gemini request to OpenAI chat completion")
+	return translated, &openAIToGemini{toolCalls: make(map[int]*geminiStreamedCall)}, nil
+}
+
+// geminiToOpenAIRequest converts a Gemini generateContent body to a chat completion body. Fields without an
+// OpenAI counterpart, such as built-in tools or topK, are dropped with a warning.
+func geminiToOpenAIRequest(body []byte, model string, stream bool, logger *logrus.Entry) ([]byte, error) {
+	var in struct {
+		Contents          []geminiContent  `json:"contents"`
+		SystemInstruction *geminiContent   `json:"systemInstruction"`
+		GenerationConfig  map[string]any   `json:"generationConfig"`
+		Tools             []map[string]any `json:"tools"`
+		ToolConfig        struct {
+			FunctionCallingConfig struct {
+				Mode                 string   `json:"mode"`
+				AllowedFunctionNames []string `json:"allowedFunctionNames"`
+			} `json:"functionCallingConfig"`
+		} `json:"toolConfig"`
+	}
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid generateContent request: %w", err)
+	}
+	if len(in.Contents) == 0 {
+		return nil, fmt.Errorf("generateContent request has no contents")
+	}
+
+	var dropped []string
+	var messages []any
+	if in.SystemInstruction != nil {
+		if text := in.SystemInstruction.text(); text != "" {
+			messages = append(messages, map[string]any{"role": "system", "content": text})
+		}
+	}
+
+	// Gemini function calls have no IDs; responses are matched to the calls of the same name in order.
+	pendingCalls := make(map[string][]string)
+	callCount := 0
+	for _, content := range in.Contents {
+		if content.Role == "model" {
+			message := map[string]any{"role": "assistant", "content": nil}
+			if text := content.text(); text != "" {
+				message["content"] = text
+			}
+			var toolCalls []any
+			for _, part := range content.Parts {
+				if part.FunctionCall == nil {
+					continue
+				}
+				callCount++
+				id := fmt.Sprintf("call_%d", callCount)
+				pendingCalls[part.FunctionCall.Name] = append(pendingCalls[part.FunctionCall.Name], id)
+				arguments, _ := json.Marshal(part.FunctionCall.Args)
+				toolCalls = append(toolCalls, map[string]any{
+					"id":       id,
+					"type":     "function",
+					"function": map[string]any{"name": part.FunctionCall.Name, "arguments": string(arguments)},
+				})
+			}
+			if len(toolCalls) > 0 {
+				message["tool_calls"] = toolCalls
+			}
+			messages = append(messages, message)
+			continue
+		}
+
+		var parts []any
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionResponse != nil:
+				name := part.FunctionResponse.Name
+				id := "call_" + name
+				if ids := pendingCalls[name]; len(ids) > 0 {
+					id, pendingCalls[name] = ids[0], ids[1:]
+				}
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				messages = append(messages, map[string]any{"role": "tool", "tool_call_id": id, "content": string(result)})
+			case part.Text != "":
+				parts = append(parts, map[string]any{"type": "text", "text": part.Text})
+			case part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "image/"):
+				parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]any{
+					"url": "data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data,
+				}})
+			case part.InlineData != nil || part.FileData != nil:
+				dropped = append(dropped, "non-image file parts")
+			}
+		}
+		if len(parts) > 0 {
+			messages = append(messages, map[string]any{"role": "user", "content": parts})
+		}
+	}
+
+	out := map[string]any{"model": model, "messages": messages}
+	if stream {
+		out["stream"] = true
+		out["stream_options"] = map[string]any{"include_usage": true}
+	}
+
+	for geminiField, openAIField := range map[string]string{
+		"temperature":      "temperature",
+		"topP":             "top_p",
+		"maxOutputTokens":  "max_tokens",
+		"stopSequences":    "stop",
+		"candidateCount":   "n",
+		"presencePenalty":  "presence_penalty",
+		"frequencyPenalty": "frequency_penalty",
+		"seed":             "seed",
+	} {
+		if value, ok := in.GenerationConfig[geminiField]; ok {
+			out[openAIField] = value
+		}
+	}
+	if schema, ok := in.GenerationConfig["responseSchema"]; ok {
+		out["response_format"] = map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "response", "schema": schema},
+		}
+	} else if in.GenerationConfig["responseMimeType"] == "application/json" {
+		out["response_format"] = map[string]any{"type": "json_object"}
+	}
+	for _, field := range []string{"topK", "thinkingConfig", "responseModalities", "speechConfig"} {
+		if _, ok := in.GenerationConfig[field]; ok {
+			dropped = append(dropped, "generationConfig."+field)
+		}
+	}
+
+	var tools []any
+	for _, tool := range in.Tools {
+		for name, value := range tool {
+			if name != "functionDeclarations" {
+				dropped = append(dropped, "tools."+name)
+				continue
+			}
+			declarations, _ := value.([]any)
+			for _, item := range declarations {
+				declaration, _ := item.(map[string]any)
+				function := map[string]any{"name": declaration["name"]}
+				if description, ok := declaration["description"]; ok {
+					function["description"] = description
+				}
+				if parameters, ok := declaration["parameters"]; ok {
+					function["parameters"] = parameters
+				}
+				tools = append(tools, map[string]any{"type": "function", "function": function})
+			}
+		}
+	}
+	if len(tools) > 0 {
+		out["tools"] = tools
+		config := in.ToolConfig.FunctionCallingConfig
+		switch strings.ToUpper(config.Mode) {
+		case "NONE":
+			out["tool_choice"] = "none"
+		case "ANY":
+			out["tool_choice"] = "required"
+			if len(config.AllowedFunctionNames) == 1 {
+				out["tool_choice"] = map[string]any{"type": "function", "function": map[string]any{"name": config.AllowedFunctionNames[0]}}
+			} else if len(config.AllowedFunctionNames) > 1 {
+				dropped = append(dropped, "toolConfig.functionCallingConfig.allowedFunctionNames")
+			}
+		}
+	}
+
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		logger.WithField("dropped", strings.Join(dropped, ", ")).Warn("Dropped Gemini request fields without an OpenAI counterpart")
+	}
+	return json.Marshal(out)
+}
+
+// geminiContent is a Gemini content: a role and its parts.
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text       string `json:"text,omitempty"`
+	InlineData *struct {
+		MimeType string `json:"mimeType"`
+		Data     string `json:"data"`
+	} `json:"inlineData,omitempty"`
+	FileData *struct {
+		MimeType string `json:"mimeType"`
+		FileURI  string `json:"fileUri"`
+	} `json:"fileData,omitempty"`
+	FunctionCall *struct {
+		Name string         `json:"name"`
+		Args map[string]any `json:"args"`
+	} `json:"functionCall,omitempty"`
+	FunctionResponse *struct {
+		Name     string `json:"name"`
+		Response any    `json:"response"`
+	} `json:"functionResponse,omitempty"`
+}
+
+// text joins the text parts of the content.
+func (c *geminiContent) text() string {
+	var texts []string
+	for _, part := range c.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "")
+}
+
+// geminiStreamedCall accumulates a tool call streamed in fragments.
+type geminiStreamedCall struct {
+	name      string
+	arguments strings.Builder
+}
+
+// openAIToGemini converts chat completion responses of a translated request to generateContent responses.
+// Streamed tool calls arrive in fragments, so they are sent with the final event.
+type openAIToGemini struct {
+	model        string
+	toolCalls    map[int]*geminiStreamedCall
+	finishReason string
+	usage        map[string]any
+	finished     bool
+}
+
+// TranslateBody converts a chat completion to a generateContent response.
+func (t *openAIToGemini) TranslateBody(body []byte) ([]byte, error) {
+	var in struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Index   int `json:"index"`
+			Message struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage map[string]any `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid chat completion response: %w", err)
+	}
+
+	candidates := make([]any, 0, len(in.Choices))
+	for _, choice := range in.Choices {
+		var parts []any
+		if choice.Message.ReasoningContent != "" {
+			parts = append(parts, map[string]any{"text": choice.Message.ReasoningContent, "thought": true})
+		}
+		if choice.Message.Content != "" {
+			parts = append(parts, map[string]any{"text": choice.Message.Content})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			parts = append(parts, geminiFunctionCallPart(call.Function.Name, call.Function.Arguments))
+		}
+		candidates = append(candidates, geminiCandidate(choice.Index, parts, choice.FinishReason))
+	}
+
+	out := map[string]any{"candidates": candidates, "modelVersion": in.Model}
+	if in.Usage != nil {
+		out["usageMetadata"] = geminiUsage(in.Usage)
+	}
+	return json.Marshal(out)
+}
+
+// TranslateEvent converts a chat completion chunk to a generateContent stream event.
+func (t *openAIToGemini) TranslateEvent(data []byte) ([]byte, error) {
+	if string(data) == "[DONE]" {
+		return t.finish(), nil
+	}
+
+	var chunk struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Index    int `json:"index"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage map[string]any `json:"usage"`
+		Error map[string]any `json:"error"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, fmt.Errorf("invalid chat completion chunk: %w", err)
+	}
+	if chunk.Error != nil {
+		return sseData(map[string]any{"error": chunk.Error}), nil
+	}
+	if chunk.Model != "" {
+		t.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		t.usage = chunk.Usage
+	}
+
+	var parts []any
+	for _, choice := range chunk.Choices {
+		if choice.Delta.ReasoningContent != "" {
+			parts = append(parts, map[string]any{"text": choice.Delta.ReasoningContent, "thought": true})
+		}
+		if choice.Delta.Content != "" {
+			parts = append(parts, map[string]any{"text": choice.Delta.Content})
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			streamed := t.toolCalls[call.Index]
+			if streamed == nil {
+				streamed = &geminiStreamedCall{}
+				t.toolCalls[call.Index] = streamed
+			}
+			if call.Function.Name != "" {
+				streamed.name = call.Function.Name
+			}
+			streamed.arguments.WriteString(call.Function.Arguments)
+		}
+		if choice.FinishReason != "" {
+			t.finishReason = choice.FinishReason
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	return sseData(map[string]any{"candidates": []any{geminiCandidate(0, parts, "")}, "modelVersion": t.model}), nil
+}
+
+// FinishStream sends the final event, unless the "[DONE]" marker already did.
+func (t *openAIToGemini) FinishStream() []byte {
+	return t.finish()
+}
+
+// finish returns the final event with the streamed tool calls, the finish reason and the usage, once.
+func (t *openAIToGemini) finish() []byte {
+	if t.finished {
+		return nil
+	}
+	t.finished = true
+
+	indexes := make([]int, 0, len(t.toolCalls))
+	for index := range t.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	parts := []any{}
+	for _, index := range indexes {
+		call := t.toolCalls[index]
+		parts = append(parts, geminiFunctionCallPart(call.name, call.arguments.String()))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, map[string]any{"text": ""})
+	}
+	if t.finishReason == "" {
+		t.finishReason = "stop"
+	}
+
+	event := map[string]any{"candidates": []any{geminiCandidate(0, parts, t.finishReason)}, "modelVersion": t.model}
+	if t.usage != nil {
+		event["usageMetadata"] = geminiUsage(t.usage)
+	}
+	return sseData(event)
+}
+
+func geminiCandidate(index int, parts []any, finishReason string) map[string]any {
+	if parts == nil {
+		parts = []any{}
+	}
+	candidate := map[string]any{"index": index, "content": map[string]any{"role": "model", "parts": parts}}
+	if finishReason != "" {
+		reason, ok := geminiFinishReasons[finishReason]
+		if !ok {
+			reason = "OTHER"
+		}
+		candidate["finishReason"] = reason
+	}
+	return candidate
+}
+
+func geminiFunctionCallPart(name, arguments string) map[string]any {
+	args := map[string]any{}
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			args = map[string]any{}
+		}
+	}
+	return map[string]any{"functionCall": map[string]any{"name": name, "args": args}}
+}
+
+func geminiUsage(usage map[string]any) map[string]any {
+	return map[string]any{
+		"promptTokenCount":     intField(usage, "prompt_tokens"),
+		"candidatesTokenCount": intField(usage, "completion_tokens"),
+		"totalTokenCount":      intField(usage, "total_tokens"),
+	}
+}
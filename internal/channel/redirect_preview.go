@@ -0,0 +1,69 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectPreview describes how a request would be rewritten by a group's model redirect rules.
+type RedirectPreview struct {
+	OriginalModel string `json:"original_model"`
+	ResolvedModel string `json:"resolved_model"`
+	Redirected    bool   `json:"redirected"`
+	OriginalPath  string `json:"original_path"`
+	UpstreamPath  string `json:"upstream_path"`
+	Error         string `json:"error,omitempty"`
+}
+
+// PreviewModelRedirect runs the channel's ApplyModelRedirect against a throwaway request built from a
+// sample path and body, and reports the planned rewrite. Nothing is sent upstream. The path is relative
+// to the group's proxy endpoint; per-key rewrites done later by ModifyRequest are not included.
+// A model rejected by strict mode is reported in Error rather than returned as an error.
+func PreviewModelRedirect(ch ChannelProxy, group *models.Group, method string, path string, contentType string, body []byte) (*RedirectPreview, error) {
+	if method == "" {
+		method = http.MethodPost
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	requestURL, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	upstreamURL, err := ch.BuildUpstreamURL(requestURL, group.Name)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, upstreamURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	preview := &RedirectPreview{
+		OriginalModel: ch.ExtractModel(&gin.Context{Request: req}, body),
+		OriginalPath:  req.URL.Path,
+	}
+
+	finalBody, err := ch.ApplyModelRedirect(req, body, group)
+	preview.UpstreamPath = req.URL.Path
+	if err != nil {
+		preview.ResolvedModel = preview.OriginalModel
+		preview.Error = err.Error()
+		return preview, nil
+	}
+
+	preview.ResolvedModel = ch.ExtractModel(&gin.Context{Request: req}, finalBody)
+	preview.Redirected = preview.ResolvedModel != preview.OriginalModel || preview.UpstreamPath != preview.OriginalPath
+	return preview, nil
+}
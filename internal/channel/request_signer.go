@@ -0,0 +1,44 @@
+package channel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"gpt-load/internal/models"
+)
+
+// RequestSigner authenticates an upstream request with the credentials of a key. A channel whose upstream
+// requires signed requests sets one on its BaseChannel. The proxy signs every request last, after
+// ModifyRequest and header rules, so the signature covers the final headers; channels sign the requests
+// they build themselves, e.g. in ValidateKey, the same way.
+type RequestSigner interface {
+	SignRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error
+}
+
+// SignRequest signs req with the channel's signer, if it has one.
+func (b *BaseChannel) SignRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
+	if b.Signer == nil {
+		return nil
+	}
+	return b.Signer.SignRequest(req, apiKey, group)
+}
+
+// readSignedBody returns the body a signature must cover and restores it for sending. The proxy may
+// replace the body after building the request, so it is read from Body rather than GetBody.
+func readSignedBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return body, nil
+}
@@ -0,0 +1,46 @@
+package channel
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps the cooldown an upstream can request for a key.
+const maxRetryAfter = time.Hour
+
+// rateLimitResetHeaders are OpenAI-style headers holding the time until a rate limit resets (e.g. "6m0s").
+var rateLimitResetHeaders = []string{"X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens"}
+
+// ParseRetryAfter returns how long to wait before retrying with the same key, read from the standard
+// Retry-After header or OpenAI-style reset headers. It returns 0 when the response does not say.
+func (b *BaseChannel) ParseRetryAfter(resp *http.Response) time.Duration {
+	return parseRetryAfterHeaders(resp.Header)
+}
+
+func parseRetryAfterHeaders(header http.Header) time.Duration {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return clampRetryAfter(time.Duration(seconds) * time.Second)
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return clampRetryAfter(time.Until(at))
+		}
+	}
+
+	var longest time.Duration
+	for _, name := range rateLimitResetHeaders {
+		if d, err := time.ParseDuration(strings.TrimSpace(header.Get(name))); err == nil && d > longest {
+			longest = d
+		}
+	}
+	return clampRetryAfter(longest)
+}
+
+func clampRetryAfter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return min(d, maxRetryAfter)
+}
@@ -0,0 +1,200 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+// awsCredentials are the AWS credentials stored in a key, either as JSON
+// ({"access_key_id": ..., "secret_access_key": ..., "session_token": ..., "region": ...})
+// or as "ACCESS_KEY_ID:SECRET_ACCESS_KEY[:REGION]".
+type awsCredentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+	Region          string `json:"region"`
+}
+
+func parseAWSCredentials(keyValue string) (awsCredentials, error) {
+	var creds awsCredentials
+	keyValue = strings.TrimSpace(keyValue)
+	if strings.HasPrefix(keyValue, "{") {
+		if err := json.Unmarshal([]byte(keyValue), &creds); err != nil {
+			return creds, fmt.Errorf("invalid AWS credentials JSON: %w", err)
+		}
+	} else {
+		parts := strings.Split(keyValue, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return creds, fmt.Errorf("AWS credentials must be JSON or ACCESS_KEY_ID:SECRET_ACCESS_KEY[:REGION]")
+		}
+		creds.AccessKeyID, creds.SecretAccessKey = parts[0], parts[1]
+		if len(parts) == 3 {
+			creds.Region = parts[2]
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, fmt.Errorf("AWS credentials require an access key ID and a secret access key")
+	}
+	return creds, nil
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4 using the AWS credentials of the key. The region
+// comes from the credentials, then from a "{service}.{region}.amazonaws.com" style host, then DefaultRegion.
+type SigV4Signer struct {
+	Service       string
+	DefaultRegion string
+}
+
+// SignRequest implements RequestSigner.
+func (s *SigV4Signer) SignRequest(req *http.Request, apiKey *models.APIKey, _ *models.Group) error {
+	creds, err := parseAWSCredentials(apiKey.KeyValue)
+	if err != nil {
+		return err
+	}
+	body, err := readSignedBody(req)
+	if err != nil {
+		return fmt.Errorf("failed to read request body for signing: %w", err)
+	}
+
+	s.sign(req, creds, body, time.Now().UTC())
+	return nil
+}
+
+func (s *SigV4Signer) sign(req *http.Request, creds awsCredentials, body []byte, t time.Time) {
+	region := s.region(req, creds)
+	amzDate := t.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{t.Format(sigV4DateFormat), region, s.Service, "aws4_request"}, "/")
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	} else {
+		req.Header.Del("X-Amz-Security-Token")
+	}
+
+	signedHeaders, canonicalHeaders := sigV4CanonicalHeaders(req)
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4CanonicalURI(req),
+		sigV4CanonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), t.Format(sigV4DateFormat))
+	for _, part := range []string{region, s.Service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func (s *SigV4Signer) region(req *http.Request, creds awsCredentials) string {
+	if creds.Region != "" {
+		return creds.Region
+	}
+	host := req.URL.Hostname()
+	if labels := strings.Split(host, "."); strings.HasSuffix(host, ".amazonaws.com") && len(labels) >= 4 {
+		return labels[len(labels)-3]
+	}
+	return s.DefaultRegion
+}
+
+// sigV4CanonicalHeaders signs the host, content type and all x-amz-* headers.
+func sigV4CanonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, value := range values {
+				trimmed[i] = strings.Join(strings.Fields(value), " ")
+			}
+			headers[lower] = strings.Join(trimmed, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// sigV4CanonicalURI encodes the path as sent once more, as required for every service except S3.
+func sigV4CanonicalURI(req *http.Request) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sigV4CanonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes every byte except the RFC 3986 unreserved characters.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
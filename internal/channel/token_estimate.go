@@ -0,0 +1,68 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"gpt-load/internal/models"
+	"net/http"
+	"unicode/utf8"
+)
+
+// 输入 token 估算方式
+const (
+	TokenEstimationHeuristic   = "heuristic"
+	TokenEstimationCountTokens = "count_tokens"
+)
+
+// promptFields lists the request body fields that contribute to input tokens across supported formats.
+var promptFields = []string{"messages", "contents", "system", "systemInstruction", "system_instruction", "prompt", "input", "instructions", "tools"}
+
+// TokenCounter is implemented by channels that can ask the upstream for an exact input token count.
+// req is the fully prepared upstream request, including authentication, and apiKey the key it was
+// prepared for, so the count goes out through the same egress as the request.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req *http.Request, apiKey *models.APIKey, bodyBytes []byte) (int, error)
+}
+
+// EstimateInputTokens returns a cheap local estimate of the input tokens of a request body,
+// assuming roughly four characters per token.
+func EstimateInputTokens(bodyBytes []byte) int {
+	var payload map[string]any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return (utf8.RuneCount(bodyBytes) + 3) / 4
+	}
+
+	chars := 0
+	for _, field := range promptFields {
+		if value, ok := payload[field]; ok {
+			chars += countTextChars(value)
+		}
+	}
+	return (chars + 3) / 4
+}
+
+// countTextChars sums the rune length of all string values in a decoded JSON value.
+func countTextChars(value any) int {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v)
+	case []any:
+		total := 0
+		for _, item := range v {
+			total += countTextChars(item)
+		}
+		return total
+	case map[string]any:
+		total := 0
+		for key, item := range v {
+			// Inline binary payloads are billed per media item, not per base64 character.
+			if key == "data" || key == "image_url" || key == "inlineData" || key == "inline_data" {
+				continue
+			}
+			total += countTextChars(item)
+		}
+		return total
+	default:
+		return 0
+	}
+}
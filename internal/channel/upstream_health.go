@@ -0,0 +1,69 @@
+package channel
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// An upstream failing upstreamFailureThreshold requests in a row is left out of selection for
+// upstreamCooldown. Afterwards it is selected again, and a single failure sends it back to cooldown.
+const (
+	upstreamFailureThreshold = 3
+	upstreamCooldown         = 30 * time.Second
+)
+
+// available reports whether the upstream can be selected.
+func (up *UpstreamInfo) available(now time.Time) bool {
+	return !now.Before(up.unhealthyUntil)
+}
+
+// ReportUpstreamResult records whether a request to the upstream the URL was built from failed at the
+// upstream level, i.e. with a connection error or a 5xx status. Only groups with several upstreams track
+// their health.
+func (b *BaseChannel) ReportUpstreamResult(upstreamURL string, healthy bool) {
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+
+	if len(b.Upstreams) < 2 {
+		return
+	}
+	up := b.upstreamFor(upstreamURL)
+	if up == nil {
+		return
+	}
+
+	if healthy {
+		if up.failures >= upstreamFailureThreshold {
+			logrus.WithFields(logrus.Fields{"channel": b.Name, "upstream": up.URL.Redacted()}).Info("Upstream recovered")
+		}
+		up.failures = 0
+		up.unhealthyUntil = time.Time{}
+		return
+	}
+
+	up.failures++
+	if up.failures >= upstreamFailureThreshold {
+		up.unhealthyUntil = time.Now().Add(upstreamCooldown)
+		if up.failures == upstreamFailureThreshold {
+			logrus.WithFields(logrus.Fields{"channel": b.Name, "upstream": up.URL.Redacted(), "cooldown": upstreamCooldown}).Warn("Upstream marked unhealthy after consecutive failures")
+		}
+	}
+}
+
+// upstreamFor returns the upstream whose URL, upstream path prefix included, is the longest prefix of a
+// URL built from it. The caller holds upstreamLock.
+func (b *BaseChannel) upstreamFor(upstreamURL string) *UpstreamInfo {
+	var best *UpstreamInfo
+	bestLen := -1
+	for i := range b.Upstreams {
+		baseURL := *b.Upstreams[i].URL
+		b.withPathPrefix(&baseURL)
+		base := strings.TrimRight(baseURL.String(), "/")
+		if len(base) > bestLen && (upstreamURL == base || strings.HasPrefix(upstreamURL, base+"/") || strings.HasPrefix(upstreamURL, base+"?")) {
+			best, bestLen = &b.Upstreams[i], len(base)
+		}
+	}
+	return best
+}
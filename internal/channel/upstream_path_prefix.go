@@ -0,0 +1,47 @@
+package channel
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizeUpstreamPathPrefix turns an upstream path prefix setting such as "llm", "/llm/" or "//llm//v2"
+// into "/llm" or "/llm/v2". Empty segments are dropped, and an empty setting yields "".
+func normalizeUpstreamPathPrefix(prefix string) string {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSpace(prefix), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// withPathPrefix prepends the group's upstream path prefix to the path of an outgoing request URL, in
+// front of the upstream's own base path. Upstream URLs are stored without it.
+func (b *BaseChannel) withPathPrefix(u *url.URL) {
+	if b.pathPrefix == "" {
+		return
+	}
+	if u.RawPath != "" {
+		u.RawPath = (&url.URL{Path: b.pathPrefix}).EscapedPath() + u.RawPath
+	}
+	if u.Path != "" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	u.Path = b.pathPrefix + u.Path
+}
+
+// upstreamURLFor returns the configured upstream an outgoing request URL was built from, or nil.
+func (b *BaseChannel) upstreamURLFor(u *url.URL) *url.URL {
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+
+	if up := b.upstreamFor(u.String()); up != nil {
+		return up.URL
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func newPrefixedTestChannel(t *testing.T, prefix string, upstreams ...string) *BaseChannel {
+	t.Helper()
+	b := &BaseChannel{Name: "test", pathPrefix: normalizeUpstreamPathPrefix(prefix)}
+	for _, raw := range upstreams {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("parse upstream %q: %v", raw, err)
+		}
+		b.Upstreams = append(b.Upstreams, UpstreamInfo{URL: u, Weight: 1})
+	}
+	return b
+}
+
+func TestNormalizeUpstreamPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"/", ""},
+		{"llm", "/llm"},
+		{"/llm", "/llm"},
+		{"/llm/", "/llm"},
+		{"//llm//v2//", "/llm/v2"},
+		{" /llm ", "/llm"},
+	}
+	for _, tt := range tests {
+		if got := normalizeUpstreamPathPrefix(tt.prefix); got != tt.want {
+			t.Errorf("normalizeUpstreamPathPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestBuildUpstreamURLWithPathPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream string
+		prefix   string
+		request  string
+		want     string
+	}{
+		{
+			name:     "no prefix",
+			upstream: "https://gw.example.com/openai",
+			request:  "/proxy/g/v1/chat/completions",
+			want:     "https://gw.example.com/openai/v1/chat/completions",
+		},
+		{
+			name:     "prefix in front of upstream base path",
+			upstream: "https://gw.example.com/openai",
+			prefix:   "llm",
+			request:  "/proxy/g/v1/chat/completions",
+			want:     "https://gw.example.com/llm/openai/v1/chat/completions",
+		},
+		{
+			name:     "upstream without path",
+			upstream: "https://gw.example.com",
+			prefix:   "/llm",
+			request:  "/proxy/g/v1/models",
+			want:     "https://gw.example.com/llm/v1/models",
+		},
+		{
+			name:     "query string kept",
+			upstream: "https://gw.example.com",
+			prefix:   "llm",
+			request:  "/proxy/g/v1beta/models?pageSize=50&pageToken=abc",
+			want:     "https://gw.example.com/llm/v1beta/models?pageSize=50&pageToken=abc",
+		},
+		{
+			name:     "trailing slashes",
+			upstream: "https://gw.example.com/openai/",
+			prefix:   "/llm/",
+			request:  "/proxy/g/v1/chat/completions",
+			want:     "https://gw.example.com/llm/openai/v1/chat/completions",
+		},
+		{
+			name:     "duplicate slashes in prefix",
+			upstream: "https://gw.example.com/",
+			prefix:   "//llm//v2//",
+			request:  "/proxy/g/v1/embeddings",
+			want:     "https://gw.example.com/llm/v2/v1/embeddings",
+		},
+		{
+			name:     "empty request path",
+			upstream: "https://gw.example.com",
+			prefix:   "llm",
+			request:  "/proxy/g",
+			want:     "https://gw.example.com/llm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPrefixedTestChannel(t, tt.prefix, tt.upstream)
+			requestURL, err := url.Parse(tt.request)
+			if err != nil {
+				t.Fatalf("parse request: %v", err)
+			}
+			got, err := b.BuildUpstreamURL(requestURL, "g")
+			if err != nil {
+				t.Fatalf("BuildUpstreamURL: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildUpstreamURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBedrockBuildUpstreamURLWithPathPrefix(t *testing.T) {
+	ch := &BedrockChannel{BaseChannel: newPrefixedTestChannel(t, "llm", "https://bedrock-runtime.us-east-1.amazonaws.com")}
+	requestURL, err := url.Parse("/proxy/g/model/arn%3Aaws%3Abedrock%3Aus-east-1%3A123%3Ainference-profile%2Fus.anthropic.claude/converse?x=1")
+	if err != nil {
+		t.Fatalf("parse request: %v", err)
+	}
+
+	got, err := ch.BuildUpstreamURL(requestURL, "g")
+	if err != nil {
+		t.Fatalf("BuildUpstreamURL: %v", err)
+	}
+	want := "https://bedrock-runtime.us-east-1.amazonaws.com/llm/model/arn%3Aaws%3Abedrock%3Aus-east-1%3A123%3Ainference-profile%2Fus.anthropic.claude/converse?x=1"
+	if got != want {
+		t.Errorf("BuildUpstreamURL = %q, want %q", got, want)
+	}
+}
+
+func TestBuildVertexModelMethodURLWithPathPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream string
+		prefix   string
+		want     string
+	}{
+		{
+			name:     "resource path upstream",
+			upstream: "https://aiplatform.googleapis.com/v1/projects/p/locations/global",
+			prefix:   "llm",
+			want:     "https://aiplatform.googleapis.com/llm/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent",
+		},
+		{
+			name:     "reverse proxy base path kept after prefix",
+			upstream: "https://gw.example.com/vertex/v1/projects/p/locations/global?alt=sse",
+			prefix:   "/llm/",
+			want:     "https://gw.example.com/llm/vertex/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent",
+		},
+		{
+			name:     "reverse proxy base path without prefix",
+			upstream: "https://gw.example.com/vertex/",
+			want:     "https://gw.example.com/vertex/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &VertexGeminiChannel{BaseChannel: newPrefixedTestChannel(t, tt.prefix, tt.upstream)}
+			got, err := ch.buildVertexModelMethodURL(ch.Upstreams[0].URL, "p", "us-central1", "gemini-2.0-flash", "generateContent")
+			if err != nil {
+				t.Fatalf("buildVertexModelMethodURL: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("buildVertexModelMethodURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamMatchingWithPathPrefix(t *testing.T) {
+	b := newPrefixedTestChannel(t, "llm", "https://gw.example.com/openai", "https://gw.example.com/openai/v2")
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://gw.example.com/llm/openai/v1/chat/completions", "https://gw.example.com/openai"},
+		{"https://gw.example.com/llm/openai/v2/chat/completions?x=1", "https://gw.example.com/openai/v2"},
+		{"https://gw.example.com/openai/v1/chat/completions", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.url, err)
+		}
+		got := ""
+		if upstream := b.upstreamURLFor(u); upstream != nil {
+			got = upstream.String()
+		}
+		if got != tt.want {
+			t.Errorf("upstreamURLFor(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	if base := b.upstreamBasePath("/llm/openai/v2/chat/completions"); base != "/llm/openai/v2" {
+		t.Errorf("upstreamBasePath = %q, want %q", base, "/llm/openai/v2")
+	}
+}
+
+func TestIsOpenAICompatiblePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream string
+		prefix   string
+		path     string
+		segments []string
+		want     bool
+	}{
+		{
+			name:     "OpenAI-compatible endpoint",
+			upstream: "https://generativelanguage.googleapis.com",
+			path:     "/v1beta/openai/chat/completions",
+			segments: []string{"v1beta", "openai"},
+			want:     true,
+		},
+		{
+			name:     "native endpoint",
+			upstream: "https://generativelanguage.googleapis.com",
+			path:     "/v1beta/models/gemini-2.0-flash:generateContent",
+			segments: []string{"v1beta", "openai"},
+		},
+		{
+			name:     "substring of a segment",
+			upstream: "https://gw.example.com/teams/openai-team",
+			path:     "/teams/openai-team/v1/projects/p/locations/l/publishers/google/models/gemini-2.0-flash:generateContent",
+			segments: []string{"openai"},
+		},
+		{
+			name:     "misleading segment in the upstream base path",
+			upstream: "https://gw.example.com/openai/vertex",
+			path:     "/openai/vertex/v1/projects/p/locations/l/publishers/google/models/gemini-2.0-flash:generateContent",
+			segments: []string{"openai"},
+		},
+		{
+			name:     "misleading segment in the path prefix",
+			upstream: "https://gw.example.com/vertex",
+			prefix:   "/v1beta/openai",
+			path:     "/v1beta/openai/vertex/v1beta/models/gemini-2.0-flash:generateContent",
+			segments: []string{"v1beta", "openai"},
+		},
+		{
+			name:     "OpenAI-compatible endpoint behind a misleading base path",
+			upstream: "https://gw.example.com/openai/vertex",
+			path:     "/openai/vertex/v1/projects/p/locations/l/endpoints/openai/chat/completions",
+			segments: []string{"openai"},
+			want:     true,
+		},
+		{
+			name:     "upstream base path ending in the segments",
+			upstream: "https://gw.example.com/proxy/v1beta/openai",
+			path:     "/proxy/v1beta/openai/chat/completions",
+			segments: []string{"v1beta", "openai"},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPrefixedTestChannel(t, tt.prefix, tt.upstream)
+			if got := b.isOpenAICompatiblePath(tt.path, tt.segments...); got != tt.want {
+				t.Errorf("isOpenAICompatiblePath(%q, %v) = %v, want %v", tt.path, tt.segments, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyModelRedirectBehindMisleadingPrefix(t *testing.T) {
+	ch := &GeminiChannel{BaseChannel: newPrefixedTestChannel(t, "", "https://gw.example.com/teams/v1beta/openai/gemini")}
+	group := &models.Group{Name: "g", ModelRedirectMap: map[string]string{"gemini-pro": "gemini-2.0-flash"}}
+	req := httptest.NewRequest(http.MethodPost, "/teams/v1beta/openai/gemini/v1beta/models/gemini-pro:generateContent", nil)
+	body := `{"contents":[]}`
+
+	got, err := ch.ApplyModelRedirect(req, []byte(body), group)
+	if err != nil {
+		t.Fatalf("ApplyModelRedirect: %v", err)
+	}
+	if want := "/teams/v1beta/openai/gemini/v1beta/models/gemini-2.0-flash:generateContent"; req.URL.Path != want {
+		t.Errorf("path = %s, want the native redirect %s", req.URL.Path, want)
+	}
+	if string(got) != body {
+		t.Errorf("body rewritten to %s", got)
+	}
+}
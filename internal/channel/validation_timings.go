@@ -0,0 +1,33 @@
+package channel
+
+import (
+	"context"
+	"time"
+)
+
+// ValidationTimings records how long the phases of a key validation took.
+// A nil duration means the phase did not run (e.g. the access token was served from cache).
+type ValidationTimings struct {
+	TokenMint *time.Duration
+	Upstream  *time.Duration
+}
+
+type validationTimingsKey struct{}
+
+// WithValidationTimings returns a context that collects validation timings into the returned struct.
+func WithValidationTimings(ctx context.Context) (context.Context, *ValidationTimings) {
+	timings := &ValidationTimings{}
+	return context.WithValue(ctx, validationTimingsKey{}, timings), timings
+}
+
+func recordTokenMintTiming(ctx context.Context, d time.Duration) {
+	if timings, ok := ctx.Value(validationTimingsKey{}).(*ValidationTimings); ok {
+		timings.TokenMint = &d
+	}
+}
+
+func recordUpstreamTiming(ctx context.Context, d time.Duration) {
+	if timings, ok := ctx.Value(validationTimingsKey{}).(*ValidationTimings); ok {
+		timings.Upstream = &d
+	}
+}
@@ -0,0 +1,79 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+// newTestTokenEndpoint starts an OAuth token endpoint that grants hour-long tokens and counts the mints.
+func newTestTokenEndpoint(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var mints atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := mints.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": fmt.Sprintf("token-%d", n), "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+	return server, &mints
+}
+
+func TestValidateKeysReportsPhaseDurations(t *testing.T) {
+	tokenEndpoint, _ := newTestTokenEndpoint(t)
+	var authorizations []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		io.WriteString(w, `{"candidates":[]}`)
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL + "/v1/projects/p/locations/us-central1")
+	if err != nil {
+		t.Fatalf("parse upstream: %v", err)
+	}
+	ch := newTestVertexChannel(t)
+	ch.Upstreams = []UpstreamInfo{{URL: upstreamURL, Weight: 1}}
+	ch.HTTPClient = upstream.Client()
+	ch.TestModel = "gemini-2.0-flash"
+
+	keyJSON, err := json.Marshal(newTestServiceAccount(t, tokenEndpoint.URL))
+	if err != nil {
+		t.Fatalf("marshal service account: %v", err)
+	}
+	group := &models.Group{Name: "vertex", ChannelType: "vertex_gemini"}
+	group.EffectiveConfig.VertexMintTimeout = 5
+	group.EffectiveConfig.VertexTokenCache = VertexTokenCacheMemory
+	group.EffectiveConfig.KeyValidationTimeoutSeconds = 5
+	group.EffectiveConfig.KeyValidationConcurrency = 1
+
+	// Both keys share the service account; the second one is validated with the cached token.
+	keys := []*models.APIKey{{ID: 1, KeyValue: string(keyJSON)}, {ID: 2, KeyValue: string(keyJSON)}}
+	results := ch.ValidateKeys(context.Background(), keys, group)
+
+	for i, result := range results {
+		if !result.IsValid {
+			t.Fatalf("key %d invalid: %v", i+1, result.Err)
+		}
+		if result.Timings == nil || result.Timings.Upstream == nil {
+			t.Errorf("key %d: upstream duration missing", i+1)
+		}
+	}
+	if results[0].Timings.TokenMint == nil {
+		t.Error("token mint duration missing for the key that minted")
+	}
+	if results[1].Timings.TokenMint != nil {
+		t.Errorf("token mint duration %v reported for a cached token", *results[1].Timings.TokenMint)
+	}
+	if len(authorizations) != 2 || authorizations[0] != "Bearer token-1" || authorizations[1] != "Bearer token-1" {
+		t.Errorf("upstream authorizations = %v, want both with the minted token", authorizations)
+	}
+}
@@ -0,0 +1,153 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// vertexGCSUploadURL is the media upload endpoint of the Cloud Storage JSON API.
+const vertexGCSUploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o"
+
+// splitGCSBucket splits a "bucket" or "bucket/prefix" setting into the bucket and an object prefix
+// ending in "/".
+func splitGCSBucket(value string) (bucket, prefix string) {
+	value = strings.Trim(strings.TrimPrefix(strings.TrimSpace(value), "gs://"), "/")
+	bucket, prefix, _ = strings.Cut(value, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return bucket, prefix
+}
+
+// offloadVertexInlineData uploads inline media parts of Gemini generateContent and streamGenerateContent
+// requests that exceed the group's threshold to its GCS bucket, and replaces them with fileData parts
+// referencing the objects. Objects are named by the SHA-256 of their content, so a part sent again is
+// not uploaded twice.
+func (ch *VertexGeminiChannel) offloadVertexInlineData(req *http.Request, client *http.Client, accessToken string, group *models.Group) error {
+	bucket, prefix := splitGCSBucket(group.EffectiveConfig.VertexGCSBucket)
+	threshold := group.EffectiveConfig.VertexGCSThresholdKB * 1024
+	if bucket == "" || req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.URL.Path, "/publishers/google/") {
+		return nil
+	}
+	path := req.URL.Path
+	method := path[strings.LastIndex(path, ":")+1:]
+	if method != "generateContent" && method != "streamGenerateContent" {
+		return nil
+	}
+	if req.ContentLength >= 0 && req.ContentLength <= int64(threshold) {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	var payload map[string]any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return nil
+	}
+	contents, _ := payload["contents"].([]any)
+
+	offloaded := 0
+	for _, item := range contents {
+		content, _ := item.(map[string]any)
+		parts, _ := content["parts"].([]any)
+		for i, rawPart := range parts {
+			part, _ := rawPart.(map[string]any)
+			inline, _ := part["inlineData"].(map[string]any)
+			encoded, _ := inline["data"].(string)
+			mimeType, _ := inline["mimeType"].(string)
+			if len(encoded) <= threshold*4/3 {
+				continue
+			}
+			// Parts that are not valid base64 are left for Vertex to reject.
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(data) <= threshold {
+				continue
+			}
+
+			uri, err := ch.uploadToGCS(req.Context(), client, accessToken, bucket, prefix, mimeType, data)
+			if err != nil {
+				return err
+			}
+			parts[i] = map[string]any{"fileData": map[string]any{"mimeType": mimeType, "fileUri": uri}}
+			offloaded++
+		}
+	}
+	if offloaded == 0 {
+		return nil
+	}
+
+	newBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(newBody))
+	req.ContentLength = int64(len(newBody))
+
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group":       group.Name,
+		"bucket":      bucket,
+		"parts":       offloaded,
+		"size_before": len(bodyBytes),
+		"size_after":  len(newBody),
+	}).Info("Offloaded inline media parts to GCS")
+	return nil
+}
+
+// uploadToGCS uploads data to a content-addressed object of the bucket and returns its gs:// URI.
+// An object that already exists is reused.
+func (ch *VertexGeminiChannel) uploadToGCS(ctx context.Context, client *http.Client, accessToken, bucket, prefix, mimeType string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	object := prefix + hex.EncodeToString(sum[:])
+	if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+		object += extensions[0]
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", object)
+	query.Set("ifGenerationMatch", "0")
+	uploadURL := fmt.Sprintf(vertexGCSUploadURL, url.PathEscape(bucket)) + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create GCS upload request: %w", err)
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", mimeType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload inline data to GCS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 412 means the object exists already, which is fine since its name is its content hash.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPreconditionFailed {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("failed to upload inline data to GCS bucket %s [status %d]: %s", bucket, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return "gs://" + bucket + "/" + object, nil
+}
@@ -4,16 +4,23 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/tracing"
 	"gpt-load/internal/utils"
 	"io"
 	"net/http"
@@ -24,11 +31,27 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	vertexDefaultTokenURI = "https://oauth2.googleapis.com/token"
-	vertexOAuthScope      = "https://www.googleapis.com/auth/cloud-platform"
+	vertexDefaultTokenURI   = "https://oauth2.googleapis.com/token"
+	vertexOAuthScope        = "https://www.googleapis.com/auth/cloud-platform"
+	vertexIAMCredentialsURI = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+
+	vertexPreferredLocation = "us-central1"
+
+	// vertexLiveAPIPath is the WebSocket endpoint of the Gemini Live API on Vertex AI.
+	vertexLiveAPIPath = "/ws/google.cloud.aiplatform.v1.LlmBidiService/BidiGenerateContent"
+
+	vertexTokenLockTTL      = 10 * time.Second
+	vertexTokenPollInterval = 200 * time.Millisecond
+)
+
+// Vertex 访问令牌缓存模式
+const (
+	VertexTokenCacheShared = "shared"
+	VertexTokenCacheMemory = "memory"
 )
 
 func init() {
@@ -38,13 +61,46 @@ func init() {
 type VertexGeminiChannel struct {
 	*BaseChannel
 
+	store         store.Store
+	encryptionSvc encryption.Service
+
 	tokenCacheMu sync.Mutex
-	tokenCache   map[uint]vertexAccessToken
+	tokenCache   map[string]vertexAccessToken
+	mintGroup    singleflight.Group
+
+	locationCacheMu sync.Mutex
+	locationCache   map[string]string
+
+	testModelCacheMu sync.Mutex
+	testModelCache   map[string]string
+	testModelGroup   singleflight.Group
+
+	accountMu        sync.Mutex
+	accountCooldowns map[string]time.Time
+	accountCursors   map[uint]int
 }
 
 type vertexAccessToken struct {
-	AccessToken string
-	Expiry      time.Time
+	AccessToken string    `json:"access_token"`
+	Expiry      time.Time `json:"expiry"`
+	MintedAt    time.Time `json:"minted_at"`
+}
+
+// refreshAt returns when a cached token must be replaced: refresh skew before expiry, or once it reaches
+// the group's max token age, whichever comes first.
+func (t vertexAccessToken) refreshAt(group *models.Group) time.Time {
+	refreshAt := t.Expiry.Add(-vertexTokenSkew(group))
+	if maxAge := time.Duration(group.EffectiveConfig.VertexTokenMaxAge) * time.Second; maxAge > 0 {
+		if byAge := t.MintedAt.Add(maxAge); byAge.Before(refreshAt) {
+			refreshAt = byAge
+		}
+	}
+	return refreshAt
+}
+
+// usable reports whether a cached token can still be handed out.
+func (t vertexAccessToken) usable(group *models.Group) bool {
+	return t.AccessToken != "" && time.Now().Before(t.refreshAt(group))
 }
 
 type gcpServiceAccount struct {
@@ -53,6 +109,10 @@ type gcpServiceAccount struct {
 	PrivateKey   string `json:"private_key"`
 	ClientEmail  string `json:"client_email"`
 	TokenURI     string `json:"token_uri"`
+
+	// ImpersonateServiceAccount is an optional target principal. When set, the token minted for
+	// ClientEmail is exchanged for an access token of this account via IAM generateAccessToken.
+	ImpersonateServiceAccount string `json:"impersonate_service_account,omitempty"`
 }
 
 func newVertexGeminiChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
@@ -62,40 +122,117 @@ func newVertexGeminiChannel(f *Factory, group *models.Group) (ChannelProxy, erro
 	}
 
 	return &VertexGeminiChannel{
-		BaseChannel: base,
-		tokenCache:  make(map[uint]vertexAccessToken),
+		BaseChannel:      base,
+		store:            f.store,
+		encryptionSvc:    f.encryptionSvc,
+		tokenCache:       make(map[string]vertexAccessToken),
+		locationCache:    make(map[string]string),
+		testModelCache:   make(map[string]string),
+		accountCooldowns: make(map[string]time.Time),
+		accountCursors:   make(map[uint]int),
 	}, nil
 }
 
 func (ch *VertexGeminiChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
-	sa, err := parseGCPServiceAccount(apiKey.KeyValue)
+	sa, accessToken, err := ch.accountAccessToken(req, apiKey, group)
 	if err != nil {
 		return err
 	}
+	client := ch.ClientForKey(apiKey, false)
+
+	location := extractVertexLocation(req.URL)
+	if location == "" && group.EffectiveConfig.VertexAutoRegion {
+		projectID := extractVertexProjectID(req.URL)
+		if projectID == "" {
+			projectID = sa.ProjectID
+		}
+		// Discovery lists locations below the upstream the request was built from, not the request path.
+		if upstreamURL := ch.upstreamURLFor(req.URL); upstreamURL != nil {
+			location = ch.discoverVertexLocation(req.Context(), client, upstreamURL, projectID, accessToken)
+		}
+	}
 
 	// Compatibility rewrite: allow proxy-side Gemini native paths, but call Vertex upstream.
-	ch.rewriteGeminiNativePathToVertex(req, sa)
+	ch.rewriteGeminiNativePathToVertex(req, sa, location)
+	if err := applyPartnerModelMethod(req); err != nil {
+		return err
+	}
+	applyVertexModelLocation(req, group)
+	if err := applyVertexCachedContent(req, group); err != nil {
+		return err
+	}
+	if err := ch.offloadVertexInlineData(req, client, accessToken, group); err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+// accountAccessToken selects the service account of the key that serves the request and returns its access token.
+func (ch *VertexGeminiChannel) accountAccessToken(req *http.Request, apiKey *models.APIKey, group *models.Group) (gcpServiceAccount, string, error) {
+	accounts, err := parseGCPServiceAccounts(apiKey.KeyValue)
+	if err != nil {
+		return gcpServiceAccount{}, "", err
+	}
+	sa := ch.selectServiceAccount(apiKey, accounts, group)
+	if len(accounts) > 1 && sa.ProjectID != "" {
+		// Each account of a multi-account key serves its own project.
+		replaceVertexProjectID(req.URL, sa.ProjectID)
+	}
+
+	accessToken, err := ch.getOrMintAccessToken(req.Context(), ch.ClientForKey(apiKey, false), sa, group)
+	if err != nil {
+		return gcpServiceAccount{}, "", err
+	}
+	return sa, accessToken, nil
+}
 
-	accessToken, err := ch.getOrMintAccessToken(req.Context(), apiKey.ID, sa)
+// SupportsWebSocket reports whether the path is a Gemini Live API endpoint.
+func (ch *VertexGeminiChannel) SupportsWebSocket(path string) bool {
+	return strings.HasSuffix(path, "BidiGenerateContent")
+}
+
+// ModifyWebSocketRequest routes a Gemini Live API connection to the Vertex Live endpoint in the upstream's
+// location and authorizes the upgrade with the key's access token. Clients may connect with either the Gemini
+// API path (.../GenerativeService.BidiGenerateContent) or the Vertex one.
+func (ch *VertexGeminiChannel) ModifyWebSocketRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) error {
+	_, accessToken, err := ch.accountAccessToken(req, apiKey, group)
 	if err != nil {
 		return err
 	}
 
+	if location := extractVertexLocation(req.URL); location != "" {
+		req.URL.Host = vertexHostForLocation(req.URL.Host, location)
+	}
+	req.URL.Path = ch.pathPrefix + vertexLiveAPIPath
+	req.URL.RawPath = ""
+	query := req.URL.Query()
+	query.Del("key")
+	req.URL.RawQuery = query.Encode()
+	req.Host = req.URL.Host
+
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	return nil
 }
 
 func (ch *VertexGeminiChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
-	path := c.Request.URL.Path
-	if strings.HasSuffix(path, ":streamGenerateContent") {
+	return isVertexStreamRequest(c.Request.URL, c.Request.Header, bodyBytes)
+}
+
+// isVertexStreamRequest reports whether a request asks for a streamed response. IsStreamRequest and the
+// partner model method selection share it, so requests the proxy streams go to a streaming method.
+func isVertexStreamRequest(u *url.URL, header http.Header, bodyBytes []byte) bool {
+	path := u.Path
+	if strings.HasSuffix(path, ":streamGenerateContent") || strings.HasSuffix(path, ":streamRawPredict") {
 		return true
 	}
 
 	// Also check for standard streaming indicators as a fallback.
-	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+	if strings.Contains(header.Get("Accept"), "text/event-stream") {
 		return true
 	}
-	if c.Query("stream") == "true" {
+	if u.Query().Get("stream") == "true" {
 		return true
 	}
 
@@ -127,24 +264,65 @@ func (ch *VertexGeminiChannel) ExtractModel(c *gin.Context, bodyBytes []byte) st
 	}
 	var p modelPayload
 	if err := json.Unmarshal(bodyBytes, &p); err == nil && p.Model != "" {
-		return p.Model
+		return bareVertexModelID(p.Model)
 	}
 
 	return ""
 }
 
+// bareVertexModelID strips the resource prefixes the Vertex OpenAI-compatible endpoint accepts in the
+// "model" field ("publishers/google/models/gemini-1.5-flash", "google/gemini-1.5-flash") so that
+// models are keyed the same way as on native paths.
+func bareVertexModelID(model string) string {
+	if idx := strings.LastIndex(model, "models/"); idx != -1 {
+		return model[idx+len("models/"):]
+	}
+	if _, rest, found := strings.Cut(model, "/"); found {
+		return rest
+	}
+	return model
+}
+
 func (ch *VertexGeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
 	upstreamURL := ch.getUpstreamURL()
 	if upstreamURL == nil {
 		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
 	}
 
-	sa, err := parseGCPServiceAccount(apiKey.KeyValue)
+	accounts, err := parseGCPServiceAccounts(apiKey.KeyValue)
 	if err != nil {
 		return false, err
 	}
+	for i, sa := range accounts {
+		isValid, err := ch.validateServiceAccount(ctx, upstreamURL, apiKey, group, sa, len(accounts) > 1)
+		if !isValid {
+			if len(accounts) > 1 && err != nil {
+				err = fmt.Errorf("service account %d (%s): %w", i+1, sa.ClientEmail, err)
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
 
+// validateServiceAccount validates one service account of a key. Accounts of a multi-account key
+// are validated against their own project rather than the upstream URL project.
+func (ch *VertexGeminiChannel) validateServiceAccount(ctx context.Context, upstreamURL *url.URL, apiKey *models.APIKey, group *models.Group, sa gcpServiceAccount, multiAccount bool) (bool, error) {
 	projectID := extractVertexProjectID(upstreamURL)
+	if multiAccount && sa.ProjectID != "" {
+		projectID = sa.ProjectID
+	}
+	if projectID != "" && sa.ProjectID != "" && projectID != sa.ProjectID {
+		if group.EffectiveConfig.VertexStrictProject {
+			return false, fmt.Errorf("upstream url project %q does not match service account project %q", projectID, sa.ProjectID)
+		}
+		utils.LoggerFromContext(ctx).WithFields(logrus.Fields{
+			"group":        group.Name,
+			"url_project":  projectID,
+			"key_project":  sa.ProjectID,
+			"client_email": sa.ClientEmail,
+		}).Warn("Vertex upstream url project differs from service account project, using the url project")
+	}
 	if projectID == "" {
 		projectID = sa.ProjectID
 	}
@@ -152,17 +330,34 @@ func (ch *VertexGeminiChannel) ValidateKey(ctx context.Context, apiKey *models.A
 		return false, fmt.Errorf("missing project_id (not found in upstream url path or service account json)")
 	}
 
+	client := ch.ClientForKey(apiKey, false)
+	accessToken, err := ch.getOrMintAccessToken(ctx, client, sa, group)
+	if err != nil {
+		return false, err
+	}
+
 	location := extractVertexLocation(upstreamURL)
+	if mapped, ok := group.VertexLocationMap[ch.TestModel]; ok {
+		location = mapped
+	}
+	if location == "" && group.EffectiveConfig.VertexAutoRegion {
+		location = ch.discoverVertexLocation(ctx, client, upstreamURL, projectID, accessToken)
+	}
 	if location == "" {
-		return false, fmt.Errorf("unable to infer vertex location from upstream host/path")
+		return false, vertexLocationError(upstreamURL)
 	}
 
-	accessToken, err := ch.getOrMintAccessToken(ctx, apiKey.ID, sa)
-	if err != nil {
-		return false, err
+	// Without a configured test model, validate with a cheap model the project can use.
+	locationURL := vertexURLForLocation(upstreamURL, location)
+	testModel := ch.TestModel
+	if ch.usesAutoTestModel() {
+		testModel, err = ch.discoverVertexTestModel(ctx, client, locationURL, projectID, accessToken)
+		if err != nil {
+			return false, err
+		}
 	}
 
-	reqURL, err := buildVertexModelMethodURL(upstreamURL, projectID, location, ch.TestModel, "generateContent")
+	reqURL, err := ch.buildVertexModelMethodURL(locationURL, projectID, location, testModel, "generateContent")
 	if err != nil {
 		return false, err
 	}
@@ -195,7 +390,9 @@ func (ch *VertexGeminiChannel) ValidateKey(ctx context.Context, apiKey *models.A
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
-	resp, err := ch.HTTPClient.Do(req)
+	upstreamStart := time.Now()
+	resp, err := client.Do(req)
+	recordUpstreamTiming(ctx, time.Since(upstreamStart))
 	if err != nil {
 		return false, fmt.Errorf("failed to send validation request: %w", err)
 	}
@@ -205,6 +402,11 @@ func (ch *VertexGeminiChannel) ValidateKey(ctx context.Context, apiKey *models.A
 		return true, nil
 	}
 
+	// A discovered model the upstream no longer serves is discovered anew on the next validation.
+	if resp.StatusCode == http.StatusNotFound && ch.usesAutoTestModel() {
+		ch.forgetVertexTestModel(locationURL, projectID)
+	}
+
 	errorBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
@@ -214,19 +416,201 @@ func (ch *VertexGeminiChannel) ValidateKey(ctx context.Context, apiKey *models.A
 	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
 }
 
+// Liveness checks that the service accounts parse and their private keys load, and that cached access
+// tokens are still valid. Only when no usable token is cached does it mint one; it never calls the model.
+func (ch *VertexGeminiChannel) Liveness(ctx context.Context, apiKey *models.APIKey, group *models.Group) error {
+	accounts, err := parseGCPServiceAccounts(apiKey.KeyValue)
+	if err != nil {
+		return err
+	}
+
+	for _, sa := range accounts {
+		if _, err := newJWTSigner(sa.PrivateKey); err != nil {
+			return err
+		}
+
+		cacheKey := sa.tokenCacheKey(vertexOAuthScopes(group))
+		ch.tokenCacheMu.Lock()
+		cached, ok := ch.tokenCache[cacheKey]
+		ch.tokenCacheMu.Unlock()
+		if ok && cached.usable(group) {
+			continue
+		}
+
+		if _, err := ch.getOrMintAccessToken(ctx, ch.ClientForKey(apiKey, false), sa, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateKeys validates keys concurrently, bounded by the group's key validation concurrency.
+// Keys sharing a service account reuse one access token.
+func (ch *VertexGeminiChannel) ValidateKeys(ctx context.Context, keys []*models.APIKey, group *models.Group) []KeyValidationResult {
+	return validateKeysConcurrently(ctx, keys, group, group.EffectiveConfig.KeyValidationConcurrency, ch.ValidateKey)
+}
+
 func (ch *VertexGeminiChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
 	if len(group.ModelRedirectMap) == 0 {
 		return bodyBytes, nil
 	}
 
 	// Allow OpenAI-compatible payloads when upstream supports it.
-	if strings.Contains(req.URL.Path, "/openai/") {
-		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
+	if ch.isOpenAICompatiblePath(req.URL.Path, "openai") {
+		return ch.applyOpenAICompatibleRedirect(req, bodyBytes, group)
 	}
 
 	return ch.applyNativeFormatRedirect(req, bodyBytes, group)
 }
 
+// CountTokens asks Vertex for the exact input token count of a Gemini native generateContent request.
+// It is sent through the key's own client so that a per-key egress proxy also applies to it.
+func (ch *VertexGeminiChannel) CountTokens(ctx context.Context, req *http.Request, apiKey *models.APIKey, bodyBytes []byte) (int, error) {
+	path := req.URL.Path
+	colonIdx := strings.LastIndex(path, ":")
+	if colonIdx == -1 || !strings.Contains(path, "/publishers/google/") {
+		return 0, fmt.Errorf("countTokens is only supported for Gemini native requests")
+	}
+	method := path[colonIdx+1:]
+	if method != "generateContent" && method != "streamGenerateContent" {
+		return 0, fmt.Errorf("countTokens is not supported for method %s", method)
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return 0, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	countPayload := make(map[string]json.RawMessage)
+	for _, field := range []string{"contents", "systemInstruction", "tools"} {
+		if value, ok := payload[field]; ok {
+			countPayload[field] = value
+		}
+	}
+	countBody, err := json.Marshal(countPayload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal countTokens payload: %w", err)
+	}
+
+	countURL := *req.URL
+	countURL.Path = path[:colonIdx+1] + "countTokens"
+	countURL.RawPath = ""
+	countURL.RawQuery = ""
+
+	countReq, err := http.NewRequestWithContext(ctx, "POST", countURL.String(), bytes.NewReader(countBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create countTokens request: %w", err)
+	}
+	countReq.Header.Set("Authorization", req.Header.Get("Authorization"))
+	countReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := ch.ClientForKey(apiKey, false).Do(countReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send countTokens request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read countTokens response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("[status %d] %s", resp.StatusCode, app_errors.ParseUpstreamError(respBody))
+	}
+
+	var result struct {
+		TotalTokens int `json:"totalTokens"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse countTokens response: %w", err)
+	}
+	return result.TotalTokens, nil
+}
+
+// applyPartnerModelMethod selects :rawPredict or :streamRawPredict for partner (non-Google) publisher models
+// by whether the request is streamed, as IsStreamRequest decides it. It runs on the Vertex path, after
+// Gemini native paths are rewritten.
+func applyPartnerModelMethod(req *http.Request) error {
+	path := req.URL.Path
+	idx := strings.Index(path, "/publishers/")
+	if idx == -1 {
+		return nil
+	}
+	publisher, _, _ := strings.Cut(path[idx+len("/publishers/"):], "/")
+	if publisher == "" || publisher == "google" {
+		return nil
+	}
+
+	colonIdx := strings.LastIndex(path, ":")
+	if colonIdx == -1 {
+		return nil
+	}
+	method := path[colonIdx+1:]
+	if method != "rawPredict" && method != "streamRawPredict" {
+		return nil
+	}
+
+	var bodyBytes []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		bodyBytes, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
+	targetMethod := "rawPredict"
+	if isVertexStreamRequest(req.URL, req.Header, bodyBytes) {
+		targetMethod = "streamRawPredict"
+	}
+	if method != targetMethod {
+		req.URL.Path = path[:colonIdx+1] + targetMethod
+		req.URL.RawPath = ""
+	}
+	return nil
+}
+
+// applyOpenAICompatibleRedirect matches redirect rules against the bare model id, keeping any
+// resource prefix of the original "model" value in front of the target model.
+func (ch *VertexGeminiChannel) applyOpenAICompatibleRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if _, ok := multipartBoundary(req); ok {
+		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		return bodyBytes, nil
+	}
+	model, _ := requestData["model"].(string)
+	bare := bareVertexModelID(model)
+	if model == "" || bare == model {
+		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
+	}
+
+	if targetModel, found := redirectTarget(req, group, bare); found {
+		if targetModel == bare {
+			return bodyBytes, nil
+		}
+		requestData["model"] = strings.TrimSuffix(model, bare) + targetModel
+
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+			"group":          group.Name,
+			"original_model": model,
+			"target_model":   requestData["model"],
+			"channel":        "json_body",
+		}).Debug("Model redirected")
+
+		return json.Marshal(requestData)
+	}
+
+	if group.ModelRedirectStrict {
+		return nil, fmt.Errorf("model '%s' is not configured in redirect rules", bare)
+	}
+
+	return bodyBytes, nil
+}
+
 func (ch *VertexGeminiChannel) applyNativeFormatRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
 	path := req.URL.Path
 	parts := strings.Split(path, "/")
@@ -236,7 +620,12 @@ func (ch *VertexGeminiChannel) applyNativeFormatRedirect(req *http.Request, body
 			modelPart := parts[i+1]
 			originalModel := strings.Split(modelPart, ":")[0]
 
-			if targetModel, found := group.ModelRedirectMap[originalModel]; found {
+			if targetModel, found := redirectTarget(req, group, originalModel); found {
+				// Identity redirects only allow the model in strict mode, leave the path untouched.
+				if targetModel == originalModel {
+					return bodyBytes, nil
+				}
+
 				suffix := ""
 				if colonIndex := strings.Index(modelPart, ":"); colonIndex != -1 {
 					suffix = modelPart[colonIndex:]
@@ -244,7 +633,7 @@ func (ch *VertexGeminiChannel) applyNativeFormatRedirect(req *http.Request, body
 				parts[i+1] = targetModel + suffix
 				req.URL.Path = strings.Join(parts, "/")
 
-				logrus.WithFields(logrus.Fields{
+				utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
 					"group":          group.Name,
 					"original_model": originalModel,
 					"target_model":   targetModel,
@@ -269,12 +658,14 @@ func (ch *VertexGeminiChannel) applyNativeFormatRedirect(req *http.Request, body
 func (ch *VertexGeminiChannel) TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error) {
 	var response map[string]any
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
-		logrus.WithError(err).Debug("Failed to parse model list response, returning empty")
+		utils.LoggerFromContext(req.Context()).WithError(err).Debug("Failed to parse model list response, returning empty")
 		return nil, err
 	}
 
 	if modelsInterface, hasModels := response["models"]; hasModels {
-		return ch.transformGeminiNativeFormat(req, response, modelsInterface, group), nil
+		result := ch.transformGeminiNativeFormat(req, response, modelsInterface, group)
+		annotateModelCapabilities(result, group)
+		return result, nil
 	}
 
 	if _, hasData := response["data"]; hasData {
@@ -290,7 +681,7 @@ func (ch *VertexGeminiChannel) transformGeminiNativeFormat(req *http.Request, re
 		return response
 	}
 
-	configuredModels := buildConfiguredGeminiModels(group.ModelRedirectMap)
+	configuredModels := buildConfiguredGeminiModels(ch.configuredModelIDs(group))
 
 	if group.ModelRedirectStrict {
 		response["models"] = configuredModels
@@ -303,11 +694,11 @@ func (ch *VertexGeminiChannel) transformGeminiNativeFormat(req *http.Request, re
 		return response
 	}
 
-	response["models"] = upstreamModels
+	response["models"] = excludeGeminiModels(upstreamModels, configuredModels)
 	return response
 }
 
-func (ch *VertexGeminiChannel) rewriteGeminiNativePathToVertex(req *http.Request, sa gcpServiceAccount) {
+func (ch *VertexGeminiChannel) rewriteGeminiNativePathToVertex(req *http.Request, sa gcpServiceAccount, location string) {
 	const geminiModelsPrefixV1Beta = "/v1beta/models"
 	const geminiModelsPrefixV1 = "/v1/models"
 
@@ -328,7 +719,11 @@ func (ch *VertexGeminiChannel) rewriteGeminiNativePathToVertex(req *http.Request
 	prefixBefore := req.URL.Path[:idx]
 	suffixAfter := req.URL.Path[idx+len(matchedPrefix):]
 
-	replacement, ok := ch.vertexModelsReplacement(prefixBefore, req.URL, sa)
+	// The method suffix (":generateContent", ":rawPredict", ...) is carried over unchanged in suffixAfter.
+	model, method, _ := strings.Cut(strings.TrimPrefix(suffixAfter, "/"), ":")
+	publisher := vertexPublisherFor(model, method)
+
+	replacement, ok := ch.vertexModelsReplacement(prefixBefore, sa, location, publisher)
 	if !ok {
 		return
 	}
@@ -336,7 +731,7 @@ func (ch *VertexGeminiChannel) rewriteGeminiNativePathToVertex(req *http.Request
 	req.URL.Path = prefixBefore + replacement + suffixAfter
 }
 
-func (ch *VertexGeminiChannel) vertexModelsReplacement(prefixBefore string, u *url.URL, sa gcpServiceAccount) (string, bool) {
+func (ch *VertexGeminiChannel) vertexModelsReplacement(prefixBefore string, sa gcpServiceAccount, location string, publisher string) (string, bool) {
 	// If upstream base path already includes a Vertex prefix, only append the missing parts.
 	switch {
 	case strings.Contains(prefixBefore, "/publishers/google/models"):
@@ -349,59 +744,321 @@ func (ch *VertexGeminiChannel) vertexModelsReplacement(prefixBefore string, u *u
 		}
 		return "/models", true
 	case strings.Contains(prefixBefore, "/projects/") && strings.Contains(prefixBefore, "/locations/"):
-		// Already at ".../projects/{p}/locations/{l}", append "/publishers/google/models".
-		return "/publishers/google/models", true
+		// Already at ".../projects/{p}/locations/{l}", append "/publishers/{publisher}/models".
+		return fmt.Sprintf("/publishers/%s/models", publisher), true
 	}
 
 	// Otherwise build a full Vertex models prefix under any upstream prefix path.
 	if sa.ProjectID == "" {
 		return "", false
 	}
-	location := extractVertexLocation(u)
 	if location == "" {
 		return "", false
 	}
 
-	return fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/google/models", sa.ProjectID, location), true
+	return fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/%s/models", sa.ProjectID, location, publisher), true
+}
+
+// vertexPartnerPublishers maps model name prefixes of partner models to their Vertex publisher.
+var vertexPartnerPublishers = []struct {
+	prefix    string
+	publisher string
+}{
+	{"claude", "anthropic"},
+	{"mistral", "mistralai"},
+	{"codestral", "mistralai"},
+	{"llama", "meta"},
+	{"jamba", "ai21"},
+}
+
+// vertexPublisherFor returns the Vertex publisher of a model. Partner models are only reachable through
+// rawPredict/streamRawPredict, so other methods always resolve to the google publisher.
+func vertexPublisherFor(model string, method string) string {
+	if method != "rawPredict" && method != "streamRawPredict" {
+		return "google"
+	}
+	lower := strings.ToLower(model)
+	for _, p := range vertexPartnerPublishers {
+		if strings.HasPrefix(lower, p.prefix) {
+			return p.publisher
+		}
+	}
+	return "google"
+}
+
+// discoverVertexLocation lists the locations available to a project and picks one, preferring
+// vertexPreferredLocation. Results are cached per upstream host and project. It returns "" on failure.
+func (ch *VertexGeminiChannel) discoverVertexLocation(ctx context.Context, client *http.Client, upstreamURL *url.URL, projectID string, accessToken string) string {
+	if upstreamURL == nil || projectID == "" {
+		return ""
+	}
+
+	cacheKey := upstreamURL.Host + "/" + projectID
+	ch.locationCacheMu.Lock()
+	location, ok := ch.locationCache[cacheKey]
+	ch.locationCacheMu.Unlock()
+	if ok {
+		return location
+	}
+
+	listURL := *upstreamURL
+	basePath := strings.TrimRight(listURL.Path, "/")
+	if idx := strings.Index(basePath, "/v1/projects/"); idx != -1 {
+		basePath = basePath[:idx]
+	}
+	listURL.Path = strings.TrimRight(basePath, "/") + fmt.Sprintf("/v1/projects/%s/locations", projectID)
+	listURL.RawPath = ""
+	ch.withPathPrefix(&listURL)
+	listURL.RawQuery = ""
+
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL.String(), nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		utils.LoggerFromContext(ctx).WithError(err).WithField("project_id", projectID).Warn("Failed to list vertex locations")
+		return ""
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		utils.LoggerFromContext(ctx).WithFields(logrus.Fields{"project_id": projectID, "status": resp.StatusCode}).Warn("Failed to list vertex locations")
+		return ""
+	}
+
+	var list struct {
+		Locations []struct {
+			LocationID string `json:"locationId"`
+		} `json:"locations"`
+	}
+	if err := json.Unmarshal(bodyBytes, &list); err != nil {
+		return ""
+	}
+
+	for _, l := range list.Locations {
+		if l.LocationID == vertexPreferredLocation {
+			location = l.LocationID
+			break
+		}
+		if location == "" {
+			location = l.LocationID
+		}
+	}
+	if location == "" {
+		return ""
+	}
+
+	utils.LoggerFromContext(ctx).WithFields(logrus.Fields{"project_id": projectID, "location": location}).Info("Discovered vertex location")
+
+	ch.locationCacheMu.Lock()
+	ch.locationCache[cacheKey] = location
+	ch.locationCacheMu.Unlock()
+
+	return location
 }
 
-func (ch *VertexGeminiChannel) getOrMintAccessToken(ctx context.Context, apiKeyID uint, sa gcpServiceAccount) (string, error) {
-	// Key IDs should always exist for stored keys, but be defensive for ad-hoc tests.
-	cacheKey := apiKeyID
+// tokenCacheKey identifies the credential an access token is minted for, so that keys sharing
+// a service account (and impersonation target) share one token.
+func (sa gcpServiceAccount) tokenCacheKey(scopes []string) string {
+	sum := sha256.Sum256([]byte(sa.ClientEmail + "|" + sa.PrivateKeyID + "|" + sa.ImpersonateServiceAccount + "|" + sa.TokenURI + "|" + strings.Join(scopes, " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// vertexTokenSkew returns how long before expiry a cached access token is refreshed.
+func vertexTokenSkew(group *models.Group) time.Duration {
+	return time.Duration(group.EffectiveConfig.VertexTokenSkew) * time.Second
+}
+
+func (ch *VertexGeminiChannel) getOrMintAccessToken(ctx context.Context, client *http.Client, sa gcpServiceAccount, group *models.Group) (string, error) {
+	cacheKey := sa.tokenCacheKey(vertexOAuthScopes(group))
 
 	ch.tokenCacheMu.Lock()
 	cached, ok := ch.tokenCache[cacheKey]
-	if ok && cached.AccessToken != "" && time.Until(cached.Expiry) > 2*time.Minute {
+	if ok && cached.usable(group) {
 		token := cached.AccessToken
 		ch.tokenCacheMu.Unlock()
 		return token, nil
 	}
 	ch.tokenCacheMu.Unlock()
 
-	token, expiry, err := ch.mintAccessTokenFromServiceAccount(ctx, sa)
+	// Concurrent requests for the same credential wait for a single mint. It runs detached from the
+	// request that started it, bounded by the mint timeout, so that request's cancellation or short
+	// deadline does not fail the others; each caller only stops waiting when its own context ends.
+	mintCtx := context.WithoutCancel(ctx)
+	result := ch.mintGroup.DoChan(cacheKey, func() (any, error) {
+		if group.EffectiveConfig.VertexTokenCache == VertexTokenCacheShared && ch.store != nil {
+			return ch.getOrMintSharedAccessToken(mintCtx, client, cacheKey, sa, group)
+		}
+
+		token, expiry, err := ch.mintAndLogAccessToken(mintCtx, client, sa, group)
+		if err != nil {
+			return "", err
+		}
+		ch.cacheLocalAccessToken(cacheKey, vertexAccessToken{AccessToken: token, Expiry: expiry, MintedAt: time.Now()})
+		return token, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", app_errors.WrapTimeout(app_errors.TimeoutTagTokenMint, ctx.Err())
+	case r := <-result:
+		if r.Err != nil {
+			return "", r.Err
+		}
+		return r.Val.(string), nil
+	}
+}
+
+// getOrMintSharedAccessToken looks up the access token in the shared store so that all instances reuse
+// one token per credential. A per-credential lock ensures only one instance mints while the others wait for its result.
+func (ch *VertexGeminiChannel) getOrMintSharedAccessToken(ctx context.Context, client *http.Client, cacheKey string, sa gcpServiceAccount, group *models.Group) (string, error) {
+	storeKey := "vertex_token:" + cacheKey
+	lockKey := storeKey + ":lock"
+
+	if cached, ok := ch.loadSharedAccessToken(ctx, storeKey, group); ok {
+		ch.cacheLocalAccessToken(cacheKey, cached)
+		return cached.AccessToken, nil
+	}
+
+	acquired, err := ch.store.SetNX(lockKey, []byte("1"), vertexTokenLockTTL)
+	if err != nil {
+		utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to acquire vertex token lock, minting without it")
+		acquired = true
+	}
+
+	if !acquired {
+		// Another instance is minting; wait for its token instead of stampeding the token endpoint.
+		deadline := time.Now().Add(vertexTokenLockTTL)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return "", app_errors.WrapTimeout(app_errors.TimeoutTagTokenMint, ctx.Err())
+			case <-time.After(vertexTokenPollInterval):
+			}
+			if cached, ok := ch.loadSharedAccessToken(ctx, storeKey, group); ok {
+				ch.cacheLocalAccessToken(cacheKey, cached)
+				return cached.AccessToken, nil
+			}
+		}
+	} else {
+		defer func() {
+			if err := ch.store.Delete(lockKey); err != nil {
+				utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to release vertex token lock")
+			}
+		}()
+	}
+
+	token, expiry, err := ch.mintAndLogAccessToken(ctx, client, sa, group)
 	if err != nil {
 		return "", err
 	}
 
+	minted := vertexAccessToken{AccessToken: token, Expiry: expiry, MintedAt: time.Now()}
+	ch.cacheLocalAccessToken(cacheKey, minted)
+	ch.saveSharedAccessToken(ctx, storeKey, minted, group)
+
+	return token, nil
+}
+
+// loadSharedAccessToken reads a still-valid access token from the shared store.
+func (ch *VertexGeminiChannel) loadSharedAccessToken(ctx context.Context, storeKey string, group *models.Group) (vertexAccessToken, bool) {
+	data, err := ch.store.Get(storeKey)
+	if err != nil {
+		if err != store.ErrNotFound {
+			utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to read vertex token from store")
+		}
+		return vertexAccessToken{}, false
+	}
+
+	decrypted, err := ch.encryptionSvc.Decrypt(string(data))
+	if err != nil {
+		utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to decrypt cached vertex token")
+		return vertexAccessToken{}, false
+	}
+
+	var cached vertexAccessToken
+	if err := json.Unmarshal([]byte(decrypted), &cached); err != nil {
+		return vertexAccessToken{}, false
+	}
+	if !cached.usable(group) {
+		return vertexAccessToken{}, false
+	}
+	return cached, true
+}
+
+// saveSharedAccessToken writes an access token to the shared store, expiring it once it is due for refresh.
+func (ch *VertexGeminiChannel) saveSharedAccessToken(ctx context.Context, storeKey string, token vertexAccessToken, group *models.Group) {
+	ttl := time.Until(token.refreshAt(group))
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return
+	}
+	encrypted, err := ch.encryptionSvc.Encrypt(string(data))
+	if err != nil {
+		utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to encrypt vertex token for store")
+		return
+	}
+	if err := ch.store.Set(storeKey, []byte(encrypted), ttl); err != nil {
+		utils.LoggerFromContext(ctx).WithError(err).Warn("Failed to write vertex token to store")
+	}
+}
+
+func (ch *VertexGeminiChannel) cacheLocalAccessToken(cacheKey string, token vertexAccessToken) {
 	ch.tokenCacheMu.Lock()
-	ch.tokenCache[cacheKey] = vertexAccessToken{AccessToken: token, Expiry: expiry}
+	ch.tokenCache[cacheKey] = token
 	ch.tokenCacheMu.Unlock()
+}
 
-	return token, nil
+func (ch *VertexGeminiChannel) mintAndLogAccessToken(ctx context.Context, client *http.Client, sa gcpServiceAccount, group *models.Group) (string, time.Time, error) {
+	ctx, span := tracing.Start(ctx, "vertex.token_mint", tracing.KindClient, tracing.String("group", group.Name), tracing.String("channel_type", group.ChannelType))
+	defer span.End()
+
+	mintStart := time.Now()
+	token, expiry, err := ch.mintAccessTokenFromServiceAccount(ctx, client, sa, vertexOAuthScopes(group), group.EffectiveConfig.VertexTokenURI, time.Duration(group.EffectiveConfig.VertexMintTimeout)*time.Second)
+	recordTokenMintTiming(ctx, time.Since(mintStart))
+	metrics.ObserveTokenMint(group.Name, err, time.Since(mintStart))
+	if err != nil {
+		if _, ok := app_errors.AsTokenMintError(err); !ok {
+			err = &app_errors.TokenMintError{Err: err}
+		}
+		err = app_errors.WrapTimeout(app_errors.TimeoutTagTokenMint, err)
+		span.RecordError(err)
+		utils.LogRequestLifecycle(ctx, group, true, logrus.Fields{"client_email": sa.ClientEmail, "error": err}, "Failed to mint Vertex access token")
+		return "", time.Time{}, err
+	}
+	utils.LogRequestLifecycle(ctx, group, false, logrus.Fields{"client_email": sa.ClientEmail, "expiry": expiry}, "Vertex access token minted")
+
+	return token, expiry, nil
 }
 
-func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Context, sa gcpServiceAccount) (string, time.Time, error) {
-	if sa.ClientEmail == "" || sa.PrivateKey == "" {
-		return "", time.Time{}, fmt.Errorf("invalid service account json: missing client_email/private_key")
+// vertexOAuthScopes returns the OAuth scopes configured for the group, defaulting to cloud-platform.
+func vertexOAuthScopes(group *models.Group) []string {
+	if scopes := utils.SplitAndTrim(group.EffectiveConfig.VertexOAuthScopes, ","); len(scopes) > 0 {
+		return scopes
 	}
+	return []string{vertexOAuthScope}
+}
 
-	tokenCtx := ctx
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
-		var cancel context.CancelFunc
-		tokenCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
+// mintAccessTokenFromServiceAccount exchanges a signed JWT for an access token. tokenEndpoint, when set,
+// overrides where the exchange is sent (e.g. an internal mirror); the JWT audience stays the service
+// account's token_uri. The exchange, impersonation included, is bounded by timeout even when ctx has a
+// longer deadline or none, as for streaming requests, so a hung token endpoint fails fast.
+func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Context, client *http.Client, sa gcpServiceAccount, scopes []string, tokenEndpoint string, timeout time.Duration) (string, time.Time, error) {
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return "", time.Time{}, &app_errors.TokenMintError{Permanent: true, Err: fmt.Errorf("invalid service account json: missing client_email/private_key")}
 	}
 
+	tokenCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	tokenURI := sa.TokenURI
 	if tokenURI == "" {
 		tokenURI = vertexDefaultTokenURI
@@ -423,13 +1080,18 @@ func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Con
 		Exp   int64  `json:"exp"`
 	}
 
-	headerJSON, err := json.Marshal(jwtHeader{Alg: "RS256", Typ: "JWT", Kid: sa.PrivateKeyID})
+	signer, err := newJWTSigner(sa.PrivateKey)
+	if err != nil {
+		return "", time.Time{}, &app_errors.TokenMintError{Permanent: true, Err: err}
+	}
+
+	headerJSON, err := json.Marshal(jwtHeader{Alg: signer.alg, Typ: "JWT", Kid: sa.PrivateKeyID})
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to marshal jwt header: %w", err)
 	}
 	claimsJSON, err := json.Marshal(jwtClaims{
 		Iss:   sa.ClientEmail,
-		Scope: vertexOAuthScope,
+		Scope: strings.Join(scopes, " "),
 		Aud:   tokenURI,
 		Iat:   now,
 		Exp:   exp,
@@ -442,9 +1104,9 @@ func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Con
 	claimsB64 := base64.RawURLEncoding.EncodeToString(claimsJSON)
 	unsigned := headerB64 + "." + claimsB64
 
-	sigB64, err := rs256Sign(unsigned, sa.PrivateKey)
+	sigB64, err := signer.Sign(unsigned)
 	if err != nil {
-		return "", time.Time{}, err
+		return "", time.Time{}, &app_errors.TokenMintError{Permanent: true, Err: err}
 	}
 
 	assertion := unsigned + "." + sigB64
@@ -453,13 +1115,16 @@ func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Con
 	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
 	form.Set("assertion", assertion)
 
-	req, err := http.NewRequestWithContext(tokenCtx, "POST", tokenURI, strings.NewReader(form.Encode()))
+	if tokenEndpoint == "" {
+		tokenEndpoint = tokenURI
+	}
+	req, err := http.NewRequestWithContext(tokenCtx, "POST", tokenEndpoint, strings.NewReader(form.Encode()))
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to create token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := ch.HTTPClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to exchange access token: %w", err)
 	}
@@ -472,7 +1137,7 @@ func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Con
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		parsed := app_errors.ParseUpstreamError(bodyBytes)
-		return "", time.Time{}, fmt.Errorf("[status %d] %s", resp.StatusCode, parsed)
+		return "", time.Time{}, newTokenEndpointError(resp.StatusCode, bodyBytes, fmt.Errorf("[status %d] %s", resp.StatusCode, parsed))
 	}
 
 	var tr struct {
@@ -491,25 +1156,135 @@ func (ch *VertexGeminiChannel) mintAccessTokenFromServiceAccount(ctx context.Con
 		expiresIn = 3600
 	}
 	expiry := time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	if sa.ImpersonateServiceAccount != "" {
+		return ch.generateImpersonatedAccessToken(tokenCtx, client, tr.AccessToken, sa.ImpersonateServiceAccount, scopes)
+	}
 	return tr.AccessToken, expiry, nil
 }
 
-func rs256Sign(unsigned string, privateKeyPEM string) (string, error) {
-	priv, err := parseRSAPrivateKeyFromPEM(privateKeyPEM)
+// generateImpersonatedAccessToken exchanges a source access token for a short-lived access token of the
+// target service account using the IAM Credentials API.
+func (ch *VertexGeminiChannel) generateImpersonatedAccessToken(ctx context.Context, client *http.Client, sourceToken string, target string, scopes []string) (string, time.Time, error) {
+	payload, err := json.Marshal(map[string]any{
+		"scope":    scopes,
+		"lifetime": "3600s",
+	})
 	if err != nil {
-		return "", err
+		return "", time.Time{}, fmt.Errorf("failed to marshal impersonation request: %w", err)
 	}
 
-	sum := sha256.Sum256([]byte(unsigned))
-	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sum[:])
+	reqURL := fmt.Sprintf(vertexIAMCredentialsURI, url.PathEscape(target))
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create impersonation request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+sourceToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to impersonate service account %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign jwt: %w", err)
+		return "", time.Time{}, fmt.Errorf("failed to read impersonation response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		parsed := app_errors.ParseUpstreamError(bodyBytes)
+		return "", time.Time{}, newTokenEndpointError(resp.StatusCode, bodyBytes, fmt.Errorf("[status %d] impersonate %s: %s", resp.StatusCode, target, parsed))
 	}
 
-	return base64.RawURLEncoding.EncodeToString(sig), nil
+	var tr struct {
+		AccessToken string `json:"accessToken"`
+		ExpireTime  string `json:"expireTime"`
+	}
+	if err := json.Unmarshal(bodyBytes, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse impersonation response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("impersonation response missing accessToken")
+	}
+
+	expiry, err := time.Parse(time.RFC3339, tr.ExpireTime)
+	if err != nil {
+		expiry = time.Now().Add(time.Hour)
+	}
+	return tr.AccessToken, expiry, nil
+}
+
+// newTokenEndpointError classifies a failed token endpoint response. Rejected credentials
+// (invalid_grant, invalid_client, unauthorized_client, or a 401/403) are permanent; anything else
+// such as 429 or 5xx is treated as transient.
+func newTokenEndpointError(statusCode int, body []byte, err error) *app_errors.TokenMintError {
+	permanent := statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+	if statusCode == http.StatusBadRequest {
+		bodyStr := string(body)
+		for _, code := range []string{"invalid_grant", "invalid_client", "unauthorized_client"} {
+			if strings.Contains(bodyStr, code) {
+				permanent = true
+				break
+			}
+		}
+	}
+	return &app_errors.TokenMintError{Permanent: permanent, StatusCode: statusCode, Err: err}
+}
+
+// jwtSigner signs JWT assertions with a service account private key, choosing the algorithm
+// from the key type: RS256 for RSA keys and ES256 for EC P-256 keys.
+type jwtSigner struct {
+	key crypto.Signer
+	alg string
+}
+
+func newJWTSigner(privateKeyPEM string) (*jwtSigner, error) {
+	priv, err := parsePrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := priv.(type) {
+	case *rsa.PrivateKey:
+		return &jwtSigner{key: key, alg: "RS256"}, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ec curve %s, only P-256 is supported", key.Curve.Params().Name)
+		}
+		return &jwtSigner{key: key, alg: "ES256"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+}
+
+func (s *jwtSigner) Sign(unsigned string) (string, error) {
+	sum := sha256.Sum256([]byte(unsigned))
+
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			return "", fmt.Errorf("failed to sign jwt: %w", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(sig), nil
+	case *ecdsa.PrivateKey:
+		r, sv, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			return "", fmt.Errorf("failed to sign jwt: %w", err)
+		}
+		// JWS encodes ES256 signatures as fixed-size R || S, not ASN.1.
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		sv.FillBytes(sig[32:])
+		return base64.RawURLEncoding.EncodeToString(sig), nil
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", s.key)
+	}
 }
 
-func parseRSAPrivateKeyFromPEM(pemStr string) (*rsa.PrivateKey, error) {
+func parsePrivateKeyFromPEM(pemStr string) (crypto.PrivateKey, error) {
 	block, _ := pem.Decode([]byte(pemStr))
 	if block == nil {
 		return nil, fmt.Errorf("invalid private key pem")
@@ -517,18 +1292,22 @@ func parseRSAPrivateKeyFromPEM(pemStr string) (*rsa.PrivateKey, error) {
 
 	// Service account keys are usually PKCS8 ("BEGIN PRIVATE KEY").
 	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
-		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
-			return rsaKey, nil
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return key, nil
 		}
-		return nil, fmt.Errorf("private key is not rsa")
+		return nil, fmt.Errorf("private key is neither rsa nor ec")
 	}
 
-	// Fallback to PKCS1 ("BEGIN RSA PRIVATE KEY") if needed.
+	// Fallback to PKCS1 ("BEGIN RSA PRIVATE KEY") or SEC1 ("BEGIN EC PRIVATE KEY") if needed.
 	if rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
 		return rsaKey, nil
 	}
+	if ecKey, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return ecKey, nil
+	}
 
-	return nil, fmt.Errorf("failed to parse rsa private key")
+	return nil, fmt.Errorf("failed to parse private key")
 }
 
 func parseGCPServiceAccount(keyValue string) (gcpServiceAccount, error) {
@@ -550,39 +1329,178 @@ func parseGCPServiceAccount(keyValue string) (gcpServiceAccount, error) {
 	return sa, nil
 }
 
+// vertexLocationCandidate is one source considered when inferring the Vertex location of an upstream URL.
+type vertexLocationCandidate struct {
+	Source   string
+	Location string
+}
+
 func extractVertexLocation(u *url.URL) string {
+	for _, candidate := range vertexLocationCandidates(u) {
+		if candidate.Location != "" {
+			return candidate.Location
+		}
+	}
+	return ""
+}
+
+// vertexLocationCandidates lists, in priority order, every source checked when inferring the location
+// of an upstream URL. Candidates that did not yield a location have an empty Location.
+func vertexLocationCandidates(u *url.URL) []vertexLocationCandidate {
+	const suffix = "-aiplatform.googleapis.com"
+	candidates := []vertexLocationCandidate{
+		{Source: "path segment '/locations/{location}'"},
+		{Source: "hostname '{location}" + suffix + "'"},
+		{Source: "global hostname 'aiplatform.googleapis.com'"},
+	}
 	if u == nil {
-		return ""
+		return candidates
 	}
 
 	// Prefer extracting from path: .../locations/{location}/...
 	parts := strings.Split(u.Path, "/")
 	for i, part := range parts {
-		if part == "locations" && i+1 < len(parts) {
-			location := parts[i+1]
-			if location != "" {
-				return location
-			}
+		if part == "locations" && i+1 < len(parts) && parts[i+1] != "" {
+			candidates[0].Location = parts[i+1]
+			break
 		}
 	}
 
 	// Fallback to hostname convention: {location}-aiplatform.googleapis.com / aiplatform.googleapis.com (global)
 	host := u.Hostname()
-	if host == "" {
-		return ""
+	if strings.HasSuffix(host, suffix) {
+		candidates[1].Location = strings.TrimSuffix(host, suffix)
 	}
 	if host == "aiplatform.googleapis.com" {
-		return "global"
+		candidates[2].Location = "global"
 	}
-	const suffix = "-aiplatform.googleapis.com"
-	if strings.HasSuffix(host, suffix) {
-		location := strings.TrimSuffix(host, suffix)
-		if location != "" {
-			return location
+
+	return candidates
+}
+
+// vertexLocationError explains which location sources were tried for an upstream URL.
+func vertexLocationError(u *url.URL) error {
+	candidates := vertexLocationCandidates(u)
+	tried := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		tried = append(tried, candidate.Source)
+	}
+
+	upstream := ""
+	if u != nil {
+		upstream = u.String()
+	}
+	return fmt.Errorf("unable to infer vertex location from upstream %q; tried %s. Add '/v1/projects/{project}/locations/{location}' to the upstream path or use a regional host", upstream, strings.Join(tried, ", "))
+}
+
+// applyVertexModelLocation routes a request to the location configured for its model in the group's
+// Vertex location map. Requests for unmapped models keep the location of the upstream URL.
+func applyVertexModelLocation(req *http.Request, group *models.Group) {
+	if len(group.VertexLocationMap) == 0 || req == nil || req.URL == nil {
+		return
+	}
+
+	parts := strings.Split(req.URL.Path, "/")
+	model := ""
+	for i, part := range parts {
+		if part == "models" && i+1 < len(parts) {
+			model = strings.Split(parts[i+1], ":")[0]
+			break
+		}
+	}
+	location, ok := group.VertexLocationMap[model]
+	if model == "" || !ok {
+		return
+	}
+
+	for i, part := range parts {
+		if part == "locations" && i+1 < len(parts) {
+			parts[i+1] = location
+			req.URL.Path = strings.Join(parts, "/")
+			req.URL.RawPath = ""
+			break
 		}
 	}
 
-	return ""
+	if host := vertexHostForLocation(req.URL.Host, location); host != req.URL.Host {
+		req.URL.Host = host
+		req.Host = host
+	}
+}
+
+// applyVertexCachedContent references the group's cached content in Gemini generateContent and
+// streamGenerateContent requests that do not name one themselves. A bare cache ID is expanded to a
+// resource name in the project and location of the request.
+func applyVertexCachedContent(req *http.Request, group *models.Group) error {
+	cachedContent := strings.TrimSpace(group.EffectiveConfig.VertexCachedContent)
+	if cachedContent == "" || req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.URL.Path, "/publishers/google/") {
+		return nil
+	}
+	path := req.URL.Path
+	method := path[strings.LastIndex(path, ":")+1:]
+	if method != "generateContent" && method != "streamGenerateContent" {
+		return nil
+	}
+
+	if !strings.Contains(cachedContent, "/") {
+		projectID, location := extractVertexProjectID(req.URL), extractVertexLocation(req.URL)
+		if projectID == "" || location == "" {
+			return nil
+		}
+		cachedContent = fmt.Sprintf("projects/%s/locations/%s/cachedContents/%s", projectID, location, cachedContent)
+	}
+
+	bodyBytes, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return nil
+	}
+	if _, ok := payload["cachedContent"]; ok {
+		return nil
+	}
+	payload["cachedContent"], _ = json.Marshal(cachedContent)
+	newBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(newBody))
+	req.ContentLength = int64(len(newBody))
+	return nil
+}
+
+// vertexHostForLocation returns the regional Vertex host for a location when host follows the
+// {location}-aiplatform.googleapis.com convention. Other hosts (e.g. reverse proxies) are returned unchanged.
+func vertexHostForLocation(host string, location string) string {
+	const globalHost = "aiplatform.googleapis.com"
+	const suffix = "-aiplatform.googleapis.com"
+
+	hostname, port, hasPort := strings.Cut(host, ":")
+	if hostname != globalHost && !strings.HasSuffix(hostname, suffix) {
+		return host
+	}
+
+	hostname = location + suffix
+	if location == "global" {
+		hostname = globalHost
+	}
+	if hasPort {
+		return hostname + ":" + port
+	}
+	return hostname
+}
+
+// vertexURLForLocation returns a copy of the upstream URL whose host targets the given location.
+func vertexURLForLocation(u *url.URL, location string) *url.URL {
+	result := *u
+	result.Host = vertexHostForLocation(u.Host, location)
+	return &result
 }
 
 func extractVertexProjectID(u *url.URL) string {
@@ -602,7 +1520,9 @@ func extractVertexProjectID(u *url.URL) string {
 	return ""
 }
 
-func buildVertexModelMethodURL(upstreamURL *url.URL, projectID string, location string, model string, method string) (string, error) {
+// buildVertexModelMethodURL builds the URL of a model method below an upstream, keeping its base path and
+// the upstream path prefix.
+func (ch *VertexGeminiChannel) buildVertexModelMethodURL(upstreamURL *url.URL, projectID string, location string, model string, method string) (string, error) {
 	if upstreamURL == nil {
 		return "", fmt.Errorf("nil upstream url")
 	}
@@ -611,9 +1531,10 @@ func buildVertexModelMethodURL(upstreamURL *url.URL, projectID string, location
 	}
 
 	vertexPath := fmt.Sprintf(
-		"/v1/projects/%s/locations/%s/publishers/google/models/%s:%s",
+		"/v1/projects/%s/locations/%s/publishers/%s/models/%s:%s",
 		projectID,
 		location,
+		vertexPublisherFor(model, method),
 		model,
 		method,
 	)
@@ -626,6 +1547,8 @@ func buildVertexModelMethodURL(upstreamURL *url.URL, projectID string, location
 		basePath = basePath[:idx]
 	}
 	finalURL.Path = strings.TrimRight(basePath, "/") + vertexPath
+	finalURL.RawPath = ""
+	ch.withPathPrefix(&finalURL)
 	finalURL.RawQuery = ""
 
 	return finalURL.String(), nil
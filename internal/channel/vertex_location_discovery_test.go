@@ -0,0 +1,126 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+// newTestLocationsServer mocks the Vertex locations list of project p, answering with status and body.
+func newTestLocationsServer(t *testing.T, status int, body string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path != "/base/v1/projects/p/locations" || r.Header.Get("Authorization") != "Bearer token" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func TestDiscoverVertexLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "preferred location",
+			status: http.StatusOK,
+			body:   `{"locations":[{"locationId":"europe-west4"},{"locationId":"us-central1"},{"locationId":"asia-east1"}]}`,
+			want:   vertexPreferredLocation,
+		},
+		{
+			name:   "first location without the preferred one",
+			status: http.StatusOK,
+			body:   `{"locations":[{"locationId":"europe-west4"},{"locationId":"asia-east1"}]}`,
+			want:   "europe-west4",
+		},
+		{name: "empty list", status: http.StatusOK, body: `{"locations":[]}`},
+		{name: "malformed list", status: http.StatusOK, body: `not json`},
+		{name: "listing denied", status: http.StatusForbidden, body: `{"error":{"message":"permission denied"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requested := newTestLocationsServer(t, tt.status, tt.body)
+			upstreamURL, _ := url.Parse(server.URL + "/base/v1/projects/p")
+			ch := newTestVertexChannel(t)
+
+			for range 2 {
+				if got := ch.discoverVertexLocation(context.Background(), server.Client(), upstreamURL, "p", "token"); got != tt.want {
+					t.Errorf("discoverVertexLocation = %q, want %q", got, tt.want)
+				}
+			}
+
+			// Only discovered locations are cached; failures are retried.
+			wantRequests := 2
+			if tt.want != "" {
+				wantRequests = 1
+			}
+			if len(*requested) != wantRequests {
+				t.Errorf("locations listed %d times, want %d", len(*requested), wantRequests)
+			}
+		})
+	}
+}
+
+func TestValidateKeyDiscoversLocation(t *testing.T) {
+	tokenEndpoint, _ := newTestTokenEndpoint(t)
+	var validated []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/projects/p/locations" {
+			io.WriteString(w, `{"locations":[{"locationId":"europe-west4"}]}`)
+			return
+		}
+		validated = append(validated, r.URL.Path)
+		io.WriteString(w, `{"candidates":[]}`)
+	}))
+	defer upstream.Close()
+
+	// The global upstream URL names no location.
+	upstreamURL, _ := url.Parse(upstream.URL + "/v1/projects/p")
+	ch := newTestVertexChannel(t)
+	ch.Upstreams = []UpstreamInfo{{URL: upstreamURL, Weight: 1}}
+	ch.HTTPClient = upstream.Client()
+	ch.TestModel = "gemini-2.0-flash"
+
+	keyJSON, err := json.Marshal(newTestServiceAccount(t, tokenEndpoint.URL))
+	if err != nil {
+		t.Fatalf("marshal service account: %v", err)
+	}
+	key := &models.APIKey{ID: 1, KeyValue: string(keyJSON)}
+
+	for _, autoRegion := range []bool{false, true} {
+		group := &models.Group{Name: "vertex", ChannelType: "vertex_gemini"}
+		group.EffectiveConfig.VertexMintTimeout = 5
+		group.EffectiveConfig.VertexTokenCache = VertexTokenCacheMemory
+		group.EffectiveConfig.VertexAutoRegion = autoRegion
+
+		valid, err := ch.ValidateKey(context.Background(), key, group)
+		if !autoRegion {
+			if valid || err == nil {
+				t.Error("key validated without a location or auto region discovery")
+			}
+			continue
+		}
+		if !valid {
+			t.Fatalf("ValidateKey with auto region discovery: %v", err)
+		}
+	}
+	want := "/v1/projects/p/locations/europe-west4/publishers/google/models/gemini-2.0-flash:generateContent"
+	if len(validated) != 1 || validated[0] != want {
+		t.Errorf("validation requests = %v, want one to %s", validated, want)
+	}
+}
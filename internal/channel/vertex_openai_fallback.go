@@ -0,0 +1,81 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// vertexOpenAIEndpointPath is the OpenAI-compatible chat completions endpoint of a Vertex project location.
+const vertexOpenAIEndpointPath = "/endpoints/openapi/chat/completions"
+
+// FallbackRequest retries a native Gemini generateContent request on the OpenAI-compatible endpoint of the
+// same project and location when Vertex rejects the method for the model. The body is translated to a chat
+// completion and the response back to generateContent. It requires vertex_openai_fallback.
+func (ch *VertexGeminiChannel) FallbackRequest(req *http.Request, body []byte, group *models.Group, statusCode int, errorBody []byte) (*http.Request, ResponseTranslator, error) {
+	if !group.EffectiveConfig.VertexOpenAIFallback || !isUnsupportedMethodError(statusCode, errorBody) {
+		return nil, nil, nil
+	}
+
+	path := req.URL.Path
+	const modelsSegment = "/publishers/google/models/"
+	index := strings.Index(path, modelsSegment)
+	if index < 0 {
+		return nil, nil, nil
+	}
+	model, method, ok := strings.Cut(path[index+len(modelsSegment):], ":")
+	if !ok || model == "" || (method != "generateContent" && method != "streamGenerateContent") {
+		return nil, nil, nil
+	}
+
+	logger := utils.LoggerFromContext(req.Context()).WithField("group", group.Name)
+	// The OpenAI-compatible endpoint addresses models by publisher.
+	translated, err := geminiToOpenAIRequest(body, "google/"+model, method == "streamGenerateContent", logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fallbackURL := *req.URL
+	fallbackURL.Path = path[:index] + vertexOpenAIEndpointPath
+	fallbackURL.RawPath = ""
+	fallbackURL.RawQuery = ""
+
+	fallbackReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, fallbackURL.String(), bytes.NewReader(translated))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create fallback request: %w", err)
+	}
+	fallbackReq.Header = req.Header.Clone()
+	fallbackReq.Header.Set("Content-Type", "application/json")
+	// Let the transport decompress the response, which is rewritten anyway.
+	fallbackReq.Header.Del("Accept-Encoding")
+
+	logger.WithFields(logrus.Fields{
+		"model":         model,
+		"status":        statusCode,
+		"fallback_path": fallbackURL.Path,
+	}).Warn("Vertex rejected the native method for the model, falling back to the OpenAI-compatible endpoint")
+	return fallbackReq, &openAIToGemini{toolCalls: make(map[int]*geminiStreamedCall)}, nil
+}
+
+// isUnsupportedMethodError reports whether a Vertex error response says the model does not support the
+// method called, as opposed to a problem with the request itself.
+func isUnsupportedMethodError(statusCode int, errorBody []byte) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+	default:
+		return false
+	}
+
+	message := strings.ToLower(app_errors.ParseUpstreamError(errorBody))
+	if !strings.Contains(message, "generatecontent") && !strings.Contains(message, "method") {
+		return false
+	}
+	return strings.Contains(message, "not supported") || strings.Contains(message, "unsupported") || strings.Contains(message, "does not support")
+}
@@ -0,0 +1,139 @@
+package channel
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+const unsupportedMethodError = `{"error":{"code":400,"message":"The model does not support generateContent. Use the chat completions endpoint instead.","status":"FAILED_PRECONDITION"}}`
+
+func TestIsUnsupportedMethodError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"unsupported generateContent", http.StatusBadRequest, unsupportedMethodError, true},
+		{"unsupported method on 404", http.StatusNotFound, `{"error":{"message":"Method generateContent is not supported for this model."}}`, true},
+		{"invalid argument", http.StatusBadRequest, `{"error":{"message":"Request contains an invalid argument."}}`, false},
+		{"model not found", http.StatusNotFound, `{"error":{"message":"Publisher model was not found."}}`, false},
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"Method generateContent is not supported."}}`, false},
+		{"server error", http.StatusInternalServerError, unsupportedMethodError, false},
+	}
+	for _, tt := range tests {
+		if got := isUnsupportedMethodError(tt.status, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: isUnsupportedMethodError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVertexFallbackRequest(t *testing.T) {
+	ch := &VertexGeminiChannel{BaseChannel: &BaseChannel{}}
+	enabled := &models.Group{Name: "vertex"}
+	enabled.EffectiveConfig.VertexOpenAIFallback = true
+	body := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"maxOutputTokens":8}}`
+
+	tests := []struct {
+		name       string
+		url        string
+		group      *models.Group
+		status     int
+		errorBody  string
+		wantURL    string
+		wantStream bool
+	}{
+		{
+			name:      "unary",
+			url:       "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/m:generateContent",
+			group:     enabled,
+			status:    http.StatusBadRequest,
+			errorBody: unsupportedMethodError,
+			wantURL:   "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/endpoints/openapi/chat/completions",
+		},
+		{
+			name:       "streaming behind a path prefix",
+			url:        "https://gw.example.com/llm/v1/projects/p/locations/global/publishers/google/models/m:streamGenerateContent?alt=sse",
+			group:      enabled,
+			status:     http.StatusBadRequest,
+			errorBody:  unsupportedMethodError,
+			wantURL:    "https://gw.example.com/llm/v1/projects/p/locations/global/endpoints/openapi/chat/completions",
+			wantStream: true,
+		},
+		{
+			name:      "disabled",
+			url:       "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/m:generateContent",
+			group:     &models.Group{Name: "vertex"},
+			status:    http.StatusBadRequest,
+			errorBody: unsupportedMethodError,
+		},
+		{
+			name:      "other errors",
+			url:       "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/m:generateContent",
+			group:     enabled,
+			status:    http.StatusBadRequest,
+			errorBody: `{"error":{"message":"Request contains an invalid argument."}}`,
+		},
+		{
+			name:      "partner models",
+			url:       "https://aiplatform.googleapis.com/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude:rawPredict",
+			group:     enabled,
+			status:    http.StatusBadRequest,
+			errorBody: `{"error":{"message":"Method rawPredict is not supported."}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.url, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			fallbackReq, translator, err := ch.FallbackRequest(req, []byte(body), tt.group, tt.status, []byte(tt.errorBody))
+			if err != nil {
+				t.Fatalf("FallbackRequest: %v", err)
+			}
+			if tt.wantURL == "" {
+				if fallbackReq != nil {
+					t.Fatalf("got fallback to %s, want none", fallbackReq.URL)
+				}
+				return
+			}
+			if fallbackReq == nil || translator == nil {
+				t.Fatalf("got no fallback, want %s", tt.wantURL)
+			}
+			if got := fallbackReq.URL.String(); got != tt.wantURL {
+				t.Errorf("fallback URL = %q, want %q", got, tt.wantURL)
+			}
+			if got := fallbackReq.Header.Get("Authorization"); got != "Bearer token" {
+				t.Errorf("Authorization = %q, want the native request's", got)
+			}
+			if got := fallbackReq.Header.Get("Accept-Encoding"); got != "" {
+				t.Errorf("Accept-Encoding = %q, want it removed", got)
+			}
+
+			sent, err := io.ReadAll(fallbackReq.Body)
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+			var chat struct {
+				Model     string           `json:"model"`
+				Messages  []map[string]any `json:"messages"`
+				MaxTokens int              `json:"max_tokens"`
+				Stream    bool             `json:"stream"`
+			}
+			if err := json.Unmarshal(sent, &chat); err != nil {
+				t.Fatalf("fallback body %s: %v", sent, err)
+			}
+			if chat.Model != "google/m" || len(chat.Messages) != 1 || chat.MaxTokens != 8 || chat.Stream != tt.wantStream {
+				t.Errorf("fallback body = %s", sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package channel
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestApplyPartnerModelMethod(t *testing.T) {
+	const partnerPath = "https://aiplatform.googleapis.com/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet"
+
+	tests := []struct {
+		name     string
+		url      string
+		accept   string
+		body     string
+		wantPath string
+	}{
+		{
+			name:     "unary stays rawPredict",
+			url:      partnerPath + ":rawPredict",
+			body:     `{"messages":[]}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:rawPredict",
+		},
+		{
+			name:     "stream body selects streamRawPredict",
+			url:      partnerPath + ":rawPredict",
+			body:     `{"stream":true}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:streamRawPredict",
+		},
+		{
+			name:     "SSE accept header selects streamRawPredict",
+			url:      partnerPath + ":rawPredict",
+			accept:   "text/event-stream",
+			body:     `{}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:streamRawPredict",
+		},
+		{
+			name:     "stream query selects streamRawPredict",
+			url:      partnerPath + ":rawPredict?stream=true",
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:streamRawPredict",
+		},
+		{
+			name:     "streamRawPredict without stream body is kept",
+			url:      partnerPath + ":streamRawPredict",
+			body:     `{"messages":[]}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:streamRawPredict",
+		},
+		{
+			name:     "google models are left alone",
+			url:      "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent",
+			body:     `{"stream":true}`,
+			wantPath: "/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newPartnerTestRequest(t, tt.url, tt.accept, tt.body)
+			if err := applyPartnerModelMethod(req); err != nil {
+				t.Fatalf("applyPartnerModelMethod: %v", err)
+			}
+			if req.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", req.URL.Path, tt.wantPath)
+			}
+			assertBodyKept(t, req, tt.body)
+
+			// The selected method must agree with how the proxy treats the request.
+			stream := isVertexStreamRequest(req.URL, req.Header, []byte(tt.body))
+			if partner := !strings.Contains(tt.wantPath, "/publishers/google/"); partner && stream != strings.HasSuffix(tt.wantPath, ":streamRawPredict") {
+				t.Errorf("isVertexStreamRequest = %v, disagrees with method of %q", stream, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestApplyPartnerModelMethodAfterGeminiNativeRewrite(t *testing.T) {
+	ch := &VertexGeminiChannel{BaseChannel: &BaseChannel{}}
+	sa := gcpServiceAccount{ProjectID: "p"}
+
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		wantPath string
+	}{
+		{
+			name:     "unary",
+			url:      "https://aiplatform.googleapis.com/v1beta/models/claude-3-5-sonnet:rawPredict",
+			body:     `{"max_tokens":16}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:rawPredict",
+		},
+		{
+			name:     "streaming",
+			url:      "https://aiplatform.googleapis.com/v1beta/models/claude-3-5-sonnet:rawPredict",
+			body:     `{"max_tokens":16,"stream":true}`,
+			wantPath: "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet:streamRawPredict",
+		},
+		{
+			name:     "streaming behind a path prefix",
+			url:      "https://gw.example.com/llm/v1beta/models/mistral-large:rawPredict",
+			body:     `{"stream":true}`,
+			wantPath: "/llm/v1/projects/p/locations/us-east5/publishers/mistralai/models/mistral-large:streamRawPredict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newPartnerTestRequest(t, tt.url, "", tt.body)
+			ch.rewriteGeminiNativePathToVertex(req, sa, "us-east5")
+			if err := applyPartnerModelMethod(req); err != nil {
+				t.Fatalf("applyPartnerModelMethod: %v", err)
+			}
+			if req.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", req.URL.Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func newPartnerTestRequest(t *testing.T, rawURL, accept, body string) *http.Request {
+	t.Helper()
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(http.MethodPost, rawURL, bodyReader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return req
+}
+
+func assertBodyKept(t *testing.T, req *http.Request, want string) {
+	t.Helper()
+	if req.Body == nil {
+		if want != "" {
+			t.Errorf("body dropped, want %q", want)
+		}
+		return
+	}
+	got, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestValidateKeyProjectMismatch(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	tokenEndpoint, _ := newTestTokenEndpoint(t)
+	var validated []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		validated = append(validated, r.URL.Path)
+		io.WriteString(w, `{"candidates":[]}`)
+	}))
+	defer upstream.Close()
+
+	// The service account belongs to project p.
+	keyJSON, err := json.Marshal(newTestServiceAccount(t, tokenEndpoint.URL))
+	if err != nil {
+		t.Fatalf("marshal service account: %v", err)
+	}
+	key := &models.APIKey{ID: 1, KeyValue: string(keyJSON)}
+
+	tests := []struct {
+		name        string
+		urlProject  string
+		strict      bool
+		wantValid   bool
+		wantWarning bool
+	}{
+		{name: "matching project", urlProject: "p", wantValid: true},
+		{name: "matching project in strict mode", urlProject: "p", strict: true, wantValid: true},
+		{name: "mismatching project warns", urlProject: "other", wantValid: true, wantWarning: true},
+		{name: "mismatching project fails in strict mode", urlProject: "other", strict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			validated = nil
+			upstreamURL, _ := url.Parse(upstream.URL + "/v1/projects/" + tt.urlProject + "/locations/us-central1")
+			ch := newTestVertexChannel(t)
+			ch.Upstreams = []UpstreamInfo{{URL: upstreamURL, Weight: 1}}
+			ch.HTTPClient = upstream.Client()
+			ch.TestModel = "gemini-2.0-flash"
+			group := &models.Group{Name: "vertex", ChannelType: "vertex_gemini"}
+			group.EffectiveConfig.VertexMintTimeout = 5
+			group.EffectiveConfig.VertexTokenCache = VertexTokenCacheMemory
+			group.EffectiveConfig.VertexStrictProject = tt.strict
+
+			valid, err := ch.ValidateKey(context.Background(), key, group)
+			if valid != tt.wantValid {
+				t.Fatalf("ValidateKey = %v, %v; want valid %v", valid, err, tt.wantValid)
+			}
+			if !tt.wantValid {
+				if err == nil || !strings.Contains(err.Error(), `upstream url project "other" does not match service account project "p"`) {
+					t.Errorf("error = %v, want the project mismatch", err)
+				}
+				if len(validated) != 0 {
+					t.Errorf("mismatching key sent upstream: %v", validated)
+				}
+				return
+			}
+
+			// The url project is used either way.
+			if len(validated) != 1 || !strings.HasPrefix(validated[0], "/v1/projects/"+tt.urlProject+"/") {
+				t.Errorf("validation requests = %v, want one to project %s", validated, tt.urlProject)
+			}
+			warned := false
+			for _, entry := range hook.AllEntries() {
+				if entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "differs from service account project") {
+					warned = true
+					if entry.Data["url_project"] != tt.urlProject || entry.Data["key_project"] != "p" {
+						t.Errorf("warning fields = %v", entry.Data)
+					}
+				}
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("mismatch warning logged = %v, want %v", warned, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Vertex 多服务账号密钥的账号选择方式
+const (
+	VertexAccountOrderFailover   = "failover"
+	VertexAccountOrderRoundRobin = "round_robin"
+)
+
+// vertexAccountCooldown is how long an account that hit a quota error is skipped when the upstream
+// does not send Retry-After.
+const vertexAccountCooldown = 60 * time.Second
+
+// parseGCPServiceAccounts parses a key holding either one service account JSON object or a JSON array
+// of them. Accounts of an array are used for project failover.
+func parseGCPServiceAccounts(keyValue string) ([]gcpServiceAccount, error) {
+	trimmed := strings.TrimSpace(keyValue)
+	if !strings.HasPrefix(trimmed, "[") {
+		sa, err := parseGCPServiceAccount(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return []gcpServiceAccount{sa}, nil
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal([]byte(trimmed), &raws); err != nil {
+		return nil, fmt.Errorf("vertex_gemini expects a GCP service account JSON or an array of them as key: %w", err)
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("empty service account array")
+	}
+
+	accounts := make([]gcpServiceAccount, 0, len(raws))
+	for i, raw := range raws {
+		sa, err := parseGCPServiceAccount(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("service account %d: %w", i+1, err)
+		}
+		accounts = append(accounts, sa)
+	}
+	return accounts, nil
+}
+
+// accountID identifies a service account of a multi-account key for cooldown tracking.
+func (sa gcpServiceAccount) accountID() string {
+	return sa.ClientEmail + "|" + sa.ProjectID
+}
+
+// selectServiceAccount picks the account to use for a request. Accounts cooling down after a quota
+// error are skipped; if all are cooling down, the one that recovers first is used.
+func (ch *VertexGeminiChannel) selectServiceAccount(apiKey *models.APIKey, accounts []gcpServiceAccount, group *models.Group) gcpServiceAccount {
+	if len(accounts) == 1 {
+		return accounts[0]
+	}
+
+	ch.accountMu.Lock()
+	defer ch.accountMu.Unlock()
+
+	start := 0
+	if group.EffectiveConfig.VertexAccountOrder == VertexAccountOrderRoundRobin {
+		start = ch.accountCursors[apiKey.ID] % len(accounts)
+		ch.accountCursors[apiKey.ID] = start + 1
+	}
+
+	now := time.Now()
+	best := accounts[start]
+	var bestUntil time.Time
+	for i := range accounts {
+		sa := accounts[(start+i)%len(accounts)]
+		until, cooling := ch.accountCooldowns[sa.accountID()]
+		if !cooling || !now.Before(until) {
+			return sa
+		}
+		if bestUntil.IsZero() || until.Before(bestUntil) {
+			best, bestUntil = sa, until
+		}
+	}
+	return best
+}
+
+// ObserveUpstreamResponse puts the account that served a request of a multi-account key on cooldown
+// when the upstream reports a quota error, so that following requests fail over to the next project.
+func (ch *VertexGeminiChannel) ObserveUpstreamResponse(req *http.Request, apiKey *models.APIKey, group *models.Group, resp *http.Response) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	accounts, err := parseGCPServiceAccounts(apiKey.KeyValue)
+	if err != nil || len(accounts) < 2 {
+		return
+	}
+
+	projectID := extractVertexProjectID(req.URL)
+	for _, sa := range accounts {
+		if sa.ProjectID != projectID {
+			continue
+		}
+
+		cooldown := vertexAccountCooldown
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			cooldown = time.Duration(seconds) * time.Second
+		}
+
+		ch.accountMu.Lock()
+		ch.accountCooldowns[sa.accountID()] = time.Now().Add(cooldown)
+		ch.accountMu.Unlock()
+
+		utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+			"group":        group.Name,
+			"project_id":   projectID,
+			"client_email": sa.ClientEmail,
+			"cooldown":     cooldown,
+		}).Warn("Vertex project hit quota, failing over to the next service account")
+		return
+	}
+}
+
+// ParseRetryAfter reads the standard headers and, failing that, the RetryInfo detail Google attaches
+// to RESOURCE_EXHAUSTED errors ({"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "30s"}).
+func (ch *VertexGeminiChannel) ParseRetryAfter(resp *http.Response) time.Duration {
+	if d := parseRetryAfterHeaders(resp.Header); d > 0 {
+		return d
+	}
+	if resp.Body == nil {
+		return 0
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return 0
+	}
+
+	var errResp struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(bodyBytes, &errResp); err != nil {
+		return 0
+	}
+	for _, detail := range errResp.Error.Details {
+		if !strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+			continue
+		}
+		if d, err := time.ParseDuration(detail.RetryDelay); err == nil {
+			return clampRetryAfter(d)
+		}
+	}
+	return 0
+}
+
+// replaceVertexProjectID replaces the project segment of a Vertex path, if any.
+func replaceVertexProjectID(u *url.URL, projectID string) {
+	parts := strings.Split(u.Path, "/")
+	for i, part := range parts {
+		if part == "projects" && i+1 < len(parts) && parts[i+1] != "" {
+			parts[i+1] = projectID
+			u.Path = strings.Join(parts, "/")
+			u.RawPath = ""
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// vertexAutoTestModel as a group's test model lets key validation pick a model available to the project.
+const vertexAutoTestModel = "auto"
+
+// vertexCheapTestModels are the models preferred for validation requests, cheapest first.
+var vertexCheapTestModels = []string{
+	"gemini-2.0-flash-lite",
+	"gemini-2.5-flash-lite",
+	"gemini-2.0-flash",
+	"gemini-2.5-flash",
+	"gemini-1.5-flash",
+}
+
+// usesAutoTestModel reports whether the test model is left to discovery.
+func (ch *VertexGeminiChannel) usesAutoTestModel() bool {
+	return ch.TestModel == "" || strings.EqualFold(ch.TestModel, vertexAutoTestModel)
+}
+
+// discoverVertexTestModel lists the Google publisher models available to a project in a location and picks
+// the first of vertexCheapTestModels, or else any flash model. Results are cached per upstream host and
+// project, and concurrent validations of the same project share one discovery call.
+func (ch *VertexGeminiChannel) discoverVertexTestModel(ctx context.Context, client *http.Client, upstreamURL *url.URL, projectID string, accessToken string) (string, error) {
+	cacheKey := upstreamURL.Host + "/" + projectID
+	ch.testModelCacheMu.Lock()
+	model, ok := ch.testModelCache[cacheKey]
+	ch.testModelCacheMu.Unlock()
+	if ok {
+		return model, nil
+	}
+
+	result, err, _ := ch.testModelGroup.Do(cacheKey, func() (any, error) {
+		model, err := ch.listVertexTestModel(ctx, client, upstreamURL, projectID, accessToken)
+		if err != nil {
+			return "", err
+		}
+
+		utils.LoggerFromContext(ctx).WithFields(logrus.Fields{"project_id": projectID, "test_model": model}).Info("Discovered vertex test model")
+
+		ch.testModelCacheMu.Lock()
+		ch.testModelCache[cacheKey] = model
+		ch.testModelCacheMu.Unlock()
+		return model, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// forgetVertexTestModel drops a discovered test model, e.g. after the upstream no longer serves it.
+func (ch *VertexGeminiChannel) forgetVertexTestModel(upstreamURL *url.URL, projectID string) {
+	ch.testModelCacheMu.Lock()
+	delete(ch.testModelCache, upstreamURL.Host+"/"+projectID)
+	ch.testModelCacheMu.Unlock()
+}
+
+// listVertexTestModel queries the publisher models endpoint, billed to the project, and picks a test model.
+func (ch *VertexGeminiChannel) listVertexTestModel(ctx context.Context, client *http.Client, upstreamURL *url.URL, projectID string, accessToken string) (string, error) {
+	listURL := *upstreamURL
+	basePath := strings.TrimRight(listURL.Path, "/")
+	if idx := strings.Index(basePath, "/v1/projects/"); idx != -1 {
+		basePath = basePath[:idx]
+	}
+	listURL.Path = strings.TrimRight(basePath, "/") + "/v1beta1/publishers/google/models"
+	listURL.RawPath = ""
+	ch.withPathPrefix(&listURL)
+	listURL.RawQuery = url.Values{"pageSize": {"200"}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create model list request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("X-Goog-User-Project", projectID)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to list vertex models for test model discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vertex model list: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to list vertex models for test model discovery: [status %d] %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	var list struct {
+		PublisherModels []struct {
+			Name string `json:"name"`
+		} `json:"publisherModels"`
+	}
+	if err := json.Unmarshal(bodyBytes, &list); err != nil {
+		return "", fmt.Errorf("failed to parse vertex model list: %w", err)
+	}
+
+	available := make([]string, 0, len(list.PublisherModels))
+	for _, m := range list.PublisherModels {
+		available = append(available, bareVertexModelID(m.Name))
+	}
+	if model := pickVertexTestModel(available); model != "" {
+		return model, nil
+	}
+	return "", fmt.Errorf("no Gemini flash model is available to project %s, set the group's test model explicitly", projectID)
+}
+
+// pickVertexTestModel returns the first available model of vertexCheapTestModels, exact IDs winning over
+// versioned ones such as "gemini-2.0-flash-001", or else the first flash model. It returns "" if none fits.
+func pickVertexTestModel(available []string) string {
+	for _, preferred := range vertexCheapTestModels {
+		versioned := ""
+		for _, model := range available {
+			if model == preferred {
+				return model
+			}
+			if versioned == "" && strings.HasPrefix(model, preferred+"-0") {
+				versioned = model
+			}
+		}
+		if versioned != "" {
+			return versioned
+		}
+	}
+	for _, model := range available {
+		if strings.HasPrefix(model, "gemini-") && strings.Contains(model, "flash") {
+			return model
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+func TestVertexTokenRefreshAt(t *testing.T) {
+	minted := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token := vertexAccessToken{AccessToken: "t", Expiry: minted.Add(time.Hour), MintedAt: minted}
+
+	tests := []struct {
+		name   string
+		skew   int
+		maxAge int
+		want   time.Time
+	}{
+		{name: "expiry without max age", want: minted.Add(time.Hour)},
+		{name: "refresh skew before expiry", skew: 300, want: minted.Add(55 * time.Minute)},
+		{name: "max age before expiry", skew: 300, maxAge: 900, want: minted.Add(15 * time.Minute)},
+		{name: "max age beyond the refresh skew", skew: 300, maxAge: 7200, want: minted.Add(55 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: "vertex"}
+			group.EffectiveConfig.VertexTokenSkew = tt.skew
+			group.EffectiveConfig.VertexTokenMaxAge = tt.maxAge
+			if got := token.refreshAt(group); !got.Equal(tt.want) {
+				t.Errorf("refreshAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVertexTokenRemintedAtMaxAge(t *testing.T) {
+	const maxAge = 15 * time.Minute
+
+	for _, cache := range []string{VertexTokenCacheMemory, VertexTokenCacheShared} {
+		t.Run(cache, func(t *testing.T) {
+			tokenEndpoint, mints := newTestTokenEndpoint(t)
+			ch := newTestVertexChannel(t)
+			sa := newTestServiceAccount(t, tokenEndpoint.URL)
+			group := &models.Group{Name: "vertex", ChannelType: "vertex_gemini"}
+			group.EffectiveConfig.VertexMintTimeout = 5
+			group.EffectiveConfig.VertexTokenCache = cache
+			group.EffectiveConfig.VertexTokenMaxAge = int(maxAge / time.Second)
+			cacheKey := sa.tokenCacheKey(vertexOAuthScopes(group))
+
+			// ageToken makes the cached token look as if it had been minted age ago, well before its one-hour expiry.
+			ageToken := func(age time.Duration) {
+				t.Helper()
+				ch.tokenCacheMu.Lock()
+				token := ch.tokenCache[cacheKey]
+				token.MintedAt = time.Now().Add(-age)
+				if cache == VertexTokenCacheShared {
+					// The instance's own copy is gone; the shared one decides.
+					delete(ch.tokenCache, cacheKey)
+				} else {
+					ch.tokenCache[cacheKey] = token
+				}
+				ch.tokenCacheMu.Unlock()
+
+				if cache == VertexTokenCacheShared {
+					data, err := json.Marshal(token)
+					if err != nil {
+						t.Fatalf("marshal token: %v", err)
+					}
+					encrypted, err := ch.encryptionSvc.Encrypt(string(data))
+					if err != nil {
+						t.Fatalf("encrypt token: %v", err)
+					}
+					if err := ch.store.Set("vertex_token:"+cacheKey, []byte(encrypted), time.Hour); err != nil {
+						t.Fatalf("store token: %v", err)
+					}
+				}
+			}
+			accessToken := func() string {
+				t.Helper()
+				token, err := ch.getOrMintAccessToken(context.Background(), http.DefaultClient, sa, group)
+				if err != nil {
+					t.Fatalf("getOrMintAccessToken: %v", err)
+				}
+				return token
+			}
+
+			if got := accessToken(); got != "token-1" {
+				t.Fatalf("first token = %s, want token-1", got)
+			}
+			ageToken(maxAge - time.Minute)
+			if got := accessToken(); got != "token-1" || mints.Load() != 1 {
+				t.Errorf("token under the max age: got %s after %d mints, want the cached token-1", got, mints.Load())
+			}
+			ageToken(maxAge + time.Second)
+			if got := accessToken(); got != "token-2" || mints.Load() != 2 {
+				t.Errorf("token past the max age: got %s after %d mints, want a re-minted token-2", got, mints.Load())
+			}
+			if got := accessToken(); got != "token-2" || mints.Load() != 2 {
+				t.Errorf("re-minted token not cached: got %s after %d mints", got, mints.Load())
+			}
+		})
+	}
+}
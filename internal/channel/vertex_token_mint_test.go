@@ -0,0 +1,110 @@
+package channel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+)
+
+// newTestServiceAccount returns a service account with a fresh P-256 key whose tokens are minted at tokenURI.
+func newTestServiceAccount(t *testing.T, tokenURI string) gcpServiceAccount {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return gcpServiceAccount{
+		ProjectID:    "p",
+		PrivateKeyID: "kid",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "svc@p.iam.gserviceaccount.com",
+		TokenURI:     tokenURI,
+	}
+}
+
+func newTestVertexChannel(t *testing.T) *VertexGeminiChannel {
+	t.Helper()
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+	return &VertexGeminiChannel{
+		BaseChannel:   &BaseChannel{},
+		store:         store.NewMemoryStore(),
+		encryptionSvc: encSvc,
+		tokenCache:    make(map[string]vertexAccessToken),
+		locationCache: make(map[string]string),
+	}
+}
+
+func TestTokenMintTimeoutsAreTagged(t *testing.T) {
+	release := make(chan struct{})
+	hangingTokenEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hangingTokenEndpoint.Close()
+	defer close(release)
+
+	tests := []struct {
+		name string
+		// cache is the group's vertex_token_cache mode.
+		cache string
+		// lockHeld simulates another instance minting the shared token.
+		lockHeld bool
+		// callerTimeout bounds the caller's context; zero leaves only the mint timeout.
+		callerTimeout time.Duration
+	}{
+		{name: "caller deadline while minting", cache: VertexTokenCacheMemory, callerTimeout: 50 * time.Millisecond},
+		{name: "mint timeout", cache: VertexTokenCacheMemory},
+		{name: "caller deadline waiting for another instance's mint", cache: VertexTokenCacheShared, lockHeld: true, callerTimeout: 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := newTestVertexChannel(t)
+			sa := newTestServiceAccount(t, hangingTokenEndpoint.URL)
+			group := &models.Group{Name: "vertex", ChannelType: "vertex_gemini"}
+			group.EffectiveConfig.VertexMintTimeout = 1
+			group.EffectiveConfig.VertexTokenCache = tt.cache
+			if tt.lockHeld {
+				lockKey := "vertex_token:" + sa.tokenCacheKey(vertexOAuthScopes(group)) + ":lock"
+				if _, err := ch.store.SetNX(lockKey, []byte("1"), time.Minute); err != nil {
+					t.Fatalf("SetNX: %v", err)
+				}
+			}
+
+			ctx := context.Background()
+			if tt.callerTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.callerTimeout)
+				defer cancel()
+			}
+
+			_, err := ch.getOrMintAccessToken(ctx, http.DefaultClient, sa, group)
+			if err == nil {
+				t.Fatal("token minted, want a timeout")
+			}
+			if phase := app_errors.TimeoutPhase(err); phase != app_errors.TimeoutTagTokenMint {
+				t.Errorf("timeout phase of %v = %q, want %q", err, phase, app_errors.TimeoutTagTokenMint)
+			}
+		})
+	}
+}
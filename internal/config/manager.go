@@ -47,6 +47,8 @@ type Config struct {
 	CORS          types.CORSConfig
 	Performance   types.PerformanceConfig
 	Log           types.LogConfig
+	Metrics       types.MetricsConfig
+	Tracing       types.TracingConfig
 	Database      types.DatabaseConfig
 	RedisDSN      string
 	EncryptionKey string
@@ -97,6 +99,18 @@ func (m *Manager) ReloadConfig() error {
 			Format:     utils.GetEnvOrDefault("LOG_FORMAT", "text"),
 			EnableFile: utils.ParseBoolean(os.Getenv("LOG_ENABLE_FILE"), false),
 			FilePath:   utils.GetEnvOrDefault("LOG_FILE_PATH", "./data/logs/app.log"),
+
+			EnableAccessLog: utils.ParseBoolean(os.Getenv("ACCESS_LOG_ENABLED"), false),
+			AccessLogPath:   utils.GetEnvOrDefault("ACCESS_LOG_PATH", "stdout"),
+		},
+		Metrics: types.MetricsConfig{
+			Enabled:  utils.ParseBoolean(os.Getenv("ENABLE_METRICS"), false),
+			KeyLabel: utils.ParseBoolean(os.Getenv("METRICS_KEY_LABEL"), false),
+		},
+		Tracing: types.TracingConfig{
+			Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+			Headers:     utils.ParseKeyValuePairs(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+			ServiceName: utils.GetEnvOrDefault("OTEL_SERVICE_NAME", "gpt-load"),
 		},
 		Database: types.DatabaseConfig{
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
@@ -139,6 +153,16 @@ func (m *Manager) GetLogConfig() types.LogConfig {
 	return m.config.Log
 }
 
+// GetMetricsConfig returns metrics configuration
+func (m *Manager) GetMetricsConfig() types.MetricsConfig {
+	return m.config.Metrics
+}
+
+// GetTracingConfig returns tracing configuration
+func (m *Manager) GetTracingConfig() types.TracingConfig {
+	return m.config.Tracing
+}
+
 // GetRedisDSN returns the Redis DSN string.
 func (m *Manager) GetRedisDSN() string {
 	return m.config.RedisDSN
@@ -210,6 +234,8 @@ func (m *Manager) DisplayServerConfig() {
 	corsConfig := m.GetCORSConfig()
 	perfConfig := m.GetPerformanceConfig()
 	logConfig := m.GetLogConfig()
+	metricsConfig := m.GetMetricsConfig()
+	tracingConfig := m.GetTracingConfig()
 	dbConfig := m.GetDatabaseConfig()
 	redisDSN := m.GetRedisDSN()
 	encryptionKey := m.GetEncryptionKey()
@@ -246,6 +272,21 @@ func (m *Manager) DisplayServerConfig() {
 	if logConfig.EnableFile {
 		logrus.Infof("    Log File Path: %s", logConfig.FilePath)
 	}
+	logrus.Infof("    Access Log: %t", logConfig.EnableAccessLog)
+	if logConfig.EnableAccessLog {
+		logrus.Infof("    Access Log Path: %s", logConfig.AccessLogPath)
+	}
+
+	logrus.Info("  --- Observability ---")
+	logrus.Infof("    Metrics Endpoint: %t", metricsConfig.Enabled)
+	if metricsConfig.Enabled {
+		logrus.Infof("    Per-Key Label: %t", metricsConfig.KeyLabel)
+	}
+	if tracingConfig.Endpoint != "" {
+		logrus.Infof("    Tracing: enabled (OTLP endpoint: %s)", tracingConfig.Endpoint)
+	} else {
+		logrus.Info("    Tracing: disabled")
+	}
 
 	logrus.Info("  --- Dependencies ---")
 	if dbConfig.DSN != "" {
@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/db"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
+	"net/url"
 	"os"
+	"path"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -289,6 +293,13 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.Bool:
 			if _, ok := value.(bool); !ok {
@@ -306,13 +317,19 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					options := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(options, strVal) {
+						return fmt.Errorf("invalid value for %s: must be one of %s", key, strings.Join(options, ", "))
+					}
+				}
 			}
 		default:
 			return fmt.Errorf("unsupported type for setting key validation: %s", key)
 		}
 	}
 
-	return nil
+	return validateSettingValues(settingsMap)
 }
 
 // ValidateGroupConfigOverrides validates a map of group-level configuration overrides.
@@ -363,6 +380,13 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.String:
 			strVal, ok := value.(string)
@@ -376,6 +400,12 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					options := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(options, strVal) {
+						return fmt.Errorf("invalid value for %s: must be one of %s", key, strings.Join(options, ", "))
+					}
+				}
 			}
 		case reflect.Bool:
 			_, ok := value.(bool)
@@ -387,6 +417,75 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 		}
 	}
 
+	return validateSettingValues(configMap)
+}
+
+// validateSettingValues 校验无法用 validate 标签表达的配置值
+func validateSettingValues(settingsMap map[string]any) error {
+	if err := validateTLSSettings(settingsMap); err != nil {
+		return err
+	}
+	if tokenURI, ok := settingsMap["vertex_token_uri"].(string); ok && tokenURI != "" {
+		u, err := url.Parse(tokenURI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid value for vertex_token_uri: must be an http(s) URL")
+		}
+	}
+	if webhookURL, ok := settingsMap["webhook_url"].(string); ok && webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid value for webhook_url: must be an http(s) URL")
+		}
+	}
+	if codes, ok := settingsMap["retry_status_codes"].(string); ok {
+		if _, err := utils.ParseStatusCodes(codes); err != nil {
+			return fmt.Errorf("invalid value for retry_status_codes: %w", err)
+		}
+	}
+	if fields, ok := settingsMap["request_log_fields"].(string); ok {
+		if _, err := utils.ParseRequestLogFields(fields); err != nil {
+			return fmt.Errorf("invalid value for request_log_fields: %w", err)
+		}
+	}
+	for _, key := range []string{"model_allowlist", "model_denylist"} {
+		if patterns, ok := settingsMap[key].(string); ok {
+			for _, pattern := range utils.SplitAndTrim(patterns, ",") {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("invalid value for %s: invalid model pattern '%s'", key, pattern)
+				}
+			}
+		}
+	}
+	if prefix, ok := settingsMap["upstream_path_prefix"].(string); ok && prefix != "" {
+		if strings.ContainsAny(prefix, "?#") || slices.Contains(strings.Split(prefix, "/"), "..") {
+			return fmt.Errorf("invalid value for upstream_path_prefix: must be a plain path such as /llm")
+		}
+	}
+	if aliases, ok := settingsMap["model_aliases"].(string); ok {
+		if _, err := utils.ParseModelAliases(aliases); err != nil {
+			return fmt.Errorf("invalid value for model_aliases: %w", err)
+		}
+	}
+	if prices, ok := settingsMap["model_prices"].(string); ok {
+		if _, err := utils.ParseModelPrices(prices); err != nil {
+			return fmt.Errorf("invalid value for model_prices: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateTLSSettings 校验 TLS 配置可解析，且不会严格到无法连接 Google 等上游
+func validateTLSSettings(settingsMap map[string]any) error {
+	if version, ok := settingsMap["tls_min_version"].(string); ok {
+		if _, err := httpclient.ParseTLSVersion(version); err != nil {
+			return err
+		}
+	}
+	if suites, ok := settingsMap["tls_cipher_suites"].(string); ok {
+		if _, err := httpclient.ParseCipherSuites(suites); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -406,6 +505,8 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 	logrus.Infof("    Idle Connection Timeout: %d seconds", settings.IdleConnTimeout)
 	logrus.Infof("    Max Idle Connections: %d", settings.MaxIdleConns)
 	logrus.Infof("    Max Idle Connections Per Host: %d", settings.MaxIdleConnsPerHost)
+	logrus.Infof("    TLS Handshake Timeout: %d seconds", settings.TLSHandshakeTimeout)
+	logrus.Infof("    Minimum TLS Version: %s", settings.TLSMinVersion)
 
 	logrus.Info("  --- Key & Group Behavior ---")
 	logrus.Infof("    Max Retries: %d", settings.MaxRetries)
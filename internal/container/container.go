@@ -10,6 +10,7 @@ import (
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/notify"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/router"
 	"gpt-load/internal/services"
@@ -47,6 +48,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(channel.NewFactory); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(notify.NewNotifier); err != nil {
+		return nil, err
+	}
 
 	// Business Services
 	if err := container.Provide(services.NewTaskService); err != nil {
@@ -61,6 +65,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewKeyImportService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewGCPKeyImportService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewKeyDeleteService); err != nil {
 		return nil, err
 	}
@@ -82,6 +89,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewGroupService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewQuotaService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewAggregateGroupService); err != nil {
 		return nil, err
 	}
@@ -94,6 +104,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(keypool.NewCronChecker); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(keypool.NewExpiryChecker); err != nil {
+		return nil, err
+	}
 
 	// Handlers
 	if err := container.Provide(handler.NewServer); err != nil {
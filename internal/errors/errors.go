@@ -38,6 +38,10 @@ var (
 	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
 	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
 	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrUnsupportedMedia   = &APIError{HTTPStatus: http.StatusUnsupportedMediaType, Code: "UNSUPPORTED_MEDIA_TYPE", Message: "Unsupported request content type"}
+	ErrInputTooLarge      = &APIError{HTTPStatus: http.StatusBadRequest, Code: "INPUT_TOO_LARGE", Message: "Estimated input tokens exceed the limit"}
+	ErrQuotaExceeded      = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "QUOTA_EXCEEDED", Message: "Group quota exhausted"}
+	ErrPayloadTooLarge    = &APIError{HTTPStatus: http.StatusRequestEntityTooLarge, Code: "PAYLOAD_TOO_LARGE", Message: "Body exceeds the size limit"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Timeout phase tags, used to tell apart where a request ran out of time.
+const (
+	TimeoutTagTokenMint = "token_mint_timeout"
+	TimeoutTagUpstream  = "upstream_timeout"
+)
+
+// TimeoutError tags a deadline error with the request phase in which it occurred.
+type TimeoutError struct {
+	Phase string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// WrapTimeout tags err with the given phase if it is a deadline error.
+// Other errors, and errors that are already tagged, are returned unchanged.
+func WrapTimeout(phase string, err error) error {
+	if err == nil || TimeoutPhase(err) != "" {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Phase: phase, Err: err}
+	}
+	return err
+}
+
+// TimeoutPhase returns the phase tag of a tagged timeout error, or an empty string.
+func TimeoutPhase(err error) string {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Phase
+	}
+	return ""
+}
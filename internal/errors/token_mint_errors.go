@@ -0,0 +1,39 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TokenMintError is returned when a channel fails to obtain an upstream access token for a key,
+// as opposed to the upstream model call itself failing.
+type TokenMintError struct {
+	// Permanent is true when the credential itself is unusable (e.g. revoked key, invalid_grant)
+	// and retrying with the same key cannot succeed.
+	Permanent  bool
+	StatusCode int
+	Err        error
+}
+
+// Error implements the error interface.
+func (e *TokenMintError) Error() string {
+	kind := "transient"
+	if e.Permanent {
+		kind = "permanent"
+	}
+	return fmt.Sprintf("token mint failed (%s): %v", kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TokenMintError) Unwrap() error {
+	return e.Err
+}
+
+// AsTokenMintError returns the TokenMintError in err's chain, if any.
+func AsTokenMintError(err error) (*TokenMintError, bool) {
+	var mintErr *TokenMintError
+	if errors.As(err, &mintErr) {
+		return mintErr, true
+	}
+	return nil, false
+}
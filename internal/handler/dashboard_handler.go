@@ -236,6 +236,16 @@ func (s *Server) getRPMStats(now time.Time) (models.StatCard, error) {
 	}, nil
 }
 
+// KeyPoolStatus returns the key pool status of every standard group.
+func (s *Server) KeyPoolStatus(c *gin.Context) {
+	statuses, err := s.GroupService.GetKeyPoolStatus(c.Request.Context())
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, statuses)
+}
+
 // getSecurityWarnings 检查安全配置并返回警告信息
 func (s *Server) getSecurityWarnings(c *gin.Context) []models.SecurityWarning {
 	var warnings []models.SecurityWarning
@@ -45,21 +45,24 @@ func (s *Server) handleGroupError(c *gin.Context, err error) bool {
 
 // GroupCreateRequest defines the payload for creating a group.
 type GroupCreateRequest struct {
-	Name                string              `json:"name"`
-	DisplayName         string              `json:"display_name"`
-	Description         string              `json:"description"`
-	GroupType           string              `json:"group_type"` // 'standard' or 'aggregate'
-	Upstreams           json.RawMessage     `json:"upstreams"`
-	ChannelType         string              `json:"channel_type"`
-	Sort                int                 `json:"sort"`
-	TestModel           string              `json:"test_model"`
-	ValidationEndpoint  string              `json:"validation_endpoint"`
-	ParamOverrides      map[string]any      `json:"param_overrides"`
-	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
-	ModelRedirectStrict bool                `json:"model_redirect_strict"`
-	Config              map[string]any      `json:"config"`
-	HeaderRules         []models.HeaderRule `json:"header_rules"`
-	ProxyKeys           string              `json:"proxy_keys"`
+	Name                string                            `json:"name"`
+	DisplayName         string                            `json:"display_name"`
+	Description         string                            `json:"description"`
+	GroupType           string                            `json:"group_type"` // 'standard' or 'aggregate'
+	Upstreams           json.RawMessage                   `json:"upstreams"`
+	ChannelType         string                            `json:"channel_type"`
+	Sort                int                               `json:"sort"`
+	TestModel           string                            `json:"test_model"`
+	ValidationEndpoint  string                            `json:"validation_endpoint"`
+	ParamOverrides      map[string]any                    `json:"param_overrides"`
+	ModelRedirectRules  map[string]any                    `json:"model_redirect_rules"`
+	ModelRedirectStrict bool                              `json:"model_redirect_strict"`
+	ModelCapabilities   map[string]models.ModelCapability `json:"model_capabilities"`
+	VertexLocations     map[string]string                 `json:"vertex_locations"`
+	Config              map[string]any                    `json:"config"`
+	HeaderRules         []models.HeaderRule               `json:"header_rules"`
+	BodyRules           []models.BodyRule                 `json:"body_rules"`
+	ProxyKeys           string                            `json:"proxy_keys"`
 }
 
 // CreateGroup handles the creation of a new group.
@@ -83,8 +86,11 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		ParamOverrides:      req.ParamOverrides,
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
+		ModelCapabilities:   req.ModelCapabilities,
+		VertexLocations:     req.VertexLocations,
 		Config:              req.Config,
 		HeaderRules:         req.HeaderRules,
+		BodyRules:           req.BodyRules,
 		ProxyKeys:           req.ProxyKeys,
 	}
 
@@ -114,21 +120,24 @@ func (s *Server) ListGroups(c *gin.Context) {
 // GroupUpdateRequest defines the payload for updating a group.
 // Using a dedicated struct avoids issues with zero values being ignored by GORM's Update.
 type GroupUpdateRequest struct {
-	Name                *string             `json:"name,omitempty"`
-	DisplayName         *string             `json:"display_name,omitempty"`
-	Description         *string             `json:"description,omitempty"`
-	GroupType           *string             `json:"group_type,omitempty"`
-	Upstreams           json.RawMessage     `json:"upstreams"`
-	ChannelType         *string             `json:"channel_type,omitempty"`
-	Sort                *int                `json:"sort"`
-	TestModel           string              `json:"test_model"`
-	ValidationEndpoint  *string             `json:"validation_endpoint,omitempty"`
-	ParamOverrides      map[string]any      `json:"param_overrides"`
-	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
-	ModelRedirectStrict *bool               `json:"model_redirect_strict"`
-	Config              map[string]any      `json:"config"`
-	HeaderRules         []models.HeaderRule `json:"header_rules"`
-	ProxyKeys           *string             `json:"proxy_keys,omitempty"`
+	Name                *string                           `json:"name,omitempty"`
+	DisplayName         *string                           `json:"display_name,omitempty"`
+	Description         *string                           `json:"description,omitempty"`
+	GroupType           *string                           `json:"group_type,omitempty"`
+	Upstreams           json.RawMessage                   `json:"upstreams"`
+	ChannelType         *string                           `json:"channel_type,omitempty"`
+	Sort                *int                              `json:"sort"`
+	TestModel           string                            `json:"test_model"`
+	ValidationEndpoint  *string                           `json:"validation_endpoint,omitempty"`
+	ParamOverrides      map[string]any                    `json:"param_overrides"`
+	ModelRedirectRules  map[string]any                    `json:"model_redirect_rules"`
+	ModelRedirectStrict *bool                             `json:"model_redirect_strict"`
+	ModelCapabilities   map[string]models.ModelCapability `json:"model_capabilities"`
+	VertexLocations     map[string]string                 `json:"vertex_locations"`
+	Config              map[string]any                    `json:"config"`
+	HeaderRules         []models.HeaderRule               `json:"header_rules"`
+	BodyRules           []models.BodyRule                 `json:"body_rules"`
+	ProxyKeys           *string                           `json:"proxy_keys,omitempty"`
 }
 
 // UpdateGroup handles updating an existing group.
@@ -156,6 +165,8 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		ParamOverrides:      req.ParamOverrides,
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
+		ModelCapabilities:   req.ModelCapabilities,
+		VertexLocations:     req.VertexLocations,
 		Config:              req.Config,
 		ProxyKeys:           req.ProxyKeys,
 	}
@@ -175,6 +186,11 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		params.HeaderRules = &rules
 	}
 
+	if req.BodyRules != nil {
+		rules := req.BodyRules
+		params.BodyRules = &rules
+	}
+
 	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), params)
 	if s.handleGroupError(c, err) {
 		return
@@ -199,8 +215,11 @@ type GroupResponse struct {
 	ParamOverrides      datatypes.JSONMap   `json:"param_overrides"`
 	ModelRedirectRules  datatypes.JSONMap   `json:"model_redirect_rules"`
 	ModelRedirectStrict bool                `json:"model_redirect_strict"`
+	ModelCapabilities   datatypes.JSONMap   `json:"model_capabilities"`
+	VertexLocations     datatypes.JSONMap   `json:"vertex_locations"`
 	Config              datatypes.JSONMap   `json:"config"`
 	HeaderRules         []models.HeaderRule `json:"header_rules"`
+	BodyRules           []models.BodyRule   `json:"body_rules"`
 	ProxyKeys           string              `json:"proxy_keys"`
 	LastValidatedAt     *time.Time          `json:"last_validated_at"`
 	CreatedAt           time.Time           `json:"created_at"`
@@ -228,6 +247,14 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	bodyRules := make([]models.BodyRule, 0)
+	if len(group.BodyRules) > 0 {
+		if err := json.Unmarshal(group.BodyRules, &bodyRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal body rules")
+			bodyRules = make([]models.BodyRule, 0)
+		}
+	}
+
 	return &GroupResponse{
 		ID:                  group.ID,
 		Name:                group.Name,
@@ -243,8 +270,11 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		ParamOverrides:      group.ParamOverrides,
 		ModelRedirectRules:  group.ModelRedirectRules,
 		ModelRedirectStrict: group.ModelRedirectStrict,
+		ModelCapabilities:   group.ModelCapabilities,
+		VertexLocations:     group.VertexLocations,
 		Config:              group.Config,
 		HeaderRules:         headerRules,
+		BodyRules:           bodyRules,
 		ProxyKeys:           group.ProxyKeys,
 		LastValidatedAt:     group.LastValidatedAt,
 		CreatedAt:           group.CreatedAt,
@@ -319,6 +349,138 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// GetGroupLiveness runs a lightweight health probe against a group's next key.
+func (s *Server) GetGroupLiveness(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	groupDB, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
+	result, err := s.KeyService.CheckLiveness(c.Request.Context(), group)
+	if err != nil {
+		if apiErr, ok := err.(*app_errors.APIError); ok {
+			response.Error(c, apiErr)
+		} else {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetGroupQuota returns a group's request and token consumption in its current quota window.
+func (s *Server) GetGroupQuota(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	groupDB, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
+	usage, err := s.QuotaService.Usage(group)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+
+	response.Success(c, usage)
+}
+
+// RefreshGroupModelList drops the group's cached model lists and fetches its model list from upstream.
+func (s *Server) RefreshGroupModelList(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	groupDB, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+	if groupDB.GroupType == "aggregate" {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.model_list_aggregate_group")
+		return
+	}
+
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
+	count, err := s.ProxyServer.RefreshModelList(c.Request.Context(), group)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		return
+	}
+
+	response.SuccessI18n(c, "success.model_list_refreshed", gin.H{"model_count": count}, map[string]any{"count": count})
+}
+
+// RedirectPreviewRequest defines the payload for previewing a model redirect.
+type RedirectPreviewRequest struct {
+	Method      string `json:"method"`
+	Path        string `json:"path" binding:"required"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// PreviewModelRedirect shows how a sample request would be rewritten by the group's redirect rules without forwarding it.
+func (s *Server) PreviewModelRedirect(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req RedirectPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	contentType := req.ContentType
+	if contentType == "" && req.Body != "" {
+		contentType = "application/json"
+	}
+
+	preview, err := s.GroupService.PreviewModelRedirect(c.Request.Context(), uint(id), services.RedirectPreviewParams{
+		Method:      req.Method,
+		Path:        req.Path,
+		ContentType: contentType,
+		Body:        []byte(req.Body),
+	})
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, preview)
+}
+
 // GroupCopyRequest defines the payload for copying a group.
 type GroupCopyRequest struct {
 	CopyKeys string `json:"copy_keys"` // "none"|"valid_only"|"all"
@@ -9,6 +9,7 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
 
@@ -29,8 +30,11 @@ type Server struct {
 	TaskService                *services.TaskService
 	KeyService                 *services.KeyService
 	KeyImportService           *services.KeyImportService
+	GCPKeyImportService        *services.GCPKeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	QuotaService               *services.QuotaService
+	ProxyServer                *proxy.ProxyServer
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -48,8 +52,11 @@ type NewServerParams struct {
 	TaskService                *services.TaskService
 	KeyService                 *services.KeyService
 	KeyImportService           *services.KeyImportService
+	GCPKeyImportService        *services.GCPKeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	QuotaService               *services.QuotaService
+	ProxyServer                *proxy.ProxyServer
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -67,8 +74,11 @@ func NewServer(params NewServerParams) *Server {
 		TaskService:                params.TaskService,
 		KeyService:                 params.KeyService,
 		KeyImportService:           params.KeyImportService,
+		GCPKeyImportService:        params.GCPKeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
 		LogService:                 params.LogService,
+		QuotaService:               params.QuotaService,
+		ProxyServer:                params.ProxyServer,
 		CommonHandler:              params.CommonHandler,
 		EncryptionSvc:              params.EncryptionSvc,
 	}
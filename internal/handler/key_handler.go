@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -84,7 +88,8 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+	groupDB, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
 		return
 	}
 
@@ -92,7 +97,13 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	result, err := s.KeyService.AddMultipleKeys(req.GroupID, req.KeysText)
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
+	result, err := s.KeyService.AddMultipleKeys(group, req.KeysText)
 	if err != nil {
 		if strings.Contains(err.Error(), "batch size exceeds the limit") {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
@@ -115,7 +126,7 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
-	group, ok := s.findGroupByID(c, req.GroupID)
+	groupDB, ok := s.findGroupByID(c, req.GroupID)
 	if !ok {
 		return
 	}
@@ -124,6 +135,12 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
 	taskStatus, err := s.KeyImportService.StartImportTask(group, req.KeysText)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
@@ -145,7 +162,7 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 	}
 
 	statusFilter := c.Query("status")
-	if statusFilter != "" && statusFilter != models.KeyStatusActive && statusFilter != models.KeyStatusInvalid {
+	if statusFilter != "" && statusFilter != models.KeyStatusActive && statusFilter != models.KeyStatusInvalid && statusFilter != models.KeyStatusDraining {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_status_filter")
 		return
 	}
@@ -156,7 +173,7 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 		searchHash = s.EncryptionSvc.Hash(searchKeyword)
 	}
 
-	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHash)
+	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHash, keypool.ParseKeyTags(c.Query("tags")))
 
 	var keys []models.APIKey
 	paginatedResult, err := response.Paginate(c, query, &keys)
@@ -180,6 +197,72 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 	response.Success(c, paginatedResult)
 }
 
+// ImportGCPKeysRequest defines the payload for importing service account keys from a GCP folder or organization.
+type ImportGCPKeysRequest struct {
+	GroupID        uint   `json:"group_id" binding:"required"`
+	ServiceAccount string `json:"service_account" binding:"required"`
+	Parent         string `json:"parent" binding:"required"`
+	AccountID      string `json:"account_id" binding:"required"`
+	// Confirm must repeat the parent to create keys; otherwise the projects that would get a key are only listed.
+	Confirm string `json:"confirm"`
+}
+
+// ImportGCPKeys creates a key of a service account in every project below a GCP folder or organization
+// and imports the keys into a Vertex group. Creating keys needs the parent repeated in confirm; without
+// it nothing is created and the affected projects are returned for review.
+func (s *Server) ImportGCPKeys(c *gin.Context) {
+	var req ImportGCPKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	groupDB, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
+		return
+	}
+	group, err := s.GroupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found")
+		return
+	}
+
+	params := services.GCPKeyImportParams{
+		ServiceAccount: req.ServiceAccount,
+		Parent:         strings.TrimSpace(req.Parent),
+		AccountID:      strings.TrimSpace(req.AccountID),
+		ClientIP:       c.ClientIP(),
+	}
+	if err := s.GCPKeyImportService.ValidateParams(group, params); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	if strings.TrimSpace(req.Confirm) != params.Parent {
+		preview, err := s.GCPKeyImportService.Preview(c.Request.Context(), group, params)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+			return
+		}
+		response.Success(c, preview)
+		return
+	}
+
+	taskStatus, err := s.GCPKeyImportService.StartImport(c.Request.Context(), group, params)
+	if err != nil {
+		if errors.Is(err, services.ErrGCPNothingToImport) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		} else if strings.Contains(err.Error(), "a task is already running") {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
+		} else {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		}
+		return
+	}
+
+	response.Success(c, taskStatus)
+}
+
 // DeleteMultipleKeys handles deleting keys from a text block within a specific group.
 func (s *Server) DeleteMultipleKeys(c *gin.Context) {
 	var req KeyTextRequest
@@ -441,6 +524,26 @@ func (s *Server) ExportKeys(c *gin.Context) {
 	}
 }
 
+// FindDuplicateKeys reports keys in a group that share the same key value or service account client_email.
+func (s *Server) FindDuplicateKeys(c *gin.Context) {
+	groupID, ok := validateGroupIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, groupID); !ok {
+		return
+	}
+
+	result, err := s.KeyService.FindDuplicateKeys(groupID)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // UpdateKeyNotesRequest defines the payload for updating a key's notes.
 type UpdateKeyNotesRequest struct {
 	Notes string `json:"notes"`
@@ -487,3 +590,241 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// UpdateKeyProxyRequest defines the payload for updating a key's proxy URL.
+type UpdateKeyProxyRequest struct {
+	ProxyURL string `json:"proxy_url"`
+}
+
+// UpdateKeyProxy sets the upstream proxy URL of a specific API key, overriding the group's proxy.
+// An empty proxy URL clears the override.
+func (s *Server) UpdateKeyProxy(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	req.ProxyURL = strings.TrimSpace(req.ProxyURL)
+	if req.ProxyURL != "" {
+		if len(req.ProxyURL) > 512 {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "proxy_url length must be <= 512 characters"))
+			return
+		}
+		parsed, err := url.Parse(req.ProxyURL)
+		if err != nil || parsed.Host == "" {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "invalid proxy_url"))
+			return
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "proxy_url scheme must be http, https, socks5 or socks5h"))
+			return
+		}
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyProxy(key.ID, req.ProxyURL); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UpdateKeyExpiryRequest defines the payload for updating a key's expiry. A null expires_at clears it.
+type UpdateKeyExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UpdateKeyExpiry sets when a specific API key expires, to be warned about it ahead of time.
+func (s *Server) UpdateKeyExpiry(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.DB.Model(&key).Update("expires_at", req.ExpiresAt).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// DrainKey takes a key out of rotation and deletes it once its in-flight requests have finished,
+// so that rotating out a credential does not abort running streams.
+func (s *Server) DrainKey(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if key.Status == models.KeyStatusDraining {
+		response.Success(c, nil)
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.DrainKey(key.ID); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListDrainingKeys lists the draining keys of a group with their remaining in-flight request counts.
+func (s *Server) ListDrainingKeys(c *gin.Context) {
+	groupID, ok := validateGroupIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, groupID); !ok {
+		return
+	}
+
+	keys, err := s.KeyService.KeyProvider.DrainingKeys(groupID)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, keys)
+}
+
+// UpdateKeyWeightRequest defines the payload for updating a key's rotation weight.
+type UpdateKeyWeightRequest struct {
+	Weight int `json:"weight"`
+}
+
+// UpdateKeyWeight sets the rotation weight of a specific API key. A key with weight N receives
+// roughly N times the traffic of a weight-1 key in the same group.
+func (s *Server) UpdateKeyWeight(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyWeightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if req.Weight < 1 || req.Weight > keypool.MaxKeyWeight {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("weight must be between 1 and %d", keypool.MaxKeyWeight)))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyWeight(key.ID, req.Weight); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UpdateKeyTagsRequest defines the payload for updating a key's tags.
+type UpdateKeyTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// UpdateKeyTags replaces the tags of a specific API key. Requests that require tags, through the group's
+// required_key_tags or the X-GPTLoad-Key-Tags header, are only served by keys carrying them.
+func (s *Server) UpdateKeyTags(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	tags, err := keypool.NormalizeKeyTags(req.Tags)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyTags(key.ID, tags); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
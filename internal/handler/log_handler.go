@@ -6,6 +6,7 @@ import (
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
 	"time"
 
@@ -61,3 +62,19 @@ func (s *Server) ExportLogs(c *gin.Context) {
 		return
 	}
 }
+
+// GetUsageSummary returns token and cost totals of the filtered logs, grouped by group, key or model.
+func (s *Server) GetUsageSummary(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", services.UsageGroupByGroup)
+	if groupBy != services.UsageGroupByGroup && groupBy != services.UsageGroupByKey && groupBy != services.UsageGroupByModel {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_usage_group_by")
+		return
+	}
+
+	summary, err := s.LogService.GetUsageSummary(c, groupBy)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, summary)
+}
@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// http2Transport sends every request over HTTP/2 with prior knowledge, for upstream gateways that do not
+// speak HTTP/1.1: https upstreams use HTTP/2 over TLS, http upstreams cleartext h2c.
+type http2Transport struct {
+	tls       *http2.Transport
+	cleartext *http2.Transport
+}
+
+// newHTTP2Transport builds the HTTP/2 transports for a configuration. HTTP proxies cannot carry these
+// connections, and response header timeouts are covered by the client timeout only.
+func newHTTP2Transport(config *Config) *http2Transport {
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http2Transport{
+		tls: &http2.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion:   config.TLSMinVersion,
+				CipherSuites: config.CipherSuites,
+			},
+			DisableCompression: config.DisableCompression,
+			IdleConnTimeout:    config.IdleConnTimeout,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				if config.TLSHandshakeTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, config.ConnectTimeout+config.TLSHandshakeTimeout)
+					defer cancel()
+				}
+				return (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, network, addr)
+			},
+		},
+		cleartext: &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: config.DisableCompression,
+			IdleConnTimeout:    config.IdleConnTimeout,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (t *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.cleartext.RoundTrip(req)
+	}
+	return t.tls.RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach both transports.
+func (t *http2Transport) CloseIdleConnections() {
+	t.tls.CloseIdleConnections()
+	t.cleartext.CloseIdleConnections()
+}
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -27,6 +28,10 @@ type Config struct {
 	TLSHandshakeTimeout   time.Duration
 	ExpectContinueTimeout time.Duration
 	ProxyURL              string
+	TLSMinVersion         uint16
+	CipherSuites          []uint16
+	// PriorKnowledgeHTTP2 speaks HTTP/2 without negotiation, including cleartext h2c for http upstreams.
+	PriorKnowledgeHTTP2 bool
 }
 
 // HTTPClientManager manages the lifecycle of HTTP clients.
@@ -67,6 +72,18 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 		return client
 	}
 
+	if config.PriorKnowledgeHTTP2 {
+		if config.ProxyURL != "" {
+			logrus.Warnf("Proxy URL '%s' is ignored because HTTP/2 prior knowledge connects to upstreams directly", config.ProxyURL)
+		}
+		newClient := &http.Client{
+			Transport: newHTTP2Transport(config),
+			Timeout:   config.RequestTimeout,
+		}
+		m.clients[fingerprint] = newClient
+		return newClient
+	}
+
 	// Create a new transport and client with the specified configuration.
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{
@@ -85,6 +102,13 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 		ReadBufferSize:        config.ReadBufferSize,
 	}
 
+	if config.TLSMinVersion != 0 || len(config.CipherSuites) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion:   config.TLSMinVersion,
+			CipherSuites: config.CipherSuites,
+		}
+	}
+
 	// Set http proxy.
 	if config.ProxyURL != "" {
 		proxyURL, err := url.Parse(config.ProxyURL)
@@ -110,7 +134,7 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 // getFingerprint generates a unique string representation of the client configuration.
 func (c *Config) getFingerprint() string {
 	return fmt.Sprintf(
-		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s",
+		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s|tlsv:%d|cs:%v|h2pk:%t",
 		c.ConnectTimeout.Seconds(),
 		c.RequestTimeout.Seconds(),
 		c.IdleConnTimeout.Seconds(),
@@ -124,5 +148,8 @@ func (c *Config) getFingerprint() string {
 		c.TLSHandshakeTimeout.Seconds(),
 		c.ExpectContinueTimeout.Seconds(),
 		c.ProxyURL,
+		c.TLSMinVersion,
+		c.CipherSuites,
+		c.PriorKnowledgeHTTP2,
 	)
 }
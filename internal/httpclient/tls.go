@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// tlsVersions maps configuration values to TLS protocol versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// googleCompatibleCipherSuites are TLS 1.2 suites accepted by Google front ends.
+// At least one of them must stay enabled, otherwise Vertex/Gemini and the OAuth token endpoint become unreachable.
+var googleCompatibleCipherSuites = map[uint16]struct{}{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:       {},
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384:       {},
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256: {},
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:         {},
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:         {},
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:   {},
+}
+
+// ParseTLSVersion converts a version string such as "1.2" to a TLS version constant.
+// An empty string means the Go default.
+func ParseTLSVersion(version string) (uint16, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version: %s", version)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites converts a comma-separated list of cipher suite names (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+// to their IDs. An empty string means the Go default. TLS 1.3 suites are not configurable and always enabled.
+func ParseCipherSuites(names string) ([]uint16, error) {
+	names = strings.TrimSpace(names)
+	if names == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	compatible := false
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %s", name)
+		}
+		if _, ok := googleCompatibleCipherSuites[id]; ok {
+			compatible = true
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if !compatible {
+		return nil, fmt.Errorf("cipher suite list is too restrictive: at least one ECDHE AES-GCM or ChaCha20-Poly1305 suite is required to reach Google endpoints")
+	}
+	return ids, nil
+}
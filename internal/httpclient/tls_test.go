@@ -0,0 +1,176 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint16
+		wantErr bool
+	}{
+		{"", 0, false},
+		{" 1.2 ", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.4", 0, true},
+		{"TLS1.3", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTLSVersion(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseTLSVersion(%q) = %d, %v, want %d, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []uint16
+		wantErr string
+	}{
+		{name: "empty means default", value: ""},
+		{name: "only separators", value: " , ,"},
+		{
+			name:  "Google compatible suites",
+			value: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+			want:  []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{
+			name:  "incompatible suites allowed next to a compatible one",
+			value: "TLS_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			want:  []uint16{tls.TLS_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+		},
+		{name: "unknown suite", value: "TLS_MADE_UP", wantErr: "unknown cipher suite"},
+		{name: "too restrictive", value: "TLS_RSA_WITH_AES_128_GCM_SHA256,TLS_RSA_WITH_AES_256_CBC_SHA", wantErr: "too restrictive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCipherSuites(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseCipherSuites error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCipherSuites: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseCipherSuites = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTLSSettingsAgainstEnforcingServer connects clients built from TLS settings to servers that only
+// accept specific TLS versions or cipher suites.
+func TestTLSSettingsAgainstEnforcingServer(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverMin     uint16
+		serverMax     uint16
+		serverSuites  []uint16
+		clientMin     string
+		clientSuites  string
+		wantConnected bool
+		wantVersion   uint16
+	}{
+		{
+			name:          "TLS 1.3 server, TLS 1.3 client",
+			serverMin:     tls.VersionTLS13,
+			clientMin:     "1.3",
+			wantConnected: true,
+			wantVersion:   tls.VersionTLS13,
+		},
+		{
+			name:          "TLS 1.2 only server, default client",
+			serverMax:     tls.VersionTLS12,
+			wantConnected: true,
+			wantVersion:   tls.VersionTLS12,
+		},
+		{
+			name:          "TLS 1.2 only server, TLS 1.2 client",
+			serverMax:     tls.VersionTLS12,
+			clientMin:     "1.2",
+			wantConnected: true,
+			wantVersion:   tls.VersionTLS12,
+		},
+		{
+			name:      "TLS 1.2 only server, TLS 1.3 client",
+			serverMax: tls.VersionTLS12,
+			clientMin: "1.3",
+		},
+		{
+			name:          "shared cipher suite",
+			serverMax:     tls.VersionTLS12,
+			serverSuites:  []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			clientSuites:  "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			wantConnected: true,
+			wantVersion:   tls.VersionTLS12,
+		},
+		{
+			name:         "no shared cipher suite",
+			serverMax:    tls.VersionTLS12,
+			serverSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			clientSuites: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			server.TLS = &tls.Config{MinVersion: tt.serverMin, MaxVersion: tt.serverMax, CipherSuites: tt.serverSuites}
+			server.StartTLS()
+			defer server.Close()
+
+			minVersion, err := ParseTLSVersion(tt.clientMin)
+			if err != nil {
+				t.Fatalf("ParseTLSVersion: %v", err)
+			}
+			suites, err := ParseCipherSuites(tt.clientSuites)
+			if err != nil {
+				t.Fatalf("ParseCipherSuites: %v", err)
+			}
+			client := NewHTTPClientManager().GetClient(&Config{TLSMinVersion: minVersion, CipherSuites: suites})
+			trustServer(t, client, server)
+
+			resp, err := client.Get(server.URL)
+			if !tt.wantConnected {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("handshake succeeded with TLS version %x, want it to fail", resp.TLS.Version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.TLS == nil || resp.TLS.Version != tt.wantVersion {
+				t.Errorf("negotiated TLS state %+v, want version %x", resp.TLS, tt.wantVersion)
+			}
+		})
+	}
+}
+
+// trustServer makes a client built by the manager trust the test server's certificate, keeping its TLS settings.
+func trustServer(t *testing.T, client *http.Client, server *httptest.Server) {
+	t.Helper()
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("client transport is %T, want *http.Transport", client.Transport)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+}
@@ -21,6 +21,9 @@ type CronChecker struct {
 	EncryptionSvc   encryption.Service
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
+
+	backoffMu sync.Mutex
+	backoff   map[uint]*revalidationBackoff
 }
 
 // NewCronChecker creates a new CronChecker.
@@ -36,6 +39,7 @@ func NewCronChecker(
 		Validator:       validator,
 		EncryptionSvc:   encryptionSvc,
 		stopChan:        make(chan struct{}),
+		backoff:         make(map[uint]*revalidationBackoff),
 	}
 }
 
@@ -123,12 +127,13 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 		logrus.Errorf("CronChecker: Failed to get invalid keys for group %s: %v", group.Name, err)
 		return
 	}
+	invalidKeys = s.dueForRevalidation(group, invalidKeys)
 
 	if len(invalidKeys) == 0 {
 		if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
 			logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
 		}
-		logrus.Infof("CronChecker: Group '%s' has no invalid keys to check.", group.Name)
+		logrus.Infof("CronChecker: Group '%s' has no invalid keys due for a check.", group.Name)
 		return
 	}
 
@@ -148,6 +153,10 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 						return
 					}
 
+					if !s.waitForLowTraffic(group) {
+						return
+					}
+
 					// Decrypt the key before validation
 					decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
 					if err != nil {
@@ -160,6 +169,7 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 					keyForValidation.KeyValue = decryptedKey
 
 					isValid, _ := s.Validator.ValidateSingleKey(&keyForValidation, group)
+					s.recordRevalidation(group, key.ID, isValid)
 					if isValid {
 						atomic.AddInt32(&becameValidCount, 1)
 					}
@@ -195,3 +205,37 @@ DistributeLoop:
 		duration.String(),
 	)
 }
+
+// lowTrafficPollInterval is how often paused validation rechecks the live traffic.
+var lowTrafficPollInterval = time.Second
+
+// waitForLowTraffic pauses background validation while live traffic exceeds the configured threshold,
+// so that validation never competes with client requests. It returns false if the checker is stopping.
+func (s *CronChecker) waitForLowTraffic(group *models.Group) bool {
+	threshold := int64(group.EffectiveConfig.KeyValidationMaxInFlight)
+	if threshold <= 0 {
+		return true
+	}
+
+	paused := false
+	for {
+		inFlight := s.Validator.keypoolProvider.InFlightRequests()
+		if inFlight <= threshold {
+			if paused {
+				logrus.Debugf("CronChecker: Live traffic dropped to %d, resuming validation for group '%s'.", inFlight, group.Name)
+			}
+			return true
+		}
+
+		if !paused {
+			logrus.Infof("CronChecker: Live traffic (%d in-flight requests) exceeds threshold %d, pausing validation for group '%s'.", inFlight, threshold, group.Name)
+			paused = true
+		}
+
+		select {
+		case <-time.After(lowTrafficPollInterval):
+		case <-s.stopChan:
+			return false
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package keypool
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+func newTrafficTestChecker(t *testing.T) (*CronChecker, *KeyProvider) {
+	t.Helper()
+	interval := lowTrafficPollInterval
+	lowTrafficPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { lowTrafficPollInterval = interval })
+
+	provider := &KeyProvider{}
+	return NewCronChecker(nil, nil, &KeyValidator{keypoolProvider: provider}, nil), provider
+}
+
+func TestWaitForLowTrafficBacksOffUnderLoad(t *testing.T) {
+	checker, provider := newTrafficTestChecker(t)
+	group := &models.Group{Name: "g"}
+	group.EffectiveConfig.KeyValidationMaxInFlight = 1
+
+	if !checker.waitForLowTraffic(group) {
+		t.Fatal("validation paused without live traffic")
+	}
+
+	endFirst := provider.TrackRequest()
+	endSecond := provider.TrackRequest()
+	resumed := make(chan bool, 1)
+	go func() { resumed <- checker.waitForLowTraffic(group) }()
+
+	select {
+	case <-resumed:
+		t.Fatal("validation ran while live traffic exceeded the threshold")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	endSecond()
+	select {
+	case ok := <-resumed:
+		if !ok {
+			t.Error("validation stopped instead of resuming")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("validation did not resume once traffic dropped to the threshold")
+	}
+	endFirst()
+}
+
+func TestWaitForLowTrafficStopsWithChecker(t *testing.T) {
+	checker, provider := newTrafficTestChecker(t)
+	group := &models.Group{Name: "g"}
+	group.EffectiveConfig.KeyValidationMaxInFlight = 1
+	defer provider.TrackRequest()()
+	defer provider.TrackRequest()()
+
+	resumed := make(chan bool, 1)
+	go func() { resumed <- checker.waitForLowTraffic(group) }()
+	close(checker.stopChan)
+
+	select {
+	case ok := <-resumed:
+		if ok {
+			t.Error("validation resumed although the checker is stopping")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("paused validation did not notice the checker stopping")
+	}
+}
+
+func TestWaitForLowTrafficWithoutThreshold(t *testing.T) {
+	checker, provider := newTrafficTestChecker(t)
+	for range 10 {
+		defer provider.TrackRequest()()
+	}
+	if !checker.waitForLowTraffic(&models.Group{Name: "g"}) {
+		t.Error("validation paused although no threshold is configured")
+	}
+}
@@ -0,0 +1,95 @@
+package keypool
+
+import (
+	"fmt"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// drainPollInterval is how often a draining key is checked for remaining in-flight requests.
+const drainPollInterval = time.Second
+
+// DrainingKeyStatus reports the progress of a draining key.
+type DrainingKeyStatus struct {
+	KeyID    uint   `json:"key_id"`
+	Notes    string `json:"notes"`
+	InFlight int    `json:"in_flight"`
+}
+
+// DrainKey takes a key out of rotation without aborting its in-flight requests. The key is deleted
+// automatically once its last in-flight request on this instance has finished.
+func (p *KeyProvider) DrainKey(keyID uint) error {
+	var key models.APIKey
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.First(&key, keyID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&key).Update("status", models.KeyStatusDraining).Error; err != nil {
+			return fmt.Errorf("failed to mark key as draining in DB: %w", err)
+		}
+
+		if err := p.removeActiveKey(key.GroupID, keyID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusDraining}); err != nil {
+			return fmt.Errorf("failed to mark key as draining in store: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"keyID": keyID, "in_flight": p.KeyInFlight(keyID)}).Info("Key is draining")
+	go p.removeWhenDrained(keyID, key.GroupID)
+	return nil
+}
+
+// DrainingKeys lists the draining keys of a group with their remaining in-flight requests.
+func (p *KeyProvider) DrainingKeys(groupID uint) ([]DrainingKeyStatus, error) {
+	var keys []models.APIKey
+	if err := p.db.Select("id, notes").Where("group_id = ? AND status = ?", groupID, models.KeyStatusDraining).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	statuses := make([]DrainingKeyStatus, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, DrainingKeyStatus{KeyID: key.ID, Notes: key.Notes, InFlight: p.KeyInFlight(key.ID)})
+	}
+	return statuses, nil
+}
+
+// removeWhenDrained deletes a draining key once it has no in-flight requests left. It gives up if the
+// key was deleted or its status changed meanwhile.
+func (p *KeyProvider) removeWhenDrained(keyID uint, groupID uint) {
+	keyHashKey := fmt.Sprintf("key:%d", keyID)
+	for p.KeyInFlight(keyID) > 0 {
+		time.Sleep(drainPollInterval)
+	}
+
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil || keyDetails["status"] != models.KeyStatusDraining {
+		return
+	}
+
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND status = ?", keyID, models.KeyStatusDraining).Delete(&models.APIKey{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return p.removeKeyFromStore(keyID, groupID)
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to remove drained key")
+		return
+	}
+	logrus.WithField("keyID", keyID).Info("Drained key removed")
+}
@@ -0,0 +1,202 @@
+package keypool
+
+import (
+	"context"
+	"fmt"
+	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notify"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// expiryCheckInterval is how often key expiries are resolved and checked.
+const expiryCheckInterval = time.Hour
+
+// ExpiryChecker periodically warns about keys that expire within the key_expiry_warning_hours window.
+// Keys of channels that can look up their expiry upstream get it filled in first.
+type ExpiryChecker struct {
+	DB              *gorm.DB
+	SettingsManager *config.SystemSettingsManager
+	EncryptionSvc   encryption.Service
+	channelFactory  *channel.Factory
+	notifier        *notify.Notifier
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+
+	// resolved holds the keys whose expiry was looked up, so keys without expiry are looked up once.
+	resolved map[uint]bool
+	// warned holds the expiry each key was last warned about, so a key is warned about once per expiry.
+	warned map[uint]time.Time
+}
+
+// NewExpiryChecker creates a new ExpiryChecker.
+func NewExpiryChecker(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	encryptionSvc encryption.Service,
+	channelFactory *channel.Factory,
+	notifier *notify.Notifier,
+) *ExpiryChecker {
+	return &ExpiryChecker{
+		DB:              db,
+		SettingsManager: settingsManager,
+		EncryptionSvc:   encryptionSvc,
+		channelFactory:  channelFactory,
+		notifier:        notifier,
+		stopChan:        make(chan struct{}),
+		resolved:        make(map[uint]bool),
+		warned:          make(map[uint]time.Time),
+	}
+}
+
+// Start begins the periodic expiry checks.
+func (s *ExpiryChecker) Start() {
+	logrus.Debug("Starting ExpiryChecker...")
+	s.wg.Add(1)
+	go s.runLoop()
+}
+
+// Stop stops the expiry checks, respecting the context for shutdown timeout.
+func (s *ExpiryChecker) Stop(ctx context.Context) {
+	close(s.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("ExpiryChecker stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("ExpiryChecker stop timed out.")
+	}
+}
+
+func (s *ExpiryChecker) runLoop() {
+	defer s.wg.Done()
+
+	s.check()
+
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+func (s *ExpiryChecker) check() {
+	window := time.Duration(s.SettingsManager.GetSettings().KeyExpiryWarningHours) * time.Hour
+	if window <= 0 {
+		return
+	}
+
+	var groups []models.Group
+	if err := s.DB.Where("group_type != ? OR group_type IS NULL", "aggregate").Find(&groups).Error; err != nil {
+		logrus.Errorf("ExpiryChecker: Failed to get groups: %v", err)
+		return
+	}
+
+	deadline := time.Now().Add(window)
+	for i := range groups {
+		group := &groups[i]
+		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+		s.resolveExpiries(group)
+		s.warnExpiringKeys(group, deadline)
+	}
+}
+
+// resolveExpiries looks up the expiry of keys that have none yet, if the group's channel can.
+func (s *ExpiryChecker) resolveExpiries(group *models.Group) {
+	channelHandler, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return
+	}
+	resolver, ok := channelHandler.(channel.KeyExpiryResolver)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	if err := s.DB.Where("group_id = ? AND expires_at IS NULL AND status != ?", group.ID, models.KeyStatusDraining).Find(&keys).Error; err != nil {
+		logrus.Errorf("ExpiryChecker: Failed to get keys for group %s: %v", group.Name, err)
+		return
+	}
+
+	for i := range keys {
+		key := &keys[i]
+		if s.resolved[key.ID] {
+			continue
+		}
+		s.resolved[key.ID] = true
+
+		decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+		if err != nil {
+			logrus.WithError(err).WithField("key_id", key.ID).Warn("ExpiryChecker: Failed to decrypt key, skipping")
+			continue
+		}
+		key.KeyValue = decryptedKey
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
+		expiresAt, err := resolver.ResolveKeyExpiry(ctx, key, group)
+		cancel()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"group": group.Name, "key_id": key.ID, "error": err}).Debug("ExpiryChecker: Could not resolve key expiry")
+			continue
+		}
+		if expiresAt == nil {
+			continue
+		}
+		if err := s.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).Update("expires_at", expiresAt).Error; err != nil {
+			logrus.Errorf("ExpiryChecker: Failed to store expiry of key %d: %v", key.ID, err)
+		}
+	}
+}
+
+// warnExpiringKeys logs and notifies about keys expiring before the deadline, once per key and expiry.
+func (s *ExpiryChecker) warnExpiringKeys(group *models.Group, deadline time.Time) {
+	var keys []models.APIKey
+	if err := s.DB.Select("id, expires_at").
+		Where("group_id = ? AND expires_at IS NOT NULL AND expires_at <= ? AND status != ?", group.ID, deadline, models.KeyStatusDraining).
+		Find(&keys).Error; err != nil {
+		logrus.Errorf("ExpiryChecker: Failed to get expiring keys for group %s: %v", group.Name, err)
+		return
+	}
+
+	for _, key := range keys {
+		if warnedAt, ok := s.warned[key.ID]; ok && warnedAt.Equal(*key.ExpiresAt) {
+			continue
+		}
+		s.warned[key.ID] = *key.ExpiresAt
+
+		reason := fmt.Sprintf("expires in %s", time.Until(*key.ExpiresAt).Round(time.Minute))
+		if key.ExpiresAt.Before(time.Now()) {
+			reason = "expired"
+		}
+		logrus.WithFields(logrus.Fields{
+			"group":      group.Name,
+			"key_id":     key.ID,
+			"expires_at": key.ExpiresAt.Format(time.RFC3339),
+		}).Warnf("Key %s", reason)
+		s.notifier.Notify(notify.Event{
+			Type:      notify.EventKeyExpiring,
+			Group:     group.Name,
+			KeyID:     key.ID,
+			Reason:    reason,
+			ExpiresAt: key.ExpiresAt,
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package keypool
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// KeyCooldown reports a key that is out of rotation until its rate limit resets.
+type KeyCooldown struct {
+	KeyID         uint      `json:"key_id"`
+	CooldownUntil time.Time `json:"cooldown_until"`
+}
+
+// groupCooldownsKey is the store hash mapping the IDs of a group's cooling-down keys to the Unix time
+// their cooldown ends. Fields are removed when the cooldown ends or the key is deleted.
+func groupCooldownsKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:cooldowns", groupID)
+}
+
+// KeyCooldowns lists the keys of a group that are cooling down, soonest recovery first.
+func (p *KeyProvider) KeyCooldowns(groupID uint) ([]KeyCooldown, error) {
+	fields, err := p.store.HGetAll(groupCooldownsKey(groupID))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	cooldowns := make([]KeyCooldown, 0, len(fields))
+	for field, value := range fields {
+		until, _ := strconv.ParseInt(value, 10, 64)
+		if until <= now {
+			continue
+		}
+		keyID, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		cooldowns = append(cooldowns, KeyCooldown{KeyID: uint(keyID), CooldownUntil: time.Unix(until, 0)})
+	}
+
+	sort.Slice(cooldowns, func(i, j int) bool {
+		if !cooldowns[i].CooldownUntil.Equal(cooldowns[j].CooldownUntil) {
+			return cooldowns[i].CooldownUntil.Before(cooldowns[j].CooldownUntil)
+		}
+		return cooldowns[i].KeyID < cooldowns[j].KeyID
+	})
+	return cooldowns, nil
+}
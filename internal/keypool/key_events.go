@@ -0,0 +1,61 @@
+package keypool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/notify"
+
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamStatusPattern matches the "[status N]" prefix of key validation errors.
+var upstreamStatusPattern = regexp.MustCompile(`\[status (\d{3})\]`)
+
+// upstreamStatusOf extracts the upstream HTTP status from a validation error message, or returns 0.
+func upstreamStatusOf(errorMessage string) int {
+	match := upstreamStatusPattern.FindStringSubmatch(errorMessage)
+	if match == nil {
+		return 0
+	}
+	status, _ := strconv.Atoi(match[1])
+	return status
+}
+
+// notifyKeyEvent reports a key state change through the webhook.
+func (p *KeyProvider) notifyKeyEvent(eventType string, apiKey *models.APIKey, group *models.Group, reason string, statusCode int) {
+	p.notifier.Notify(notify.Event{
+		Type:           eventType,
+		Group:          group.Name,
+		KeyID:          apiKey.ID,
+		KeyHash:        notify.KeyHash(apiKey.KeyValue),
+		Reason:         reason,
+		UpstreamStatus: statusCode,
+	})
+}
+
+// notifyIfGroupExhausted reports a group whose last active key was just invalidated. A group is reported
+// once until one of its keys recovers.
+func (p *KeyProvider) notifyIfGroupExhausted(group *models.Group, reason string, statusCode int) {
+	length, err := p.store.LLen(fmt.Sprintf("group:%d:active_keys", group.ID))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "error": err}).Warn("Failed to check active keys of group")
+		return
+	}
+	if length > 0 {
+		return
+	}
+	if _, reported := p.exhaustedGroups.LoadOrStore(group.ID, true); reported {
+		return
+	}
+
+	logrus.WithField("group", group.Name).Warn("Group has no active keys left")
+	p.notifier.Notify(notify.Event{
+		Type:           notify.EventGroupExhausted,
+		Group:          group.Name,
+		Reason:         reason,
+		UpstreamStatus: statusCode,
+	})
+}
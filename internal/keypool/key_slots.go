@@ -0,0 +1,58 @@
+package keypool
+
+import "sync"
+
+// keySlots counts the in-flight requests of each key on this instance.
+type keySlots struct {
+	mu       sync.Mutex
+	inFlight map[uint]int
+}
+
+// TryAcquireKey claims a request slot on a key if it has fewer than limit requests in flight.
+// A limit of 0 means unlimited; the slot is still counted. The returned release function is
+// idempotent, so it can be deferred and also called early. Slots are not shared through the store:
+// each instance enforces the limit on its own requests only.
+func (p *KeyProvider) TryAcquireKey(keyID uint, limit int) (func(), bool) {
+	p.slots.mu.Lock()
+	defer p.slots.mu.Unlock()
+
+	if p.slots.inFlight == nil {
+		p.slots.inFlight = make(map[uint]int)
+	}
+	if limit > 0 && p.slots.inFlight[keyID] >= limit {
+		return nil, false
+	}
+	p.slots.inFlight[keyID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.slots.mu.Lock()
+			defer p.slots.mu.Unlock()
+			if p.slots.inFlight[keyID] <= 1 {
+				delete(p.slots.inFlight, keyID)
+			} else {
+				p.slots.inFlight[keyID]--
+			}
+		})
+	}, true
+}
+
+// KeyInFlight returns the number of requests currently in flight on a key on this instance.
+func (p *KeyProvider) KeyInFlight(keyID uint) int {
+	p.slots.mu.Lock()
+	defer p.slots.mu.Unlock()
+	return p.slots.inFlight[keyID]
+}
+
+// KeysInFlight returns a snapshot of the keys with requests in flight on this instance and their counts.
+func (p *KeyProvider) KeysInFlight() map[uint]int {
+	p.slots.mu.Lock()
+	defer p.slots.mu.Unlock()
+
+	snapshot := make(map[uint]int, len(p.slots.inFlight))
+	for keyID, count := range p.slots.inFlight {
+		snapshot[keyID] = count
+	}
+	return snapshot
+}
@@ -0,0 +1,137 @@
+package keypool
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MaxKeyTags bounds the tags of a key, which are stored comma-separated in a 255 character column.
+const MaxKeyTags = 8
+
+// keyTagPattern restricts tags to characters that need no escaping in the comma-separated column and
+// in LIKE filters.
+var keyTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.:-]{0,31}$`)
+
+// ParseKeyTags splits a comma-separated tag list into lowercase, de-duplicated tags.
+func ParseKeyTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeKeyTags validates tags and returns them sorted and comma-separated, as stored on a key.
+func NormalizeKeyTags(tags []string) (string, error) {
+	normalized := ParseKeyTags(strings.Join(tags, ","))
+	if len(normalized) > MaxKeyTags {
+		return "", fmt.Errorf("a key can have at most %d tags", MaxKeyTags)
+	}
+	for _, tag := range normalized {
+		if !keyTagPattern.MatchString(tag) {
+			return "", fmt.Errorf("invalid tag '%s': use up to 32 letters, digits, '.', ':' or '-'", tag)
+		}
+	}
+	slices.Sort(normalized)
+	return strings.Join(normalized, ","), nil
+}
+
+// KeyHasTags reports whether a key's comma-separated tags include every required tag.
+func KeyHasTags(keyTags string, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	tags := ParseKeyTags(keyTags)
+	for _, tag := range required {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// WhereKeyHasTag narrows a key query to keys carrying the tag.
+func WhereKeyHasTag(query *gorm.DB, tag string) *gorm.DB {
+	return query.Where("(tags = ? OR tags LIKE ? OR tags LIKE ? OR tags LIKE ?)", tag, tag+",%", "%,"+tag, "%,"+tag+",%")
+}
+
+// SelectKeyWithTags selects a key of the group carrying all required tags. Without required tags it is
+// SelectKey. Otherwise round_robin rotates the active list until a matching key comes up, and
+// least_recently_used picks the matching key selected longest ago.
+func (p *KeyProvider) SelectKeyWithTags(group *models.Group, required []string) (*models.APIKey, error) {
+	if len(required) == 0 {
+		return p.SelectKey(group)
+	}
+	if group.EffectiveConfig.KeySelectionStrategy == KeySelectionLeastRecentlyUsed {
+		return p.selectLeastRecentlyUsedKeyWithTags(group.ID, required)
+	}
+
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
+	length, err := p.store.LLen(activeKeysListKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active key count: %w", err)
+	}
+	for range length {
+		keyID, keyDetails, err := p.rotateKey(activeKeysListKey)
+		if err != nil {
+			return nil, err
+		}
+		if KeyHasTags(keyDetails["tags"], required) {
+			return p.apiKeyFromDetails(keyID, group.ID, keyDetails), nil
+		}
+	}
+	return nil, fmt.Errorf("%w with tags: %s", app_errors.ErrNoActiveKeys, strings.Join(required, ", "))
+}
+
+// selectLeastRecentlyUsedKeyWithTags walks the group's keys from the one selected longest ago and
+// stamps the first one carrying all required tags as used.
+func (p *KeyProvider) selectLeastRecentlyUsedKeyWithTags(groupID uint, required []string) (*models.APIKey, error) {
+	keyIDs, err := p.store.ZRange(groupLRUKeysKey(groupID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list least recently used keys: %w", err)
+	}
+	for _, keyIDStr := range keyIDs {
+		keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		}
+		if !KeyHasTags(keyDetails["tags"], required) {
+			continue
+		}
+		if err := p.store.ZAdd(groupLRUKeysKey(groupID), map[string]float64{keyIDStr: float64(time.Now().UnixMicro())}); err != nil {
+			return nil, fmt.Errorf("failed to record key use: %w", err)
+		}
+		return p.apiKeyFromDetails(uint(keyID), groupID, keyDetails), nil
+	}
+	return nil, fmt.Errorf("%w with tags: %s", app_errors.ErrNoActiveKeys, strings.Join(required, ", "))
+}
+
+// UpdateKeyTags sets the comma-separated tags of a key in the DB and the store.
+func (p *KeyProvider) UpdateKeyTags(keyID uint, tags string) error {
+	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ?", keyID).Update("tags", tags).Error; err != nil {
+			return fmt.Errorf("failed to update key tags in DB: %w", err)
+		}
+		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		if err := p.store.HSet(keyHashKey, map[string]any{"tags": tags}); err != nil {
+			return fmt.Errorf("failed to update key tags in store: %w", err)
+		}
+		return nil
+	})
+}
@@ -7,10 +7,13 @@ import (
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/notify"
 	"gpt-load/internal/store"
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,44 +25,138 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	notifier        *notify.Notifier
+	inFlight        atomic.Int64
+	slots           keySlots
+	// exhaustedGroups holds the groups reported as having no active keys, until a key of theirs recovers.
+	exhaustedGroups sync.Map
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
-func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyProvider {
+func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service, notifier *notify.Notifier) *KeyProvider {
 	return &KeyProvider{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		encryptionSvc:   encryptionSvc,
+		notifier:        notifier,
 	}
 }
 
+// TrackRequest marks a live proxy request as in flight and returns a function that ends the tracking.
+func (p *KeyProvider) TrackRequest() func() {
+	p.inFlight.Add(1)
+	return func() {
+		p.inFlight.Add(-1)
+	}
+}
+
+// InFlightRequests returns the number of live proxy requests currently being processed by this instance.
+func (p *KeyProvider) InFlightRequests() int64 {
+	return p.inFlight.Load()
+}
+
+// 密钥选择策略
+const (
+	KeySelectionRoundRobin        = "round_robin"
+	KeySelectionLeastRecentlyUsed = "least_recently_used"
+)
+
+// groupLRUKeysKey is the store sorted set of a group's active keys, scored by the Unix microsecond
+// time of their last selection. Keys that have not been selected since they were listed score 0.
+func groupLRUKeysKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:lru_keys", groupID)
+}
+
 // SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
-func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+func (p *KeyProvider) SelectKey(group *models.Group) (*models.APIKey, error) {
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
-	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
+	if group.EffectiveConfig.KeySelectionStrategy == KeySelectionLeastRecentlyUsed {
+		return p.selectLeastRecentlyUsedKey(group.ID)
+	}
+
+	keyID, keyDetails, err := p.rotateKey(activeKeysListKey)
+	if err != nil {
+		return nil, err
+	}
+	return p.apiKeyFromDetails(keyID, group.ID, keyDetails), nil
+}
+
+// HasActiveKeys reports whether the group has keys in its active rotation. Store errors report keys as available.
+func (p *KeyProvider) HasActiveKeys(groupID uint) bool {
+	length, err := p.store.LLen(fmt.Sprintf("group:%d:active_keys", groupID))
+	if err != nil {
+		logrus.WithError(err).WithField("group_id", groupID).Debug("Error checking active keys, assuming available")
+		return true
+	}
+	return length > 0
+}
+
+// selectLeastRecentlyUsedKey picks the key of the group selected longest ago and stamps it as used in
+// the same atomic step, so concurrent requests are handed different keys whatever their outcome.
+func (p *KeyProvider) selectLeastRecentlyUsedKey(groupID uint) (*models.APIKey, error) {
+	keyIDStr, err := p.store.ZRotateMin(groupLRUKeysKey(groupID), float64(time.Now().UnixMicro()))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, app_errors.ErrNoActiveKeys
 		}
-		return nil, fmt.Errorf("failed to rotate key from store: %w", err)
+		return nil, fmt.Errorf("failed to select least recently used key: %w", err)
 	}
 
 	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
 	}
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+	}
+	return p.apiKeyFromDetails(uint(keyID), groupID, keyDetails), nil
+}
+
+// rotateKey atomically rotates the next key ID out of the active list and loads its details.
+func (p *KeyProvider) rotateKey(activeKeysListKey string) (uint, map[string]string, error) {
+	// 1. Atomically rotate the key ID from the list
+	keyIDStr, err := p.store.Rotate(activeKeysListKey)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil, app_errors.ErrNoActiveKeys
+		}
+		return 0, nil, fmt.Errorf("failed to rotate key from store: %w", err)
+	}
+
+	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+	}
 
 	// 2. Get key details from HASH
 	keyHashKey := fmt.Sprintf("key:%d", keyID)
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		return 0, nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+	}
+
+	return uint(keyID), keyDetails, nil
+}
+
+// GetForcedKey returns a specific key of a group, bypassing rotation. It fails if the key does not
+// belong to the group, and reports whether the key is currently usable (active and not cooling down).
+func (p *KeyProvider) GetForcedKey(groupID uint, keyID uint) (*models.APIKey, bool, error) {
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil || len(keyDetails) == 0 || keyDetails["group_id"] != strconv.FormatUint(uint64(groupID), 10) {
+		return nil, false, fmt.Errorf("key %d not found in this group", keyID)
 	}
 
-	// 3. Manually unmarshal the map into an APIKey struct
+	apiKey := p.apiKeyFromDetails(keyID, groupID, keyDetails)
+	cooldownUntil, _ := strconv.ParseInt(keyDetails["cooldown_until"], 10, 64)
+	usable := apiKey.Status == models.KeyStatusActive && time.Now().Unix() >= cooldownUntil
+	return apiKey, usable, nil
+}
+
+// apiKeyFromDetails builds an APIKey from its store hash, decrypting the key value.
+func (p *KeyProvider) apiKeyFromDetails(keyID uint, groupID uint, keyDetails map[string]string) *models.APIKey {
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
 
@@ -75,26 +172,27 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 		decryptedKeyValue = encryptedKeyValue
 	}
 
-	apiKey := &models.APIKey{
-		ID:           uint(keyID),
+	return &models.APIKey{
+		ID:           keyID,
 		KeyValue:     decryptedKeyValue,
 		Status:       keyDetails["status"],
 		FailureCount: failureCount,
 		GroupID:      groupID,
+		ProxyURL:     keyDetails["proxy_url"],
+		Weight:       keyWeight(keyDetails["weight"]),
+		Tags:         keyDetails["tags"],
 		CreatedAt:    time.Unix(createdAt, 0),
 	}
-
-	return apiKey, nil
 }
 
-// UpdateStatus 异步地提交一个 Key 状态更新任务。
-func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string) {
+// UpdateStatus 异步地提交一个 Key 状态更新任务。statusCode is the upstream HTTP status of a failure,
+// or 0 when there was no upstream response.
+func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int, errorMessage string) {
 	go func() {
 		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
 		if isSuccess {
-			if err := p.handleSuccess(apiKey.ID, keyHashKey, activeKeysListKey); err != nil {
+			if err := p.handleSuccess(apiKey, group, keyHashKey); err != nil {
 				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key success")
 			}
 		} else {
@@ -104,7 +202,7 @@ func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, i
 					"error": errorMessage,
 				}).Debug("Uncounted error, skipping failure handling")
 			} else {
-				if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey); err != nil {
+				if err := p.handleFailure(apiKey, group, keyHashKey, statusCode, errorMessage); err != nil {
 					logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
 				}
 			}
@@ -139,7 +237,8 @@ func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) er
 	return err
 }
 
-func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey string) error {
+func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, group *models.Group, keyHashKey string) error {
+	keyID := apiKey.ID
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
@@ -148,11 +247,12 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	isActive := keyDetails["status"] == models.KeyStatusActive
 
-	if failureCount == 0 && isActive {
+	// A draining key must not be brought back into rotation.
+	if (failureCount == 0 && isActive) || keyDetails["status"] == models.KeyStatusDraining {
 		return nil
 	}
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
 		var key models.APIKey
 		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, keyID).Error; err != nil {
 			return fmt.Errorf("failed to lock key %d for update: %w", keyID, err)
@@ -167,31 +267,44 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 			return fmt.Errorf("failed to update key in DB: %w", err)
 		}
 
-		if err := p.store.HSet(keyHashKey, updates); err != nil {
-			return fmt.Errorf("failed to update key details in store: %w", err)
-		}
-
+		// List a recovered key before marking it active in the store, so that a failed push leaves the
+		// store as it was when the DB transaction rolls back.
 		if !isActive {
 			logrus.WithField("keyID", keyID).Debug("Key has recovered and is being restored to active pool.")
-			if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
-				return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
+			if err := p.listActiveKey(group.ID, keyID, keyWeight(keyDetails["weight"])); err != nil {
+				return fmt.Errorf("failed to push key back to active list: %w", err)
 			}
-			if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
-				return fmt.Errorf("failed to LPush key back to active list: %w", err)
+		}
+
+		if err := p.store.HSet(keyHashKey, updates); err != nil {
+			if !isActive {
+				if lremErr := p.removeActiveKey(group.ID, keyID); lremErr != nil {
+					logrus.WithFields(logrus.Fields{"keyID": keyID, "error": lremErr}).Error("Failed to take key back out of active list")
+				}
 			}
+			return fmt.Errorf("failed to update key details in store: %w", err)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if !isActive {
+		p.exhaustedGroups.Delete(group.ID)
+		p.notifyKeyEvent(notify.EventKeyRecovered, apiKey, group, "", 0)
+	}
+	return nil
 }
 
-func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string) error {
+func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey string, statusCode int, errorMessage string) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
 	}
 
-	if keyDetails["status"] == models.KeyStatusInvalid {
+	if keyDetails["status"] == models.KeyStatusInvalid || keyDetails["status"] == models.KeyStatusDraining {
 		return nil
 	}
 
@@ -199,17 +312,16 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 
 	// 获取该分组的有效配置
 	blacklistThreshold := group.EffectiveConfig.BlacklistThreshold
+	newFailureCount := failureCount + 1
+	shouldBlacklist := blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold)
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
 		var key models.APIKey
 		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, apiKey.ID).Error; err != nil {
 			return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
 		}
 
-		newFailureCount := failureCount + 1
-
 		updates := map[string]any{"failure_count": newFailureCount}
-		shouldBlacklist := blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold)
 		if shouldBlacklist {
 			updates["status"] = models.KeyStatusInvalid
 		}
@@ -224,7 +336,7 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 
 		if shouldBlacklist {
 			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
-			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			if err := p.removeActiveKey(group.ID, apiKey.ID); err != nil {
 				return fmt.Errorf("failed to LRem key from active list: %w", err)
 			}
 			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
@@ -234,6 +346,139 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if shouldBlacklist {
+		p.notifyKeyEvent(notify.EventKeyInvalid, apiKey, group, errorMessage, statusCode)
+		p.notifyIfGroupExhausted(group, errorMessage, statusCode)
+	}
+	return nil
+}
+
+// DisableKey immediately marks a key as invalid, regardless of the blacklist threshold.
+// It is used for failures that retrying with the same key cannot fix, such as a revoked credential.
+func (p *KeyProvider) DisableKey(apiKey *models.APIKey, group *models.Group, reason string) {
+	go func() {
+		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
+
+		err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("status", models.KeyStatusInvalid).Error; err != nil {
+				return fmt.Errorf("failed to disable key in DB: %w", err)
+			}
+			if err := p.removeActiveKey(group.ID, apiKey.ID); err != nil {
+				return fmt.Errorf("failed to LRem key from active list: %w", err)
+			}
+			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
+				return fmt.Errorf("failed to update key status to invalid in store: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to disable key")
+			return
+		}
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "reason": reason}).Warn("Key has been disabled.")
+		p.notifyKeyEvent(notify.EventKeyInvalid, apiKey, group, reason, 0)
+		p.notifyIfGroupExhausted(group, reason, 0)
+	}()
+}
+
+// CooldownKey takes a rate-limited key out of rotation for the duration the upstream asked for, then puts it back.
+// Unlike UpdateStatus it does not count a failure, so a key that is only throttled is never blacklisted.
+func (p *KeyProvider) CooldownKey(apiKey *models.APIKey, group *models.Group, cooldown time.Duration) {
+	go func() {
+		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
+		until := time.Now().Add(cooldown)
+
+		if err := p.removeActiveKey(group.ID, apiKey.ID); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to remove rate-limited key from active list")
+			return
+		}
+		if err := p.store.HSet(keyHashKey, map[string]any{"cooldown_until": until.Unix()}); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to record key cooldown")
+		}
+		if err := p.store.HSet(groupCooldownsKey(group.ID), map[string]any{strconv.FormatUint(uint64(apiKey.ID), 10): until.Unix()}); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to record key cooldown")
+		}
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "cooldown": cooldown}).Info("Key is rate limited, cooling down")
+
+		time.AfterFunc(cooldown, func() {
+			p.endCooldown(apiKey.ID, group.ID, keyHashKey)
+		})
+	}()
+}
+
+// endCooldown returns a key to rotation after its cooldown, unless it has been disabled meanwhile.
+func (p *KeyProvider) endCooldown(keyID, groupID uint, keyHashKey string) {
+	if err := p.store.HDel(groupCooldownsKey(groupID), strconv.FormatUint(uint64(keyID), 10)); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to clear key cooldown")
+	}
+
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil {
+		// The key was deleted during the cooldown.
+		return
+	}
+	if keyDetails["status"] != models.KeyStatusActive {
+		return
+	}
+
+	if err := p.store.HSet(keyHashKey, map[string]any{"cooldown_until": 0}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to clear key cooldown")
+	}
+	if err := p.listActiveKey(groupID, keyID, keyWeight(keyDetails["weight"])); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to restore key after cooldown")
+		return
+	}
+	logrus.WithField("keyID", keyID).Debug("Key cooldown ended")
+}
+
+// UpdateKeyWeight sets the rotation weight of a key in the DB and the store. An active key that is in
+// rotation is re-listed with its new weight; a cooling-down key picks it up when its cooldown ends.
+func (p *KeyProvider) UpdateKeyWeight(keyID uint, weight int) error {
+	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		var key models.APIKey
+		if err := tx.First(&key, keyID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&key).Update("weight", weight).Error; err != nil {
+			return fmt.Errorf("failed to update key weight in DB: %w", err)
+		}
+
+		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		keyDetails, err := p.store.HGetAll(keyHashKey)
+		if err != nil {
+			return fmt.Errorf("failed to get key details from store: %w", err)
+		}
+		if err := p.store.HSet(keyHashKey, map[string]any{"weight": weight}); err != nil {
+			return fmt.Errorf("failed to update key weight in store: %w", err)
+		}
+
+		cooldownUntil, _ := strconv.ParseInt(keyDetails["cooldown_until"], 10, 64)
+		if keyDetails["status"] == models.KeyStatusActive && time.Now().Unix() >= cooldownUntil {
+			activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
+			if err := p.pushActiveKey(activeKeysListKey, keyID, weight); err != nil {
+				return fmt.Errorf("failed to re-list key with new weight: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateKeyProxy sets the egress proxy URL of a key in the DB and the store.
+func (p *KeyProvider) UpdateKeyProxy(keyID uint, proxyURL string) error {
+	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ?", keyID).Update("proxy_url", proxyURL).Error; err != nil {
+			return fmt.Errorf("failed to update key proxy in DB: %w", err)
+		}
+		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		if err := p.store.HSet(keyHashKey, map[string]any{"proxy_url": proxyURL}); err != nil {
+			return fmt.Errorf("failed to update key proxy in store: %w", err)
+		}
+		return nil
+	})
 }
 
 // LoadKeysFromDB 从数据库加载所有分组和密钥，并填充到 Store 中。
@@ -241,7 +486,8 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 	logrus.Debug("First time startup, loading keys from DB...")
 
 	// 1. 分批从数据库加载并使用 Pipeline 写入 Redis
-	allActiveKeyIDs := make(map[uint][]any)
+	allActiveKeys := make(map[uint][]weightedKey)
+	drainingKeys := make(map[uint]uint)
 	batchSize := 1000
 	var batchKeys []*models.APIKey
 
@@ -265,8 +511,11 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 				}
 			}
 
+			if key.Status == models.KeyStatusDraining {
+				drainingKeys[key.ID] = key.GroupID
+			}
 			if key.Status == models.KeyStatusActive {
-				allActiveKeyIDs[key.GroupID] = append(allActiveKeyIDs[key.GroupID], key.ID)
+				allActiveKeys[key.GroupID] = append(allActiveKeys[key.GroupID], weightedKey{id: key.ID, weight: keyWeight(strconv.Itoa(key.Weight))})
 			}
 		}
 
@@ -284,16 +533,25 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 
 	// 2. 更新所有分组的 active_keys 列表
 	logrus.Info("Updating active key lists for all groups...")
-	for groupID, activeIDs := range allActiveKeyIDs {
-		if len(activeIDs) > 0 {
+	for groupID, activeKeys := range allActiveKeys {
+		if len(activeKeys) > 0 {
 			activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
 			p.store.Delete(activeKeysListKey)
-			if err := p.store.LPush(activeKeysListKey, activeIDs...); err != nil {
+			if err := p.store.LPush(activeKeysListKey, interleaveByWeight(activeKeys)...); err != nil {
 				logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to LPush active keys for group")
 			}
+			p.store.Delete(groupLRUKeysKey(groupID))
+			if err := p.store.ZAdd(groupLRUKeysKey(groupID), lruScores(activeKeys, 0)); err != nil {
+				logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to list active keys for least recently used selection")
+			}
 		}
 	}
 
+	// 3. 清理重启前未完成排空的密钥，重启后它们已没有进行中的请求
+	for keyID, groupID := range drainingKeys {
+		go p.removeWhenDrained(keyID, groupID)
+	}
+
 	return nil
 }
 
@@ -308,11 +566,9 @@ func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 			return err
 		}
 
-		for _, key := range keys {
-			if err := p.addKeyToStore(&key); err != nil {
-				logrus.WithFields(logrus.Fields{"keyID": key.ID, "error": err}).Error("Failed to add key to store after DB creation, rolling back transaction")
-				return err
-			}
+		if err := p.addKeysToStore(keys); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to add keys to store after DB creation, rolling back transaction")
+			return err
 		}
 		return nil
 	})
@@ -395,13 +651,13 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 		}
 		restoredCount = result.RowsAffected
 
-		for _, key := range invalidKeys {
-			key.Status = models.KeyStatusActive
-			key.FailureCount = 0
-			if err := p.addKeyToStore(&key); err != nil {
-				logrus.WithFields(logrus.Fields{"keyID": key.ID, "error": err}).Error("Failed to restore key in store after DB update, rolling back transaction")
-				return err
-			}
+		for i := range invalidKeys {
+			invalidKeys[i].Status = models.KeyStatusActive
+			invalidKeys[i].FailureCount = 0
+		}
+		if err := p.addKeysToStore(invalidKeys); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to restore keys in store after DB update, rolling back transaction")
+			return err
 		}
 		return nil
 	})
@@ -451,13 +707,13 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 		}
 		restoredCount = result.RowsAffected
 
-		for _, key := range keysToRestore {
-			key.Status = models.KeyStatusActive
-			key.FailureCount = 0
-			if err := p.addKeyToStore(&key); err != nil {
-				logrus.WithFields(logrus.Fields{"keyID": key.ID, "error": err}).Error("Failed to restore key in store after DB update")
-				return err
-			}
+		for i := range keysToRestore {
+			keysToRestore[i].Status = models.KeyStatusActive
+			keysToRestore[i].FailureCount = 0
+		}
+		if err := p.addKeysToStore(keysToRestore); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to restore keys in store after DB update")
+			return err
 		}
 
 		return nil
@@ -535,6 +791,20 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 		}).Error("Failed to delete active keys list")
 		return err
 	}
+	if err := p.store.Delete(groupLRUKeysKey(groupID)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Failed to delete least recently used keys set")
+		return err
+	}
+	if err := p.store.Delete(groupCooldownsKey(groupID)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Failed to delete key cooldowns")
+		return err
+	}
 
 	// 第二步：批量删除所有相关的key hash
 	for _, keyID := range keyIDs {
@@ -555,23 +825,38 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 	return nil
 }
 
-// addKeyToStore is a helper to add a single key to the cache.
-func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
-	// 1. Store key details in HASH
-	keyHashKey := fmt.Sprintf("key:%d", key.ID)
-	keyDetails := p.apiKeyToMap(key)
-	if err := p.store.HSet(keyHashKey, keyDetails); err != nil {
-		return fmt.Errorf("failed to HSet key details for key %d: %w", key.ID, err)
+// addKeysToStore is a helper to add keys to the cache. The active keys of each group are listed
+// together, so that the entries of heavy keys are interleaved with those of the other new keys.
+func (p *KeyProvider) addKeysToStore(keys []models.APIKey) error {
+	activeKeys := make(map[uint][]weightedKey)
+	var groupIDs []uint
+	for i := range keys {
+		key := &keys[i]
+
+		// 1. Store key details in HASH
+		keyHashKey := fmt.Sprintf("key:%d", key.ID)
+		keyDetails := p.apiKeyToMap(key)
+		if err := p.store.HSet(keyHashKey, keyDetails); err != nil {
+			return fmt.Errorf("failed to HSet key details for key %d: %w", key.ID, err)
+		}
+
+		if key.Status == models.KeyStatusActive {
+			if _, ok := activeKeys[key.GroupID]; !ok {
+				groupIDs = append(groupIDs, key.GroupID)
+			}
+			activeKeys[key.GroupID] = append(activeKeys[key.GroupID], weightedKey{id: key.ID, weight: keyWeight(strconv.Itoa(key.Weight))})
+		}
 	}
 
-	// 2. If active, add to the active LIST
-	if key.Status == models.KeyStatusActive {
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
-		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
-			return fmt.Errorf("failed to LRem key %d before LPush for group %d: %w", key.ID, key.GroupID, err)
+	// 2. Add the active keys to the active LIST of their group
+	for _, groupID := range groupIDs {
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+		if err := p.pushActiveKeys(activeKeysListKey, activeKeys[groupID]); err != nil {
+			return fmt.Errorf("failed to push %d keys to group %d: %w", len(activeKeys[groupID]), groupID, err)
 		}
-		if err := p.store.LPush(activeKeysListKey, key.ID); err != nil {
-			return fmt.Errorf("failed to LPush key %d to group %d: %w", key.ID, key.GroupID, err)
+		// New and restored keys have not been selected yet, so least_recently_used picks them first.
+		if err := p.store.ZAdd(groupLRUKeysKey(groupID), lruScores(activeKeys[groupID], 0)); err != nil {
+			return fmt.Errorf("failed to list %d keys of group %d for least recently used selection: %w", len(activeKeys[groupID]), groupID, err)
 		}
 	}
 	return nil
@@ -579,10 +864,12 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 
 // removeKeyFromStore is a helper to remove a single key from the cache.
 func (p *KeyProvider) removeKeyFromStore(keyID, groupID uint) error {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-	if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+	if err := p.removeActiveKey(groupID, keyID); err != nil {
 		logrus.WithFields(logrus.Fields{"keyID": keyID, "groupID": groupID, "error": err}).Error("Failed to LRem key from active list")
 	}
+	if err := p.store.HDel(groupCooldownsKey(groupID), strconv.FormatUint(uint64(keyID), 10)); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "groupID": groupID, "error": err}).Error("Failed to clear key cooldown")
+	}
 
 	keyHashKey := fmt.Sprintf("key:%d", keyID)
 	if err := p.store.Delete(keyHashKey); err != nil {
@@ -600,6 +887,9 @@ func (p *KeyProvider) apiKeyToMap(key *models.APIKey) map[string]any {
 		"failure_count": key.FailureCount,
 		"group_id":      key.GroupID,
 		"created_at":    key.CreatedAt.Unix(),
+		"proxy_url":     key.ProxyURL,
+		"weight":        keyWeight(strconv.Itoa(key.Weight)),
+		"tags":          key.Tags,
 	}
 }
 
@@ -611,3 +901,87 @@ func pluckIDs(keys []models.APIKey) []uint {
 	}
 	return ids
 }
+
+// MaxKeyWeight bounds key weights, as a key is listed once per weight unit in the active list.
+const MaxKeyWeight = 100
+
+type weightedKey struct {
+	id     uint
+	weight int
+}
+
+// keyWeight parses a stored key weight. Keys without a valid weight count as 1.
+func keyWeight(value string) int {
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 1 {
+		return 1
+	}
+	return min(weight, MaxKeyWeight)
+}
+
+// pushActiveKey (re)lists a key in a group's active list once per weight unit, so that rotation hands
+// out keys in proportion to their weights. Existing entries are removed first so the count stays exact.
+func (p *KeyProvider) pushActiveKey(activeKeysListKey string, keyID uint, weight int) error {
+	return p.pushActiveKeys(activeKeysListKey, []weightedKey{{id: keyID, weight: weight}})
+}
+
+// pushActiveKeys (re)lists keys in a group's active list with their weights. Only the given keys' entries
+// are touched, interleaved among themselves; the rest of the list and its rotation position are kept.
+func (p *KeyProvider) pushActiveKeys(activeKeysListKey string, keys []weightedKey) error {
+	for _, key := range keys {
+		if err := p.store.LRem(activeKeysListKey, 0, key.id); err != nil {
+			return err
+		}
+	}
+	return p.store.LPush(activeKeysListKey, interleaveByWeight(keys)...)
+}
+
+// listActiveKey returns a key to a group's rotation. It counts as used now, so least_recently_used
+// does not pick a key coming back from a failure or cooldown before the keys that stayed in rotation.
+func (p *KeyProvider) listActiveKey(groupID, keyID uint, weight int) error {
+	if err := p.pushActiveKey(fmt.Sprintf("group:%d:active_keys", groupID), keyID, weight); err != nil {
+		return err
+	}
+	return p.store.ZAdd(groupLRUKeysKey(groupID), map[string]float64{strconv.FormatUint(uint64(keyID), 10): float64(time.Now().UnixMicro())})
+}
+
+// removeActiveKey takes a key out of a group's rotation.
+func (p *KeyProvider) removeActiveKey(groupID, keyID uint) error {
+	if err := p.store.LRem(fmt.Sprintf("group:%d:active_keys", groupID), 0, keyID); err != nil {
+		return err
+	}
+	return p.store.ZRem(groupLRUKeysKey(groupID), keyID)
+}
+
+// lruScores maps keys to the same least_recently_used score.
+func lruScores(keys []weightedKey, score float64) map[string]float64 {
+	scores := make(map[string]float64, len(keys))
+	for _, key := range keys {
+		scores[strconv.FormatUint(uint64(key.id), 10)] = score
+	}
+	return scores
+}
+
+// interleaveByWeight expands weighted keys into active list entries using smooth weighted round-robin,
+// so that a heavy key's turns are spread across the list instead of coming in one burst.
+func interleaveByWeight(keys []weightedKey) []any {
+	totalWeight := 0
+	for _, key := range keys {
+		totalWeight += key.weight
+	}
+
+	current := make([]int, len(keys))
+	entries := make([]any, 0, totalWeight)
+	for range totalWeight {
+		best := 0
+		for i, key := range keys {
+			current[i] += key.weight
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= totalWeight
+		entries = append(entries, keys[best].id)
+	}
+	return entries
+}
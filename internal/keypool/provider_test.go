@@ -0,0 +1,302 @@
+package keypool
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+)
+
+func TestKeyWeight(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 1},
+		{"abc", 1},
+		{"0", 1},
+		{"-3", 1},
+		{"1", 1},
+		{"7", 7},
+		{"100", MaxKeyWeight},
+		{"1000", MaxKeyWeight},
+	}
+	for _, tt := range tests {
+		if got := keyWeight(tt.value); got != tt.want {
+			t.Errorf("keyWeight(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestInterleaveByWeight(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []weightedKey
+		want []uint
+	}{
+		{
+			name: "no keys",
+			want: []uint{},
+		},
+		{
+			name: "equal weights keep key order",
+			keys: []weightedKey{{id: 1, weight: 1}, {id: 2, weight: 1}, {id: 3, weight: 1}},
+			want: []uint{1, 2, 3},
+		},
+		{
+			name: "heavy key spread around a light one",
+			keys: []weightedKey{{id: 1, weight: 3}, {id: 2, weight: 1}},
+			want: []uint{1, 1, 2, 1},
+		},
+		{
+			name: "heavy key never listed back-to-back with room to spread",
+			keys: []weightedKey{{id: 1, weight: 2}, {id: 2, weight: 1}, {id: 3, weight: 1}},
+			want: []uint{1, 2, 3, 1},
+		},
+		{
+			name: "classic smooth weighted round-robin",
+			keys: []weightedKey{{id: 1, weight: 5}, {id: 2, weight: 1}, {id: 3, weight: 1}},
+			want: []uint{1, 1, 2, 1, 3, 1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := interleaveByWeight(tt.keys)
+			got := make([]uint, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, entry.(uint))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("interleaveByWeight = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterleaveByWeightListsEachKeyOncePerWeightUnit(t *testing.T) {
+	keys := []weightedKey{{id: 1, weight: 7}, {id: 2, weight: 3}, {id: 3, weight: 1}, {id: 4, weight: MaxKeyWeight}}
+	counts := make(map[uint]int)
+	for _, entry := range interleaveByWeight(keys) {
+		counts[entry.(uint)]++
+	}
+	for _, key := range keys {
+		if counts[key.id] != key.weight {
+			t.Errorf("key %d listed %d times, want %d", key.id, counts[key.id], key.weight)
+		}
+	}
+}
+
+func TestPushActiveKeyKeepsRestOfList(t *testing.T) {
+	p := &KeyProvider{store: store.NewMemoryStore()}
+	const listKey = "group:1:active_keys"
+	if err := p.pushActiveKeys(listKey, []weightedKey{{id: 1, weight: 1}, {id: 2, weight: 1}, {id: 3, weight: 2}}); err != nil {
+		t.Fatalf("pushActiveKeys: %v", err)
+	}
+	// Rotate once so the list no longer starts at its initial position.
+	if _, err := p.store.Rotate(listKey); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	before := listItems(t, p, listKey)
+
+	if err := p.pushActiveKey(listKey, 2, 3); err != nil {
+		t.Fatalf("pushActiveKey: %v", err)
+	}
+	after := listItems(t, p, listKey)
+
+	var others []string
+	for _, item := range before {
+		if item != "2" {
+			others = append(others, item)
+		}
+	}
+	// The other keys keep their turns; the re-listed key's entries come up after them.
+	if want := append(others, "2", "2", "2"); !slices.Equal(after, want) {
+		t.Errorf("active list = %v, want %v (was %v)", after, want, before)
+	}
+}
+
+// listItems returns the items of a list in rotation order, leaving the list as it was.
+func listItems(t *testing.T, p *KeyProvider, listKey string) []string {
+	t.Helper()
+	length, err := p.store.LLen(listKey)
+	if err != nil {
+		t.Fatalf("LLen: %v", err)
+	}
+	items := make([]string, 0, length)
+	for range length {
+		item, err := p.store.Rotate(listKey)
+		if err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// newLRUTestProvider returns a provider over a memory store holding active keys of group 1.
+func newLRUTestProvider(t *testing.T, keys ...models.APIKey) *KeyProvider {
+	t.Helper()
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+	p := &KeyProvider{store: store.NewMemoryStore(), encryptionSvc: encSvc}
+	for i := range keys {
+		keys[i].GroupID = 1
+		keys[i].Status = models.KeyStatusActive
+	}
+	if err := p.addKeysToStore(keys); err != nil {
+		t.Fatalf("addKeysToStore: %v", err)
+	}
+	return p
+}
+
+func lruTestGroup() *models.Group {
+	group := &models.Group{ID: 1}
+	group.EffectiveConfig.KeySelectionStrategy = KeySelectionLeastRecentlyUsed
+	return group
+}
+
+func TestSelectLeastRecentlyUsedKey(t *testing.T) {
+	p := newLRUTestProvider(t, models.APIKey{ID: 1}, models.APIKey{ID: 2}, models.APIKey{ID: 3, Weight: 5})
+	group := lruTestGroup()
+	selectKey := func() uint {
+		t.Helper()
+		key, err := p.SelectKey(group)
+		if err != nil {
+			t.Fatalf("SelectKey: %v", err)
+		}
+		return key.ID
+	}
+
+	// Selection stamps the use, whether or not the request then succeeds, and weights do not count.
+	var got []uint
+	for range 6 {
+		got = append(got, selectKey())
+	}
+	if want := []uint{1, 2, 3, 1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("selected keys = %v, want %v", got, want)
+	}
+
+	// A key returning to rotation queues up behind the keys that stayed in it.
+	if err := p.removeActiveKey(1, 1); err != nil {
+		t.Fatalf("removeActiveKey: %v", err)
+	}
+	if got := []uint{selectKey(), selectKey()}; !slices.Equal(got, []uint{2, 3}) {
+		t.Errorf("selected keys without key 1 = %v, want [2 3]", got)
+	}
+	if err := p.listActiveKey(1, 1, 1); err != nil {
+		t.Fatalf("listActiveKey: %v", err)
+	}
+	if got := []uint{selectKey(), selectKey(), selectKey()}; !slices.Equal(got, []uint{2, 3, 1}) {
+		t.Errorf("selected keys after key 1 returned = %v, want [2 3 1]", got)
+	}
+
+	for _, id := range []uint{1, 2, 3} {
+		if err := p.removeActiveKey(1, id); err != nil {
+			t.Fatalf("removeActiveKey: %v", err)
+		}
+	}
+	if _, err := p.SelectKey(group); !errors.Is(err, app_errors.ErrNoActiveKeys) {
+		t.Errorf("SelectKey without active keys = %v, want ErrNoActiveKeys", err)
+	}
+}
+
+func TestSelectLeastRecentlyUsedKeyConcurrently(t *testing.T) {
+	const keyCount = 20
+	keys := make([]models.APIKey, keyCount)
+	for i := range keys {
+		keys[i].ID = uint(i + 1)
+	}
+	p := newLRUTestProvider(t, keys...)
+	group := lruTestGroup()
+
+	var mu sync.Mutex
+	selected := make(map[uint]int)
+	var wg sync.WaitGroup
+	for range keyCount {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := p.SelectKey(group)
+			if err != nil {
+				t.Errorf("SelectKey: %v", err)
+				return
+			}
+			mu.Lock()
+			selected[key.ID]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(selected) != keyCount {
+		t.Errorf("%d concurrent selections used %d distinct keys, want one each: %v", keyCount, len(selected), selected)
+	}
+}
+
+func TestSelectLeastRecentlyUsedKeyWithTags(t *testing.T) {
+	p := newLRUTestProvider(t, models.APIKey{ID: 1, Tags: "eu"}, models.APIKey{ID: 2}, models.APIKey{ID: 3, Tags: "eu,paid"})
+	group := lruTestGroup()
+
+	var got []uint
+	for range 3 {
+		key, err := p.SelectKeyWithTags(group, []string{"eu"})
+		if err != nil {
+			t.Fatalf("SelectKeyWithTags: %v", err)
+		}
+		got = append(got, key.ID)
+	}
+	if want := []uint{1, 3, 1}; !slices.Equal(got, want) {
+		t.Errorf("selected keys = %v, want %v", got, want)
+	}
+
+	// Tagged selections count as uses for untagged ones.
+	if key, err := p.SelectKey(group); err != nil || key.ID != 2 {
+		t.Errorf("SelectKey = %v, %v; want key 2", key, err)
+	}
+	if _, err := p.SelectKeyWithTags(group, []string{"us"}); !errors.Is(err, app_errors.ErrNoActiveKeys) {
+		t.Errorf("SelectKeyWithTags without matching keys = %v, want ErrNoActiveKeys", err)
+	}
+}
+
+func TestKeyCooldownsClearedWithKeys(t *testing.T) {
+	p := newLRUTestProvider(t, models.APIKey{ID: 1}, models.APIKey{ID: 2}, models.APIKey{ID: 3})
+	cooldownsKey := groupCooldownsKey(1)
+	if err := p.store.HSet(cooldownsKey, map[string]any{"1": 1, "2": 1, "3": 1}); err != nil {
+		t.Fatalf("HSet: %v", err)
+	}
+	cooldowns := func() map[string]string {
+		t.Helper()
+		fields, err := p.store.HGetAll(cooldownsKey)
+		if err != nil {
+			t.Fatalf("HGetAll: %v", err)
+		}
+		return fields
+	}
+
+	p.endCooldown(1, 1, "key:1")
+	if fields := cooldowns(); len(fields) != 2 || fields["1"] != "" {
+		t.Errorf("cooldowns after key 1 recovered = %v, want keys 2 and 3", fields)
+	}
+
+	if err := p.removeKeyFromStore(2, 1); err != nil {
+		t.Fatalf("removeKeyFromStore: %v", err)
+	}
+	if fields := cooldowns(); len(fields) != 1 || fields["3"] == "" {
+		t.Errorf("cooldowns after key 2 was deleted = %v, want key 3", fields)
+	}
+
+	// Deleting the group drops the whole hash.
+	if err := p.RemoveKeysFromStore(1, []uint{1, 3}); err != nil {
+		t.Fatalf("RemoveKeysFromStore: %v", err)
+	}
+	if exists, err := p.store.Exists(cooldownsKey); err != nil || exists {
+		t.Errorf("cooldowns of the deleted group exist = %v, %v; want gone", exists, err)
+	}
+}
@@ -0,0 +1,72 @@
+package keypool
+
+import (
+	"math/rand"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+// revalidationBackoff tracks the failed background re-validations of one invalid key.
+type revalidationBackoff struct {
+	groupID  uint
+	failures int
+	nextAt   time.Time
+}
+
+// dueForRevalidation returns the invalid keys whose backoff has elapsed. Backoff state of keys that are
+// no longer invalid, e.g. restored manually or by live traffic, is dropped so it starts over next time.
+func (s *CronChecker) dueForRevalidation(group *models.Group, invalidKeys []models.APIKey) []models.APIKey {
+	s.backoffMu.Lock()
+	defer s.backoffMu.Unlock()
+
+	invalid := make(map[uint]struct{}, len(invalidKeys))
+	for _, key := range invalidKeys {
+		invalid[key.ID] = struct{}{}
+	}
+	for keyID, state := range s.backoff {
+		if _, ok := invalid[keyID]; !ok && state.groupID == group.ID {
+			delete(s.backoff, keyID)
+		}
+	}
+
+	now := time.Now()
+	due := invalidKeys[:0:0]
+	for _, key := range invalidKeys {
+		if state, ok := s.backoff[key.ID]; ok && now.Before(state.nextAt) {
+			continue
+		}
+		due = append(due, key)
+	}
+	return due
+}
+
+// recordRevalidation resets a key's backoff on success and otherwise schedules its next check after an
+// exponentially growing, jittered delay, so keys recovering from an outage are not all re-checked at once.
+func (s *CronChecker) recordRevalidation(group *models.Group, keyID uint, isValid bool) {
+	s.backoffMu.Lock()
+	defer s.backoffMu.Unlock()
+
+	if isValid {
+		delete(s.backoff, keyID)
+		return
+	}
+
+	state := s.backoff[keyID]
+	if state == nil {
+		state = &revalidationBackoff{groupID: group.ID}
+		s.backoff[keyID] = state
+	}
+	state.failures++
+
+	base := time.Duration(group.EffectiveConfig.RevalidationBackoffBase) * time.Minute
+	maxDelay := time.Duration(group.EffectiveConfig.RevalidationBackoffMax) * time.Minute
+	delay := maxDelay
+	if shift := state.failures - 1; shift < 32 && base<<shift < maxDelay {
+		delay = base << shift
+	}
+
+	// Equal jitter: keep half of the delay and randomize the other half.
+	half := delay / 2
+	state.nextAt = time.Now().Add(half + time.Duration(rand.Int63n(int64(half)+1)))
+}
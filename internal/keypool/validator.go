@@ -16,9 +16,11 @@ import (
 
 // KeyTestResult holds the validation result for a single key.
 type KeyTestResult struct {
-	KeyValue string `json:"key_value"`
-	IsValid  bool   `json:"is_valid"`
-	Error    string `json:"error,omitempty"`
+	KeyValue    string `json:"key_value"`
+	IsValid     bool   `json:"is_valid"`
+	Error       string `json:"error,omitempty"`
+	TokenMintMs *int64 `json:"token_mint_ms,omitempty"`
+	UpstreamMs  *int64 `json:"upstream_ms,omitempty"`
 }
 
 // KeyValidator provides methods to validate API keys.
@@ -52,10 +54,14 @@ func NewKeyValidator(params KeyValidatorParams) *KeyValidator {
 
 // ValidateSingleKey performs a validation check on a single API key.
 func (s *KeyValidator) ValidateSingleKey(key *models.APIKey, group *models.Group) (bool, error) {
+	return s.validateSingleKey(context.Background(), key, group)
+}
+
+func (s *KeyValidator) validateSingleKey(parent context.Context, key *models.APIKey, group *models.Group) (bool, error) {
 	if group.EffectiveConfig.AppUrl == "" {
 		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
 	defer cancel()
 
 	ch, err := s.channelFactory.GetChannel(group)
@@ -69,7 +75,7 @@ func (s *KeyValidator) ValidateSingleKey(key *models.APIKey, group *models.Group
 	if !isValid && validationErr != nil {
 		errorMsg = validationErr.Error()
 	}
-	s.keypoolProvider.UpdateStatus(key, group, isValid, errorMsg)
+	s.keypoolProvider.UpdateStatus(key, group, isValid, upstreamStatusOf(errorMsg), errorMsg)
 
 	if !isValid {
 		logrus.WithFields(logrus.Fields{
@@ -116,6 +122,18 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 		existingKeyMap[k.KeyHash] = k
 	}
 
+	if group.EffectiveConfig.AppUrl == "" {
+		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+	}
+	ch, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel for group %s: %w", group.Name, err)
+	}
+	batchValidator, isBatch := ch.(channel.BatchKeyValidator)
+
+	var batchKeys []*models.APIKey
+	var batchIndexes []int
+
 	for i, kv := range keyValues {
 		keyHash := s.encryptionSvc.Hash(kv)
 		apiKey, exists := existingKeyMap[keyHash]
@@ -130,17 +148,107 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 
 		apiKey.KeyValue = kv
 
-		isValid, validationErr := s.ValidateSingleKey(&apiKey, group)
+		if isBatch {
+			batchKeys = append(batchKeys, &apiKey)
+			batchIndexes = append(batchIndexes, i)
+			continue
+		}
+
+		ctx, timings := channel.WithValidationTimings(context.Background())
+		isValid, validationErr := s.validateSingleKey(ctx, &apiKey, group)
 
 		results[i] = KeyTestResult{
-			KeyValue: kv,
-			IsValid:  isValid,
-			Error:    "",
+			KeyValue:    kv,
+			IsValid:     isValid,
+			Error:       "",
+			TokenMintMs: durationMs(timings.TokenMint),
+			UpstreamMs:  durationMs(timings.Upstream),
 		}
 		if validationErr != nil {
 			results[i].Error = validationErr.Error()
 		}
 	}
 
+	if len(batchKeys) > 0 {
+		for j, result := range batchValidator.ValidateKeys(context.Background(), batchKeys, group) {
+			var errorMsg string
+			if !result.IsValid && result.Err != nil {
+				errorMsg = result.Err.Error()
+			}
+			s.keypoolProvider.UpdateStatus(result.APIKey, group, result.IsValid, upstreamStatusOf(errorMsg), errorMsg)
+
+			i := batchIndexes[j]
+			results[i] = KeyTestResult{
+				KeyValue: keyValues[i],
+				IsValid:  result.IsValid,
+				Error:    errorMsg,
+			}
+			if result.Timings != nil {
+				results[i].TokenMintMs = durationMs(result.Timings.TokenMint)
+				results[i].UpstreamMs = durationMs(result.Timings.Upstream)
+			}
+		}
+	}
+
 	return results, nil
 }
+
+// LivenessResult holds the outcome of a group liveness probe.
+type LivenessResult struct {
+	Healthy   bool   `json:"healthy"`
+	Probe     string `json:"probe"`
+	KeyID     uint   `json:"key_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// CheckLiveness probes a group with the key that would serve the next request. Channels implementing
+// channel.LivenessChecker use their cheap check; others fall back to ValidateKey. Key status is not updated.
+func (s *KeyValidator) CheckLiveness(ctx context.Context, group *models.Group) (*LivenessResult, error) {
+	if group.EffectiveConfig.AppUrl == "" {
+		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+	}
+	ch, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel for group %s: %w", group.Name, err)
+	}
+
+	apiKey, err := s.keypoolProvider.SelectKey(group)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	result := &LivenessResult{KeyID: apiKey.ID}
+	start := time.Now()
+	var probeErr error
+	if checker, ok := ch.(channel.LivenessChecker); ok {
+		result.Probe = "liveness"
+		probeErr = checker.Liveness(ctx, apiKey, group)
+	} else {
+		result.Probe = "validate"
+		var isValid bool
+		isValid, probeErr = ch.ValidateKey(ctx, apiKey, group)
+		if !isValid && probeErr == nil {
+			probeErr = fmt.Errorf("key validation failed")
+		}
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Healthy = probeErr == nil
+	if probeErr != nil {
+		result.Error = probeErr.Error()
+	}
+
+	return result, nil
+}
+
+// durationMs converts an optional duration to milliseconds.
+func durationMs(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	ms := d.Milliseconds()
+	return &ms
+}
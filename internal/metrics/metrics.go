@@ -0,0 +1,154 @@
+// Package metrics collects proxy request statistics and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxModelsPerGroup bounds the model label: models beyond the first ones seen for a group are
+// reported as "other", so arbitrary client-supplied model names cannot blow up the series count.
+const maxModelsPerGroup = 50
+
+var (
+	enabled  atomic.Bool
+	keyLabel atomic.Bool
+
+	durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	mintBuckets     = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+	requestsTotal      *counterVec
+	requestDuration    *histogramVec
+	upstreamDuration   *histogramVec
+	tokenMintDuration  *histogramVec
+	tokenMintFailures  *counterVec
+	modelsMu           sync.Mutex
+	knownModelsByGroup = make(map[string]map[string]struct{})
+)
+
+func init() {
+	register(false)
+}
+
+// register (re)creates the metric families. The key label changes the label set of the request
+// counter, so it is decided once at startup.
+func register(withKeyLabel bool) {
+	requestLabels := []string{"group", "channel_type", "model", "status_class", "request_type"}
+	if withKeyLabel {
+		requestLabels = append(requestLabels, "key_id")
+	}
+	requestsTotal = newCounterVec("gpt_load_requests_total", "Proxy request attempts by outcome.", requestLabels...)
+	requestDuration = newHistogramVec("gpt_load_request_duration_seconds", "End-to-end duration of proxy requests, retries included.", durationBuckets,
+		"group", "channel_type", "model", "status_class")
+	upstreamDuration = newHistogramVec("gpt_load_upstream_request_duration_seconds", "Time until the upstream returned response headers, per attempt.", durationBuckets,
+		"group", "channel_type", "status_class")
+	tokenMintDuration = newHistogramVec("gpt_load_vertex_token_mint_duration_seconds", "Duration of Vertex service account access token mints.", mintBuckets,
+		"group", "result")
+	tokenMintFailures = newCounterVec("gpt_load_vertex_token_mint_failures_total", "Failed Vertex service account access token mints.", "group")
+}
+
+// Configure applies the metrics configuration. It must be called before any request is served.
+func Configure(cfg types.MetricsConfig) {
+	enabled.Store(cfg.Enabled)
+	if cfg.KeyLabel != keyLabel.Load() {
+		keyLabel.Store(cfg.KeyLabel)
+		register(cfg.KeyLabel)
+	}
+}
+
+// Enabled reports whether metrics are collected.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// ObserveRequest records one proxy request attempt. The duration histogram only tracks final attempts,
+// whose duration spans the whole request.
+func ObserveRequest(group, channelType, model string, keyID uint, statusCode int, requestType string, isFinal bool, duration time.Duration) {
+	if !enabled.Load() {
+		return
+	}
+	model = boundedModel(group, model)
+	class := statusClass(statusCode)
+
+	if keyLabel.Load() {
+		requestsTotal.inc(group, channelType, model, class, requestType, strconv.FormatUint(uint64(keyID), 10))
+	} else {
+		requestsTotal.inc(group, channelType, model, class, requestType)
+	}
+	if isFinal {
+		requestDuration.observe(duration.Seconds(), group, channelType, model, class)
+	}
+}
+
+// ObserveUpstream records how long an upstream took to answer one attempt. A statusCode of 0 means
+// the attempt failed without a response.
+func ObserveUpstream(group, channelType string, statusCode int, duration time.Duration) {
+	if !enabled.Load() {
+		return
+	}
+	upstreamDuration.observe(duration.Seconds(), group, channelType, statusClass(statusCode))
+}
+
+// ObserveTokenMint records a Vertex access token mint.
+func ObserveTokenMint(group string, err error, duration time.Duration) {
+	if !enabled.Load() {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+		tokenMintFailures.inc(group)
+	}
+	tokenMintDuration.observe(duration.Seconds(), group, result)
+}
+
+// Handler serves all metrics in the Prometheus text exposition format.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var buf bytes.Buffer
+		requestsTotal.write(&buf)
+		requestDuration.write(&buf)
+		upstreamDuration.write(&buf)
+		tokenMintDuration.write(&buf)
+		tokenMintFailures.write(&buf)
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+	}
+}
+
+func boundedModel(group, model string) string {
+	if model == "" {
+		return "unknown"
+	}
+
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	known := knownModelsByGroup[group]
+	if known == nil {
+		known = make(map[string]struct{})
+		knownModelsByGroup[group] = known
+	}
+	if _, ok := known[model]; ok {
+		return model
+	}
+	if len(known) >= maxModelsPerGroup {
+		return "other"
+	}
+	known[model] = struct{}{}
+	return model
+}
+
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"gpt-load/internal/types"
 	"time"
 
@@ -9,8 +10,9 @@ import (
 
 // Key状态
 const (
-	KeyStatusActive  = "active"
-	KeyStatusInvalid = "invalid"
+	KeyStatusActive   = "active"
+	KeyStatusInvalid  = "invalid"
+	KeyStatusDraining = "draining"
 )
 
 // SystemSetting 对应 system_settings 表
@@ -26,25 +28,109 @@ type SystemSetting struct {
 // GroupConfig 存储特定于分组的配置
 type GroupConfig struct {
 	RequestTimeout               *int    `json:"request_timeout,omitempty"`
+	MaxRequestTimeout            *int    `json:"max_request_timeout,omitempty"`
 	IdleConnTimeout              *int    `json:"idle_conn_timeout,omitempty"`
 	ConnectTimeout               *int    `json:"connect_timeout,omitempty"`
 	MaxIdleConns                 *int    `json:"max_idle_conns,omitempty"`
 	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
 	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
+	TLSHandshakeTimeout          *int    `json:"tls_handshake_timeout,omitempty"`
+	ForceHTTP2                   *bool   `json:"force_http2,omitempty"`
 	ProxyURL                     *string `json:"proxy_url,omitempty"`
+	TLSMinVersion                *string `json:"tls_min_version,omitempty"`
+	TLSCipherSuites              *string `json:"tls_cipher_suites,omitempty"`
+	AllowedContentTypes          *string `json:"allowed_content_types,omitempty"`
+	PassthroughHeaders           *string `json:"passthrough_headers,omitempty"`
+	GroundingMetadataMode        *string `json:"grounding_metadata_mode,omitempty"`
+	StripReasoningContent        *bool   `json:"strip_reasoning_content,omitempty"`
+	ClientAPIFormat              *string `json:"client_api_format,omitempty"`
+	VertexOAuthScopes            *string `json:"vertex_oauth_scopes,omitempty"`
+	VertexTokenCache             *string `json:"vertex_token_cache,omitempty"`
+	VertexTokenURI               *string `json:"vertex_token_uri,omitempty"`
+	VertexCachedContent          *string `json:"vertex_cached_content,omitempty"`
+	VertexGCSBucket              *string `json:"vertex_gcs_bucket,omitempty"`
+	VertexGCSThresholdKB         *int    `json:"vertex_gcs_threshold_kb,omitempty"`
+	VertexTokenMaxAge            *int    `json:"vertex_token_max_age,omitempty"`
+	VertexAccountOrder           *string `json:"vertex_account_order,omitempty"`
+	VertexAutoRegion             *bool   `json:"vertex_auto_region,omitempty"`
+	VertexStrictProject          *bool   `json:"vertex_strict_project,omitempty"`
+	VertexOpenAIFallback         *bool   `json:"vertex_openai_fallback,omitempty"`
+	VertexTokenSkew              *int    `json:"vertex_token_skew,omitempty"`
+	VertexMintTimeout            *int    `json:"vertex_mint_timeout,omitempty"`
+	AzureAPIVersion              *string `json:"azure_api_version,omitempty"`
+	UpstreamPathPrefix           *string `json:"upstream_path_prefix,omitempty"`
+	GroupMaxConcurrency          *int    `json:"group_max_concurrency,omitempty"`
+	KeyMaxConcurrency            *int    `json:"key_max_concurrency,omitempty"`
+	FairShareClientHeader        *string `json:"fair_share_client_header,omitempty"`
+	SessionAffinityHeader        *string `json:"session_affinity_header,omitempty"`
+	ResponseCacheTTL             *int    `json:"response_cache_ttl,omitempty"`
+	ResponseCacheMaxMB           *int    `json:"response_cache_max_mb,omitempty"`
+	CoalesceRequests             *bool   `json:"coalesce_requests,omitempty"`
+	QuotaWindow                  *string `json:"quota_window,omitempty"`
+	QuotaMaxRequests             *int    `json:"quota_max_requests,omitempty"`
+	QuotaMaxTokens               *int    `json:"quota_max_tokens,omitempty"`
+	FallbackGroups               *string `json:"fallback_groups,omitempty"`
+	ShadowGroup                  *string `json:"shadow_group,omitempty"`
+	ShadowPercentage             *int    `json:"shadow_percentage,omitempty"`
+	AllowForceKey                *bool   `json:"allow_force_key,omitempty"`
+	RejectUnknownModels          *bool   `json:"reject_unknown_models,omitempty"`
+	ModelAliases                 *string `json:"model_aliases,omitempty"`
+	ModelListCacheTTL            *int    `json:"model_list_cache_ttl,omitempty"`
+	ModelAllowlist               *string `json:"model_allowlist,omitempty"`
+	ModelDenylist                *string `json:"model_denylist,omitempty"`
+	MaxRequestBodyMB             *int    `json:"max_request_body_mb,omitempty"`
+	MaxResponseBodyMB            *int    `json:"max_response_body_mb,omitempty"`
+	MaxInputTokens               *int    `json:"max_input_tokens,omitempty"`
+	StreamTokensPerSecond        *int    `json:"stream_tokens_per_second,omitempty"`
+	InputTokenEstimation         *string `json:"input_token_estimation,omitempty"`
 	MaxRetries                   *int    `json:"max_retries,omitempty"`
+	RetryStatusCodes             *string `json:"retry_status_codes,omitempty"`
+	RetryStreamRequests          *bool   `json:"retry_stream_requests,omitempty"`
 	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
 	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
+	RevalidationBackoffBase      *int    `json:"revalidation_backoff_base,omitempty"`
+	RevalidationBackoffMax       *int    `json:"revalidation_backoff_max,omitempty"`
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
+	KeyValidationMaxInFlight     *int    `json:"key_validation_max_inflight,omitempty"`
+	DedupeKeysOnImport           *bool   `json:"dedupe_keys_on_import,omitempty"`
+	KeySelectionStrategy         *string `json:"key_selection_strategy,omitempty"`
+	RequiredKeyTags              *string `json:"required_key_tags,omitempty"`
 	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	RequestLifecycleLogLevel     *string `json:"request_lifecycle_log_level,omitempty"`
+	LogUpstreamHeaders           *bool   `json:"log_upstream_headers,omitempty"`
+	RequestLogFields             *string `json:"request_log_fields,omitempty"`
+}
+
+// ModelCapability describes optional capability metadata exposed in model lists.
+type ModelCapability struct {
+	Streaming     *bool `json:"streaming,omitempty"`
+	Tools         *bool `json:"tools,omitempty"`
+	Vision        *bool `json:"vision,omitempty"`
+	ContextWindow int   `json:"context_window,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
 type HeaderRule struct {
-	Key    string `json:"key"`
-	Value  string `json:"value"`
-	Action string `json:"action"` // "set" or "remove"
+	Key    string           `json:"key"`
+	Value  string           `json:"value"`
+	Action string           `json:"action"` // "set" or "remove"
+	Match  *HeaderRuleMatch `json:"match,omitempty"`
+}
+
+// HeaderRuleMatch restricts a header rule to matching requests. Unset fields match every request.
+type HeaderRuleMatch struct {
+	Models     []string `json:"models,omitempty"`      // model glob patterns, e.g. "*-preview*"
+	PathPrefix string   `json:"path_prefix,omitempty"` // request path below the group, e.g. "/v1beta/models"
+	Stream     *bool    `json:"stream,omitempty"`
+}
+
+// BodyRule defines a single rule for request body manipulation. Path names a JSON field, e.g.
+// "generationConfig.temperature"; numeric segments index arrays.
+type BodyRule struct {
+	Path   string          `json:"path"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Action string          `json:"action"` // "set", "remove" or "default"
 }
 
 // GroupSubGroup 聚合分组和子分组的关联表
@@ -95,8 +181,11 @@ type Group struct {
 	ParamOverrides       datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
 	Config               datatypes.JSONMap    `gorm:"type:json" json:"config"`
 	HeaderRules          datatypes.JSON       `gorm:"type:json" json:"header_rules"`
+	BodyRules            datatypes.JSON       `gorm:"type:json" json:"body_rules"`
 	ModelRedirectRules   datatypes.JSONMap    `gorm:"type:json" json:"model_redirect_rules"`
 	ModelRedirectStrict  bool                 `gorm:"default:false" json:"model_redirect_strict"`
+	ModelCapabilities    datatypes.JSONMap    `gorm:"type:json" json:"model_capabilities"`
+	VertexLocations      datatypes.JSONMap    `gorm:"type:json" json:"vertex_locations"`
 	APIKeys              []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
 	SubGroups            []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
 	LastValidatedAt      *time.Time           `json:"last_validated_at"`
@@ -104,9 +193,20 @@ type Group struct {
 	UpdatedAt            time.Time            `json:"updated_at"`
 
 	// For cache
-	ProxyKeysMap      map[string]struct{} `gorm:"-" json:"-"`
-	HeaderRuleList    []HeaderRule        `gorm:"-" json:"-"`
-	ModelRedirectMap  map[string]string   `gorm:"-" json:"-"`
+	ProxyKeysMap     map[string]struct{} `gorm:"-" json:"-"`
+	HeaderRuleList   []HeaderRule        `gorm:"-" json:"-"`
+	BodyRuleList     []BodyRule          `gorm:"-" json:"-"`
+	ModelRedirectMap map[string]string   `gorm:"-" json:"-"`
+	// ModelRedirectCanaries holds the canary rules; their models map to themselves in ModelRedirectMap.
+	ModelRedirectCanaries map[string]ModelRedirectCanary `gorm:"-" json:"-"`
+	ModelCapabilityMap    map[string]ModelCapability     `gorm:"-" json:"-"`
+	VertexLocationMap     map[string]string              `gorm:"-" json:"-"`
+}
+
+// ModelRedirectCanary sends a percentage of a model's requests to another model. The rest keep the model.
+type ModelRedirectCanary struct {
+	Target     string `json:"target"`
+	Percentage int    `json:"percentage"`
 }
 
 // APIKey 对应 api_keys 表
@@ -117,41 +217,49 @@ type APIKey struct {
 	GroupID      uint       `gorm:"not null;index" json:"group_id"`
 	Status       string     `gorm:"type:varchar(50);not null;default:'active'" json:"status"`
 	Notes        string     `gorm:"type:varchar(255);default:''" json:"notes"`
+	ProxyURL     string     `gorm:"type:varchar(512);default:''" json:"proxy_url"`
+	Weight       int        `gorm:"not null;default:1" json:"weight"`
+	Tags         string     `gorm:"type:varchar(255);default:''" json:"tags"`
 	RequestCount int64      `gorm:"not null;default:0" json:"request_count"`
 	FailureCount int64      `gorm:"not null;default:0" json:"failure_count"`
 	LastUsedAt   *time.Time `json:"last_used_at"`
+	ExpiresAt    *time.Time `gorm:"index" json:"expires_at"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // RequestType 请求类型常量
 const (
-	RequestTypeRetry = "retry"
-	RequestTypeFinal = "final"
+	RequestTypeRetry  = "retry"
+	RequestTypeFinal  = "final"
+	RequestTypeShadow = "shadow"
 )
 
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
-	ID              string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Timestamp       time.Time `gorm:"not null;index" json:"timestamp"`
-	GroupID         uint      `gorm:"not null;index" json:"group_id"`
-	GroupName       string    `gorm:"type:varchar(255);index" json:"group_name"`
-	ParentGroupID   uint      `gorm:"index" json:"parent_group_id"`
-	ParentGroupName string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
-	KeyValue        string    `gorm:"type:text" json:"key_value"`
-	KeyHash         string    `gorm:"type:varchar(128);index" json:"key_hash"`
-	Model           string    `gorm:"type:varchar(255);index" json:"model"`
-	IsSuccess       bool      `gorm:"not null" json:"is_success"`
-	SourceIP        string    `gorm:"type:varchar(64)" json:"source_ip"`
-	StatusCode      int       `gorm:"not null" json:"status_code"`
-	RequestPath     string    `gorm:"type:varchar(500)" json:"request_path"`
-	Duration        int64     `gorm:"not null" json:"duration_ms"`
-	ErrorMessage    string    `gorm:"type:text" json:"error_message"`
-	UserAgent       string    `gorm:"type:varchar(512)" json:"user_agent"`
-	RequestType     string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
-	UpstreamAddr    string    `gorm:"type:varchar(500)" json:"upstream_addr"`
-	IsStream        bool      `gorm:"not null" json:"is_stream"`
-	RequestBody     string    `gorm:"type:text" json:"request_body"`
+	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp        time.Time `gorm:"not null;index" json:"timestamp"`
+	GroupID          uint      `gorm:"not null;index" json:"group_id"`
+	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
+	ParentGroupID    uint      `gorm:"index" json:"parent_group_id"`
+	ParentGroupName  string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
+	KeyValue         string    `gorm:"type:text" json:"key_value"`
+	KeyHash          string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	Model            string    `gorm:"type:varchar(255);index" json:"model"`
+	IsSuccess        bool      `gorm:"not null" json:"is_success"`
+	SourceIP         string    `gorm:"type:varchar(64)" json:"source_ip"`
+	StatusCode       int       `gorm:"not null" json:"status_code"`
+	RequestPath      string    `gorm:"type:varchar(500)" json:"request_path"`
+	Duration         int64     `gorm:"not null" json:"duration_ms"`
+	ErrorMessage     string    `gorm:"type:text" json:"error_message"`
+	UserAgent        string    `gorm:"type:varchar(512)" json:"user_agent"`
+	RequestType      string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
+	UpstreamAddr     string    `gorm:"type:varchar(500)" json:"upstream_addr"`
+	IsStream         bool      `gorm:"not null" json:"is_stream"`
+	RequestBody      string    `gorm:"type:text" json:"request_body"`
+	PromptTokens     int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	Cost             float64   `gorm:"not null;default:0" json:"cost"`
 }
 
 // StatCard 用于仪表盘的单个统计卡片数据
@@ -195,11 +303,14 @@ type ChartData struct {
 
 // GroupHourlyStat 对应 group_hourly_stats 表，用于存储每个分组每小时的请求统计
 type GroupHourlyStat struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Time         time.Time `gorm:"not null;uniqueIndex:idx_group_time" json:"time"` // 整点时间
-	GroupID      uint      `gorm:"not null;uniqueIndex:idx_group_time" json:"group_id"`
-	SuccessCount int64     `gorm:"not null;default:0" json:"success_count"`
-	FailureCount int64     `gorm:"not null;default:0" json:"failure_count"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Time             time.Time `gorm:"not null;uniqueIndex:idx_group_time" json:"time"` // 整点时间
+	GroupID          uint      `gorm:"not null;uniqueIndex:idx_group_time" json:"group_id"`
+	SuccessCount     int64     `gorm:"not null;default:0" json:"success_count"`
+	FailureCount     int64     `gorm:"not null;default:0" json:"failure_count"`
+	PromptTokens     int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	Cost             float64   `gorm:"not null;default:0" json:"cost"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
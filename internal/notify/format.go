@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook formats
+const (
+	FormatRaw     = "raw"
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+)
+
+// maxDiscordEmbeds is the number of embeds Discord accepts per message.
+const maxDiscordEmbeds = 10
+
+// eventStyle is how an event type is presented in chat messages.
+type eventStyle struct {
+	title string
+	// slackColor is a Slack attachment color, discordColor the same color as a Discord embed integer.
+	slackColor   string
+	discordColor int
+}
+
+var eventStyles = map[string]eventStyle{
+	EventKeyExpiring:    {title: "Key expiring soon", slackColor: "warning", discordColor: 0xDAA038},
+	EventKeyInvalid:     {title: "Key invalidated", slackColor: "danger", discordColor: 0xD40E0D},
+	EventKeyRecovered:   {title: "Key recovered", slackColor: "good", discordColor: 0x2EB67D},
+	EventGroupExhausted: {title: "Group has no active keys", slackColor: "danger", discordColor: 0xD40E0D},
+	EventQuotaExhausted: {title: "Group quota exhausted", slackColor: "warning", discordColor: 0xDAA038},
+}
+
+func styleOf(eventType string) eventStyle {
+	if style, ok := eventStyles[eventType]; ok {
+		return style
+	}
+	return eventStyle{title: eventType, slackColor: "#808080", discordColor: 0x808080}
+}
+
+// eventField is a labelled value of an event, shown as a field of an attachment or embed.
+type eventField struct {
+	name  string
+	value string
+}
+
+// eventFields lists the fields of an event that are set.
+func eventFields(event Event) []eventField {
+	var fields []eventField
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, eventField{name: name, value: value})
+		}
+	}
+	add("Group", event.Group)
+	if event.KeyID != 0 {
+		add("Key ID", strconv.FormatUint(uint64(event.KeyID), 10))
+	}
+	add("Key hash", event.KeyHash)
+	if event.UpstreamStatus != 0 {
+		add("Upstream status", strconv.Itoa(event.UpstreamStatus))
+	}
+	if event.ExpiresAt != nil {
+		add("Expires at", event.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if event.ResetsAt != nil {
+		add("Resets at", event.ResetsAt.UTC().Format(time.RFC3339))
+	}
+	return fields
+}
+
+// formatBody returns the webhook body of a batch in the given format.
+func formatBody(format string, events []Event, suppressed int) any {
+	switch format {
+	case FormatSlack:
+		return slackBody(events, suppressed)
+	case FormatDiscord:
+		return discordBody(events, suppressed)
+	default:
+		return payload{Source: "gpt-load", Events: events, Suppressed: suppressed}
+	}
+}
+
+// summary is the message text of a batch, such as "gpt-load: 3 events (2 more suppressed)".
+func summary(events []Event, suppressed int) string {
+	text := fmt.Sprintf("gpt-load: %d event(s)", len(events))
+	if len(events) == 1 {
+		text = "gpt-load: " + styleOf(events[0].Type).title
+	}
+	if suppressed > 0 {
+		text += fmt.Sprintf(" (%d more suppressed)", suppressed)
+	}
+	return text
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text,omitempty"`
+	Fields   []slackField `json:"fields,omitempty"`
+	Fallback string       `json:"fallback"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// slackBody formats a batch as a Slack incoming webhook message with one attachment per event.
+func slackBody(events []Event, suppressed int) slackMessage {
+	message := slackMessage{Text: summary(events, suppressed)}
+	for _, event := range events {
+		style := styleOf(event.Type)
+		attachment := slackAttachment{
+			Color:    style.slackColor,
+			Title:    style.title,
+			Text:     event.Reason,
+			Fallback: fallbackText(style.title, event),
+			Ts:       event.Time.Unix(),
+		}
+		for _, field := range eventFields(event) {
+			attachment.Fields = append(attachment.Fields, slackField{Title: field.name, Value: field.value, Short: true})
+		}
+		message.Attachments = append(message.Attachments, attachment)
+	}
+	return message
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// discordBody formats a batch as a Discord webhook message with one embed per event. Discord takes at most
+// maxDiscordEmbeds embeds, so the remaining events are listed in the message text.
+func discordBody(events []Event, suppressed int) discordMessage {
+	embedded := events[:min(len(events), maxDiscordEmbeds)]
+	message := discordMessage{Content: summary(events, suppressed)}
+	for _, event := range embedded {
+		style := styleOf(event.Type)
+		embed := discordEmbed{
+			Title:       style.title,
+			Description: truncate(event.Reason, 1024),
+			Color:       style.discordColor,
+			Timestamp:   event.Time.UTC().Format(time.RFC3339),
+		}
+		for _, field := range eventFields(event) {
+			embed.Fields = append(embed.Fields, discordField{Name: field.name, Value: field.value, Inline: true})
+		}
+		message.Embeds = append(message.Embeds, embed)
+	}
+
+	if rest := events[len(embedded):]; len(rest) > 0 {
+		lines := []string{message.Content}
+		for _, event := range rest {
+			lines = append(lines, "• "+fallbackText(styleOf(event.Type).title, event))
+		}
+		// Discord limits message content to 2000 characters.
+		message.Content = truncate(strings.Join(lines, "\n"), 2000)
+	}
+	return message
+}
+
+// fallbackText is a one-line plain text rendering of an event.
+func fallbackText(title string, event Event) string {
+	parts := []string{title}
+	for _, field := range eventFields(event) {
+		parts = append(parts, field.name+": "+field.value)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// truncate shortens text to at most limit runes.
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,159 @@
+// Package notify delivers operational events, such as failing keys or exhausted quotas, to a webhook as
+// raw JSON or as Slack or Discord messages.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event types
+const (
+	EventKeyExpiring    = "key_expiring"
+	EventKeyInvalid     = "key_invalid"
+	EventKeyRecovered   = "key_recovered"
+	EventGroupExhausted = "group_exhausted"
+	EventQuotaExhausted = "quota_exhausted"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	// maxBatchEvents bounds the events of one webhook request; further events of the window are only counted.
+	maxBatchEvents = 100
+)
+
+// Event is one notification. Fields not relevant to an event type are omitted.
+type Event struct {
+	Type  string `json:"type"`
+	Group string `json:"group,omitempty"`
+	KeyID uint   `json:"key_id,omitempty"`
+	// KeyHash identifies the key without revealing it, see KeyHash.
+	KeyHash        string     `json:"key_hash,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	UpstreamStatus int        `json:"upstream_status,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ResetsAt       *time.Time `json:"resets_at,omitempty"`
+	Time           time.Time  `json:"time"`
+}
+
+// payload is the JSON body posted to the webhook.
+type payload struct {
+	Source string  `json:"source"`
+	Events []Event `json:"events"`
+	// Suppressed counts the events of the window dropped beyond maxBatchEvents.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// KeyHash returns a short SHA-256 digest of a key value, letting receivers tell keys apart and match a
+// key they hold without the key itself leaving the proxy.
+func KeyHash(keyValue string) string {
+	sum := sha256.Sum256([]byte(keyValue))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Notifier posts events to the webhook_url system setting in the webhook_format format. Events are batched over the
+// webhook_batch_seconds window, so an outage invalidating many keys results in one request per window.
+type Notifier struct {
+	settingsManager *config.SystemSettingsManager
+	client          *http.Client
+
+	mu         sync.Mutex
+	pending    []Event
+	suppressed int
+	scheduled  bool
+}
+
+// NewNotifier creates a new Notifier.
+func NewNotifier(settingsManager *config.SystemSettingsManager) *Notifier {
+	return &Notifier{
+		settingsManager: settingsManager,
+		client:          &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify queues an event for the next webhook request. The first event of a window schedules the request
+// at the end of the window. Without a webhook URL it does nothing.
+func (n *Notifier) Notify(event Event) {
+	settings := n.settingsManager.GetSettings()
+	if settings.WebhookURL == "" {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.pending) < maxBatchEvents {
+		n.pending = append(n.pending, event)
+	} else {
+		n.suppressed++
+	}
+	if n.scheduled {
+		return
+	}
+	n.scheduled = true
+	window := time.Duration(settings.WebhookBatchSeconds) * time.Second
+	if window <= 0 {
+		go n.flush()
+		return
+	}
+	time.AfterFunc(window, n.flush)
+}
+
+// flush posts the queued events in one request.
+func (n *Notifier) flush() {
+	n.mu.Lock()
+	events, suppressed := n.pending, n.suppressed
+	n.pending, n.suppressed, n.scheduled = nil, 0, false
+	n.mu.Unlock()
+
+	settings := n.settingsManager.GetSettings()
+	if len(events) == 0 || settings.WebhookURL == "" {
+		return
+	}
+
+	body := formatBody(settings.WebhookFormat, events, suppressed)
+	if err := n.post(context.Background(), settings.WebhookURL, body); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"events":     len(events),
+			"suppressed": suppressed,
+		}).Warn("Failed to deliver webhook notification")
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, webhookURL string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
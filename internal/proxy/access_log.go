@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// accessLogContextKey stores the in-progress access log record of a request in the gin context.
+const accessLogContextKey = "access_log_record"
+
+// accessLogRecord is one line of the JSON access log. It is filled in as the request moves through the
+// proxy and written once the response is complete.
+type accessLogRecord struct {
+	Time           time.Time `json:"time"`
+	TraceID        string    `json:"trace_id,omitempty"`
+	Group          string    `json:"group"`
+	SubGroup       string    `json:"sub_group,omitempty"`
+	ChannelType    string    `json:"channel_type,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	ClientIP       string    `json:"client_ip"`
+	Stream         bool      `json:"stream"`
+	Attempts       int       `json:"attempts"`
+	UpstreamStatus int       `json:"upstream_status,omitempty"`
+	Status         int       `json:"status"`
+	BytesIn        int       `json:"bytes_in"`
+	BytesOut       int       `json:"bytes_out"`
+	LatencyMs      int64     `json:"latency_ms"`
+	TTFBMs         *int64    `json:"ttfb_ms,omitempty"`
+
+	firstByteAt time.Time
+}
+
+// accessLogWriter serializes access log lines to their own output, independent of the application log.
+type accessLogWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// newAccessLogWriter returns nil when access logging is disabled. A path of "stdout" or "stderr" writes to
+// that stream; anything else is opened as a file in append mode.
+func newAccessLogWriter(logConfig types.LogConfig) *accessLogWriter {
+	if !logConfig.EnableAccessLog {
+		return nil
+	}
+
+	switch logConfig.AccessLogPath {
+	case "", "stdout":
+		return &accessLogWriter{out: os.Stdout}
+	case "stderr":
+		return &accessLogWriter{out: os.Stderr}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logConfig.AccessLogPath), 0755); err != nil {
+		logrus.Warnf("Failed to create access log directory, access log disabled: %v", err)
+		return nil
+	}
+	file, err := os.OpenFile(logConfig.AccessLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		logrus.Warnf("Failed to open access log file, access log disabled: %v", err)
+		return nil
+	}
+	return &accessLogWriter{out: file}
+}
+
+func (w *accessLogWriter) write(record *accessLogRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to encode access log record")
+		return
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(line); err != nil {
+		logrus.WithError(err).Warn("Failed to write access log record")
+	}
+}
+
+// beginAccessLog starts the access log record of a request and wraps the response writer to catch the
+// time of the first byte. It returns nil when access logging is disabled.
+func (ps *ProxyServer) beginAccessLog(c *gin.Context, startTime time.Time) *accessLogRecord {
+	if ps.accessLog == nil {
+		return nil
+	}
+	record := &accessLogRecord{
+		Time:     startTime,
+		TraceID:  utils.TraceIDFromContext(c.Request.Context()),
+		Group:    c.Param("group_name"),
+		Method:   c.Request.Method,
+		Path:     c.Request.URL.Path,
+		ClientIP: c.ClientIP(),
+	}
+	c.Set(accessLogContextKey, record)
+	c.Writer = &firstByteWriter{ResponseWriter: c.Writer, record: record}
+	return record
+}
+
+// finishAccessLog completes the record with the client response and writes it.
+func (ps *ProxyServer) finishAccessLog(c *gin.Context, record *accessLogRecord) {
+	if record == nil {
+		return
+	}
+	record.Status = c.Writer.Status()
+	record.BytesOut = max(c.Writer.Size(), 0)
+	record.LatencyMs = time.Since(record.Time).Milliseconds()
+	if record.Stream && !record.firstByteAt.IsZero() {
+		ttfb := record.firstByteAt.Sub(record.Time).Milliseconds()
+		record.TTFBMs = &ttfb
+	}
+	ps.accessLog.write(record)
+}
+
+// accessLogFrom returns the access log record of the request, or nil when access logging is disabled.
+func accessLogFrom(c *gin.Context) *accessLogRecord {
+	if value, ok := c.Get(accessLogContextKey); ok {
+		record, _ := value.(*accessLogRecord)
+		return record
+	}
+	return nil
+}
+
+// firstByteWriter records when the first response body byte is written to the client.
+type firstByteWriter struct {
+	gin.ResponseWriter
+	record *accessLogRecord
+}
+
+func (w *firstByteWriter) Write(data []byte) (int, error) {
+	w.markFirstByte(len(data))
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *firstByteWriter) WriteString(s string) (int, error) {
+	w.markFirstByte(len(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *firstByteWriter) markFirstByte(n int) {
+	if n > 0 && w.record.firstByteAt.IsZero() {
+		w.record.firstByteAt = time.Now()
+	}
+}
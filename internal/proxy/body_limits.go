@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// errResponseTooLarge aborts a stream that grew past the group's response size limit.
+var errResponseTooLarge = errors.New("upstream response exceeds the size limit")
+
+// checkContentType rejects a request whose body has a content type the group does not allow.
+// Requests without a body or Content-Type are let through.
+func checkContentType(req *http.Request, group *models.Group) *app_errors.APIError {
+	contentType := req.Header.Get("Content-Type")
+	if req.ContentLength == 0 && contentType == "" {
+		return nil
+	}
+	if !channel.IsContentTypeAllowed(contentType, channel.AllowedContentTypes(group)) {
+		return app_errors.NewAPIError(app_errors.ErrUnsupportedMedia, fmt.Sprintf("Content type '%s' is not allowed for group '%s'", contentType, group.Name))
+	}
+	return nil
+}
+
+// readRequestBody buffers the request body for redirection and retries. With a size limit, a declared
+// Content-Length over it is rejected before anything is read, and an undeclared body is read no further
+// than the limit.
+func readRequestBody(c *gin.Context, group *models.Group) ([]byte, *app_errors.APIError) {
+	limitMB := group.EffectiveConfig.MaxRequestBodyMB
+	if limitMB <= 0 {
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logrus.Errorf("Failed to read request body: %v", err)
+			return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body")
+		}
+		return bodyBytes, nil
+	}
+
+	limit := int64(limitMB) << 20
+	if c.Request.ContentLength > limit {
+		return nil, requestTooLargeError(group, limitMB)
+	}
+	bodyBytes, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, requestTooLargeError(group, limitMB)
+		}
+		logrus.Errorf("Failed to read request body: %v", err)
+		return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body")
+	}
+	return bodyBytes, nil
+}
+
+func requestTooLargeError(group *models.Group, limitMB int) *app_errors.APIError {
+	return app_errors.NewAPIError(app_errors.ErrPayloadTooLarge,
+		fmt.Sprintf("Request body exceeds the %d MB limit of group '%s'", limitMB, group.Name))
+}
+
+// limitResponseBody enforces the group's response size limit before the response is relayed. Regular
+// responses are buffered up to the limit so an oversized one can still be answered with a 413; streams
+// are counted as they are relayed and cut off with errResponseTooLarge once they exceed it.
+func limitResponseBody(resp *http.Response, group *models.Group, isStream bool) *app_errors.APIError {
+	limitMB := group.EffectiveConfig.MaxResponseBodyMB
+	if limitMB <= 0 {
+		return nil
+	}
+	limit := int64(limitMB) << 20
+
+	if resp.ContentLength > limit {
+		return responseTooLargeError(group, limitMB)
+	}
+	if isStream {
+		resp.Body = &limitedResponseBody{ReadCloser: resp.Body, remaining: limit, group: group.Name}
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	resp.Body.Close()
+	if int64(len(bodyBytes)) > limit {
+		return responseTooLargeError(group, limitMB)
+	}
+	// A read error surfaces again when the buffered body is relayed, where it is handled as before.
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(bodyBytes), errorReader{err}))
+	return nil
+}
+
+func responseTooLargeError(group *models.Group, limitMB int) *app_errors.APIError {
+	return app_errors.NewAPIError(app_errors.ErrPayloadTooLarge,
+		fmt.Sprintf("Upstream response exceeds the %d MB limit of group '%s'", limitMB, group.Name))
+}
+
+// limitedResponseBody fails reads once more than remaining bytes have been read.
+type limitedResponseBody struct {
+	io.ReadCloser
+	remaining int64
+	group     string
+}
+
+func (b *limitedResponseBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errResponseTooLarge
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		logrus.Warnf("Stream of group %s exceeded its response size limit and was cut off", b.group)
+		return n + int(b.remaining), errResponseTooLarge
+	}
+	return n, err
+}
+
+// errorReader returns err once the reader before it is exhausted, or io.EOF if err is nil.
+type errorReader struct{ err error }
+
+func (r errorReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType string
+		allowed     string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{
+			name:        "JSON allowed for Vertex by default",
+			channelType: "vertex_gemini",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"contents":[]}`,
+		},
+		{
+			name:        "multipart upload allowed for Vertex by default",
+			channelType: "vertex_gemini",
+			contentType: "multipart/form-data; boundary=xyz",
+			body:        "--xyz--",
+		},
+		{
+			name:        "plain text rejected for Vertex by default",
+			channelType: "vertex_gemini",
+			contentType: "text/plain",
+			body:        "hello",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "missing content type with a body rejected",
+			channelType: "vertex_gemini",
+			body:        `{"contents":[]}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "request without body or content type passes",
+			channelType: "vertex_gemini",
+		},
+		{
+			name:        "any type allowed without a default",
+			channelType: "openai",
+			contentType: "text/plain",
+			body:        "hello",
+		},
+		{
+			name:        "configured list replaces the default",
+			channelType: "vertex_gemini",
+			allowed:     "application/json",
+			contentType: "multipart/form-data; boundary=xyz",
+			body:        "--xyz--",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "configured wildcard",
+			channelType: "openai",
+			allowed:     "application/json, audio/*",
+			contentType: "audio/mpeg",
+			body:        "ID3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: "g", ChannelType: tt.channelType}
+			group.EffectiveConfig.AllowedContentTypes = tt.allowed
+			req := httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			apiErr := checkContentType(req, group)
+			if tt.wantStatus == 0 {
+				if apiErr != nil {
+					t.Errorf("checkContentType = %v, want the request allowed", apiErr)
+				}
+				return
+			}
+			if apiErr == nil || apiErr.HTTPStatus != tt.wantStatus {
+				t.Fatalf("checkContentType = %v, want status %d", apiErr, tt.wantStatus)
+			}
+			if tt.contentType != "" && !strings.Contains(apiErr.Message, tt.contentType) {
+				t.Errorf("error message %q does not name the content type", apiErr.Message)
+			}
+		})
+	}
+}
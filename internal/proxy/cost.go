@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamModelContextKey stores the model sent upstream, after redirection, in the gin context.
+const upstreamModelContextKey = "upstream_model"
+
+// estimateCost prices a request's token usage with the model price table. The model sent upstream is
+// priced first, since that is the one billed, then the model the client requested. Models without a
+// price cost nothing and are reported once the table has entries.
+func (ps *ProxyServer) estimateCost(c *gin.Context, requestedModel string, usage tokenUsage) float64 {
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		return 0
+	}
+	prices := ps.modelPrices()
+	if len(prices) == 0 {
+		return 0
+	}
+
+	for _, model := range []string{c.GetString(upstreamModelContextKey), requestedModel} {
+		if model == "" {
+			continue
+		}
+		if price, ok := prices.Lookup(model); ok {
+			return price.Cost(usage.PromptTokens, usage.CompletionTokens)
+		}
+	}
+	logrus.WithField("model", requestedModel).Warn("No price configured for model, recording its cost as zero")
+	return 0
+}
+
+// modelPrices returns the parsed price table, parsing it again only when the setting changes.
+func (ps *ProxyServer) modelPrices() utils.ModelPriceTable {
+	source := ps.settingsManager.GetSettings().ModelPrices
+
+	ps.pricesMu.Lock()
+	defer ps.pricesMu.Unlock()
+	if source != ps.pricesSource {
+		prices, err := utils.ParseModelPrices(source)
+		if err != nil {
+			logrus.WithError(err).Warn("Invalid model price table, costs are not estimated")
+		}
+		ps.prices, ps.pricesSource = prices, source
+	}
+	return ps.prices
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// endpointFallback retries a failed request on the channel's fallback endpoint, if the channel has one for
+// the failure. It returns the successful fallback response and its URL, whose response translator is set
+// for the request. Otherwise it returns nil and resp can still be read.
+func endpointFallback(c *gin.Context, channelHandler channel.ChannelProxy, client *http.Client, req *http.Request, body []byte, group *models.Group, resp *http.Response) (*http.Response, string) {
+	fallback, ok := channelHandler.(channel.EndpointFallback)
+	if !ok {
+		return nil, ""
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(errorBody))
+	if err != nil {
+		return nil, ""
+	}
+
+	fallbackReq, translator, err := fallback.FallbackRequest(req, body, group, resp.StatusCode, handleGzipCompression(resp, errorBody))
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to build fallback request")
+		return nil, ""
+	}
+	if fallbackReq == nil {
+		return nil, ""
+	}
+
+	fallbackResp, err := client.Do(fallbackReq)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Fallback request failed")
+		return nil, ""
+	}
+	if fallbackResp.StatusCode >= 400 {
+		fallbackResp.Body.Close()
+		logrus.WithFields(logrus.Fields{"group": group.Name, "status": fallbackResp.StatusCode}).Warn("Fallback endpoint also failed, returning the original error")
+		return nil, ""
+	}
+
+	c.Set(responseTranslatorContextKey, translator)
+	utils.LogRequestLifecycle(c.Request.Context(), group, false, logrus.Fields{"native_status": resp.StatusCode, "upstream_path": fallbackReq.URL.Path}, "Served by fallback endpoint")
+	return fallbackResp, fallbackReq.URL.String()
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const nativeUnsupportedError = `{"error":{"code":400,"message":"The model does not support generateContent.","status":"FAILED_PRECONDITION"}}`
+
+func TestEndpointFallbackServesNativeFailureFromOpenAIEndpoint(t *testing.T) {
+	tests := []struct {
+		name           string
+		fallbackStatus int
+		wantServed     bool
+	}{
+		{name: "fallback succeeds", fallbackStatus: http.StatusOK, wantServed: true},
+		{name: "fallback fails too", fallbackStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fallbackPath, fallbackModel string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fallbackPath = r.URL.Path
+				var chat struct {
+					Model string `json:"model"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&chat)
+				fallbackModel = chat.Model
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.fallbackStatus)
+				io.WriteString(w, `{"model":"google/m","choices":[{"index":0,"message":{"content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+			}))
+			defer upstream.Close()
+
+			group := &models.Group{Name: "vertex"}
+			group.EffectiveConfig.VertexOpenAIFallback = true
+			ch := &channel.VertexGeminiChannel{BaseChannel: &channel.BaseChannel{}}
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/vertex/v1beta/models/m:generateContent", nil)
+			req := httptest.NewRequest(http.MethodPost, upstream.URL+"/v1/projects/p/locations/us-central1/publishers/google/models/m:generateContent", nil)
+			body := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+			nativeResp := &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(nativeUnsupportedError)),
+			}
+
+			resp, servedURL := endpointFallback(c, ch, upstream.Client(), req, body, group, nativeResp)
+			if fallbackPath != "/v1/projects/p/locations/us-central1/endpoints/openapi/chat/completions" || fallbackModel != "google/m" {
+				t.Errorf("fallback request = %s for model %q", fallbackPath, fallbackModel)
+			}
+
+			if !tt.wantServed {
+				if resp != nil {
+					t.Fatalf("served by fallback with status %d, want the native error", resp.StatusCode)
+				}
+				if responseTranslatorFrom(c) != nil {
+					t.Error("response translator set for an unserved fallback")
+				}
+				// The native error must still reach the client.
+				if native, _ := io.ReadAll(nativeResp.Body); string(native) != nativeUnsupportedError {
+					t.Errorf("native error body = %q, want it kept", native)
+				}
+				return
+			}
+
+			if resp == nil {
+				t.Fatal("got no fallback response")
+			}
+			defer resp.Body.Close()
+			if servedURL != upstream.URL+fallbackPath {
+				t.Errorf("served URL = %q, want the fallback endpoint", servedURL)
+			}
+			translator := responseTranslatorFrom(c)
+			if translator == nil {
+				t.Fatal("response translator not set")
+			}
+			chatBody, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("read fallback response: %v", err)
+			}
+			translated, err := translator.TranslateBody(chatBody)
+			if err != nil {
+				t.Fatalf("TranslateBody: %v", err)
+			}
+			var gemini struct {
+				Candidates []struct {
+					Content struct {
+						Parts []struct {
+							Text string `json:"text"`
+						} `json:"parts"`
+					} `json:"content"`
+					FinishReason string `json:"finishReason"`
+				} `json:"candidates"`
+			}
+			if err := json.Unmarshal(translated, &gemini); err != nil {
+				t.Fatalf("translated response %s: %v", translated, err)
+			}
+			if len(gemini.Candidates) != 1 || gemini.Candidates[0].Content.Parts[0].Text != "hello" || gemini.Candidates[0].FinishReason != "STOP" {
+				t.Errorf("translated response = %s", translated)
+			}
+		})
+	}
+}
+
+func TestEndpointFallbackDisabled(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/vertex/v1beta/models/m:generateContent", nil)
+	req := httptest.NewRequest(http.MethodPost, "https://aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/m:generateContent", nil)
+	nativeResp := &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(nativeUnsupportedError))}
+	ch := &channel.VertexGeminiChannel{BaseChannel: &channel.BaseChannel{}}
+
+	if resp, _ := endpointFallback(c, ch, http.DefaultClient, req, []byte(`{"contents":[]}`), &models.Group{Name: "vertex"}, nativeResp); resp != nil {
+		t.Fatal("fallback used without vertex_openai_fallback")
+	}
+	if native, _ := io.ReadAll(nativeResp.Body); string(native) != nativeUnsupportedError {
+		t.Errorf("native error body = %q, want it kept", native)
+	}
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fairScheduler limits the concurrent requests of a group and, under contention, hands out freed
+// slots round-robin across clients so that a burst from one client cannot starve the others.
+type fairScheduler struct {
+	mu       sync.Mutex
+	capacity int
+	inFlight int
+	queues   map[string][]chan struct{}
+	order    []string // clients with waiting requests, in round-robin order
+}
+
+func newFairScheduler(capacity int) *fairScheduler {
+	return &fairScheduler{
+		capacity: capacity,
+		queues:   make(map[string][]chan struct{}),
+	}
+}
+
+// acquire blocks until the client is granted a slot or ctx is done. The returned function releases the slot.
+func (s *fairScheduler) acquire(ctx context.Context, client string, capacity int) (func(), error) {
+	s.mu.Lock()
+	s.capacity = capacity
+	if s.inFlight < s.capacity && len(s.order) == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+
+	ready := make(chan struct{})
+	if len(s.queues[client]) == 0 {
+		s.order = append(s.order, client)
+	}
+	s.queues[client] = append(s.queues[client], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.removeWaiter(client, ready) {
+			// The slot was handed over concurrently with cancellation; pass it on.
+			s.releaseLocked()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (s *fairScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked hands the freed slot to the next client in round-robin order, or returns it to the pool.
+func (s *fairScheduler) releaseLocked() {
+	if len(s.order) == 0 || s.inFlight > s.capacity {
+		s.inFlight--
+		return
+	}
+
+	client := s.order[0]
+	s.order = s.order[1:]
+	waiters := s.queues[client]
+	next := waiters[0]
+	if len(waiters) > 1 {
+		s.queues[client] = waiters[1:]
+		s.order = append(s.order, client)
+	} else {
+		delete(s.queues, client)
+	}
+	close(next)
+}
+
+// removeWaiter removes a waiting request from its client's queue. It returns false if it was already granted a slot.
+func (s *fairScheduler) removeWaiter(client string, ready chan struct{}) bool {
+	waiters := s.queues[client]
+	for i, w := range waiters {
+		if w != ready {
+			continue
+		}
+		waiters = append(waiters[:i], waiters[i+1:]...)
+		if len(waiters) > 0 {
+			s.queues[client] = waiters
+			return true
+		}
+		delete(s.queues, client)
+		for j, c := range s.order {
+			if c == client {
+				s.order = append(s.order[:j], s.order[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// acquireGroupSlot waits for a concurrency slot of the group when group_max_concurrency is set.
+// It returns a release function, which is a no-op when no limit applies.
+func (ps *ProxyServer) acquireGroupSlot(c *gin.Context, group *models.Group) (func(), error) {
+	capacity := group.EffectiveConfig.GroupMaxConcurrency
+	if capacity <= 0 {
+		return func() {}, nil
+	}
+
+	ps.schedulersMu.Lock()
+	scheduler, ok := ps.schedulers[group.ID]
+	if !ok {
+		scheduler = newFairScheduler(capacity)
+		ps.schedulers[group.ID] = scheduler
+	}
+	ps.schedulersMu.Unlock()
+
+	return scheduler.acquire(c.Request.Context(), fairShareClientID(c, group), capacity)
+}
+
+// fairShareClientID identifies the client of a request by the configured header, falling back to the proxy key.
+func fairShareClientID(c *gin.Context, group *models.Group) string {
+	if header := group.EffectiveConfig.FairShareClientHeader; header != "" {
+		if value := c.GetHeader(header); value != "" {
+			return "header:" + value
+		}
+	}
+	return "key:" + c.GetString("proxy_key")
+}
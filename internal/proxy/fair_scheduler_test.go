@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+type grant struct {
+	client  string
+	release func()
+}
+
+// queueWaiters starts n requests of client that wait for a slot and report their grants, and returns once all are queued.
+func queueWaiters(t *testing.T, s *fairScheduler, client string, n int, granted chan<- grant) {
+	t.Helper()
+	s.mu.Lock()
+	queued, capacity := len(s.queues[client]), s.capacity
+	s.mu.Unlock()
+
+	for range n {
+		go func() {
+			release, err := s.acquire(context.Background(), client, capacity)
+			if err != nil {
+				t.Errorf("acquire for %s: %v", client, err)
+				return
+			}
+			granted <- grant{client: client, release: release}
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		waiting := len(s.queues[client])
+		s.mu.Unlock()
+		if waiting == queued+n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d requests of %s queued", waiting-queued, n, client)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairSchedulerBurstDoesNotStarveOtherClients(t *testing.T) {
+	const capacity = 2
+	s := newFairScheduler(capacity)
+
+	// The aggressive client fills the group and queues a burst before the other client arrives.
+	var held []func()
+	for range capacity {
+		release, err := s.acquire(context.Background(), "burst", capacity)
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		held = append(held, release)
+	}
+	granted := make(chan grant, 16)
+	queueWaiters(t, s, "burst", 10, granted)
+	queueWaiters(t, s, "quiet", 2, granted)
+
+	var order []string
+	for len(order) < 12 {
+		held[0]()
+		held = held[1:]
+		select {
+		case g := <-granted:
+			order = append(order, g.client)
+			held = append(held, g.release)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no slot granted after a release; granted so far: %v", order)
+		}
+	}
+	for _, release := range held {
+		release()
+	}
+
+	// Freed slots alternate between the clients while both are waiting.
+	want := []string{"burst", "quiet", "burst", "quiet", "burst", "burst", "burst", "burst", "burst", "burst", "burst", "burst"}
+	if !slices.Equal(order, want) {
+		t.Errorf("grant order = %v, want %v", order, want)
+	}
+	if s.inFlight != 0 || len(s.order) != 0 || len(s.queues) != 0 {
+		t.Errorf("scheduler not drained: in flight %d, order %v, queues %v", s.inFlight, s.order, s.queues)
+	}
+}
+
+func TestFairSchedulerCanceledWaiterLeavesItsTurn(t *testing.T) {
+	s := newFairScheduler(1)
+	release, err := s.acquire(context.Background(), "a", 1)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := s.acquire(ctx, "b", 1)
+		canceled <- err
+	}()
+	granted := make(chan grant, 1)
+	for {
+		s.mu.Lock()
+		queued := len(s.queues["b"])
+		s.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	queueWaiters(t, s, "c", 1, granted)
+
+	cancel()
+	if err := <-canceled; err == nil {
+		t.Fatal("canceled request was granted a slot")
+	}
+	release()
+
+	select {
+	case g := <-granted:
+		if g.client != "c" {
+			t.Errorf("slot granted to %s, want c", g.client)
+		}
+		g.release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("slot of the canceled request was not passed on")
+	}
+}
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// forceKeyHeader pins a request to a key ID of the group when the group allows it.
+const forceKeyHeader = "X-GPTLoad-Force-Key"
+
+// selectKey returns the key for an attempt. Requests carrying forceKeyHeader use that key on every attempt
+// when the group enables allow_force_key; a forced key that is disabled or cooling down is skipped with
+// a warning and the normal selection is used instead, as is one lacking the required key tags. Otherwise session affinity applies, if configured.
+func (ps *ProxyServer) selectKey(c *gin.Context, group *models.Group, reassign bool) (*models.APIKey, error) {
+	forced := strings.TrimSpace(c.GetHeader(forceKeyHeader))
+	if forced == "" || !group.EffectiveConfig.AllowForceKey {
+		return ps.selectAffinityKey(c, group, reassign)
+	}
+
+	keyID, err := strconv.ParseUint(forced, 10, 64)
+	if err != nil || keyID == 0 {
+		return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid "+forceKeyHeader+" value: expected a key ID")
+	}
+
+	apiKey, usable, err := ps.keyProvider.GetForcedKey(group.ID, uint(keyID))
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, forceKeyHeader+": "+err.Error())
+	}
+	if !usable {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "key_id": keyID, "status": apiKey.Status}).
+			Warn("Forced key is disabled or cooling down, falling back to rotation")
+		return ps.selectAffinityKey(c, group, reassign)
+	}
+	if !keypool.KeyHasTags(apiKey.Tags, requiredKeyTags(c, group)) {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "key_id": keyID}).
+			Warn("Forced key lacks the required key tags, falling back to rotation")
+		return ps.selectAffinityKey(c, group, reassign)
+	}
+
+	logrus.WithFields(logrus.Fields{"group": group.Name, "key_id": keyID}).Debug("Using forced key")
+	return apiKey, nil
+}
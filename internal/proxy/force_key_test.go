@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newKeyPoolTestServer returns a proxy server whose key pool holds the given keys. Active keys of group 1
+// listed in rotation are put in its active list; cooling keys have a cooldown in the future.
+func newKeyPoolTestServer(t *testing.T, keys []models.APIKey, rotation []uint, cooling ...uint) *ProxyServer {
+	t.Helper()
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+	memStore := store.NewMemoryStore()
+	for _, key := range keys {
+		details := map[string]any{
+			"id":         key.ID,
+			"key_string": key.KeyValue,
+			"status":     key.Status,
+			"group_id":   key.GroupID,
+			"tags":       key.Tags,
+		}
+		for _, id := range cooling {
+			if id == key.ID {
+				details["cooldown_until"] = time.Now().Add(time.Minute).Unix()
+			}
+		}
+		if err := memStore.HSet(fmt.Sprintf("key:%d", key.ID), details); err != nil {
+			t.Fatalf("HSet: %v", err)
+		}
+	}
+	for _, id := range rotation {
+		if err := memStore.LPush("group:1:active_keys", id); err != nil {
+			t.Fatalf("LPush: %v", err)
+		}
+	}
+	return &ProxyServer{
+		keyProvider: keypool.NewProvider(nil, memStore, nil, encSvc, nil),
+		store:       memStore,
+	}
+}
+
+func TestSelectKeyForcedKey(t *testing.T) {
+	keys := []models.APIKey{
+		{ID: 1, GroupID: 1, KeyValue: "sk-rotation", Status: models.KeyStatusActive},
+		{ID: 2, GroupID: 1, KeyValue: "sk-forced", Status: models.KeyStatusActive},
+		{ID: 3, GroupID: 2, KeyValue: "sk-foreign", Status: models.KeyStatusActive},
+		{ID: 4, GroupID: 1, KeyValue: "sk-invalid", Status: models.KeyStatusInvalid},
+		{ID: 5, GroupID: 1, KeyValue: "sk-cooling", Status: models.KeyStatusActive},
+		{ID: 6, GroupID: 1, KeyValue: "sk-tagged", Status: models.KeyStatusActive, Tags: "eu"},
+	}
+
+	tests := []struct {
+		name        string
+		allow       bool
+		header      string
+		wantKey     string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "no header uses rotation", allow: true, wantKey: "sk-rotation"},
+		{name: "header ignored without allow_force_key", header: "2", wantKey: "sk-rotation"},
+		{name: "forced key used", allow: true, header: "2", wantKey: "sk-forced"},
+		{name: "forced key missing from the rotation list used", allow: true, header: " 6 ", wantKey: "sk-tagged"},
+		{name: "disabled forced key falls back to rotation", allow: true, header: "4", wantKey: "sk-rotation"},
+		{name: "cooling forced key falls back to rotation", allow: true, header: "5", wantKey: "sk-rotation"},
+		{
+			name: "malformed key id", allow: true, header: "sk-forced",
+			wantStatus: http.StatusBadRequest, wantMessage: "expected a key ID",
+		},
+		{
+			name: "zero key id", allow: true, header: "0",
+			wantStatus: http.StatusBadRequest, wantMessage: "expected a key ID",
+		},
+		{
+			name: "unknown key id", allow: true, header: "99",
+			wantStatus: http.StatusBadRequest, wantMessage: "key 99 not found in this group",
+		},
+		{
+			name: "key of another group", allow: true, header: "3",
+			wantStatus: http.StatusBadRequest, wantMessage: "key 3 not found in this group",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newKeyPoolTestServer(t, keys, []uint{1}, 5)
+			group := &models.Group{ID: 1, Name: "g"}
+			group.EffectiveConfig.AllowForceKey = tt.allow
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(forceKeyHeader, tt.header)
+			}
+
+			apiKey, err := ps.selectKey(c, group, false)
+			if tt.wantStatus != 0 {
+				apiErr, ok := err.(*app_errors.APIError)
+				if !ok || apiErr.HTTPStatus != tt.wantStatus {
+					t.Fatalf("selectKey error = %v, want an API error with status %d", err, tt.wantStatus)
+				}
+				if !strings.Contains(apiErr.Message, forceKeyHeader) || !strings.Contains(apiErr.Message, tt.wantMessage) {
+					t.Errorf("error message %q should name %s and contain %q", apiErr.Message, forceKeyHeader, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectKey: %v", err)
+			}
+			if apiKey.KeyValue != tt.wantKey {
+				t.Errorf("selected %s, want %s", apiKey.KeyValue, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSelectKeyForcedKeyWithoutRequiredTags(t *testing.T) {
+	keys := []models.APIKey{
+		{ID: 1, GroupID: 1, KeyValue: "sk-eu", Status: models.KeyStatusActive, Tags: "eu"},
+		{ID: 2, GroupID: 1, KeyValue: "sk-us", Status: models.KeyStatusActive, Tags: "us"},
+	}
+	ps := newKeyPoolTestServer(t, keys, []uint{1})
+	group := &models.Group{ID: 1, Name: "g"}
+	group.EffectiveConfig.AllowForceKey = true
+	group.EffectiveConfig.RequiredKeyTags = "eu"
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", nil)
+	c.Request.Header.Set(forceKeyHeader, "2")
+
+	apiKey, err := ps.selectKey(c, group, false)
+	if err != nil {
+		t.Fatalf("selectKey: %v", err)
+	}
+	if apiKey.KeyValue != "sk-eu" {
+		t.Errorf("selected %s, want the rotation key carrying the required tag", apiKey.KeyValue)
+	}
+}
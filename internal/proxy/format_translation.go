@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// responseTranslatorContextKey stores the response translator of a translated request in the gin context.
+const responseTranslatorContextKey = "response_translator"
+
+// responseTranslatorFrom returns the response translator of the request, or nil if it was not translated.
+func responseTranslatorFrom(c *gin.Context) channel.ResponseTranslator {
+	if value, ok := c.Get(responseTranslatorContextKey); ok {
+		translator, _ := value.(channel.ResponseTranslator)
+		return translator
+	}
+	return nil
+}
+
+// writeTranslatedResponse converts a complete response to the client format, then applies the processors.
+// A body that cannot be converted is passed through.
+func writeTranslatedResponse(c *gin.Context, resp *http.Response, translator channel.ResponseTranslator, processors []responseProcessor) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return err
+	}
+
+	if decompressed, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), body); err == nil {
+		if translated, err := translator.TranslateBody(decompressed); err == nil {
+			if len(processors) > 0 {
+				translated, _ = processJSONBody(translated, processors)
+			}
+			c.Writer.Header().Del("Content-Encoding")
+			c.Writer.Header().Set("Content-Type", "application/json")
+			c.Writer.Header().Set("Content-Length", strconv.Itoa(len(translated)))
+			body = translated
+		} else {
+			logrus.WithError(err).Warn("Failed to translate response, passing it through")
+		}
+	}
+
+	if _, err := c.Writer.Write(body); err != nil {
+		logUpstreamError("writing response body", err)
+		return err
+	}
+	return nil
+}
+
+// streamTranslatedResponse converts an SSE stream event by event to the client format, applying processors
+// and the throttle to the converted events. It returns a *streamInterruption if the upstream fails mid-stream.
+func streamTranslatedResponse(c *gin.Context, resp *http.Response, flusher http.Flusher, translator channel.ResponseTranslator, processors []responseProcessor, throttle *streamThrottle) error {
+	c.Writer.Header().Del("Content-Encoding")
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+
+	var tail streamTail
+	write := func(events []byte) bool {
+		for len(events) > 0 {
+			line := events
+			if i := bytes.IndexByte(events, '\n'); i >= 0 {
+				line = events[:i+1]
+			}
+			events = events[len(line):]
+
+			if len(processors) > 0 {
+				if line = processSSELine(line, processors); line == nil {
+					continue
+				}
+			}
+			if throttle != nil {
+				if err := throttle.wait(c.Request.Context(), line); err != nil {
+					logUpstreamError("pacing stream to client", err)
+					return false
+				}
+			}
+			if _, err := c.Writer.Write(line); err != nil {
+				logUpstreamError("writing stream to client", err)
+				return false
+			}
+			tail.observe(line)
+		}
+		flusher.Flush()
+		return true
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadBytes('\n')
+		content := bytes.TrimRight(line, "\r\n")
+		if payload, ok := bytes.CutPrefix(content, []byte("data:")); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimSpace(payload))
+		}
+		// A blank line, or the end of the stream, completes the event.
+		if (len(content) == 0 || err != nil) && data.Len() > 0 {
+			events, translateErr := translator.TranslateEvent(data.Bytes())
+			data.Reset()
+			if translateErr != nil {
+				logrus.WithError(translateErr).Debug("Skipping stream event that could not be translated")
+			} else if !write(events) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			write(translator.FinishStream())
+			return nil
+		}
+		if err != nil {
+			logUpstreamError("reading from upstream", err)
+			return tail.interruption(err)
+		}
+	}
+}
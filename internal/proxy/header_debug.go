@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"net/http"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamTrackingHeaders are response headers that upstream support teams ask for when investigating a request.
+var upstreamTrackingHeaders = []string{
+	"X-Debug-Tracking-Id",
+	"X-Goog-Request-Id",
+	"X-Request-Id",
+	"X-Cloud-Trace-Context",
+	"Request-Id",
+}
+
+// logUpstreamHeaders logs the outbound request headers and the upstream response headers of a failed
+// request when the group enables header logging. Credentials are masked; resp may be nil.
+func logUpstreamHeaders(group *models.Group, req *http.Request, resp *http.Response, attempt int) {
+	if !group.EffectiveConfig.LogUpstreamHeaders {
+		return
+	}
+
+	fields := logrus.Fields{
+		"group":           group.Name,
+		"attempt":         attempt,
+		"method":          req.Method,
+		"upstream_host":   req.URL.Host,
+		"upstream_path":   req.URL.Path,
+		"request_headers": utils.MaskHeaders(req.Header),
+	}
+	if resp != nil {
+		fields["status"] = resp.StatusCode
+		fields["response_headers"] = utils.MaskHeaders(resp.Header)
+
+		tracking := make(map[string]string)
+		for _, name := range upstreamTrackingHeaders {
+			if value := resp.Header.Get(name); value != "" {
+				tracking[name] = value
+			}
+		}
+		if len(tracking) > 0 {
+			fields["tracking_ids"] = tracking
+		}
+	}
+
+	utils.LoggerFromContext(req.Context()).WithFields(fields).Warn("Upstream request failed, headers captured")
+}
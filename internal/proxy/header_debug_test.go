@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogUpstreamHeaders(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	const secret = "sk-abcdefghijklmnopqrstuvwxyz"
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	req.Header.Set("X-Goog-Api-Key", secret)
+	req.Header.Set("Cookie", "sid=abc")
+	req.Header.Set("Content-Type", "application/json")
+	resp := &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{}}
+	resp.Header.Set("X-Debug-Tracking-Id", "track-123")
+	resp.Header.Set("Set-Cookie", "upstream-session=abcdefghijklmnop")
+	resp.Header.Set("Content-Type", "application/json")
+
+	group := &models.Group{Name: "g"}
+	logUpstreamHeaders(group, req, resp, 1)
+	if len(hook.AllEntries()) != 0 {
+		t.Fatal("headers logged although the group does not enable header logging")
+	}
+
+	group.EffectiveConfig.LogUpstreamHeaders = true
+	logUpstreamHeaders(group, req, resp, 2)
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel {
+		t.Fatalf("log entry = %v, want a warning", entry)
+	}
+
+	if dump := fmt.Sprint(entry.Data); strings.Contains(dump, secret) || strings.Contains(dump, "sid=abc") || strings.Contains(dump, "abcdefghijklmnop") {
+		t.Errorf("secret leaked into the log record: %s", dump)
+	}
+	requestHeaders := entry.Data["request_headers"].(map[string]string)
+	wantRequest := map[string]string{
+		"Authorization":  "Bearer sk-a****wxyz",
+		"X-Goog-Api-Key": "sk-a****wxyz",
+		"Cookie":         "****",
+		"Content-Type":   "application/json",
+	}
+	for name, want := range wantRequest {
+		if got := requestHeaders[name]; got != want {
+			t.Errorf("request header %s = %q, want %q", name, got, want)
+		}
+	}
+	responseHeaders := entry.Data["response_headers"].(map[string]string)
+	if got := responseHeaders["Set-Cookie"]; got != "upst****mnop" {
+		t.Errorf("Set-Cookie = %q, want it masked", got)
+	}
+
+	tracking, ok := entry.Data["tracking_ids"].(map[string]string)
+	if !ok || tracking["X-Debug-Tracking-Id"] != "track-123" || len(tracking) != 1 {
+		t.Errorf("tracking_ids = %v, want the X-Debug-Tracking-Id header", entry.Data["tracking_ids"])
+	}
+	if entry.Data["status"] != http.StatusInternalServerError || entry.Data["attempt"] != 2 {
+		t.Errorf("status %v, attempt %v; want 500 and 2", entry.Data["status"], entry.Data["attempt"])
+	}
+
+	// Transport errors have no response to capture.
+	logUpstreamHeaders(group, req, nil, 3)
+	if entry := hook.LastEntry(); entry.Data["tracking_ids"] != nil || entry.Data["response_headers"] != nil {
+		t.Errorf("response fields logged without a response: %v", entry.Data)
+	}
+}
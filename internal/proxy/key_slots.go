@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// keySlotSelectAttempts is how many keys are tried per round before waiting for a slot to free up.
+	keySlotSelectAttempts = 10
+	// keySlotWait bounds how long a request queues when every tried key is at its concurrency limit.
+	keySlotWait = 2 * time.Second
+	// keySlotPollInterval is how often a queued request looks for a free key again.
+	keySlotPollInterval = 50 * time.Millisecond
+)
+
+// selectKeyWithSlot selects a key and claims one of its concurrency slots. Keys at the group's
+// key_max_concurrency are skipped in favour of others; when all tried keys are busy the request
+// waits briefly for a slot. The caller must call the returned release function.
+func (ps *ProxyServer) selectKeyWithSlot(c *gin.Context, group *models.Group, retryCount int) (*models.APIKey, func(), error) {
+	limit := group.EffectiveConfig.KeyMaxConcurrency
+	deadline := time.Now().Add(keySlotWait)
+
+	for {
+		for attempt := 0; attempt < keySlotSelectAttempts; attempt++ {
+			apiKey, err := ps.selectKey(c, group, retryCount > 0 || attempt > 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			if release, ok := ps.keyProvider.TryAcquireKey(apiKey.ID, limit); ok {
+				return apiKey, release, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, nil, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, "all keys are at their concurrency limit")
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, nil, c.Request.Context().Err()
+		case <-time.After(keySlotPollInterval):
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package proxy
+
+import (
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyTagsHeader lets a client restrict its request to keys carrying comma-separated tags.
+const keyTagsHeader = "X-GPTLoad-Key-Tags"
+
+// requiredKeyTags returns the tags a key needs to serve the request: the group's required_key_tags plus
+// those of keyTagsHeader. A client can narrow the keys of the group this way, never widen them.
+func requiredKeyTags(c *gin.Context, group *models.Group) []string {
+	return keypool.ParseKeyTags(group.EffectiveConfig.RequiredKeyTags + "," + c.GetHeader(keyTagsHeader))
+}
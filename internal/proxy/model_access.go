@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"fmt"
+	"path"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+)
+
+// matchesModelPattern reports whether the model matches one of the comma-separated glob patterns.
+func matchesModelPattern(patterns string, model string) bool {
+	for _, pattern := range utils.SplitAndTrim(patterns, ",") {
+		if matched, _ := path.Match(pattern, model); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModelAccess applies the group's model allowlist and denylist to the model sent upstream, after
+// redirection. The denylist wins over the allowlist. Requests without a model are not checked.
+func checkModelAccess(group *models.Group, model string) *app_errors.APIError {
+	cfg := group.EffectiveConfig
+	if model == "" || (cfg.ModelAllowlist == "" && cfg.ModelDenylist == "") {
+		return nil
+	}
+	if matchesModelPattern(cfg.ModelDenylist, model) {
+		return app_errors.NewAPIError(app_errors.ErrForbidden, fmt.Sprintf("Model '%s' is denied in group '%s'", model, group.Name))
+	}
+	if cfg.ModelAllowlist != "" && !matchesModelPattern(cfg.ModelAllowlist, model) {
+		return app_errors.NewAPIError(app_errors.ErrForbidden, fmt.Sprintf("Model '%s' is not allowed in group '%s'", model, group.Name))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// groupModelAliases returns the group's alias map. Invalid maps are rejected when saved, so a parse error
+// only disables aliasing.
+func groupModelAliases(group *models.Group) map[string]string {
+	aliases, err := utils.ParseModelAliases(group.EffectiveConfig.ModelAliases)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Ignoring invalid model aliases")
+		return nil
+	}
+	return aliases
+}
+
+// applyModelAlias resolves an alias in the model of the request, either the "model" field of a JSON body
+// or the model segment of a path such as /v1beta/models/{model}:generateContent, to its canonical model id.
+// Everything after it, redirect rules, logs and stats included, sees the canonical id.
+func applyModelAlias(req *http.Request, group *models.Group, bodyBytes []byte) []byte {
+	aliases := groupModelAliases(group)
+	if len(aliases) == 0 {
+		return bodyBytes
+	}
+
+	if index := strings.LastIndex(req.URL.Path, "/models/"); index != -1 {
+		rest := req.URL.Path[index+len("/models/"):]
+		alias, method, hasMethod := strings.Cut(rest, ":")
+		if model, ok := aliases[alias]; ok && hasMethod {
+			req.URL.Path = req.URL.Path[:index] + "/models/" + model + ":" + method
+			req.URL.RawPath = ""
+			logAliasResolved(req, group, alias, model)
+		}
+	}
+
+	var payload map[string]any
+	if len(bodyBytes) == 0 || json.Unmarshal(bodyBytes, &payload) != nil {
+		return bodyBytes
+	}
+	alias, _ := payload["model"].(string)
+	model, ok := aliases[alias]
+	if !ok {
+		return bodyBytes
+	}
+	payload["model"] = model
+	newBody, err := json.Marshal(payload)
+	if err != nil {
+		return bodyBytes
+	}
+	logAliasResolved(req, group, alias, model)
+	return newBody
+}
+
+func logAliasResolved(req *http.Request, group *models.Group, alias, model string) {
+	utils.LoggerFromContext(req.Context()).WithFields(logrus.Fields{
+		"group": group.Name,
+		"alias": alias,
+		"model": model,
+	}).Debug("Model alias resolved")
+}
+
+// addModelAliases lists the group's aliases in a transformed model list response, each as a copy of its
+// model's entry under the alias name, or a minimal entry if the model is not listed. Gemini lists get
+// them on the first page only.
+func addModelAliases(c *gin.Context, group *models.Group, response map[string]any) {
+	aliases := groupModelAliases(group)
+	if len(aliases) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	slices.Sort(names)
+
+	if data, ok := response["data"].([]any); ok {
+		response["data"] = appendAliasEntries(data, names, aliases, "id", func(alias string) map[string]any {
+			return map[string]any{"id": alias, "object": "model", "created": 0, "owned_by": "system"}
+		})
+		return
+	}
+	if list, ok := response["models"].([]any); ok && c.Query("pageToken") == "" {
+		response["models"] = appendAliasEntries(list, names, aliases, "name", func(alias string) map[string]any {
+			return map[string]any{"name": "models/" + alias, "displayName": alias, "supportedGenerationMethods": []string{"generateContent"}}
+		})
+	}
+}
+
+// appendAliasEntries appends an entry per alias not listed yet to a model list whose entries are
+// identified by field.
+func appendAliasEntries(list []any, names []string, aliases map[string]string, field string, minimal func(string) map[string]any) []any {
+	entries := make(map[string]map[string]any, len(list))
+	for _, item := range list {
+		if entry, ok := item.(map[string]any); ok {
+			if id, ok := entry[field].(string); ok {
+				entries[catalogModelID(id)] = entry
+			}
+		}
+	}
+
+	for _, alias := range names {
+		if _, listed := entries[alias]; listed {
+			continue
+		}
+		entry := minimal(alias)
+		if canonical, ok := entries[catalogModelID(aliases[alias])]; ok {
+			entry = maps.Clone(canonical)
+			if field == "name" {
+				entry["name"] = "models/" + alias
+				entry["displayName"] = alias
+			} else {
+				entry["id"] = alias
+			}
+		}
+		list = append(list, entry)
+	}
+	return list
+}
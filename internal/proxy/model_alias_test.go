@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestApplyModelAlias(t *testing.T) {
+	const aliases = `{"fast":"gemini-2.0-flash","smart":"gpt-4o"}`
+
+	tests := []struct {
+		name      string
+		aliases   string
+		path      string
+		body      string
+		wantPath  string
+		wantModel string
+	}{
+		{
+			name:      "body alias resolved",
+			aliases:   aliases,
+			path:      "/v1/chat/completions",
+			body:      `{"model":"smart","messages":[]}`,
+			wantPath:  "/v1/chat/completions",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:      "canonical model kept",
+			aliases:   aliases,
+			path:      "/v1/chat/completions",
+			body:      `{"model":"gpt-4o","messages":[]}`,
+			wantPath:  "/v1/chat/completions",
+			wantModel: "gpt-4o",
+		},
+		{
+			name:     "path alias resolved",
+			aliases:  aliases,
+			path:     "/v1beta/models/fast:generateContent",
+			body:     `{"contents":[]}`,
+			wantPath: "/v1beta/models/gemini-2.0-flash:generateContent",
+		},
+		{
+			name:     "path without method kept",
+			aliases:  aliases,
+			path:     "/v1beta/models/fast",
+			wantPath: "/v1beta/models/fast",
+		},
+		{
+			name:      "no aliases",
+			path:      "/v1/chat/completions",
+			body:      `{"model":"smart"}`,
+			wantPath:  "/v1/chat/completions",
+			wantModel: "smart",
+		},
+		{
+			name:      "invalid aliases disable aliasing",
+			aliases:   `{"smart":"smart"}`,
+			path:      "/v1/chat/completions",
+			body:      `{"model":"smart"}`,
+			wantPath:  "/v1/chat/completions",
+			wantModel: "smart",
+		},
+		{
+			name:     "non-JSON body kept",
+			aliases:  aliases,
+			path:     "/v1/audio/transcriptions",
+			body:     "--boundary",
+			wantPath: "/v1/audio/transcriptions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: "g"}
+			group.EffectiveConfig.ModelAliases = tt.aliases
+			req := httptest.NewRequest(http.MethodPost, "https://upstream.example.com"+tt.path, nil)
+
+			body := applyModelAlias(req, group, []byte(tt.body))
+			if req.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", req.URL.Path, tt.wantPath)
+			}
+			if tt.wantModel == "" {
+				if string(body) != tt.body {
+					t.Errorf("body = %s, want it unchanged", body)
+				}
+				return
+			}
+			var payload struct {
+				Model string `json:"model"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("body %s: %v", body, err)
+			}
+			if payload.Model != tt.wantModel {
+				t.Errorf("model = %q, want %q", payload.Model, tt.wantModel)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// modelCatalogTTL bounds how long a fetched model list is trusted for rejecting unknown models.
+const modelCatalogTTL = time.Hour
+
+// modelCatalogRefreshInterval is the age after which a request triggers a background refresh of the catalog.
+// Refreshes are attempted at most once per modelCatalogRetryInterval, so a failing upstream is not hammered.
+const (
+	modelCatalogRefreshInterval = 10 * time.Minute
+	modelCatalogRetryInterval   = time.Minute
+)
+
+// modelCatalog is the set of model ids a group served in its last model list response.
+// Paginated Gemini lists are collected page by page and only used once the last page arrived.
+type modelCatalog struct {
+	models    map[string]struct{}
+	complete  bool
+	updatedAt time.Time
+
+	refreshing  bool
+	attemptedAt time.Time
+}
+
+// recordModelCatalog stores the model ids of a transformed model list response for the group.
+func (ps *ProxyServer) recordModelCatalog(c *gin.Context, group *models.Group, response map[string]any) {
+	var ids []string
+	if data, ok := response["data"].([]any); ok {
+		ids = collectModelIDs(data, "id")
+	} else if list, ok := response["models"].([]any); ok {
+		ids = collectModelIDs(list, "name")
+	} else {
+		return
+	}
+	nextPageToken, _ := response["nextPageToken"].(string)
+
+	ps.catalogsMu.Lock()
+	defer ps.catalogsMu.Unlock()
+
+	catalog := ps.catalogs[group.ID]
+	if catalog == nil {
+		catalog = &modelCatalog{}
+		ps.catalogs[group.ID] = catalog
+	}
+	if c.Query("pageToken") == "" || catalog.models == nil {
+		catalog.models = make(map[string]struct{}, len(ids))
+	}
+	for _, id := range ids {
+		catalog.models[catalogModelID(id)] = struct{}{}
+	}
+	catalog.complete = nextPageToken == ""
+	catalog.updatedAt = time.Now()
+}
+
+// checkModelKnown reports whether the model appears in the group's cached catalog and, if it does not,
+// the closest model of the catalog. Without a complete, fresh catalog nothing can be concluded and the
+// model is treated as known. A missing or aging catalog is refreshed in the background.
+func (ps *ProxyServer) checkModelKnown(group *models.Group, model string) (bool, string) {
+	ps.catalogsMu.Lock()
+	defer ps.catalogsMu.Unlock()
+
+	catalog := ps.catalogs[group.ID]
+	if catalog == nil {
+		catalog = &modelCatalog{}
+		ps.catalogs[group.ID] = catalog
+	}
+	stale := !catalog.complete || time.Since(catalog.updatedAt) > modelCatalogRefreshInterval
+	if stale && !catalog.refreshing && time.Since(catalog.attemptedAt) > modelCatalogRetryInterval {
+		catalog.refreshing, catalog.attemptedAt = true, time.Now()
+		go ps.refreshModelCatalog(group)
+	}
+
+	if !catalog.complete || time.Since(catalog.updatedAt) > modelCatalogTTL {
+		return true, ""
+	}
+	id := catalogModelID(model)
+	if _, ok := catalog.models[id]; ok {
+		return true, ""
+	}
+	return false, closestModel(catalog.models, id)
+}
+
+// unknownModelError returns a 400 error for a model missing from the group's catalog, suggesting the
+// closest known model, or nil if the model may be served.
+func (ps *ProxyServer) unknownModelError(group *models.Group, model string) *app_errors.APIError {
+	known, suggestion := ps.checkModelKnown(group, model)
+	if known {
+		return nil
+	}
+	message := fmt.Sprintf("Model '%s' is not available in group '%s'", model, group.Name)
+	if suggestion != "" {
+		message += fmt.Sprintf("; did you mean '%s'?", suggestion)
+	}
+	return app_errors.NewAPIError(app_errors.ErrBadRequest, message)
+}
+
+// refreshModelCatalog fetches the group's full model list, which records the catalog.
+func (ps *ProxyServer) refreshModelCatalog(group *models.Group) {
+	defer func() {
+		ps.catalogsMu.Lock()
+		if catalog := ps.catalogs[group.ID]; catalog != nil {
+			catalog.refreshing = false
+		}
+		ps.catalogsMu.Unlock()
+	}()
+	ps.fetchModelList(context.Background(), group, modelListPath(group))
+}
+
+// closestModel returns the catalog model with the smallest edit distance to the model, if it is close
+// enough to be a plausible typo.
+func closestModel(catalog map[string]struct{}, model string) string {
+	best, bestDistance := "", len(model)/3+2
+	for candidate := range catalog {
+		distance := editDistance(strings.ToLower(model), strings.ToLower(candidate))
+		if distance < bestDistance || (distance == bestDistance && best != "" && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func collectModelIDs(list []any, field string) []string {
+	ids := make([]string, 0, len(list))
+	for _, item := range list {
+		if entry, ok := item.(map[string]any); ok {
+			if id, ok := entry[field].(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// catalogModelID reduces Gemini and Vertex resource names ("models/x", "publishers/google/models/x") to the bare model id.
+func catalogModelID(id string) string {
+	if i := strings.LastIndex(id, "/models/"); i != -1 {
+		return id[i+len("/models/"):]
+	}
+	return strings.TrimPrefix(id, "models/")
+}
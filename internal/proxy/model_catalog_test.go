@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordTestCatalog records a model list response for the group as if it had been served at path.
+func recordTestCatalog(ps *ProxyServer, group *models.Group, path string, response map[string]any) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+	ps.recordModelCatalog(c, group, response)
+	// Keep checks from starting a background refresh.
+	ps.catalogs[group.ID].attemptedAt = time.Now()
+}
+
+func TestUnknownModelError(t *testing.T) {
+	openAIList := map[string]any{"data": []any{
+		map[string]any{"id": "gpt-4o"},
+		map[string]any{"id": "gpt-4o-mini"},
+	}}
+	geminiList := map[string]any{"models": []any{
+		map[string]any{"name": "models/gemini-2.0-flash"},
+		map[string]any{"name": "publishers/google/models/gemini-2.5-pro"},
+	}}
+
+	tests := []struct {
+		name        string
+		list        map[string]any
+		model       string
+		wantMessage string
+	}{
+		{name: "known model", list: openAIList, model: "gpt-4o"},
+		{name: "known Gemini model", list: geminiList, model: "gemini-2.0-flash"},
+		{name: "known model by resource name", list: geminiList, model: "models/gemini-2.5-pro"},
+		{
+			name:        "typo suggests the closest model",
+			list:        openAIList,
+			model:       "gpt-4o-mnii",
+			wantMessage: "Model 'gpt-4o-mnii' is not available in group 'g'; did you mean 'gpt-4o-mini'?",
+		},
+		{
+			name:        "unrelated model without suggestion",
+			list:        geminiList,
+			model:       "claude-3-opus",
+			wantMessage: "Model 'claude-3-opus' is not available in group 'g'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &ProxyServer{catalogs: make(map[uint]*modelCatalog)}
+			group := &models.Group{ID: 1, Name: "g"}
+			recordTestCatalog(ps, group, "/proxy/g/v1/models", tt.list)
+
+			apiErr := ps.unknownModelError(group, tt.model)
+			if tt.wantMessage == "" {
+				if apiErr != nil {
+					t.Errorf("unknownModelError = %v, want the model accepted", apiErr)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatal("unknown model accepted")
+			}
+			if apiErr.HTTPStatus != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", apiErr.HTTPStatus, http.StatusBadRequest)
+			}
+			if apiErr.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", apiErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestUnknownModelErrorWithoutTrustedCatalog(t *testing.T) {
+	list := map[string]any{"models": []any{map[string]any{"name": "models/gemini-2.0-flash"}}, "nextPageToken": "p2"}
+
+	tests := []struct {
+		name  string
+		setup func(ps *ProxyServer, group *models.Group)
+	}{
+		{
+			name: "no catalog yet",
+			setup: func(ps *ProxyServer, group *models.Group) {
+				ps.catalogs[group.ID] = &modelCatalog{attemptedAt: time.Now()}
+			},
+		},
+		{
+			name: "paginated list missing its last page",
+			setup: func(ps *ProxyServer, group *models.Group) {
+				recordTestCatalog(ps, group, "/proxy/g/v1beta/models", list)
+			},
+		},
+		{
+			name: "expired catalog",
+			setup: func(ps *ProxyServer, group *models.Group) {
+				delete(list, "nextPageToken")
+				recordTestCatalog(ps, group, "/proxy/g/v1beta/models", list)
+				ps.catalogs[group.ID].updatedAt = time.Now().Add(-modelCatalogTTL - time.Minute)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &ProxyServer{catalogs: make(map[uint]*modelCatalog)}
+			group := &models.Group{ID: 1, Name: "g"}
+			tt.setup(ps, group)
+			if apiErr := ps.unknownModelError(group, "gemini-9-ultra"); apiErr != nil {
+				t.Errorf("unknownModelError = %v, want the model accepted without a trusted catalog", apiErr)
+			}
+		})
+	}
+}
+
+func TestUnknownModelErrorAcrossPages(t *testing.T) {
+	ps := &ProxyServer{catalogs: make(map[uint]*modelCatalog)}
+	group := &models.Group{ID: 1, Name: "g"}
+	recordTestCatalog(ps, group, "/proxy/g/v1beta/models", map[string]any{
+		"models":        []any{map[string]any{"name": "models/gemini-2.0-flash"}},
+		"nextPageToken": "p2",
+	})
+	recordTestCatalog(ps, group, "/proxy/g/v1beta/models?pageToken=p2", map[string]any{
+		"models": []any{map[string]any{"name": "models/gemini-2.5-pro"}},
+	})
+
+	for _, model := range []string{"gemini-2.0-flash", "gemini-2.5-pro"} {
+		if apiErr := ps.unknownModelError(group, model); apiErr != nil {
+			t.Errorf("unknownModelError(%s) = %v, want the model of either page accepted", model, apiErr)
+		}
+	}
+	if apiErr := ps.unknownModelError(group, "gemini-2.5-prp"); apiErr == nil || !strings.Contains(apiErr.Message, "did you mean 'gemini-2.5-pro'?") {
+		t.Errorf("unknownModelError = %v, want a suggestion from the second page", apiErr)
+	}
+}
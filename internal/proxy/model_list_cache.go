@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxCachedModelLists bounds the cached model lists of a group, one per path and query such as a
+// Gemini page token. The group's lists are dropped when it is exceeded.
+const maxCachedModelLists = 32
+
+// cachedModelList is a transformed model list response, configured models merged in.
+type cachedModelList struct {
+	response   map[string]any
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// modelListCacheKey identifies a model list request of a group by its path and query. The Gemini API
+// key query parameter is not part of it.
+func modelListCacheKey(c *gin.Context) string {
+	path := c.Param("path")
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	query := c.Request.URL.Query()
+	query.Del("key")
+	if encoded := query.Encode(); encoded != "" {
+		return path + "?" + encoded
+	}
+	return path
+}
+
+// modelListPath returns the path and query under which the group's channel lists all its models.
+func modelListPath(group *models.Group) string {
+	if group.ChannelType == "gemini" || group.ChannelType == "vertex_gemini" {
+		return "/v1beta/models?pageSize=1000"
+	}
+	return "/v1/models"
+}
+
+// serveCachedModelList answers a model list request from the group's cache. Lists older than the TTL are
+// still served for another TTL while a background request refreshes them.
+func (ps *ProxyServer) serveCachedModelList(c *gin.Context, group *models.Group) bool {
+	ttl := time.Duration(group.EffectiveConfig.ModelListCacheTTL) * time.Second
+	key := modelListCacheKey(c)
+
+	ps.modelListsMu.Lock()
+	entry := ps.modelLists[group.ID][key]
+	if entry == nil || time.Since(entry.fetchedAt) > 2*ttl {
+		ps.modelListsMu.Unlock()
+		return false
+	}
+	if time.Since(entry.fetchedAt) > ttl && !entry.refreshing {
+		entry.refreshing = true
+		go ps.fetchModelList(context.Background(), group, key)
+	}
+	response := entry.response
+	ps.modelListsMu.Unlock()
+
+	c.Header(responseCacheHeader, "HIT")
+	c.JSON(http.StatusOK, response)
+	return true
+}
+
+// storeModelList caches a transformed model list response of the group.
+func (ps *ProxyServer) storeModelList(c *gin.Context, group *models.Group, response map[string]any) {
+	ps.modelListsMu.Lock()
+	defer ps.modelListsMu.Unlock()
+
+	lists := ps.modelLists[group.ID]
+	if lists == nil || len(lists) >= maxCachedModelLists {
+		lists = make(map[string]*cachedModelList)
+		ps.modelLists[group.ID] = lists
+	}
+	lists[modelListCacheKey(c)] = &cachedModelList{response: response, fetchedAt: time.Now()}
+}
+
+// fetchModelList requests a model list of the group through its own pipeline, as a client would, which
+// caches the list and records the model catalog. It returns the recorded response.
+func (ps *ProxyServer) fetchModelList(ctx context.Context, group *models.Group, pathAndQuery string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	log := logrus.WithField("group", group.Name)
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithField("panic", r).Error("Model list request panicked")
+			recorder.Code = http.StatusInternalServerError
+		}
+		ps.modelListsMu.Lock()
+		if entry := ps.modelLists[group.ID][pathAndQuery]; entry != nil {
+			entry.refreshing = false
+		}
+		ps.modelListsMu.Unlock()
+	}()
+
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		log.WithError(err).Warn("Skipping model list request without a channel")
+		recorder.Code = http.StatusInternalServerError
+		return recorder
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/proxy/"+group.Name+pathAndQuery, nil)
+	if err != nil {
+		log.WithError(err).Warn("Failed to create model list request")
+		recorder.Code = http.StatusInternalServerError
+		return recorder
+	}
+
+	path, _, _ := strings.Cut(pathAndQuery, "?")
+	c := gin.CreateTestContextOnly(recorder, ps.shadowEngine)
+	c.Request = req
+	c.Params = gin.Params{{Key: "group_name", Value: group.Name}, {Key: "path", Value: path}}
+	ps.executeRequestWithRetry(c, channelHandler, group, group, nil, false, time.Now(), 0)
+	log.WithFields(logrus.Fields{"path": pathAndQuery, "status": c.Writer.Status()}).Debug("Model list fetched")
+	return recorder
+}
+
+// RefreshModelList drops the group's cached model lists and fetches its full model list from upstream,
+// returning how many models it has.
+func (ps *ProxyServer) RefreshModelList(ctx context.Context, group *models.Group) (int, error) {
+	ps.modelListsMu.Lock()
+	delete(ps.modelLists, group.ID)
+	ps.modelListsMu.Unlock()
+
+	recorder := ps.fetchModelList(ctx, group, modelListPath(group))
+	if recorder.Code != http.StatusOK {
+		return 0, fmt.Errorf("model list request failed with status %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+
+	var response struct {
+		Data   []any `json:"data"`
+		Models []any `json:"models"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return 0, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	return len(response.Data) + len(response.Models), nil
+}
@@ -53,5 +53,15 @@ func (ps *ProxyServer) handleModelListResponse(c *gin.Context, resp *http.Respon
 		return
 	}
 
+	addModelAliases(c, group, response)
+
+	if group.EffectiveConfig.RejectUnknownModels {
+		ps.recordModelCatalog(c, group, response)
+	}
+	if group.EffectiveConfig.ModelListCacheTTL > 0 {
+		ps.storeModelList(c, group, response)
+	}
+
+	forwardPassthroughHeaders(c, resp, group)
 	c.JSON(http.StatusOK, response)
 }
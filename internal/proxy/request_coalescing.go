@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inflightCall is the response of an in-flight request as it is being written to its own client. Identical
+// requests arriving meanwhile follow it and replay the same bytes, so streams are fanned out as they arrive.
+type inflightCall struct {
+	mu      sync.Mutex
+	status  int
+	header  http.Header
+	body    []byte
+	done    bool
+	changed chan struct{}
+}
+
+// joinInflight returns the in-flight call for key and whether the caller leads it, i.e. must perform the
+// upstream request itself.
+func (ps *ProxyServer) joinInflight(key string) (*inflightCall, bool) {
+	ps.inflightMu.Lock()
+	defer ps.inflightMu.Unlock()
+
+	if call, ok := ps.inflight[key]; ok {
+		return call, false
+	}
+	call := &inflightCall{changed: make(chan struct{})}
+	ps.inflight[key] = call
+	return call, true
+}
+
+// finishInflight completes a led call and releases its followers. A response without a body, which never
+// went through the fanoutWriter, still hands its status and headers on.
+func (ps *ProxyServer) finishInflight(c *gin.Context, key string, call *inflightCall) {
+	ps.inflightMu.Lock()
+	delete(ps.inflight, key)
+	ps.inflightMu.Unlock()
+
+	call.publish(func() {
+		if call.status == 0 {
+			call.status = c.Writer.Status()
+			call.header = c.Writer.Header().Clone()
+		}
+		call.done = true
+	})
+}
+
+// followInflight relays a led call's response to this client until the leader finishes or the client goes away.
+func (ps *ProxyServer) followInflight(c *gin.Context, call *inflightCall) {
+	flusher, _ := c.Writer.(http.Flusher)
+	offset := 0
+	headerWritten := false
+
+	for {
+		call.mu.Lock()
+		status, header, chunk, done, changed := call.status, call.header, call.body[offset:], call.done, call.changed
+		call.mu.Unlock()
+
+		if status != 0 && !headerWritten {
+			for key, values := range header {
+				for _, value := range values {
+					c.Writer.Header().Add(key, value)
+				}
+			}
+			c.Status(status)
+			headerWritten = true
+		}
+		if len(chunk) > 0 {
+			if _, err := c.Writer.Write(chunk); err != nil {
+				logUpstreamError("writing coalesced response to client", err)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			offset += len(chunk)
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// publish applies an update under the lock and wakes all followers.
+func (call *inflightCall) publish(update func()) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	update()
+	close(call.changed)
+	call.changed = make(chan struct{})
+}
+
+// fanoutWriter publishes everything the leader writes to its client to the followers of its call.
+type fanoutWriter struct {
+	gin.ResponseWriter
+	call *inflightCall
+}
+
+func (w *fanoutWriter) Write(data []byte) (int, error) {
+	w.share(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *fanoutWriter) WriteString(s string) (int, error) {
+	w.share([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *fanoutWriter) share(data []byte) {
+	w.call.publish(func() {
+		if w.call.status == 0 {
+			w.call.status = w.Status()
+			w.call.header = w.Header().Clone()
+		}
+		w.call.body = append(w.call.body, data...)
+	})
+}
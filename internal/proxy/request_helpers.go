@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"net/http"
 
@@ -30,6 +31,13 @@ func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group
 	return json.Marshal(requestData)
 }
 
+// sendUpstream sends a request upstream. Timeouts connecting, in the TLS handshake or awaiting the response
+// headers are tagged as upstream timeouts.
+func sendUpstream(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	return resp, app_errors.WrapTimeout(app_errors.TimeoutTagUpstream, err)
+}
+
 // logUpstreamError provides a centralized way to log errors from upstream interactions.
 func logUpstreamError(context string, err error) {
 	if err == nil {
@@ -61,3 +69,21 @@ func handleGzipCompression(resp *http.Response, bodyBytes []byte) []byte {
 	}
 	return bodyBytes
 }
+
+// isRetryableFailure reports whether a failed attempt may be retried with another key. Connection errors
+// are always retryable; status codes are checked against the group's retry_status_codes, where an empty
+// list keeps the default of retrying every error status. A stream attempt can only fail before its first
+// byte reaches the client, so retrying it never replays partial output; retry_stream_requests turns it off.
+func isRetryableFailure(group *models.Group, isStream bool, err error, statusCode int) bool {
+	if isStream && !group.EffectiveConfig.RetryStreamRequests {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	codes, parseErr := utils.ParseStatusCodes(group.EffectiveConfig.RetryStatusCodes)
+	if parseErr != nil || len(codes) == 0 {
+		return true
+	}
+	return codes[statusCode]
+}
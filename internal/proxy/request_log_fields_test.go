@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordTestRequestLog logs a failed request of a group with request_log_fields set to selection and
+// returns the record the request log service received.
+func recordTestRequestLog(t *testing.T, selection string) map[string]any {
+	t.Helper()
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+	memStore := store.NewMemoryStore()
+	ps := &ProxyServer{
+		requestLogService: services.NewRequestLogService(nil, memStore, config.NewSystemSettingsManager()),
+		encryptionSvc:     encSvc,
+	}
+
+	group := &models.Group{ID: 1, Name: "g", ChannelType: "openai"}
+	group.EffectiveConfig.EnableRequestBodyLogging = true
+	group.EffectiveConfig.RequestLogFields = selection
+	body := []byte(`{"model":"gpt-4o","messages":[]}`)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", strings.NewReader(string(body)))
+	c.Request.Header.Set("User-Agent", "test-agent")
+	c.Request.RemoteAddr = "203.0.113.7:4321"
+
+	ps.logRequest(c, group, group, &models.APIKey{ID: 1, KeyValue: "sk-secret"}, time.Now().Add(-time.Second),
+		http.StatusBadGateway, errors.New("upstream failed"), false, "https://api.openai.com/v1/chat/completions",
+		&channel.OpenAIChannel{BaseChannel: &channel.BaseChannel{}}, body, models.RequestTypeFinal)
+
+	keys, err := memStore.SPopN(services.PendingLogKeysSet, 10)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("pending request logs = %v, %v; want one", keys, err)
+	}
+	data, err := memStore.Get(keys[0])
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal request log: %v", err)
+	}
+	return record
+}
+
+func TestRequestLogFieldSelection(t *testing.T) {
+	// The JSON names of the selectable request log fields.
+	recordFields := map[string][]string{
+		"model":         {"model"},
+		"key":           {"key_value", "key_hash"},
+		"source_ip":     {"source_ip"},
+		"request_path":  {"request_path"},
+		"duration":      {"duration_ms"},
+		"error_message": {"error_message"},
+		"user_agent":    {"user_agent"},
+		"upstream_addr": {"upstream_addr"},
+		"request_body":  {"request_body"},
+	}
+	if len(recordFields) != len(utils.RequestLogSelectableFields) {
+		t.Fatalf("test covers %d fields, %d are selectable", len(recordFields), len(utils.RequestLogSelectableFields))
+	}
+
+	tests := []struct {
+		name      string
+		selection string
+		want      []string
+	}{
+		{name: "everything by default", selection: "", want: utils.RequestLogSelectableFields},
+		{name: "selected fields only", selection: "model, DURATION", want: []string{"model", "duration"}},
+		{name: "key only", selection: "key", want: []string{"key"}},
+		{name: "stale selection with an unknown field records everything", selection: "model,region", want: utils.RequestLogSelectableFields},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := recordTestRequestLog(t, tt.selection)
+
+			for _, field := range utils.RequestLogSelectableFields {
+				selected := false
+				for _, want := range tt.want {
+					selected = selected || want == field
+				}
+				for _, name := range recordFields[field] {
+					value := record[name]
+					present := value != "" && value != float64(0)
+					if present != selected {
+						t.Errorf("%s = %v, want present %v", name, value, selected)
+					}
+				}
+			}
+
+			// Fields statistics depend on are always recorded.
+			if record["group_name"] != "g" || record["status_code"] != float64(http.StatusBadGateway) || record["is_success"] != false || record["request_type"] != models.RequestTypeFinal {
+				t.Errorf("required fields missing from %v", record)
+			}
+		})
+	}
+}
+
+func TestParseRequestLogFields(t *testing.T) {
+	fields, err := utils.ParseRequestLogFields(" model , Key ,")
+	if err != nil || len(fields) != 2 || !fields["model"] || !fields["key"] {
+		t.Errorf("ParseRequestLogFields = %v, %v; want model and key", fields, err)
+	}
+	if fields, err := utils.ParseRequestLogFields(""); err != nil || fields != nil {
+		t.Errorf("empty selection = %v, %v; want nil to record everything", fields, err)
+	}
+	if _, err := utils.ParseRequestLogFields("model,region"); err == nil || !strings.Contains(err.Error(), "unknown request log field 'region'") {
+		t.Errorf("unknown field error = %v", err)
+	}
+}
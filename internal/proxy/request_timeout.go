@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTimeoutHeader lets a client set the timeout of its request, in seconds or as a Go duration such as "5m".
+const requestTimeoutHeader = "X-Request-Timeout"
+
+// requestTimeout returns the timeout of an upstream attempt and whether the client set it. Without a valid
+// requestTimeoutHeader it is the group's request_timeout. A client timeout is clamped between one second
+// and max_request_timeout, or request_timeout when no maximum is set.
+func requestTimeout(c *gin.Context, group *models.Group) (time.Duration, bool) {
+	cfg := group.EffectiveConfig
+	timeout := time.Duration(cfg.RequestTimeout) * time.Second
+
+	requested, ok := parseRequestTimeout(c.GetHeader(requestTimeoutHeader))
+	if !ok {
+		return timeout, false
+	}
+	limit := timeout
+	if cfg.MaxRequestTimeout > 0 {
+		limit = time.Duration(cfg.MaxRequestTimeout) * time.Second
+	}
+	return min(max(requested, time.Second), limit), true
+}
+
+// clientDeadlineExceeded reports whether an attempt failed because the timeout the client set with
+// requestTimeoutHeader expired. Such a failure is the client's choice: it is returned to the client without
+// retrying and counts neither against the key nor against the upstream.
+func clientDeadlineExceeded(ctx context.Context, overridden bool, err error) bool {
+	return overridden && err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// clientDeadlineError is the error returned to a client whose requested timeout expired.
+func clientDeadlineError(timeout time.Duration) error {
+	return fmt.Errorf("request exceeded the %s timeout set with the %s header", timeout, requestTimeoutHeader)
+}
+
+// parseRequestTimeout parses a timeout given in seconds or as a Go duration.
+func parseRequestTimeout(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(seconds) {
+			return 0, false
+		}
+		return time.Duration(min(seconds, float64(365*24*3600)) * float64(time.Second)), true
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration, true
+	}
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRequestTimeout(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"   ", 0, false},
+		{"30", 30 * time.Second, true},
+		{" 1.5 ", 1500 * time.Millisecond, true},
+		{"0", 0, true},
+		{"5m", 5 * time.Minute, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"1e12", 365 * 24 * time.Hour, true},
+		{"NaN", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRequestTimeout(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRequestTimeout(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		requestTimeout int
+		maxTimeout     int
+		want           time.Duration
+		wantOverridden bool
+	}{
+		{
+			name:           "group timeout without header",
+			requestTimeout: 600,
+			want:           600 * time.Second,
+		},
+		{
+			name:           "invalid header ignored",
+			header:         "soon",
+			requestTimeout: 600,
+			want:           600 * time.Second,
+		},
+		{
+			name:           "shorter client timeout",
+			header:         "30",
+			requestTimeout: 600,
+			want:           30 * time.Second,
+			wantOverridden: true,
+		},
+		{
+			name:           "clamped to request_timeout without a maximum",
+			header:         "1h",
+			requestTimeout: 600,
+			want:           600 * time.Second,
+			wantOverridden: true,
+		},
+		{
+			name:           "longer client timeout up to max_request_timeout",
+			header:         "20m",
+			requestTimeout: 600,
+			maxTimeout:     1800,
+			want:           20 * time.Minute,
+			wantOverridden: true,
+		},
+		{
+			name:           "clamped to max_request_timeout",
+			header:         "2h",
+			requestTimeout: 600,
+			maxTimeout:     1800,
+			want:           1800 * time.Second,
+			wantOverridden: true,
+		},
+		{
+			name:           "at least one second",
+			header:         "0.01",
+			requestTimeout: 600,
+			want:           time.Second,
+			wantOverridden: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(requestTimeoutHeader, tt.header)
+			}
+			group := &models.Group{Name: "g"}
+			group.EffectiveConfig.RequestTimeout = tt.requestTimeout
+			group.EffectiveConfig.MaxRequestTimeout = tt.maxTimeout
+
+			got, overridden := requestTimeout(c, group)
+			if got != tt.want || overridden != tt.wantOverridden {
+				t.Errorf("requestTimeout = %v, %v, want %v, %v", got, overridden, tt.want, tt.wantOverridden)
+			}
+		})
+	}
+}
+
+func TestClientDeadlineExceeded(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	failure := errors.New("upstream failed")
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		overridden bool
+		err        error
+		want       bool
+	}{
+		{"client deadline expired", expired, true, failure, true},
+		{"group deadline expired", expired, false, failure, false},
+		{"no failure", expired, true, nil, false},
+		{"client went away", canceled, true, failure, false},
+		{"deadline not reached", context.Background(), true, failure, false},
+	}
+	for _, tt := range tests {
+		if got := clientDeadlineExceeded(tt.ctx, tt.overridden, tt.err); got != tt.want {
+			t.Errorf("%s: clientDeadlineExceeded = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
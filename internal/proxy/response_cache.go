@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseCacheHeader lets a client opt a non-deterministic request into response caching and request
+// coalescing. The proxy also answers with it to report whether a response was served from the cache.
+const responseCacheHeader = "X-GPTLoad-Cache"
+
+// responseCompleteContextKey marks a request whose response was relayed to the client in full, without
+// upstream or client write errors, so it may be cached.
+const responseCompleteContextKey = "response_complete"
+
+// cachedResponse is a complete upstream response as it was sent to the client.
+type cachedResponse struct {
+	key             string
+	status          int
+	contentType     string
+	contentEncoding string
+	body            []byte
+	expiresAt       time.Time
+}
+
+// responseCache is a size-bounded LRU cache of one group's responses.
+type responseCache struct {
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// deterministicRequestKey returns the key under which identical requests share a response, or "" if the
+// request is not a JSON POST or is neither deterministic nor opted in. The key covers the group, endpoint,
+// model, stream mode, the accepted encodings, which the upstream may compress the response with, and the
+// request body with formatting normalized.
+func deterministicRequestKey(c *gin.Context, group *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, isStream bool) string {
+	if c.Request.Method != http.MethodPost {
+		return ""
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return ""
+	}
+	optedIn := strings.EqualFold(c.GetHeader(responseCacheHeader), "true")
+	if !optedIn && !isDeterministicRequest(payload) {
+		return ""
+	}
+
+	normalized, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatUint(uint64(group.ID), 10) + "\n"))
+	h.Write([]byte(c.Request.URL.Path + "\n"))
+	h.Write([]byte(channelHandler.ExtractModel(c, bodyBytes) + "\n"))
+	h.Write([]byte(strconv.FormatBool(isStream) + "\n"))
+	h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(c.GetHeader("Accept-Encoding")), "")) + "\n"))
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isDeterministicRequest reports whether sampling is greedy: temperature is 0 and top_p, if set, is 1.
+// Both the OpenAI/Anthropic top-level fields and Gemini's generationConfig are checked.
+func isDeterministicRequest(payload map[string]any) bool {
+	params := payload
+	if generationConfig, ok := payload["generationConfig"].(map[string]any); ok {
+		params = generationConfig
+	}
+
+	temperature, ok := params["temperature"].(float64)
+	if !ok || temperature != 0 {
+		return false
+	}
+	for _, name := range []string{"top_p", "topP"} {
+		if topP, ok := params[name].(float64); ok && topP != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// serveCachedResponse replays a cached response. Streamed responses are replayed event by event so
+// streaming clients see a regular SSE stream.
+func (ps *ProxyServer) serveCachedResponse(c *gin.Context, group *models.Group, key string) bool {
+	entry := ps.getCachedResponse(group.ID, key)
+	if entry == nil {
+		return false
+	}
+
+	c.Header(responseCacheHeader, "HIT")
+	c.Header("Content-Type", entry.contentType)
+	if entry.contentEncoding != "" {
+		c.Header("Content-Encoding", entry.contentEncoding)
+	}
+	c.Status(entry.status)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok || !strings.HasPrefix(entry.contentType, "text/event-stream") {
+		_, _ = c.Writer.Write(entry.body)
+		return true
+	}
+	for rest := entry.body; len(rest) > 0; {
+		event := rest
+		if i := bytes.Index(rest, []byte("\n\n")); i != -1 {
+			event = rest[:i+2]
+		}
+		if _, err := c.Writer.Write(event); err != nil {
+			logUpstreamError("writing cached stream to client", err)
+			return true
+		}
+		flusher.Flush()
+		rest = rest[len(event):]
+	}
+	return true
+}
+
+func (ps *ProxyServer) getCachedResponse(groupID uint, key string) *cachedResponse {
+	ps.responseCachesMu.Lock()
+	defer ps.responseCachesMu.Unlock()
+
+	cache := ps.responseCaches[groupID]
+	if cache == nil {
+		return nil
+	}
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cachedResponse)
+	if time.Now().After(entry.expiresAt) {
+		cache.remove(element)
+		return nil
+	}
+	cache.order.MoveToFront(element)
+	return entry
+}
+
+// markResponseComplete records that the response of the request was relayed to the client in full.
+func markResponseComplete(c *gin.Context) {
+	c.Set(responseCompleteContextKey, true)
+}
+
+// storeCachedResponse caches a successful response that completed cleanly, evicting the least recently
+// used entries of the group once its size limit is exceeded. Interrupted or salvaged streams and responses
+// the client did not receive in full are not cached.
+func (ps *ProxyServer) storeCachedResponse(c *gin.Context, group *models.Group, key string, capture *responseCapture) {
+	maxBytes := group.EffectiveConfig.ResponseCacheMaxMB << 20
+	if !c.GetBool(responseCompleteContextKey) || capture.writeFailed || capture.overflow || capture.buf.Len() == 0 || capture.Status() < 200 || capture.Status() >= 300 {
+		return
+	}
+
+	entry := &cachedResponse{
+		key:             key,
+		status:          capture.Status(),
+		contentType:     capture.Header().Get("Content-Type"),
+		contentEncoding: capture.Header().Get("Content-Encoding"),
+		body:            bytes.Clone(capture.buf.Bytes()),
+		expiresAt:       time.Now().Add(time.Duration(group.EffectiveConfig.ResponseCacheTTL) * time.Second),
+	}
+
+	ps.responseCachesMu.Lock()
+	defer ps.responseCachesMu.Unlock()
+
+	cache := ps.responseCaches[group.ID]
+	if cache == nil {
+		cache = newResponseCache()
+		ps.responseCaches[group.ID] = cache
+	}
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	cache.size += len(entry.body)
+	for cache.size > maxBytes {
+		cache.remove(cache.order.Back())
+	}
+}
+
+func (rc *responseCache) remove(element *list.Element) {
+	entry := rc.order.Remove(element).(*cachedResponse)
+	delete(rc.entries, entry.key)
+	rc.size -= len(entry.body)
+}
+
+// responseCapture tees the response sent to the client so it can be cached. Capturing stops once the
+// body exceeds limit, as such a response could never fit in the cache.
+type responseCapture struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	limit       int
+	overflow    bool
+	writeFailed bool
+}
+
+// beginResponseCapture starts capturing the client response of the request for the cache.
+func beginResponseCapture(c *gin.Context, group *models.Group) *responseCapture {
+	capture := &responseCapture{ResponseWriter: c.Writer, limit: group.EffectiveConfig.ResponseCacheMaxMB << 20}
+	c.Writer = capture
+	c.Header(responseCacheHeader, "MISS")
+	return capture
+}
+
+func (w *responseCapture) Write(data []byte) (int, error) {
+	w.capture(data)
+	n, err := w.ResponseWriter.Write(data)
+	w.writeFailed = w.writeFailed || err != nil
+	return n, err
+}
+
+func (w *responseCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	n, err := w.ResponseWriter.WriteString(s)
+	w.writeFailed = w.writeFailed || err != nil
+	return n, err
+}
+
+func (w *responseCapture) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
@@ -3,13 +3,24 @@ package proxy
 import (
 	"io"
 	"net/http"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response) {
-	c.Header("Content-Type", "text/event-stream")
+// awsEventStreamContentType is the binary framing of Bedrock streams, relayed as is instead of as SSE.
+const awsEventStreamContentType = "application/vnd.amazon.eventstream"
+
+// handleStreamingResponse relays a stream to the client. It returns a *streamInterruption if the upstream
+// fails before the stream is complete; failures writing to the client are only logged.
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, group *models.Group) error {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), awsEventStreamContentType) {
+		c.Header("Content-Type", "text/event-stream")
+	}
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
@@ -17,32 +28,55 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
-		ps.handleNormalResponse(c, resp)
-		return
+		return ps.handleNormalResponse(c, resp, group)
+	}
+
+	if translator := responseTranslatorFrom(c); translator != nil {
+		return streamTranslatedResponse(c, resp, flusher, translator, buildResponseProcessors(group), newStreamThrottle(group))
+	}
+
+	if c.Writer.Header().Get("Content-Type") == "text/event-stream" && resp.Header.Get("Content-Encoding") == "" {
+		processors, throttle := buildResponseProcessors(group), newStreamThrottle(group)
+		if len(processors) > 0 || throttle != nil {
+			return streamProcessedResponse(c, resp, flusher, processors, throttle)
+		}
 	}
 
 	buf := make([]byte, 4*1024)
+	var tail streamTail
 	for {
 		n, err := resp.Body.Read(buf)
 		if n > 0 {
 			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
 				logUpstreamError("writing stream to client", writeErr)
-				return
+				return nil
 			}
+			tail.observe(buf[:n])
 			flusher.Flush()
 		}
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
 			logUpstreamError("reading from upstream", err)
-			return
+			return tail.interruption(err)
 		}
 	}
 }
 
-func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response) {
+func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, group *models.Group) error {
+	if translator := responseTranslatorFrom(c); translator != nil {
+		return writeTranslatedResponse(c, resp, translator, buildResponseProcessors(group))
+	}
+
+	if processors := buildResponseProcessors(group); len(processors) > 0 {
+		return writeProcessedResponse(c, resp, processors)
+	}
+
 	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+		err = app_errors.WrapTimeout(app_errors.TimeoutTagUpstream, err)
 		logUpstreamError("copying response body", err)
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwardPassthroughHeaders copies the upstream response headers listed in the group's passthrough_headers
+// setting to the client. It is used for responses the proxy writes itself (errors, model lists), which
+// otherwise carry none of the upstream headers. Entries ending in "*" match by prefix.
+func forwardPassthroughHeaders(c *gin.Context, resp *http.Response, group *models.Group) {
+	if resp == nil || group.EffectiveConfig.PassthroughHeaders == "" {
+		return
+	}
+
+	patterns := utils.SplitAndTrim(group.EffectiveConfig.PassthroughHeaders, ",")
+	for name, values := range resp.Header {
+		if !matchesHeaderPattern(name, patterns) {
+			continue
+		}
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+}
+
+// matchesHeaderPattern reports whether a header name matches one of the patterns, case-insensitively.
+func matchesHeaderPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
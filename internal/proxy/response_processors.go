@@ -0,0 +1,346 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 引用/溯源元数据处理模式
+const (
+	GroundingMetadataPassthrough = "passthrough"
+	GroundingMetadataStrip       = "strip"
+	GroundingMetadataRedact      = "redact"
+)
+
+// responseProcessor rewrites a decoded JSON payload (a whole response body or a single SSE event).
+// It returns true if the payload was modified.
+type responseProcessor func(payload map[string]any) bool
+
+// buildResponseProcessors returns the response processors enabled for a group, in execution order.
+func buildResponseProcessors(group *models.Group) []responseProcessor {
+	var processors []responseProcessor
+
+	switch group.EffectiveConfig.GroundingMetadataMode {
+	case GroundingMetadataStrip:
+		processors = append(processors, stripGroundingMetadata)
+	case GroundingMetadataRedact:
+		processors = append(processors, redactGroundingMetadata)
+	}
+
+	if group.EffectiveConfig.StripReasoningContent {
+		processors = append(processors, stripReasoningContent)
+	}
+
+	return processors
+}
+
+// applyResponseProcessors runs all processors over a payload.
+func applyResponseProcessors(payload map[string]any, processors []responseProcessor) bool {
+	modified := false
+	for _, process := range processors {
+		if process(payload) {
+			modified = true
+		}
+	}
+	return modified
+}
+
+// processJSONBody applies processors to a JSON body. The original body is returned if it is not a JSON object or nothing changed.
+func processJSONBody(body []byte, processors []responseProcessor) ([]byte, bool) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, false
+	}
+	if !applyResponseProcessors(payload, processors) {
+		return body, false
+	}
+
+	newBody, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to marshal processed response, using original body")
+		return body, false
+	}
+	return newBody, true
+}
+
+// writeProcessedResponse buffers a non-streaming response, applies processors and writes it to the client.
+func writeProcessedResponse(c *gin.Context, resp *http.Response, processors []responseProcessor) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return err
+	}
+
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	decompressed, err := utils.DecompressResponse(contentEncoding, body)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to decompress response for processing, passing through")
+		decompressed = nil
+	}
+
+	if decompressed != nil {
+		if processed, modified := processJSONBody(decompressed, processors); modified {
+			c.Writer.Header().Del("Content-Encoding")
+			c.Writer.Header().Set("Content-Length", strconv.Itoa(len(processed)))
+			body = processed
+		}
+	}
+
+	if _, err := c.Writer.Write(body); err != nil {
+		logUpstreamError("writing response body", err)
+		return err
+	}
+	return nil
+}
+
+// streamProcessedResponse forwards an SSE stream line by line, applying processors to each "data:" event
+// and pacing events with the throttle, if any. It returns a *streamInterruption if the upstream fails mid-stream.
+func streamProcessedResponse(c *gin.Context, resp *http.Response, flusher http.Flusher, processors []responseProcessor, throttle *streamThrottle) error {
+	reader := bufio.NewReader(resp.Body)
+	var tail streamTail
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && len(processors) > 0 {
+			line = processSSELine(line, processors)
+		}
+		if len(line) > 0 && throttle != nil {
+			if waitErr := throttle.wait(c.Request.Context(), line); waitErr != nil {
+				logUpstreamError("pacing stream to client", waitErr)
+				return nil
+			}
+		}
+		if len(line) > 0 {
+			if _, writeErr := c.Writer.Write(line); writeErr != nil {
+				logUpstreamError("writing stream to client", writeErr)
+				return nil
+			}
+			tail.observe(line)
+			flusher.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			logUpstreamError("reading from upstream", err)
+			return tail.interruption(err)
+		}
+	}
+}
+
+// processSSELine applies processors to the JSON payload of an SSE "data:" line, preserving the line ending.
+// It returns nil if processing left the event without any content, so the event is suppressed.
+func processSSELine(line []byte, processors []responseProcessor) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	data, ok := bytes.CutPrefix(content, []byte("data:"))
+	if !ok {
+		return line
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return line
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return line
+	}
+	if !applyResponseProcessors(payload, processors) {
+		return line
+	}
+	if isEmptyStreamEvent(payload) {
+		return nil
+	}
+	processed, err := json.Marshal(payload)
+	if err != nil {
+		return line
+	}
+
+	ending := line[len(content):]
+	result := make([]byte, 0, len(processed)+len(ending)+6)
+	result = append(result, "data: "...)
+	result = append(result, processed...)
+	return append(result, ending...)
+}
+
+// forEachCandidate calls fn for every candidate object of a Gemini response payload.
+func forEachCandidate(payload map[string]any, fn func(candidate map[string]any) bool) bool {
+	candidates, ok := payload["candidates"].([]any)
+	if !ok {
+		return false
+	}
+
+	modified := false
+	for _, item := range candidates {
+		if candidate, ok := item.(map[string]any); ok && fn(candidate) {
+			modified = true
+		}
+	}
+	return modified
+}
+
+// stripGroundingMetadata removes groundingMetadata and citationMetadata from Gemini candidates.
+func stripGroundingMetadata(payload map[string]any) bool {
+	return forEachCandidate(payload, func(candidate map[string]any) bool {
+		_, hasGrounding := candidate["groundingMetadata"]
+		_, hasCitation := candidate["citationMetadata"]
+		delete(candidate, "groundingMetadata")
+		delete(candidate, "citationMetadata")
+		return hasGrounding || hasCitation
+	})
+}
+
+// redactGroundingMetadata keeps grounding and citation metadata but removes source URIs.
+func redactGroundingMetadata(payload map[string]any) bool {
+	return forEachCandidate(payload, func(candidate map[string]any) bool {
+		modified := false
+
+		if grounding, ok := candidate["groundingMetadata"].(map[string]any); ok {
+			if chunks, ok := grounding["groundingChunks"].([]any); ok {
+				for _, item := range chunks {
+					chunk, ok := item.(map[string]any)
+					if !ok {
+						continue
+					}
+					for _, sourceType := range []string{"web", "retrievedContext"} {
+						if source, ok := chunk[sourceType].(map[string]any); ok {
+							if _, exists := source["uri"]; exists {
+								delete(source, "uri")
+								modified = true
+							}
+						}
+					}
+				}
+			}
+			if _, exists := grounding["searchEntryPoint"]; exists {
+				delete(grounding, "searchEntryPoint")
+				modified = true
+			}
+		}
+
+		if citation, ok := candidate["citationMetadata"].(map[string]any); ok {
+			for _, field := range []string{"citations", "citationSources"} {
+				sources, ok := citation[field].([]any)
+				if !ok {
+					continue
+				}
+				for _, item := range sources {
+					if source, ok := item.(map[string]any); ok {
+						if _, exists := source["uri"]; exists {
+							delete(source, "uri")
+							modified = true
+						}
+					}
+				}
+			}
+		}
+
+		return modified
+	})
+}
+
+// stripReasoningContent removes thought parts from Gemini candidates and reasoning fields from
+// OpenAI-compatible choices. Usage metadata such as thoughtsTokenCount is kept for billing.
+func stripReasoningContent(payload map[string]any) bool {
+	modified := forEachCandidate(payload, func(candidate map[string]any) bool {
+		content, ok := candidate["content"].(map[string]any)
+		if !ok {
+			return false
+		}
+		parts, ok := content["parts"].([]any)
+		if !ok {
+			return false
+		}
+
+		kept := make([]any, 0, len(parts))
+		for _, item := range parts {
+			if part, ok := item.(map[string]any); ok && part["thought"] == true {
+				continue
+			}
+			kept = append(kept, item)
+		}
+		if len(kept) == len(parts) {
+			return false
+		}
+		content["parts"] = kept
+		return true
+	})
+
+	if choices, ok := payload["choices"].([]any); ok {
+		for _, item := range choices {
+			choice, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			for _, field := range []string{"message", "delta"} {
+				if message, ok := choice[field].(map[string]any); ok {
+					for _, reasoningField := range []string{"reasoning_content", "reasoning"} {
+						if _, exists := message[reasoningField]; exists {
+							delete(message, reasoningField)
+							modified = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return modified
+}
+
+// isEmptyStreamEvent reports whether a processed stream event carries nothing for the client,
+// i.e. no content parts, deltas, finish reasons or usage.
+func isEmptyStreamEvent(payload map[string]any) bool {
+	if candidates, ok := payload["candidates"].([]any); ok {
+		for _, item := range candidates {
+			candidate, ok := item.(map[string]any)
+			if !ok {
+				return false
+			}
+			if reason, ok := candidate["finishReason"]; ok && reason != nil {
+				return false
+			}
+			if content, ok := candidate["content"].(map[string]any); ok {
+				if parts, ok := content["parts"].([]any); !ok || len(parts) > 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if choices, ok := payload["choices"].([]any); ok {
+		if usage, ok := payload["usage"]; ok && usage != nil {
+			return false
+		}
+		for _, item := range choices {
+			choice, ok := item.(map[string]any)
+			if !ok {
+				return false
+			}
+			if reason, ok := choice["finish_reason"]; ok && reason != nil {
+				return false
+			}
+			if delta, ok := choice["delta"].(map[string]any); ok {
+				for _, value := range delta {
+					if value != nil && value != "" {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+
+	return false
+}
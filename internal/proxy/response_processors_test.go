@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relayTestResponse relays an upstream body to a test client the way the proxy does for the group and returns what the client received.
+func relayTestResponse(t *testing.T, group *models.Group, stream bool, body string) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1beta/models/gemini-2.0-flash:generateContent", nil)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+
+	ps := &ProxyServer{}
+	var err error
+	if stream {
+		resp.Header.Set("Content-Type", "text/event-stream")
+		err = ps.handleStreamingResponse(c, resp, group)
+	} else {
+		resp.Header.Set("Content-Type", "application/json")
+		err = ps.handleNormalResponse(c, resp, group)
+	}
+	if err != nil {
+		t.Fatalf("relaying response: %v", err)
+	}
+	return recorder.Body.String()
+}
+
+func TestGroundingMetadataModes(t *testing.T) {
+	const candidate = `{"candidates":[{"content":{"parts":[{"text":"Paris"}]},` +
+		`"groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com/paris","title":"Paris"}}],"searchEntryPoint":{"renderedContent":"<div/>"}},` +
+		`"citationMetadata":{"citationSources":[{"uri":"https://example.com/cite","startIndex":0}]}}]}`
+
+	tests := []struct {
+		name       string
+		mode       string
+		want       []string
+		wantAbsent []string
+	}{
+		{
+			name: "passthrough by default",
+			want: []string{`"groundingMetadata"`, `"citationMetadata"`, "https://example.com/paris", "https://example.com/cite"},
+		},
+		{
+			name: "explicit passthrough",
+			mode: GroundingMetadataPassthrough,
+			want: []string{`"groundingMetadata"`, `"citationMetadata"`, "https://example.com/paris", "https://example.com/cite"},
+		},
+		{
+			name:       "strip",
+			mode:       GroundingMetadataStrip,
+			want:       []string{`"text":"Paris"`},
+			wantAbsent: []string{"groundingMetadata", "citationMetadata", "example.com"},
+		},
+		{
+			name:       "redact",
+			mode:       GroundingMetadataRedact,
+			want:       []string{`"groundingMetadata"`, `"citationMetadata"`, `"title":"Paris"`, `"startIndex":0`},
+			wantAbsent: []string{"example.com", "searchEntryPoint"},
+		},
+	}
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			name := tt.name
+			body := candidate
+			if stream {
+				name += " stream"
+				body = "data: " + candidate + "\r\n\r\n"
+			}
+			t.Run(name, func(t *testing.T) {
+				group := &models.Group{Name: "g"}
+				group.EffectiveConfig.GroundingMetadataMode = tt.mode
+
+				got := relayTestResponse(t, group, stream, body)
+				if tt.mode == "" || tt.mode == GroundingMetadataPassthrough {
+					if got != body {
+						t.Errorf("passthrough changed the response:\n got %s\nwant %s", got, body)
+					}
+					return
+				}
+				for _, want := range tt.want {
+					if !strings.Contains(got, want) {
+						t.Errorf("response %s does not contain %s", got, want)
+					}
+				}
+				for _, absent := range tt.wantAbsent {
+					if strings.Contains(got, absent) {
+						t.Errorf("response %s still contains %s", got, absent)
+					}
+				}
+				if stream && (!strings.HasPrefix(got, "data: ") || !strings.HasSuffix(got, "\r\n\r\n")) {
+					t.Errorf("stream event framing lost: %q", got)
+				}
+			})
+		}
+	}
+}
+
+func TestStripReasoningContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+		body   string
+		want   string
+	}{
+		{
+			name: "Gemini response",
+			body: `{"candidates":[{"content":{"parts":[{"text":"Let me think","thought":true},{"text":"42"}]}}],"usageMetadata":{"thoughtsTokenCount":3}}`,
+			want: `{"candidates":[{"content":{"parts":[{"text":"42"}]}}],"usageMetadata":{"thoughtsTokenCount":3}}`,
+		},
+		{
+			name: "OpenAI-compatible response",
+			body: `{"choices":[{"message":{"content":"42","reasoning_content":"Let me think"}}]}`,
+			want: `{"choices":[{"message":{"content":"42"}}]}`,
+		},
+		{
+			name:   "Gemini stream",
+			stream: true,
+			body: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Let me think\",\"thought\":true}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"More thoughts\",\"thought\":true},{\"text\":\"42\"}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[]},\"finishReason\":\"STOP\"}]}\n\n",
+			// The thought-only event is suppressed; only its blank separator line remains.
+			want: "\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"42\"}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[]},\"finishReason\":\"STOP\"}]}\n\n",
+		},
+		{
+			name:   "OpenAI-compatible stream",
+			stream: true,
+			body: "data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"Let me think\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"42\"}}]}\n\n" +
+				"data: [DONE]\n\n",
+			want: "\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"42\"}}]}\n\n" +
+				"data: [DONE]\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: "g"}
+			if got := relayTestResponse(t, group, tt.stream, tt.body); got != tt.body {
+				t.Errorf("response changed without strip_reasoning_content:\n got %q\nwant %q", got, tt.body)
+			}
+
+			group.EffectiveConfig.StripReasoningContent = true
+			if got := relayTestResponse(t, group, tt.stream, tt.body); got != tt.want {
+				t.Errorf("stripped response:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
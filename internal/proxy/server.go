@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"gpt-load/internal/channel"
@@ -16,9 +19,13 @@ import (
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/tracing"
+	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +41,31 @@ type ProxyServer struct {
 	channelFactory    *channel.Factory
 	requestLogService *services.RequestLogService
 	encryptionSvc     encryption.Service
+	store             store.Store
+	accessLog         *accessLogWriter
+	quotaService      *services.QuotaService
+
+	schedulersMu sync.Mutex
+	schedulers   map[uint]*fairScheduler
+
+	catalogsMu sync.Mutex
+	catalogs   map[uint]*modelCatalog
+
+	modelListsMu sync.Mutex
+	modelLists   map[uint]map[string]*cachedModelList
+
+	responseCachesMu sync.Mutex
+	responseCaches   map[uint]*responseCache
+
+	inflightMu sync.Mutex
+	inflight   map[string]*inflightCall
+
+	shadowEngine *gin.Engine
+	shadowSlots  chan struct{}
+
+	pricesMu     sync.Mutex
+	pricesSource string
+	prices       utils.ModelPriceTable
 }
 
 // NewProxyServer creates a new proxy server
@@ -45,6 +77,9 @@ func NewProxyServer(
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
 	encryptionSvc encryption.Service,
+	store store.Store,
+	configManager types.ConfigManager,
+	quotaService *services.QuotaService,
 ) (*ProxyServer, error) {
 	return &ProxyServer{
 		keyProvider:       keyProvider,
@@ -54,6 +89,16 @@ func NewProxyServer(
 		channelFactory:    channelFactory,
 		requestLogService: requestLogService,
 		encryptionSvc:     encryptionSvc,
+		store:             store,
+		accessLog:         newAccessLogWriter(configManager.GetLogConfig()),
+		quotaService:      quotaService,
+		schedulers:        make(map[uint]*fairScheduler),
+		catalogs:          make(map[uint]*modelCatalog),
+		modelLists:        make(map[uint]map[string]*cachedModelList),
+		responseCaches:    make(map[uint]*responseCache),
+		inflight:          make(map[string]*inflightCall),
+		shadowEngine:      gin.New(),
+		shadowSlots:       make(chan struct{}, maxShadowRequests),
 	}, nil
 }
 
@@ -61,6 +106,20 @@ func NewProxyServer(
 func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	startTime := time.Now()
 	groupName := c.Param("group_name")
+	c.Request = c.Request.WithContext(utils.WithTraceID(c.Request.Context(), utils.NewTraceID()))
+
+	spanCtx, span := tracing.Start(tracing.Extract(c.Request.Context(), c.Request.Header), "proxy.request", tracing.KindServer,
+		tracing.String("group", groupName), tracing.String("http.request.method", c.Request.Method))
+	c.Request = c.Request.WithContext(spanCtx)
+	defer func() {
+		span.SetAttributes(tracing.Int("http.response.status_code", c.Writer.Status()))
+		span.End()
+	}()
+
+	accessRecord := ps.beginAccessLog(c, startTime)
+	defer ps.finishAccessLog(c, accessRecord)
+
+	defer ps.keyProvider.TrackRequest()()
 
 	originalGroup, err := ps.groupManager.GetGroupByName(groupName)
 	if err != nil {
@@ -68,18 +127,18 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
-	// Select sub-group if this is an aggregate group
-	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup)
+	// Spill over to a fallback group if needed, then select the sub-group if it is an aggregate group
+	routedGroup, subGroupName, err := ps.routeGroup(originalGroup)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"aggregate_group": originalGroup.Name,
+			"aggregate_group": routedGroup.Name,
 			"error":           err,
 		}).Error("Failed to select sub-group from aggregate")
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, "No available sub-groups"))
 		return
 	}
 
-	group := originalGroup
+	group := routedGroup
 	if subGroupName != "" {
 		group, err = ps.groupManager.GetGroupByName(subGroupName)
 		if err != nil {
@@ -93,14 +152,39 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", groupName, err)))
 		return
 	}
+	span.SetAttributes(tracing.String("sub_group", group.Name), tracing.String("channel_type", group.ChannelType))
+	if accessRecord != nil {
+		if group.ID != originalGroup.ID {
+			accessRecord.SubGroup = group.Name
+		}
+		accessRecord.ChannelType = group.ChannelType
+	}
+
+	// Reject disallowed content types before buffering the body
+	if apiErr := checkContentType(c.Request, group); apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
 
-	bodyBytes, err := io.ReadAll(c.Request.Body)
+	release, err := ps.acquireGroupSlot(c, group)
 	if err != nil {
-		logrus.Errorf("Failed to read request body: %v", err)
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+		logrus.Debugf("Request for group %s cancelled while waiting for a concurrency slot: %v", group.Name, err)
+		return
+	}
+	defer release()
+
+	if isWebSocketUpgrade(c.Request) {
+		ps.handleWebSocket(c, channelHandler, originalGroup, group, startTime)
+		return
+	}
+
+	bodyBytes, apiErr := readRequestBody(c, group)
+	if apiErr != nil {
+		response.Error(c, apiErr)
 		return
 	}
 	c.Request.Body.Close()
+	bodyBytes = applyModelAlias(c.Request, group, bodyBytes)
 
 	finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, group)
 	if err != nil {
@@ -108,11 +192,168 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	// Upstream token counts need a prepared request and are checked per attempt instead.
+	if !usesUpstreamTokenCount(group, channelHandler) {
+		if apiErr := checkInputTokenLimit(group, channel.EstimateInputTokens(finalBodyBytes)); apiErr != nil {
+			response.Error(c, apiErr)
+			return
+		}
+	}
+
 	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+	if accessRecord != nil {
+		accessRecord.BytesIn = len(bodyBytes)
+		accessRecord.Stream = isStream
+		accessRecord.Model = channelHandler.ExtractModel(c, bodyBytes)
+	}
+	if span != nil {
+		span.SetAttributes(tracing.String("model", channelHandler.ExtractModel(c, bodyBytes)))
+	}
+
+	if group.EffectiveConfig.ModelListCacheTTL > 0 && shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		if ps.serveCachedModelList(c, group) {
+			ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusOK, nil, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	// Reject models missing from the cached catalog before a key is selected or a token minted.
+	if group.EffectiveConfig.RejectUnknownModels && !shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		if model := channelHandler.ExtractModel(c, bodyBytes); model != "" {
+			if apiErr := ps.unknownModelError(group, model); apiErr != nil {
+				response.Error(c, apiErr)
+				ps.logRequest(c, originalGroup, group, nil, startTime, apiErr.HTTPStatus, apiErr, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+				return
+			}
+		}
+	}
+
+	// Identical deterministic requests are answered from the response cache or share one in-flight upstream call.
+	requestKey := ""
+	if group.EffectiveConfig.ResponseCacheTTL > 0 || group.EffectiveConfig.CoalesceRequests {
+		requestKey = deterministicRequestKey(c, group, channelHandler, finalBodyBytes, isStream)
+	}
+	if requestKey != "" && group.EffectiveConfig.ResponseCacheTTL > 0 {
+		if ps.serveCachedResponse(c, group, requestKey) {
+			ps.logRequest(c, originalGroup, group, nil, startTime, c.Writer.Status(), nil, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+		capture := beginResponseCapture(c, group)
+		defer ps.storeCachedResponse(c, group, requestKey, capture)
+	}
+	if requestKey != "" && group.EffectiveConfig.CoalesceRequests {
+		call, isLeader := ps.joinInflight(requestKey)
+		if !isLeader {
+			ps.followInflight(c, call)
+			ps.logRequest(c, originalGroup, group, nil, startTime, c.Writer.Status(), nil, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+		defer ps.finishInflight(c, requestKey, call)
+		c.Writer = &fanoutWriter{ResponseWriter: c.Writer, call: call}
+	}
 
+	recorder := beginUsageRecording(c, isStream)
+
+	// Enforce the quotas of the serving group and, for aggregates, of the selected sub-group.
+	quotaGroups := make([]*models.Group, 0, 2)
+	for _, g := range []*models.Group{routedGroup, group} {
+		if services.QuotaEnabled(g) && (len(quotaGroups) == 0 || quotaGroups[0].ID != g.ID) {
+			quotaGroups = append(quotaGroups, g)
+		}
+	}
+	if len(quotaGroups) > 0 {
+		for _, g := range quotaGroups {
+			if apiErr := ps.quotaService.Admit(g); apiErr != nil {
+				c.Header("Retry-After", strconv.Itoa(int(time.Until(services.QuotaResetsAt(g)).Seconds())+1))
+				response.Error(c, apiErr)
+				ps.logRequest(c, originalGroup, group, nil, startTime, apiErr.HTTPStatus, apiErr, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+				return
+			}
+		}
+		defer func() {
+			tokens := recorder.finish().Total()
+			for _, g := range quotaGroups {
+				ps.quotaService.AddTokens(g, tokens)
+			}
+		}()
+	}
+
+	ps.shadowRequest(c, originalGroup, bodyBytes)
 	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0)
 }
 
+// routeGroup returns the group serving a request for the primary group, and the sub-group selected when that
+// is an aggregate. The primary group serves unless it has no active keys, no selectable sub-group or no quota
+// left; its fallback groups are then tried in order, following their own fallback groups depth-first, and
+// each group at most once. If no group can serve, the request stays with the primary group.
+func (ps *ProxyServer) routeGroup(primary *models.Group) (*models.Group, string, error) {
+	if strings.TrimSpace(primary.EffectiveConfig.FallbackGroups) != "" {
+		if group, subGroupName, ok := ps.findServingGroup(primary, make(map[uint]bool)); ok {
+			if group.ID != primary.ID {
+				logrus.WithFields(logrus.Fields{
+					"group":          primary.Name,
+					"fallback_group": group.Name,
+				}).Info("Request routed to fallback group")
+			}
+			return group, subGroupName, nil
+		}
+	}
+
+	subGroupName, err := ps.subGroupManager.SelectSubGroup(primary)
+	return primary, subGroupName, err
+}
+
+// findServingGroup walks the fallback chain from group and returns the first group that can serve a request.
+func (ps *ProxyServer) findServingGroup(group *models.Group, visited map[uint]bool) (*models.Group, string, bool) {
+	if visited[group.ID] {
+		return nil, "", false
+	}
+	visited[group.ID] = true
+
+	if !ps.quotaService.Exhausted(group) {
+		if group.GroupType == "aggregate" {
+			if subGroupName, err := ps.subGroupManager.SelectSubGroup(group); err == nil {
+				return group, subGroupName, true
+			}
+		} else if ps.keyProvider.HasActiveKeys(group.ID) {
+			return group, "", true
+		}
+	}
+
+	for _, name := range strings.Split(group.EffectiveConfig.FallbackGroups, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fallback, err := ps.groupManager.GetGroupByName(name)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"group":          group.Name,
+				"fallback_group": name,
+				"error":          err,
+			}).Warn("Skipping unknown fallback group")
+			continue
+		}
+		if serving, subGroupName, ok := ps.findServingGroup(fallback, visited); ok {
+			return serving, subGroupName, true
+		}
+	}
+	return nil, "", false
+}
+
+// markPrepareFailure counts a failure to prepare the upstream request against the key. Token mint failures are
+// classified by the channel: rejected credentials disable the key at once, transient token endpoint errors do not count.
+func (ps *ProxyServer) markPrepareFailure(apiKey *models.APIKey, group *models.Group, err error) {
+	if mintErr, ok := app_errors.AsTokenMintError(err); ok {
+		if mintErr.Permanent {
+			logrus.WithFields(logrus.Fields{"group": group.Name, "key": utils.MaskAPIKey(apiKey.KeyValue), "error": err.Error()}).Error("Credential rejected by token endpoint, disabling key")
+			ps.keyProvider.DisableKey(apiKey, group, err.Error())
+		}
+		return
+	}
+	ps.keyProvider.UpdateStatus(apiKey, group, false, 0, err.Error())
+}
+
 // executeRequestWithRetry is the core recursive function for handling requests and retries.
 func (ps *ProxyServer) executeRequestWithRetry(
 	c *gin.Context,
@@ -126,14 +367,23 @@ func (ps *ProxyServer) executeRequestWithRetry(
 ) {
 	cfg := group.EffectiveConfig
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	apiKey, releaseKey, err := ps.selectKeyWithSlot(c, group, retryCount)
 	if err != nil {
+		if apiErr, ok := err.(*app_errors.APIError); ok && apiErr.HTTPStatus == http.StatusBadRequest {
+			response.Error(c, apiErr)
+			ps.logRequest(c, originalGroup, group, nil, startTime, apiErr.HTTPStatus, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
 		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
+		utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 1, "error": err}, "Key selection failed")
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
 		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
 		return
 	}
 
+	defer releaseKey()
+	utils.LogRequestLifecycle(c.Request.Context(), group, false, logrus.Fields{"attempt": retryCount + 1, "key": utils.MaskAPIKey(apiKey.KeyValue)}, "Key selected")
+
 	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
@@ -142,10 +392,10 @@ func (ps *ProxyServer) executeRequestWithRetry(
 
 	var ctx context.Context
 	var cancel context.CancelFunc
-	if isStream {
+	timeout, overridden := requestTimeout(c, group)
+	if isStream && !overridden {
 		ctx, cancel = context.WithCancel(c.Request.Context())
 	} else {
-		timeout := time.Duration(cfg.RequestTimeout) * time.Second
 		ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
 	}
 	defer cancel()
@@ -164,29 +414,83 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	req.Header.Del("Authorization")
 	req.Header.Del("X-Api-Key")
 	req.Header.Del("X-Goog-Api-Key")
+	req.Header.Del(forceKeyHeader)
+	req.Header.Del(keyTagsHeader)
+	req.Header.Del(requestTimeoutHeader)
+	req.Header.Del(responseCacheHeader)
 
 	// Apply model redirection
+	_, redirectSpan := tracing.Start(ctx, "proxy.model_redirect", tracing.KindInternal, tracing.String("group", group.Name))
 	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
+	redirectSpan.RecordError(err)
+	redirectSpan.End()
+	if err == nil {
+		c.Set(upstreamModelContextKey, channelHandler.ExtractModel(&gin.Context{Request: req}, finalBodyBytes))
+	}
+	if accessRecord := accessLogFrom(c); accessRecord != nil && err == nil {
+		accessRecord.Model = c.GetString(upstreamModelContextKey)
+	}
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
 		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 		return
 	}
 
-	// Update request body if it was modified by redirection
+	if len(group.BodyRuleList) > 0 {
+		finalBodyBytes, err = utils.ApplyBodyRules(finalBodyBytes, group.BodyRuleList)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusInternalServerError, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	// Translate the request to the upstream's API format if the group accepts another client format.
+	if translator, ok := channelHandler.(channel.FormatTranslator); ok {
+		translatedBody, responseTranslator, err := translator.TranslateRequest(req, finalBodyBytes, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+		if responseTranslator != nil {
+			finalBodyBytes = translatedBody
+			c.Set(upstreamModelContextKey, channelHandler.ExtractModel(&gin.Context{Request: req}, finalBodyBytes))
+			// Let the transport decompress the response, which is rewritten anyway.
+			req.Header.Del("Accept-Encoding")
+			c.Set(responseTranslatorContextKey, responseTranslator)
+		}
+	}
+
+	if apiErr := checkModelAccess(group, c.GetString(upstreamModelContextKey)); apiErr != nil {
+		response.Error(c, apiErr)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, apiErr.HTTPStatus, apiErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+		return
+	}
+
+	// Update request body if it was modified by redirection, body rules or translation
 	if !bytes.Equal(finalBodyBytes, bodyBytes) {
 		req.Body = io.NopCloser(bytes.NewReader(finalBodyBytes))
 		req.ContentLength = int64(len(finalBodyBytes))
 	}
 
 	if err := channelHandler.ModifyRequest(req, apiKey, group); err != nil {
+		if clientDeadlineExceeded(ctx, overridden, err) {
+			ps.failClientDeadline(c, originalGroup, group, apiKey, startTime, timeout, isStream, upstreamURL, channelHandler, bodyBytes)
+			return
+		}
 		statusCode := http.StatusInternalServerError
+		if phase := app_errors.TimeoutPhase(err); phase != "" {
+			statusCode = http.StatusGatewayTimeout
+			logrus.WithFields(logrus.Fields{"group": group.Name, "key": utils.MaskAPIKey(apiKey.KeyValue), "timeout": phase}).Warn("Request timed out while preparing upstream request")
+		}
 		parsedError := err.Error()
 
 		// Mark current key as failed and decide whether to retry.
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
+		ps.markPrepareFailure(apiKey, group, err)
+		utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 1, "key": utils.MaskAPIKey(apiKey.KeyValue), "error": parsedError, "timeout": app_errors.TimeoutPhase(err)}, "Failed to prepare upstream request")
 
-		isLastAttempt := retryCount >= cfg.MaxRetries
+		isLastAttempt := retryCount >= cfg.MaxRetries || (isStream && !cfg.RetryStreamRequests)
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
@@ -199,6 +503,8 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
+		utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 2, "max_retries": cfg.MaxRetries}, "Retrying request with another key")
+		releaseKey()
 		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
 		return
 	}
@@ -206,20 +512,79 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	// Apply custom header rules
 	if len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
+		headerCtx.Model = c.GetString(upstreamModelContextKey)
+		headerCtx.Body = finalBodyBytes
+		headerCtx.Path = strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+originalGroup.Name)
+		headerCtx.Stream = isStream
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
-	var client *http.Client
+	if cfg.MaxInputTokens > 0 && usesUpstreamTokenCount(group, channelHandler) {
+		estimated := ps.countInputTokens(ctx, channelHandler.(channel.TokenCounter), req, apiKey, finalBodyBytes, group)
+		if apiErr := checkInputTokenLimit(group, estimated); apiErr != nil {
+			response.Error(c, apiErr)
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, apiErr.HTTPStatus, apiErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	// Signing covers the headers, so it comes after every other change to the request.
+	if signer, ok := channelHandler.(channel.RequestSigner); ok {
+		if err := signer.SignRequest(req, apiKey, group); err != nil {
+			ps.markPrepareFailure(apiKey, group, err)
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to sign upstream request: %v", err)))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusInternalServerError, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	utils.LogRequestLifecycle(c.Request.Context(), group, false, logrus.Fields{"attempt": retryCount + 1, "method": req.Method, "upstream_path": req.URL.Path, "upstream_host": req.URL.Host}, "Upstream request prepared")
+
+	client := channelHandler.ClientForKey(apiKey, isStream)
 	if isStream {
-		client = channelHandler.GetStreamClient()
 		req.Header.Set("X-Accel-Buffering", "no")
-	} else {
-		client = channelHandler.GetHTTPClient()
 	}
 
-	resp, err := client.Do(req)
+	upstreamCtx, upstreamSpan := tracing.Start(ctx, "proxy.upstream", tracing.KindClient,
+		tracing.String("group", group.Name), tracing.String("channel_type", group.ChannelType),
+		tracing.String("key_id", tracing.HashKeyID(apiKey.ID)), tracing.Int("attempt", retryCount+1))
+	tracing.Inject(upstreamCtx, req.Header)
+	upstreamStart := time.Now()
+	resp, err := sendUpstream(client, req)
+	upstreamSpan.RecordError(err)
+	if accessRecord := accessLogFrom(c); accessRecord != nil {
+		accessRecord.Attempts = retryCount + 1
+		accessRecord.UpstreamStatus = 0
+		if resp != nil {
+			accessRecord.UpstreamStatus = resp.StatusCode
+		}
+	}
 	if resp != nil {
 		defer resp.Body.Close()
+		metrics.ObserveUpstream(group.Name, group.ChannelType, resp.StatusCode, time.Since(upstreamStart))
+		upstreamSpan.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+	} else {
+		metrics.ObserveUpstream(group.Name, group.ChannelType, 0, time.Since(upstreamStart))
+	}
+	upstreamSpan.End()
+	if clientDeadlineExceeded(ctx, overridden, err) {
+		ps.failClientDeadline(c, originalGroup, group, apiKey, startTime, timeout, isStream, upstreamURL, channelHandler, bodyBytes)
+		return
+	}
+	if reporter, ok := channelHandler.(channel.UpstreamHealthReporter); ok && (err == nil || !app_errors.IsIgnorableError(err)) {
+		reporter.ReportUpstreamResult(upstreamURL, err == nil && resp.StatusCode < http.StatusInternalServerError)
+	}
+
+	// A method the model does not support may still be served through another endpoint of the upstream;
+	// the request log then records the endpoint that served it.
+	if err == nil && resp.StatusCode >= 400 {
+		if fallbackResp, fallbackURL := endpointFallback(c, channelHandler, client, req, finalBodyBytes, group, resp); fallbackResp != nil {
+			defer fallbackResp.Body.Close()
+			resp, upstreamURL = fallbackResp, fallbackURL
+			if accessRecord := accessLogFrom(c); accessRecord != nil {
+				accessRecord.UpstreamStatus = resp.StatusCode
+			}
+		}
 	}
 
 	// Unified error handling for retries. Exclude 404 from being a retryable error.
@@ -233,9 +598,14 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		var statusCode int
 		var errorMessage string
 		var parsedError string
+		var retryAfter time.Duration
 
 		if err != nil {
 			statusCode = 500
+			if phase := app_errors.TimeoutPhase(err); phase != "" {
+				statusCode = http.StatusGatewayTimeout
+				logrus.WithFields(logrus.Fields{"group": group.Name, "key": utils.MaskAPIKey(apiKey.KeyValue), "timeout": phase}).Warn("Request timed out waiting for upstream")
+			}
 			errorMessage = err.Error()
 			parsedError = errorMessage
 			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
@@ -249,16 +619,30 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			}
 
 			errorBody = handleGzipCompression(resp, errorBody)
+			if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+				resp.Body = io.NopCloser(bytes.NewReader(errorBody))
+				retryAfter = channelHandler.ParseRetryAfter(resp)
+			}
 			errorMessage = string(errorBody)
 			parsedError = app_errors.ParseUpstreamError(errorBody)
 			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
 		}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
+		logUpstreamHeaders(group, req, resp, retryCount+1)
+		if observer, ok := channelHandler.(channel.UpstreamResponseObserver); ok && resp != nil {
+			observer.ObserveUpstreamResponse(req, apiKey, group, resp)
+		}
+
+		// 使用解析后的错误信息更新密钥状态；上游给出重试时间的限流错误只让密钥暂停轮换
+		if retryAfter > 0 {
+			ps.keyProvider.CooldownKey(apiKey, group, retryAfter)
+		} else {
+			ps.keyProvider.UpdateStatus(apiKey, group, false, statusCode, parsedError)
+		}
+		utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 1, "key": utils.MaskAPIKey(apiKey.KeyValue), "status": statusCode, "error": parsedError}, "Upstream request failed")
 
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
+		// 判断是否为最后一次尝试；不可重试的状态码直接返回给客户端
+		isLastAttempt := retryCount >= cfg.MaxRetries || !isRetryableFailure(group, isStream, err, statusCode)
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
@@ -268,6 +652,7 @@ func (ps *ProxyServer) executeRequestWithRetry(
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
+			forwardPassthroughHeaders(c, resp, group)
 			var errorJSON map[string]any
 			if err := json.Unmarshal([]byte(errorMessage), &errorJSON); err == nil {
 				c.JSON(statusCode, errorJSON)
@@ -277,12 +662,21 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
+		utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 2, "max_retries": cfg.MaxRetries}, "Retrying request with another key")
+		releaseKey()
 		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
 		return
 	}
 
 	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
 	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	utils.LogRequestLifecycle(c.Request.Context(), group, false, logrus.Fields{"attempt": retryCount + 1, "status": resp.StatusCode, "stream": isStream}, "Upstream responded")
+
+	if apiErr := limitResponseBody(resp, group, isStream); apiErr != nil {
+		response.Error(c, apiErr)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, apiErr.HTTPStatus, apiErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+		return
+	}
 
 	// Check if this is a model list request (needs special handling)
 	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
@@ -296,15 +690,46 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		c.Status(resp.StatusCode)
 
 		if isStream {
-			ps.handleStreamingResponse(c, resp)
-		} else {
-			ps.handleNormalResponse(c, resp)
+			var interruption *streamInterruption
+			streamErr := ps.handleStreamingResponse(c, resp, group)
+			if errors.As(streamErr, &interruption) && !app_errors.IsIgnorableError(interruption.err) {
+				// Nothing reached the client yet, so the request can still be retried from the start, unless the
+				// client's own timeout ended it.
+				if c.Writer.Size() <= 0 && cfg.RetryStreamRequests && retryCount < cfg.MaxRetries && !errors.Is(interruption.err, errResponseTooLarge) && !clientDeadlineExceeded(ctx, overridden, interruption.err) {
+					ps.keyProvider.UpdateStatus(apiKey, group, false, 0, interruption.Error())
+					ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadGateway, interruption, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeRetry)
+					for key := range resp.Header {
+						c.Writer.Header().Del(key)
+					}
+					utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"attempt": retryCount + 2, "max_retries": cfg.MaxRetries}, "Retrying interrupted stream with another key")
+					releaseKey()
+					ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
+					return
+				}
+				writeStreamInterruption(c, interruption)
+				ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, interruption, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+				return
+			}
+			if streamErr == nil {
+				markResponseComplete(c)
+			}
+		} else if err := ps.handleNormalResponse(c, resp, group); err == nil {
+			markResponseComplete(c)
 		}
 	}
 
 	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 }
 
+// failClientDeadline ends a request whose timeout, set by the client with requestTimeoutHeader, expired.
+// The key and the upstream are not blamed and the request is not retried.
+func (ps *ProxyServer) failClientDeadline(c *gin.Context, originalGroup, group *models.Group, apiKey *models.APIKey, startTime time.Time, timeout time.Duration, isStream bool, upstreamURL string, channelHandler channel.ChannelProxy, bodyBytes []byte) {
+	err := clientDeadlineError(timeout)
+	utils.LogRequestLifecycle(c.Request.Context(), group, true, logrus.Fields{"key": utils.MaskAPIKey(apiKey.KeyValue), "timeout": timeout}, "Request exceeded the client's timeout")
+	response.Error(c, app_errors.NewAPIErrorWithUpstream(http.StatusGatewayTimeout, "UPSTREAM_ERROR", err.Error()))
+	ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusGatewayTimeout, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+}
+
 // logRequest is a helper function to create and record a request log.
 func (ps *ProxyServer) logRequest(
 	c *gin.Context,
@@ -324,6 +749,12 @@ func (ps *ProxyServer) logRequest(
 		return
 	}
 
+	// Shadow requests are logged like client requests, under their own type.
+	isFinal := requestType == models.RequestTypeFinal
+	if isFinal && isShadowRequest(c) {
+		requestType = models.RequestTypeShadow
+	}
+
 	var requestBodyToLog, userAgent string
 
 	if group.EffectiveConfig.EnableRequestBodyLogging {
@@ -375,7 +806,57 @@ func (ps *ProxyServer) logRequest(
 		logEntry.ErrorMessage = finalError.Error()
 	}
 
+	if recorder := usageRecorderFrom(c); recorder != nil && isFinal && logEntry.IsSuccess {
+		usage := recorder.finish()
+		logEntry.PromptTokens = usage.PromptTokens
+		logEntry.CompletionTokens = usage.CompletionTokens
+		logEntry.Cost = ps.estimateCost(c, logEntry.Model, usage)
+	}
+
+	var keyID uint
+	if apiKey != nil {
+		keyID = apiKey.ID
+	}
+	metrics.ObserveRequest(group.Name, group.ChannelType, logEntry.Model, keyID, statusCode, requestType, isFinal, time.Since(startTime))
+
+	// Settings are validated on save, so a parse error here can only come from stale data; record everything then.
+	if fields, err := utils.ParseRequestLogFields(group.EffectiveConfig.RequestLogFields); err == nil && fields != nil {
+		omitUnselectedLogFields(logEntry, fields)
+	}
+
 	if err := ps.requestLogService.Record(logEntry); err != nil {
 		logrus.Errorf("Failed to record request log: %v", err)
 	}
 }
+
+// omitUnselectedLogFields clears the optional request log fields that are not in the group's selection.
+func omitUnselectedLogFields(logEntry *models.RequestLog, fields map[string]bool) {
+	if !fields["model"] {
+		logEntry.Model = ""
+	}
+	if !fields["key"] {
+		logEntry.KeyValue = ""
+		logEntry.KeyHash = ""
+	}
+	if !fields["source_ip"] {
+		logEntry.SourceIP = ""
+	}
+	if !fields["request_path"] {
+		logEntry.RequestPath = ""
+	}
+	if !fields["duration"] {
+		logEntry.Duration = 0
+	}
+	if !fields["error_message"] {
+		logEntry.ErrorMessage = ""
+	}
+	if !fields["user_agent"] {
+		logEntry.UserAgent = ""
+	}
+	if !fields["upstream_addr"] {
+		logEntry.UpstreamAddr = ""
+	}
+	if !fields["request_body"] {
+		logEntry.RequestBody = ""
+	}
+}
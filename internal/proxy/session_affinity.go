@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// sessionAffinityTTL is how long a session stays bound to its key after its last request.
+const sessionAffinityTTL = time.Hour
+
+// selectAffinityKey keeps all requests of a session, identified by the group's session_affinity_header,
+// on the same key so upstream context caches are reused. When the bound key is unusable, or reassign asks
+// for another key (a retry, or a bound key that is at its concurrency limit), a key is picked by the
+// normal selection and the session is rebound to it. Keys always need the request's required key tags.
+func (ps *ProxyServer) selectAffinityKey(c *gin.Context, group *models.Group, reassign bool) (*models.APIKey, error) {
+	header := group.EffectiveConfig.SessionAffinityHeader
+	sessionID := ""
+	if header != "" {
+		sessionID = strings.TrimSpace(c.GetHeader(header))
+	}
+	requiredTags := requiredKeyTags(c, group)
+	if sessionID == "" {
+		return ps.keyProvider.SelectKeyWithTags(group, requiredTags)
+	}
+
+	sum := sha256.Sum256([]byte(sessionID))
+	affinityKey := fmt.Sprintf("affinity:%d:%s", group.ID, hex.EncodeToString(sum[:16]))
+
+	if !reassign {
+		if data, err := ps.store.Get(affinityKey); err == nil {
+			if keyID, err := strconv.ParseUint(string(data), 10, 64); err == nil {
+				apiKey, usable, err := ps.keyProvider.GetForcedKey(group.ID, uint(keyID))
+				if err == nil && usable && keypool.KeyHasTags(apiKey.Tags, requiredTags) {
+					ps.bindSession(affinityKey, apiKey.ID)
+					return apiKey, nil
+				}
+				logrus.WithFields(logrus.Fields{"group": group.Name, "key_id": keyID}).
+					Debug("Session key is no longer usable, reassigning the session")
+			}
+		}
+	}
+
+	apiKey, err := ps.keyProvider.SelectKeyWithTags(group, requiredTags)
+	if err != nil {
+		return nil, err
+	}
+	ps.bindSession(affinityKey, apiKey.ID)
+	return apiKey, nil
+}
+
+// bindSession (re)binds a session to a key and extends the binding's lifetime.
+func (ps *ProxyServer) bindSession(affinityKey string, keyID uint) {
+	if err := ps.store.Set(affinityKey, []byte(strconv.FormatUint(uint64(keyID), 10)), sessionAffinityTTL); err != nil {
+		logrus.WithError(err).Warn("Failed to record session key affinity")
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxShadowRequests bounds the shadow requests in flight. Requests beyond it are not shadowed.
+const maxShadowRequests = 64
+
+// shadowContextKey marks the context of a shadow request.
+const shadowContextKey = "shadow_request"
+
+// isShadowRequest reports whether the request is a shadow copy of a client request.
+func isShadowRequest(c *gin.Context) bool {
+	return c.GetBool(shadowContextKey)
+}
+
+// discardWriter is the response writer of shadow requests. Everything written to it is dropped.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header               { return w.header }
+func (w *discardWriter) Write(data []byte) (int, error)    { return len(data), nil }
+func (w *discardWriter) WriteString(s string) (int, error) { return len(s), nil }
+func (w *discardWriter) WriteHeader(int)                   {}
+func (w *discardWriter) Flush()                            {}
+
+// shadowRequest sends a copy of the request to the group's shadow_group for shadow_percentage of requests.
+// The copy runs in the background through the shadow group's own pipeline with its keys, retries and
+// request logs, the latter typed "shadow"; its response is discarded. Nothing about it reaches the client.
+func (ps *ProxyServer) shadowRequest(c *gin.Context, group *models.Group, bodyBytes []byte) {
+	cfg := group.EffectiveConfig
+	shadowName := strings.TrimSpace(cfg.ShadowGroup)
+	if shadowName == "" || shadowName == group.Name || cfg.ShadowPercentage <= 0 || isShadowRequest(c) {
+		return
+	}
+	if cfg.ShadowPercentage < 100 && rand.Intn(100) >= cfg.ShadowPercentage {
+		return
+	}
+
+	select {
+	case ps.shadowSlots <- struct{}{}:
+	default:
+		logrus.WithField("group", group.Name).Debug("Too many shadow requests in flight, skipping shadow copy")
+		return
+	}
+
+	// The gin context is reused once the client request ends, so the copy takes what it needs now.
+	path := "/proxy/" + shadowName + strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+group.Name)
+	url := *c.Request.URL
+	url.Path, url.RawPath = path, ""
+	header := c.Request.Header.Clone()
+	header.Del(forceKeyHeader)
+	header.Del(responseCacheHeader)
+	body := bytes.Clone(bodyBytes)
+	ctx := context.WithoutCancel(c.Request.Context())
+	method, remoteAddr := c.Request.Method, c.Request.RemoteAddr
+
+	go func() {
+		defer func() { <-ps.shadowSlots }()
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.WithFields(logrus.Fields{"group": group.Name, "shadow_group": shadowName, "panic": r}).Error("Shadow request panicked")
+			}
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, method, url.String(), bytes.NewReader(body))
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to create shadow request")
+			return
+		}
+		req.Header = header
+		req.RemoteAddr = remoteAddr
+		ps.serveShadow(req, shadowName, body)
+	}()
+}
+
+// serveShadow runs a shadow request against the shadow group, writing the response nowhere.
+func (ps *ProxyServer) serveShadow(req *http.Request, shadowName string, body []byte) {
+	startTime := time.Now()
+	log := logrus.WithField("shadow_group", shadowName)
+
+	shadowGroup, err := ps.groupManager.GetGroupByName(shadowName)
+	if err != nil {
+		log.WithError(err).Warn("Skipping shadow request for unknown shadow group")
+		return
+	}
+	routedGroup, subGroupName, err := ps.routeGroup(shadowGroup)
+	if err != nil {
+		log.WithError(err).Warn("Skipping shadow request without an available sub-group")
+		return
+	}
+	group := routedGroup
+	if subGroupName != "" {
+		if group, err = ps.groupManager.GetGroupByName(subGroupName); err != nil {
+			log.WithError(err).Warn("Skipping shadow request for unknown sub-group")
+			return
+		}
+	}
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		log.WithError(err).Warn("Skipping shadow request without a channel")
+		return
+	}
+
+	c := gin.CreateTestContextOnly(&discardWriter{header: make(http.Header)}, ps.shadowEngine)
+	c.Request = req
+	c.Params = gin.Params{{Key: "group_name", Value: shadowName}}
+	c.Set(shadowContextKey, true)
+
+	finalBodyBytes, err := ps.applyParamOverrides(body, group)
+	if err != nil {
+		log.WithError(err).Warn("Failed to apply parameter overrides to shadow request")
+		return
+	}
+	isStream := channelHandler.IsStreamRequest(c, body)
+	beginUsageRecording(c, isStream)
+
+	ps.executeRequestWithRetry(c, channelHandler, shadowGroup, group, finalBodyBytes, isStream, startTime, 0)
+	log.WithFields(logrus.Fields{"status": c.Writer.Status(), "duration": time.Since(startTime)}).Debug("Shadow request completed")
+}
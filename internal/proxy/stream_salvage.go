@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// streamInterruptedCode marks the error event sent when the upstream fails mid-stream.
+const streamInterruptedCode = "stream_interrupted"
+
+// streamInterruption is an upstream failure that cut a stream short.
+type streamInterruption struct {
+	err error
+	// midEvent is set when the failure left the client with a partial event.
+	midEvent bool
+}
+
+func (e *streamInterruption) Error() string { return "upstream stream interrupted: " + e.err.Error() }
+
+func (e *streamInterruption) Unwrap() error { return e.err }
+
+// streamTail remembers the last bytes relayed to the client, to tell whether a stream stopped mid-event.
+type streamTail struct {
+	last []byte
+}
+
+func (t *streamTail) observe(data []byte) {
+	t.last = append(t.last, data[max(0, len(data)-4):]...)
+	t.last = t.last[max(0, len(t.last)-4):]
+}
+
+// atEventBoundary reports whether the relayed bytes end with a complete event, i.e. a blank line with
+// LF or CRLF line endings.
+func (t *streamTail) atEventBoundary() bool {
+	return len(t.last) == 0 || bytes.HasSuffix(t.last, []byte("\n\n")) || bytes.HasSuffix(t.last, []byte("\r\n\r\n"))
+}
+
+// interruption reports a failure reading the upstream stream. A timeout reading the body is an upstream timeout.
+func (t *streamTail) interruption(err error) *streamInterruption {
+	return &streamInterruption{err: app_errors.WrapTimeout(app_errors.TimeoutTagUpstream, err), midEvent: !t.atEventBoundary()}
+}
+
+// writeStreamInterruption ends an interrupted SSE stream cleanly: a partial event is terminated, then an
+// "error" event carrying streamInterruptedCode follows, and OpenAI-style streams get their "[DONE]" marker.
+// Streams in other framings are left as they are.
+func writeStreamInterruption(c *gin.Context, interruption *streamInterruption) {
+	if c.Writer.Header().Get("Content-Type") != "text/event-stream" {
+		return
+	}
+
+	payload, err := json.Marshal(gin.H{
+		"type": "error",
+		"error": gin.H{
+			"type":    "upstream_error",
+			"code":    streamInterruptedCode,
+			"message": interruption.Error(),
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal stream interruption event")
+		return
+	}
+
+	var event strings.Builder
+	if interruption.midEvent {
+		event.WriteString("\n\n")
+	}
+	fmt.Fprintf(&event, "event: error\ndata: %s\n\n", payload)
+	if usesDoneMarker(c.Request.URL.Path) {
+		event.WriteString("data: [DONE]\n\n")
+	}
+
+	if _, err := c.Writer.WriteString(event.String()); err != nil {
+		logUpstreamError("writing stream interruption to client", err)
+		return
+	}
+	if flusher, ok := c.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// usesDoneMarker reports whether streams of the path end with "data: [DONE]", as OpenAI completions do.
+func usesDoneMarker(path string) bool {
+	return strings.HasSuffix(path, "/completions")
+}
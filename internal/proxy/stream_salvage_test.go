@@ -0,0 +1,38 @@
+package proxy
+
+import "testing"
+
+func TestStreamTailAtEventBoundary(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   bool
+	}{
+		{"nothing relayed", nil, true},
+		{"LF event", []string{"data: {}\n\n"}, true},
+		{"CRLF event", []string{"data: {}\r\n\r\n"}, true},
+		{"mid event", []string{"data: {\"a\":"}, false},
+		{"single line ending", []string{"data: {}\n"}, false},
+		{"single CRLF line ending", []string{"data: {}\r\n"}, false},
+		{"CR only", []string{"data: {}\r\r"}, false},
+		{"LF boundary split across writes", []string{"data: {}\n", "\n"}, true},
+		{"CRLF boundary split across writes", []string{"data: {}\r\n\r", "\n"}, true},
+		{"CRLF boundary split byte by byte", []string{"data: {}", "\r", "\n", "\r", "\n"}, true},
+		{"new event after boundary", []string{"data: {}\n\n", "data"}, false},
+		{"empty writes keep the tail", []string{"data: {}\r\n\r\n", "", ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tail streamTail
+			for _, write := range tt.writes {
+				tail.observe([]byte(write))
+			}
+			if got := tail.atEventBoundary(); got != tt.want {
+				t.Errorf("atEventBoundary = %v, want %v (tail %q)", got, tt.want, tail.last)
+			}
+			if len(tail.last) > 4 {
+				t.Errorf("tail keeps %d bytes, want at most 4", len(tail.last))
+			}
+		})
+	}
+}
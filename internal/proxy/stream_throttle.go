@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"gpt-load/internal/models"
+)
+
+// streamTextFields are the payload fields carrying generated text in OpenAI, Anthropic and Gemini stream events.
+var streamTextFields = map[string]bool{
+	"content":           true,
+	"text":              true,
+	"reasoning_content": true,
+	"thinking":          true,
+	"arguments":         true,
+	"partial_json":      true,
+}
+
+// streamThrottle paces a stream to an output token rate. The first event is sent right away; every later
+// event waits until the tokens sent before it fit the rate since the stream started.
+type streamThrottle struct {
+	tokensPerSecond float64
+	start           time.Time
+	sent            int
+}
+
+// newStreamThrottle returns the group's stream throttle, or nil if output tokens are not rate limited.
+func newStreamThrottle(group *models.Group) *streamThrottle {
+	rate := group.EffectiveConfig.StreamTokensPerSecond
+	if rate <= 0 {
+		return nil
+	}
+	return &streamThrottle{tokensPerSecond: float64(rate), start: time.Now()}
+}
+
+// wait blocks until the SSE line may be sent without exceeding the rate, and accounts for its tokens.
+// Only "data:" lines carry tokens, so the rest of an event's lines pass straight through.
+func (t *streamThrottle) wait(ctx context.Context, line []byte) error {
+	tokens := estimateStreamTokens(line)
+	if tokens == 0 {
+		return nil
+	}
+
+	due := t.start.Add(time.Duration(float64(t.sent) / t.tokensPerSecond * float64(time.Second)))
+	t.sent += tokens
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// estimateStreamTokens estimates the output tokens of an SSE "data:" line from its generated text,
+// assuming roughly four characters per token like the input estimate.
+func estimateStreamTokens(line []byte) int {
+	data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return 0
+	}
+	var payload any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &payload); err != nil {
+		return 0
+	}
+	return (streamTextChars(payload, false) + 3) / 4
+}
+
+// streamTextChars sums the rune length of the strings found under the generated text fields.
+func streamTextChars(value any, isText bool) int {
+	switch v := value.(type) {
+	case string:
+		if isText {
+			return utf8.RuneCountInString(v)
+		}
+		return 0
+	case []any:
+		total := 0
+		for _, item := range v {
+			total += streamTextChars(item, isText)
+		}
+		return total
+	case map[string]any:
+		total := 0
+		for key, item := range v {
+			total += streamTextChars(item, isText || streamTextFields[key])
+		}
+		return total
+	default:
+		return 0
+	}
+}
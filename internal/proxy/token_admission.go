@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// countTokensCacheTTL is how long upstream token counts are reused for identical request bodies.
+const countTokensCacheTTL = 10 * time.Minute
+
+// usesUpstreamTokenCount reports whether input admission for the group is decided by an upstream countTokens call.
+func usesUpstreamTokenCount(group *models.Group, channelHandler channel.ChannelProxy) bool {
+	if group.EffectiveConfig.InputTokenEstimation != channel.TokenEstimationCountTokens {
+		return false
+	}
+	_, ok := channelHandler.(channel.TokenCounter)
+	return ok
+}
+
+// checkInputTokenLimit returns an error if the estimated input tokens exceed the group's limit.
+func checkInputTokenLimit(group *models.Group, estimated int) *app_errors.APIError {
+	limit := group.EffectiveConfig.MaxInputTokens
+	if limit <= 0 || estimated <= limit {
+		return nil
+	}
+	return app_errors.NewAPIError(app_errors.ErrInputTooLarge, fmt.Sprintf("Estimated input tokens %d exceed the limit of %d for group '%s'", estimated, limit, group.Name))
+}
+
+// countInputTokens asks the upstream for the input token count, reusing cached counts for identical bodies.
+// It falls back to the local heuristic if the upstream call fails.
+func (ps *ProxyServer) countInputTokens(ctx context.Context, counter channel.TokenCounter, req *http.Request, apiKey *models.APIKey, bodyBytes []byte, group *models.Group) int {
+	sum := sha256.Sum256(append([]byte(req.URL.Path+"\n"), bodyBytes...))
+	cacheKey := fmt.Sprintf("count_tokens:%d:%s", group.ID, hex.EncodeToString(sum[:]))
+
+	if cached, err := ps.store.Get(cacheKey); err == nil {
+		if count, err := strconv.Atoi(string(cached)); err == nil {
+			return count
+		}
+	}
+
+	count, err := counter.CountTokens(ctx, req, apiKey, bodyBytes)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Debug("Failed to count input tokens upstream, using local estimate")
+		return channel.EstimateInputTokens(bodyBytes)
+	}
+
+	if err := ps.store.Set(cacheKey, []byte(strconv.Itoa(count)), countTokensCacheTTL); err != nil {
+		logrus.WithError(err).Debug("Failed to cache input token count")
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+)
+
+func TestInputTokenLimit(t *testing.T) {
+	// 40 characters of message text including the role, estimated as 10 tokens.
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"` + strings.Repeat("a", 36) + `"}]}`
+
+	tests := []struct {
+		name    string
+		limit   int
+		wantErr bool
+	}{
+		{name: "no limit", limit: 0},
+		{name: "under the cap", limit: 11},
+		{name: "at the cap", limit: 10},
+		{name: "over the cap", limit: 9, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: "g"}
+			group.EffectiveConfig.MaxInputTokens = tt.limit
+
+			apiErr := checkInputTokenLimit(group, channel.EstimateInputTokens([]byte(body)))
+			if !tt.wantErr {
+				if apiErr != nil {
+					t.Errorf("checkInputTokenLimit = %v, want the request admitted", apiErr)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatal("request over the cap admitted")
+			}
+			if apiErr.HTTPStatus != http.StatusBadRequest || apiErr.Code != "INPUT_TOO_LARGE" {
+				t.Errorf("error = %d %s, want 400 INPUT_TOO_LARGE", apiErr.HTTPStatus, apiErr.Code)
+			}
+			if want := "Estimated input tokens 10 exceed the limit of 9 for group 'g'"; apiErr.Message != want {
+				t.Errorf("message = %q, want %q", apiErr.Message, want)
+			}
+		})
+	}
+}
+
+type fakeTokenCounter struct {
+	count int
+	err   error
+	calls int
+}
+
+func (f *fakeTokenCounter) CountTokens(ctx context.Context, req *http.Request, apiKey *models.APIKey, bodyBytes []byte) (int, error) {
+	f.calls++
+	return f.count, f.err
+}
+
+func TestCountInputTokens(t *testing.T) {
+	body := []byte(`{"contents":[{"parts":[{"text":"` + strings.Repeat("a", 40) + `"}]}]}`)
+	group := &models.Group{ID: 1, Name: "g"}
+	group.EffectiveConfig.MaxInputTokens = 100
+	req := httptest.NewRequest(http.MethodPost, "/v1/projects/p/locations/l/publishers/google/models/gemini-2.0-flash:generateContent", nil)
+
+	t.Run("upstream count decides and is cached", func(t *testing.T) {
+		ps := &ProxyServer{store: store.NewMemoryStore()}
+		counter := &fakeTokenCounter{count: 150}
+		for range 2 {
+			count := ps.countInputTokens(context.Background(), counter, req, nil, body, group)
+			if count != 150 {
+				t.Fatalf("count = %d, want the upstream count 150", count)
+			}
+			if apiErr := checkInputTokenLimit(group, count); apiErr == nil {
+				t.Error("request over the cap by upstream count admitted")
+			}
+		}
+		if counter.calls != 1 {
+			t.Errorf("upstream counted %d times, want the second count served from the cache", counter.calls)
+		}
+	})
+
+	t.Run("falls back to the local estimate", func(t *testing.T) {
+		ps := &ProxyServer{store: store.NewMemoryStore()}
+		counter := &fakeTokenCounter{err: errors.New("countTokens unavailable")}
+		count := ps.countInputTokens(context.Background(), counter, req, nil, body, group)
+		if count != 10 {
+			t.Errorf("count = %d, want the local estimate 10", count)
+		}
+		if apiErr := checkInputTokenLimit(group, count); apiErr != nil {
+			t.Errorf("request under the cap by estimate rejected: %v", apiErr)
+		}
+	})
+}
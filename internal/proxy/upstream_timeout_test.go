@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// silentListener accepts TCP connections and never answers on them.
+func silentListener(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	return listener
+}
+
+func TestSendUpstreamTagsTimeoutsBeforeTheResponse(t *testing.T) {
+	release := make(chan struct{})
+	slowHeaders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slowHeaders.Close()
+	defer close(release)
+	silent := silentListener(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		config httpclient.Config
+	}{
+		{
+			name:   "connect",
+			url:    "http://" + silent.Addr().String(),
+			config: httpclient.Config{ConnectTimeout: time.Nanosecond},
+		},
+		{
+			name:   "TLS handshake",
+			url:    "https://" + silent.Addr().String(),
+			config: httpclient.Config{ConnectTimeout: time.Second, TLSHandshakeTimeout: 50 * time.Millisecond},
+		},
+		{
+			name:   "response headers",
+			url:    slowHeaders.URL,
+			config: httpclient.Config{ConnectTimeout: time.Second, ResponseHeaderTimeout: 50 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := httpclient.NewHTTPClientManager().GetClient(&tt.config)
+			req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			req.RequestURI = ""
+
+			resp, err := sendUpstream(client, req)
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("request succeeded, want a timeout")
+			}
+			if phase := app_errors.TimeoutPhase(err); phase != app_errors.TimeoutTagUpstream {
+				t.Errorf("timeout phase of %v = %q, want %q", err, phase, app_errors.TimeoutTagUpstream)
+			}
+		})
+	}
+}
+
+func TestSendUpstreamLeavesOtherErrorsUntagged(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := httpclient.NewHTTPClientManager().GetClient(&httpclient.Config{ConnectTimeout: time.Second})
+	req := httptest.NewRequest(http.MethodPost, "http://"+addr, nil)
+	req.RequestURI = ""
+	resp, err := sendUpstream(client, req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request to a closed port succeeded")
+	}
+	if phase := app_errors.TimeoutPhase(err); phase != "" {
+		t.Errorf("refused connection tagged %q, want no timeout phase", phase)
+	}
+}
+
+func TestResponseBodyTimeoutIsTagged(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {}\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	for _, isStream := range []bool{true, false} {
+		name := "non-streaming"
+		if isStream {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			client := httpclient.NewHTTPClientManager().GetClient(&httpclient.Config{ConnectTimeout: time.Second, RequestTimeout: 100 * time.Millisecond})
+			req := httptest.NewRequest(http.MethodPost, upstream.URL, nil)
+			req.RequestURI = ""
+			resp, err := sendUpstream(client, req)
+			if err != nil {
+				t.Fatalf("sendUpstream: %v", err)
+			}
+			defer resp.Body.Close()
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/chat/completions", nil)
+			ps := &ProxyServer{}
+			if isStream {
+				err = ps.handleStreamingResponse(c, resp, &models.Group{Name: "g"})
+				var interruption *streamInterruption
+				if !errors.As(err, &interruption) {
+					t.Fatalf("handleStreamingResponse error = %v, want a stream interruption", err)
+				}
+			} else {
+				err = ps.handleNormalResponse(c, resp, &models.Group{Name: "g"})
+			}
+			if phase := app_errors.TimeoutPhase(err); phase != app_errors.TimeoutTagUpstream {
+				t.Errorf("timeout phase of %v = %q, want %q", err, phase, app_errors.TimeoutTagUpstream)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUsageBodySize bounds how much of a non-streaming response is buffered to read its usage block.
+const maxUsageBodySize = 16 << 20
+
+// usageRecorderContextKey stores the usage recorder of a request in the gin context.
+const usageRecorderContextKey = "usage_recorder"
+
+// tokenUsage is the token consumption reported by an upstream response.
+type tokenUsage struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+}
+
+// Total returns the total token count, summing prompt and completion tokens if no total was reported.
+func (u tokenUsage) Total() int64 {
+	if u.TotalTokens > 0 {
+		return u.TotalTokens
+	}
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// merge reads the usage block of an OpenAI ("usage"), Anthropic ("usage", "message.usage") or Gemini
+// ("usageMetadata") payload. Streamed counts are cumulative, so later non-zero values win.
+func (u *tokenUsage) merge(payload map[string]any) {
+	if message, ok := payload["message"].(map[string]any); ok {
+		u.merge(message)
+	}
+	if usage, ok := payload["usage"].(map[string]any); ok {
+		setTokenCount(&u.PromptTokens, usage, "prompt_tokens", "input_tokens")
+		setTokenCount(&u.CompletionTokens, usage, "completion_tokens", "output_tokens")
+		setTokenCount(&u.TotalTokens, usage, "total_tokens")
+	}
+	if usage, ok := payload["usageMetadata"].(map[string]any); ok {
+		setTokenCount(&u.PromptTokens, usage, "promptTokenCount")
+		setTokenCount(&u.CompletionTokens, usage, "candidatesTokenCount")
+		setTokenCount(&u.TotalTokens, usage, "totalTokenCount")
+	}
+}
+
+func setTokenCount(target *int64, usage map[string]any, fields ...string) {
+	for _, field := range fields {
+		if value, ok := usage[field].(float64); ok && value > 0 {
+			*target = int64(value)
+			return
+		}
+	}
+}
+
+// usageRecorder tees the response sent to the client and extracts its token usage without altering it.
+// Streams are scanned event by event, in native SSE as well as OpenAI-style framing ending in "[DONE]",
+// since upstreams such as Vertex report usage only in the final chunks; other responses are parsed once
+// complete.
+type usageRecorder struct {
+	gin.ResponseWriter
+	stream   bool
+	buf      bytes.Buffer
+	overflow bool
+	usage    tokenUsage
+	finished bool
+}
+
+// beginUsageRecording starts extracting the token usage of the request's response.
+func beginUsageRecording(c *gin.Context, isStream bool) *usageRecorder {
+	recorder := &usageRecorder{ResponseWriter: c.Writer, stream: isStream}
+	c.Writer = recorder
+	c.Set(usageRecorderContextKey, recorder)
+	return recorder
+}
+
+// usageRecorderFrom returns the usage recorder of the request, or nil if its usage is not recorded.
+func usageRecorderFrom(c *gin.Context) *usageRecorder {
+	if value, ok := c.Get(usageRecorderContextKey); ok {
+		recorder, _ := value.(*usageRecorder)
+		return recorder
+	}
+	return nil
+}
+
+func (r *usageRecorder) Write(data []byte) (int, error) {
+	r.observe(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *usageRecorder) WriteString(s string) (int, error) {
+	r.observe([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+func (r *usageRecorder) observe(data []byte) {
+	if !r.stream {
+		if r.overflow || r.buf.Len()+len(data) > maxUsageBodySize {
+			r.overflow = true
+			r.buf.Reset()
+			return
+		}
+		r.buf.Write(data)
+		return
+	}
+
+	r.buf.Write(data)
+	for {
+		line, err := r.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write.
+			rest := bytes.Clone(line)
+			r.buf.Reset()
+			r.buf.Write(rest)
+			return
+		}
+		r.observeLine(line)
+	}
+}
+
+// observeLine parses an SSE data line that may carry usage; "[DONE]" markers and other lines are skipped.
+func (r *usageRecorder) observeLine(line []byte) {
+	data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok || !bytes.Contains(data, []byte("usage")) {
+		return
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &payload); err == nil {
+		r.usage.merge(payload)
+	}
+}
+
+// finish returns the usage found in the response. It may be called more than once.
+func (r *usageRecorder) finish() tokenUsage {
+	if r.finished {
+		return r.usage
+	}
+	r.finished = true
+
+	if r.stream {
+		if r.buf.Len() > 0 {
+			r.observeLine(r.buf.Bytes())
+			r.buf.Reset()
+		}
+		return r.usage
+	}
+
+	var payload map[string]any
+	if !r.overflow && json.Unmarshal(r.buf.Bytes(), &payload) == nil {
+		r.usage.merge(payload)
+	}
+	r.buf.Reset()
+	return r.usage
+}
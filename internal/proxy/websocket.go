@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebSocketErrorBodySize bounds how much of a rejected handshake's response body is read.
+const maxWebSocketErrorBodySize = 64 << 10
+
+// isWebSocketUpgrade reports whether the client asks to switch the connection to the WebSocket protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleWebSocket relays a WebSocket connection to the group's upstream. Keys are selected, and failed
+// handshakes counted against them and retried with another key, as for HTTP requests. Once the upstream
+// accepts the upgrade, frames are copied unchanged in both directions until either side closes.
+func (ps *ProxyServer) handleWebSocket(c *gin.Context, channelHandler channel.ChannelProxy, originalGroup *models.Group, group *models.Group, startTime time.Time) {
+	wsChannel, ok := channelHandler.(channel.WebSocketProxy)
+	if !ok || !wsChannel.SupportsWebSocket(strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+originalGroup.Name)) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("WebSocket connections to '%s' are not supported by group '%s'", c.Request.URL.Path, group.Name)))
+		return
+	}
+	if _, ok := c.Writer.(http.Hijacker); !ok {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "WebSocket connections are not supported by the server"))
+		return
+	}
+
+	cfg := group.EffectiveConfig
+	for attempt := 0; ; attempt++ {
+		apiKey, releaseKey, err := ps.selectKeyWithSlot(c, group, attempt)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
+			ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, true, "", channelHandler, nil, models.RequestTypeFinal)
+			return
+		}
+
+		resp, upstreamURL, err := ps.dialWebSocket(c, channelHandler, wsChannel, originalGroup, group, apiKey)
+		if err == nil && resp.StatusCode == http.StatusSwitchingProtocols {
+			logrus.Debugf("WebSocket connection for group %s established on attempt %d with key %s", group.Name, attempt+1, utils.MaskAPIKey(apiKey.KeyValue))
+			relayErr := relayWebSocket(c, resp)
+			releaseKey()
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusSwitchingProtocols, relayErr, true, upstreamURL, channelHandler, nil, models.RequestTypeFinal)
+			return
+		}
+
+		statusCode, errorMessage := http.StatusBadGateway, ""
+		var retryAfter time.Duration
+		var prepareErr *webSocketPrepareError
+		switch {
+		case errors.As(err, &prepareErr):
+			errorMessage = prepareErr.err.Error()
+			ps.markPrepareFailure(apiKey, group, prepareErr.err)
+		case err != nil:
+			errorMessage = err.Error()
+			ps.keyProvider.UpdateStatus(apiKey, group, false, 0, errorMessage)
+		default:
+			statusCode = resp.StatusCode
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebSocketErrorBodySize))
+			resp.Body.Close()
+			errorMessage = string(body)
+			if errorMessage == "" {
+				errorMessage = fmt.Sprintf("upstream refused the WebSocket upgrade with status %d", statusCode)
+			}
+			if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+				retryAfter = channelHandler.ParseRetryAfter(resp)
+			}
+			if retryAfter > 0 {
+				ps.keyProvider.CooldownKey(apiKey, group, retryAfter)
+			} else {
+				ps.keyProvider.UpdateStatus(apiKey, group, false, statusCode, app_errors.ParseUpstreamError(body))
+			}
+		}
+		releaseKey()
+
+		isLastAttempt := attempt >= cfg.MaxRetries || (prepareErr == nil && !isRetryableFailure(group, false, err, statusCode))
+		requestType := models.RequestTypeRetry
+		if isLastAttempt {
+			requestType = models.RequestTypeFinal
+		}
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(errorMessage), true, upstreamURL, channelHandler, nil, requestType)
+
+		if isLastAttempt {
+			response.Error(c, app_errors.NewAPIErrorWithUpstream(statusCode, "UPSTREAM_ERROR", errorMessage))
+			return
+		}
+		logrus.Debugf("WebSocket handshake for group %s failed on attempt %d, retrying with another key: %s", group.Name, attempt+1, errorMessage)
+	}
+}
+
+// webSocketPrepareError is a failure of the channel to prepare the upgrade request, e.g. to mint a token.
+type webSocketPrepareError struct{ err error }
+
+func (e *webSocketPrepareError) Error() string { return e.err.Error() }
+
+// dialWebSocket sends the client's upgrade request upstream with the key's credentials.
+func (ps *ProxyServer) dialWebSocket(c *gin.Context, channelHandler channel.ChannelProxy, wsChannel channel.WebSocketProxy, originalGroup *models.Group, group *models.Group, apiKey *models.APIKey) (*http.Response, string, error) {
+	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+	if err != nil {
+		return nil, "", &webSocketPrepareError{err: fmt.Errorf("failed to build upstream URL: %w", err)}
+	}
+	target, err := url.Parse(upstreamURL)
+	if err != nil {
+		return nil, upstreamURL, &webSocketPrepareError{err: err}
+	}
+	// The upgrade itself is an HTTP request; the ws and wss schemes only name the protocol switched to.
+	switch target.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	}
+
+	// Unlike other requests the connection has no overall timeout; the transport bounds the handshake.
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, upstreamURL, &webSocketPrepareError{err: err}
+	}
+	req.Header = c.Request.Header.Clone()
+	req.Header.Del("Authorization")
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("X-Goog-Api-Key")
+	req.Header.Del(forceKeyHeader)
+	req.Header.Del(keyTagsHeader)
+	req.Header.Del(requestTimeoutHeader)
+
+	if err := wsChannel.ModifyWebSocketRequest(req, apiKey, group); err != nil {
+		return nil, upstreamURL, &webSocketPrepareError{err: err}
+	}
+	upstreamURL = req.URL.String()
+
+	resp, err := channelHandler.ClientForKey(apiKey, true).Do(req)
+	if err != nil {
+		return nil, upstreamURL, app_errors.WrapTimeout(app_errors.TimeoutTagUpstream, err)
+	}
+	return resp, upstreamURL, nil
+}
+
+// relayWebSocket completes the client's upgrade with the upstream's handshake response and copies bytes in
+// both directions until either side closes the connection.
+func relayWebSocket(c *gin.Context, resp *http.Response) error {
+	upstream, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return errors.New("upstream connection does not support WebSocket relaying")
+	}
+	defer upstream.Close()
+
+	c.Status(http.StatusSwitchingProtocols)
+	clientConn, clientBuf, err := c.Writer.(http.Hijacker).Hijack()
+	if err != nil {
+		return fmt.Errorf("failed to take over client connection: %w", err)
+	}
+	defer clientConn.Close()
+	// Deadlines the server set for the HTTP request would otherwise cut the session short.
+	_ = clientConn.SetDeadline(time.Time{})
+
+	if err := writeSwitchingProtocols(clientBuf.Writer, resp); err != nil {
+		return fmt.Errorf("failed to complete client handshake: %w", err)
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(upstream, clientBuf.Reader)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, upstream)
+		errc <- err
+	}()
+
+	// The first side to finish ends the session; closing both connections releases the other copy.
+	err = <-errc
+	upstream.Close()
+	clientConn.Close()
+	<-errc
+	if err != nil && !app_errors.IsIgnorableError(err) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func writeSwitchingProtocols(w *bufio.Writer, resp *http.Response) error {
+	if _, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode)); err != nil {
+		return err
+	}
+	if err := resp.Header.Write(w); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\r\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"embed"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/middleware"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
@@ -64,7 +65,7 @@ func NewRouter(
 	})
 
 	// 注册路由
-	registerSystemRoutes(router, serverHandler)
+	registerSystemRoutes(router, serverHandler, configManager)
 	registerAPIRoutes(router, serverHandler, configManager)
 	registerProxyRoutes(router, proxyServer, groupManager, serverHandler)
 	registerFrontendRoutes(router, buildFS, indexPage)
@@ -73,8 +74,14 @@ func NewRouter(
 }
 
 // registerSystemRoutes 注册系统级路由
-func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
+func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server, configManager types.ConfigManager) {
 	router.GET("/health", serverHandler.Health)
+
+	metricsConfig := configManager.GetMetricsConfig()
+	metrics.Configure(metricsConfig)
+	if metricsConfig.Enabled {
+		router.GET("/metrics", middleware.Auth(configManager.GetAuthConfig()), metrics.Handler())
+	}
 }
 
 // registerAPIRoutes 注册API路由
@@ -116,6 +123,10 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		groups.PUT("/:id", serverHandler.UpdateGroup)
 		groups.DELETE("/:id", serverHandler.DeleteGroup)
 		groups.GET("/:id/stats", serverHandler.GetGroupStats)
+		groups.GET("/:id/liveness", serverHandler.GetGroupLiveness)
+		groups.GET("/:id/quota", serverHandler.GetGroupQuota)
+		groups.POST("/:id/redirect-preview", serverHandler.PreviewModelRedirect)
+		groups.POST("/:id/models/refresh", serverHandler.RefreshGroupModelList)
 		groups.POST("/:id/copy", serverHandler.CopyGroup)
 
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
@@ -130,8 +141,10 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	{
 		keys.GET("", serverHandler.ListKeysInGroup)
 		keys.GET("/export", serverHandler.ExportKeys)
+		keys.GET("/duplicates", serverHandler.FindDuplicateKeys)
 		keys.POST("/add-multiple", serverHandler.AddMultipleKeys)
 		keys.POST("/add-async", serverHandler.AddMultipleKeysAsync)
+		keys.POST("/import-gcp", serverHandler.ImportGCPKeys)
 		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
 		keys.POST("/delete-async", serverHandler.DeleteMultipleKeysAsync)
 		keys.POST("/restore-multiple", serverHandler.RestoreMultipleKeys)
@@ -141,6 +154,12 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
 		keys.PUT("/:id/notes", serverHandler.UpdateKeyNotes)
+		keys.PUT("/:id/proxy", serverHandler.UpdateKeyProxy)
+		keys.PUT("/:id/weight", serverHandler.UpdateKeyWeight)
+		keys.PUT("/:id/tags", serverHandler.UpdateKeyTags)
+		keys.PUT("/:id/expiry", serverHandler.UpdateKeyExpiry)
+		keys.POST("/:id/drain", serverHandler.DrainKey)
+		keys.GET("/draining", serverHandler.ListDrainingKeys)
 	}
 
 	// Tasks
@@ -152,6 +171,7 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		dashboard.GET("/stats", serverHandler.Stats)
 		dashboard.GET("/chart", serverHandler.Chart)
 		dashboard.GET("/encryption-status", serverHandler.EncryptionStatus)
+		dashboard.GET("/key-pool", serverHandler.KeyPoolStatus)
 	}
 
 	// 日志
@@ -159,6 +179,7 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	{
 		logs.GET("", serverHandler.GetLogs)
 		logs.GET("/export", serverHandler.ExportLogs)
+		logs.GET("/usage", serverHandler.GetUsageSummary)
 	}
 
 	// 设置
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	gcpResourceManagerURL = "https://cloudresourcemanager.googleapis.com/v3"
+	gcpIAMURL             = "https://iam.googleapis.com/v1"
+
+	// maxGCPImportProjects bounds the projects one import walks, and so the keys it creates.
+	maxGCPImportProjects = 1000
+	gcpImportTimeout     = 30 * time.Second
+	// gcpAuditField marks the log entries of GCP key imports, which create credentials in customer projects.
+	gcpAuditField = "gcp_key_import"
+)
+
+// ErrGCPNothingToImport is returned when every project below the parent already has its key in the group.
+var ErrGCPNothingToImport = errors.New("no project needs a new key")
+
+var (
+	gcpParentPattern    = regexp.MustCompile(`^(folders|organizations)/[0-9]+$`)
+	gcpAccountIDPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+)
+
+// GCPKeyImportParams describes a key import from the projects below a GCP folder or organization.
+type GCPKeyImportParams struct {
+	// ServiceAccount is the JSON key of the account used for the import. It needs to list projects and
+	// folders below Parent and to create keys of the imported accounts.
+	ServiceAccount string
+	// Parent is "folders/{id}" or "organizations/{id}". Projects in nested folders are included.
+	Parent string
+	// AccountID names the service account to create a key of in every project: {AccountID}@{project}.iam.gserviceaccount.com.
+	AccountID string
+	// ClientIP is recorded in the audit log.
+	ClientIP string
+}
+
+// GCPImportProject is a project found below the parent.
+type GCPImportProject struct {
+	ProjectID      string `json:"project_id"`
+	ServiceAccount string `json:"service_account"`
+	// Exists is set when the group already holds a key of the service account; no new key is created then.
+	Exists bool `json:"exists"`
+}
+
+// GCPKeyImportPreview lists what an import would do, without creating anything.
+type GCPKeyImportPreview struct {
+	ImportAccount string             `json:"import_account"`
+	Projects      []GCPImportProject `json:"projects"`
+	ToImport      int                `json:"to_import"`
+}
+
+// GCPImportFailure is a project whose key could not be created.
+type GCPImportFailure struct {
+	ProjectID string `json:"project_id"`
+	Error     string `json:"error"`
+}
+
+// GCPKeyImportResult holds the result of a GCP key import task.
+type GCPKeyImportResult struct {
+	KeysCreated    int                `json:"keys_created"`
+	AddedCount     int                `json:"added_count"`
+	IgnoredCount   int                `json:"ignored_count"`
+	DuplicateCount int                `json:"duplicate_count"`
+	Failures       []GCPImportFailure `json:"failures,omitempty"`
+}
+
+// GCPKeyImportService imports service account keys of Vertex groups from the projects of a GCP folder
+// or organization, creating one key per project through the IAM API.
+type GCPKeyImportService struct {
+	TaskService *TaskService
+	KeyService  *KeyService
+	client      *http.Client
+}
+
+// NewGCPKeyImportService creates a new GCPKeyImportService.
+func NewGCPKeyImportService(taskService *TaskService, keyService *KeyService) *GCPKeyImportService {
+	return &GCPKeyImportService{
+		TaskService: taskService,
+		KeyService:  keyService,
+		client:      &http.Client{Timeout: gcpImportTimeout},
+	}
+}
+
+// ValidateParams checks the group and the import parameters.
+func (s *GCPKeyImportService) ValidateParams(group *models.Group, params GCPKeyImportParams) error {
+	if group.ChannelType != "vertex_gemini" {
+		return fmt.Errorf("GCP key import requires a vertex_gemini group")
+	}
+	if !gcpParentPattern.MatchString(params.Parent) {
+		return fmt.Errorf("parent must be folders/{id} or organizations/{id}")
+	}
+	if !gcpAccountIDPattern.MatchString(params.AccountID) {
+		return fmt.Errorf("account_id must be a service account ID of 6 to 30 lowercase letters, digits or hyphens")
+	}
+	if strings.TrimSpace(params.ServiceAccount) == "" {
+		return fmt.Errorf("service_account is required")
+	}
+	return nil
+}
+
+// Preview lists the projects below the parent and whether each would get a new key.
+func (s *GCPKeyImportService) Preview(ctx context.Context, group *models.Group, params GCPKeyImportParams) (*GCPKeyImportPreview, error) {
+	token, importAccount, err := channel.MintServiceAccountAccessToken(ctx, s.client, params.ServiceAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	projectIDs, err := s.listProjects(ctx, token, params.Parent)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.KeyService.loadClientEmails(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &GCPKeyImportPreview{ImportAccount: importAccount, Projects: make([]GCPImportProject, 0, len(projectIDs))}
+	for _, projectID := range projectIDs {
+		email := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", params.AccountID, projectID)
+		project := GCPImportProject{ProjectID: projectID, ServiceAccount: email, Exists: existing[email]}
+		if !project.Exists {
+			preview.ToImport++
+		}
+		preview.Projects = append(preview.Projects, project)
+	}
+	return preview, nil
+}
+
+// StartImport lists the projects like Preview, then creates and imports the keys in a background task.
+func (s *GCPKeyImportService) StartImport(ctx context.Context, group *models.Group, params GCPKeyImportParams) (*TaskStatus, error) {
+	preview, err := s.Preview(ctx, group, params)
+	if err != nil {
+		return nil, err
+	}
+	if preview.ToImport == 0 {
+		return nil, fmt.Errorf("%w below %s", ErrGCPNothingToImport, params.Parent)
+	}
+
+	status, err := s.TaskService.StartTask(TaskTypeGCPKeyImport, group.Name, preview.ToImport)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":          gcpAuditField,
+		"client_ip":      params.ClientIP,
+		"group":          group.Name,
+		"parent":         params.Parent,
+		"account_id":     params.AccountID,
+		"import_account": preview.ImportAccount,
+		"projects":       preview.ToImport,
+	}).Warn("GCP key import started")
+
+	go s.runImport(group, params, preview)
+
+	return status, nil
+}
+
+func (s *GCPKeyImportService) runImport(group *models.Group, params GCPKeyImportParams, preview *GCPKeyImportPreview) {
+	ctx := context.Background()
+	auditLog := logrus.WithFields(logrus.Fields{"audit": gcpAuditField, "client_ip": params.ClientIP, "group": group.Name, "parent": params.Parent})
+
+	result := GCPKeyImportResult{}
+	token, _, err := channel.MintServiceAccountAccessToken(ctx, s.client, params.ServiceAccount)
+	if err != nil {
+		s.endImport(auditLog, group, result, err)
+		return
+	}
+
+	var keys []string
+	processed := 0
+	for _, project := range preview.Projects {
+		if project.Exists {
+			continue
+		}
+		keyJSON, keyName, err := s.createServiceAccountKey(ctx, token, project)
+		if err != nil {
+			auditLog.WithFields(logrus.Fields{"project_id": project.ProjectID, "error": err}).Warn("GCP key import failed for project")
+			result.Failures = append(result.Failures, GCPImportFailure{ProjectID: project.ProjectID, Error: err.Error()})
+		} else {
+			auditLog.WithFields(logrus.Fields{"project_id": project.ProjectID, "key_name": keyName}).Info("GCP service account key created")
+			keys = append(keys, keyJSON)
+			result.KeysCreated++
+		}
+
+		processed++
+		if err := s.TaskService.UpdateProgress(processed); err != nil {
+			logrus.Warnf("Failed to update task progress for group %d: %v", group.ID, err)
+		}
+	}
+
+	if len(keys) > 0 {
+		result.AddedCount, result.IgnoredCount, result.DuplicateCount, err = s.KeyService.processAndCreateKeys(group, keys, nil)
+		if err != nil {
+			auditLog.WithField("keys_created", result.KeysCreated).Error("GCP keys were created but could not be stored, delete them in GCP")
+		}
+	}
+	s.endImport(auditLog, group, result, err)
+}
+
+func (s *GCPKeyImportService) endImport(auditLog *logrus.Entry, group *models.Group, result GCPKeyImportResult, err error) {
+	if err != nil {
+		auditLog.WithError(err).Error("GCP key import failed")
+		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
+			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
+		}
+		return
+	}
+
+	auditLog.WithFields(logrus.Fields{
+		"keys_created": result.KeysCreated,
+		"added":        result.AddedCount,
+		"failures":     len(result.Failures),
+	}).Warn("GCP key import finished")
+	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
+		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
+	}
+}
+
+// listProjects returns the IDs of the active projects below the parent, walking nested folders.
+func (s *GCPKeyImportService) listProjects(ctx context.Context, token string, parent string) ([]string, error) {
+	var projectIDs []string
+	parents := []string{parent}
+	for len(parents) > 0 {
+		current := parents[0]
+		parents = parents[1:]
+
+		var projects []struct {
+			ProjectID string `json:"projectId"`
+			State     string `json:"state"`
+		}
+		if err := s.listPages(ctx, token, gcpResourceManagerURL+"/projects", current, "projects", &projects); err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			if p.State == "ACTIVE" {
+				projectIDs = append(projectIDs, p.ProjectID)
+			}
+		}
+		if len(projectIDs) > maxGCPImportProjects {
+			return nil, fmt.Errorf("more than %d projects below %s, import a narrower folder", maxGCPImportProjects, parent)
+		}
+
+		var folders []struct {
+			Name  string `json:"name"`
+			State string `json:"state"`
+		}
+		if err := s.listPages(ctx, token, gcpResourceManagerURL+"/folders", current, "folders", &folders); err != nil {
+			return nil, err
+		}
+		for _, f := range folders {
+			if f.State == "ACTIVE" {
+				parents = append(parents, f.Name)
+			}
+		}
+	}
+	return projectIDs, nil
+}
+
+// listPages collects the items of a paginated Resource Manager list call below a parent.
+func (s *GCPKeyImportService) listPages(ctx context.Context, token string, endpoint string, parent string, field string, items any) error {
+	var all []json.RawMessage
+	pageToken := ""
+	for {
+		query := url.Values{"parent": {parent}, "pageSize": {"100"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		body, err := s.doGCPRequest(ctx, token, "GET", endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to list %s of %s: %w", field, parent, err)
+		}
+
+		var page map[string]json.RawMessage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse %s of %s: %w", field, parent, err)
+		}
+		var pageItems []json.RawMessage
+		if raw, ok := page[field]; ok {
+			if err := json.Unmarshal(raw, &pageItems); err != nil {
+				return fmt.Errorf("failed to parse %s of %s: %w", field, parent, err)
+			}
+		}
+		all = append(all, pageItems...)
+
+		pageToken = ""
+		if raw, ok := page["nextPageToken"]; ok {
+			_ = json.Unmarshal(raw, &pageToken)
+		}
+		if pageToken == "" {
+			break
+		}
+	}
+
+	combined, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(combined, items)
+}
+
+// createServiceAccountKey creates a JSON key of the project's service account and returns it with the
+// resource name of the key.
+func (s *GCPKeyImportService) createServiceAccountKey(ctx context.Context, token string, project GCPImportProject) (string, string, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/serviceAccounts/%s/keys", gcpIAMURL, url.PathEscape(project.ProjectID), url.PathEscape(project.ServiceAccount))
+	payload := []byte(`{"privateKeyType":"TYPE_GOOGLE_CREDENTIALS_FILE","keyAlgorithm":"KEY_ALG_RSA_2048"}`)
+	body, err := s.doGCPRequest(ctx, token, "POST", endpoint, payload)
+	if err != nil {
+		return "", "", err
+	}
+
+	var key struct {
+		Name           string `json:"name"`
+		PrivateKeyData string `json:"privateKeyData"`
+	}
+	if err := json.Unmarshal(body, &key); err != nil {
+		return "", "", fmt.Errorf("failed to parse created key: %w", err)
+	}
+	keyJSON, err := base64.StdEncoding.DecodeString(key.PrivateKeyData)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode created key: %w", err)
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, keyJSON); err != nil {
+		return "", "", fmt.Errorf("created key is not JSON: %w", err)
+	}
+	return compacted.String(), key.Name, nil
+}
+
+// doGCPRequest sends an authorized GCP API request and returns the body of a successful response.
+func (s *GCPKeyImportService) doGCPRequest(ctx context.Context, token string, method string, endpoint string, payload []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("[status %d] %s", resp.StatusCode, app_errors.ParseUpstreamError(body))
+	}
+	return body, nil
+}
@@ -82,28 +82,80 @@ func (gm *GroupManager) Initialize() error {
 				g.HeaderRuleList = []models.HeaderRule{}
 			}
 
+			// Parse body rules with error handling
+			if len(group.BodyRules) > 0 {
+				if err := json.Unmarshal(group.BodyRules, &g.BodyRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse body rules for group")
+					g.BodyRuleList = nil
+				}
+			}
+
 			// Parse model redirect rules with error handling
 			g.ModelRedirectMap = make(map[string]string)
+			g.ModelRedirectCanaries = nil
 			if len(group.ModelRedirectRules) > 0 {
 				hasInvalidRules := false
 				for key, value := range group.ModelRedirectRules {
-					if valueStr, ok := value.(string); ok {
-						g.ModelRedirectMap[key] = valueStr
-					} else {
+					valueStr, canary, err := utils.ParseModelRedirectRule(value)
+					if err != nil {
 						logrus.WithFields(logrus.Fields{
 							"group_name": g.Name,
 							"rule_key":   key,
 							"value_type": fmt.Sprintf("%T", value),
 							"value":      value,
-						}).Error("Invalid model redirect rule value type, skipping this rule")
+							"error":      err,
+						}).Error("Invalid model redirect rule value, skipping this rule")
 						hasInvalidRules = true
+						continue
+					}
+					if canary != nil {
+						if g.ModelRedirectCanaries == nil {
+							g.ModelRedirectCanaries = make(map[string]models.ModelRedirectCanary)
+						}
+						g.ModelRedirectCanaries[key] = *canary
+						g.ModelRedirectMap[key] = key
+						continue
 					}
+					if valueStr == key {
+						logrus.WithFields(logrus.Fields{
+							"group_name": g.Name,
+							"model":      key,
+						}).Warn("Model redirect rule maps a model to itself, it has no effect beyond allowing the model in strict mode")
+					}
+					g.ModelRedirectMap[key] = valueStr
 				}
 				if hasInvalidRules {
 					logrus.WithField("group_name", g.Name).Warn("Group has invalid model redirect rules, some rules were skipped. Please check the configuration.")
 				}
 			}
 
+			// Parse model capability annotations with error handling
+			if len(group.ModelCapabilities) > 0 {
+				capabilitiesJSON, err := json.Marshal(group.ModelCapabilities)
+				if err == nil {
+					err = json.Unmarshal(capabilitiesJSON, &g.ModelCapabilityMap)
+				}
+				if err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse model capabilities for group")
+					g.ModelCapabilityMap = nil
+				}
+			}
+
+			// Parse per-model Vertex locations
+			if len(group.VertexLocations) > 0 {
+				g.VertexLocationMap = make(map[string]string, len(group.VertexLocations))
+				for model, value := range group.VertexLocations {
+					if location, ok := value.(string); ok && location != "" {
+						g.VertexLocationMap[model] = location
+					} else {
+						logrus.WithFields(logrus.Fields{
+							"group_name": g.Name,
+							"model":      model,
+						}).Warn("Invalid vertex location value, skipping this model")
+					}
+				}
+			}
+
 			// Load sub-groups for aggregate groups
 			if g.GroupType == "aggregate" {
 				if subGroups, ok := subGroupsByAggregateID[g.ID]; ok {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"path"
 	"reflect"
 	"regexp"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/utils"
 
@@ -57,6 +59,7 @@ type GroupService struct {
 	keyImportSvc          *KeyImportService
 	encryptionSvc         encryption.Service
 	aggregateGroupService *AggregateGroupService
+	channelFactory        *channel.Factory
 	channelRegistry       []string
 }
 
@@ -69,6 +72,7 @@ func NewGroupService(
 	keyImportSvc *KeyImportService,
 	encryptionSvc encryption.Service,
 	aggregateGroupService *AggregateGroupService,
+	channelFactory *channel.Factory,
 ) *GroupService {
 	return &GroupService{
 		db:                    db,
@@ -78,6 +82,7 @@ func NewGroupService(
 		keyImportSvc:          keyImportSvc,
 		encryptionSvc:         encryptionSvc,
 		aggregateGroupService: aggregateGroupService,
+		channelFactory:        channelFactory,
 		channelRegistry:       channel.GetChannels(),
 	}
 }
@@ -94,10 +99,13 @@ type GroupCreateParams struct {
 	TestModel           string
 	ValidationEndpoint  string
 	ParamOverrides      map[string]any
-	ModelRedirectRules  map[string]string
+	ModelRedirectRules  map[string]any
 	ModelRedirectStrict bool
+	ModelCapabilities   map[string]models.ModelCapability
+	VertexLocations     map[string]string
 	Config              map[string]any
 	HeaderRules         []models.HeaderRule
+	BodyRules           []models.BodyRule
 	ProxyKeys           string
 	SubGroups           []SubGroupInput
 }
@@ -116,10 +124,13 @@ type GroupUpdateParams struct {
 	HasTestModel        bool
 	ValidationEndpoint  *string
 	ParamOverrides      map[string]any
-	ModelRedirectRules  map[string]string
+	ModelRedirectRules  map[string]any
 	ModelRedirectStrict *bool
+	ModelCapabilities   map[string]models.ModelCapability
+	VertexLocations     map[string]string
 	Config              map[string]any
 	HeaderRules         *[]models.HeaderRule
+	BodyRules           *[]models.BodyRule
 	ProxyKeys           *string
 	SubGroups           *[]SubGroupInput
 }
@@ -146,6 +157,28 @@ type GroupStats struct {
 	Stats30Day  RequestStats `json:"stats_30_day"`
 }
 
+// KeyPoolStatus summarizes the key pool health of a single standard group. Draining keys are out of
+// rotation until their in-flight requests finish; cooling keys are active keys out of rotation until
+// their rate limit resets. The request slot fields describe this instance, and SaturatedKeys counts
+// keys at KeyMaxConcurrency, staying 0 when it is unlimited.
+type KeyPoolStatus struct {
+	GroupID           uint                  `json:"group_id"`
+	GroupName         string                `json:"group_name"`
+	DisplayName       string                `json:"display_name"`
+	TotalKeys         int64                 `json:"total_keys"`
+	ActiveKeys        int64                 `json:"active_keys"`
+	FailingKeys       int64                 `json:"failing_keys"`
+	InvalidKeys       int64                 `json:"invalid_keys"`
+	DrainingKeys      int64                 `json:"draining_keys"`
+	CoolingKeys       []keypool.KeyCooldown `json:"cooling_keys"`
+	InFlightRequests  int                   `json:"in_flight_requests"`
+	BusyKeys          int                   `json:"busy_keys"`
+	SaturatedKeys     int                   `json:"saturated_keys"`
+	KeyMaxConcurrency int                   `json:"key_max_concurrency"`
+	Stats1Hour        RequestStats          `json:"stats_1_hour"`
+	Stats24Hour       RequestStats          `json:"stats_24_hour"`
+}
+
 // ConfigOption describes a configurable override exposed to clients.
 type ConfigOption struct {
 	Key          string
@@ -214,6 +247,11 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		headerRulesJSON = datatypes.JSON("[]")
 	}
 
+	bodyRulesJSON, err := s.normalizeBodyRules(params.BodyRules)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate model redirect rules for aggregate groups
 	if groupType == "aggregate" && len(params.ModelRedirectRules) > 0 {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.aggregate_no_model_redirect", nil)
@@ -224,6 +262,15 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_redirect", map[string]any{"error": err.Error()})
 	}
 
+	modelCapabilities, err := normalizeModelCapabilities(params.ModelCapabilities)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_capabilities", map[string]any{"error": err.Error()})
+	}
+
+	if err := validateVertexLocations(params.VertexLocations); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_vertex_locations", map[string]any{"error": err.Error()})
+	}
+
 	group := models.Group{
 		Name:                name,
 		DisplayName:         strings.TrimSpace(params.DisplayName),
@@ -237,8 +284,11 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		ParamOverrides:      params.ParamOverrides,
 		ModelRedirectRules:  convertToJSONMap(params.ModelRedirectRules),
 		ModelRedirectStrict: params.ModelRedirectStrict,
+		ModelCapabilities:   modelCapabilities,
+		VertexLocations:     convertToJSONMap(params.VertexLocations),
 		Config:              cleanedConfig,
 		HeaderRules:         headerRulesJSON,
+		BodyRules:           bodyRulesJSON,
 		ProxyKeys:           strings.TrimSpace(params.ProxyKeys),
 	}
 
@@ -380,6 +430,21 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.ModelRedirectStrict = *params.ModelRedirectStrict
 	}
 
+	if params.ModelCapabilities != nil {
+		modelCapabilities, err := normalizeModelCapabilities(params.ModelCapabilities)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_capabilities", map[string]any{"error": err.Error()})
+		}
+		group.ModelCapabilities = modelCapabilities
+	}
+
+	if params.VertexLocations != nil {
+		if err := validateVertexLocations(params.VertexLocations); err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_vertex_locations", map[string]any{"error": err.Error()})
+		}
+		group.VertexLocations = convertToJSONMap(params.VertexLocations)
+	}
+
 	if params.ValidationEndpoint != nil {
 		validationEndpoint := strings.TrimSpace(*params.ValidationEndpoint)
 		if !isValidValidationEndpoint(validationEndpoint) {
@@ -411,6 +476,14 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.HeaderRules = headerRulesJSON
 	}
 
+	if params.BodyRules != nil {
+		bodyRulesJSON, err := s.normalizeBodyRules(*params.BodyRules)
+		if err != nil {
+			return nil, err
+		}
+		group.BodyRules = bodyRulesJSON
+	}
+
 	if err := tx.Save(&group).Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
@@ -589,6 +662,181 @@ func (s *GroupService) GetGroupStats(ctx context.Context, groupID uint) (*GroupS
 	return s.getStandardGroupStats(ctx, groupID)
 }
 
+// RedirectPreviewParams describes the sample request of a model redirect preview.
+type RedirectPreviewParams struct {
+	Method      string
+	Path        string
+	ContentType string
+	Body        []byte
+}
+
+// PreviewModelRedirect reports how a sample request would be rewritten by the group's model redirect rules.
+func (s *GroupService) PreviewModelRedirect(ctx context.Context, groupID uint, params RedirectPreviewParams) (*channel.RedirectPreview, error) {
+	var groupDB models.Group
+	if err := s.db.WithContext(ctx).First(&groupDB, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if groupDB.GroupType == "aggregate" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.aggregate_no_model_redirect", nil)
+	}
+
+	group, err := s.groupManager.GetGroupByName(groupDB.Name)
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	ch, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := channel.PreviewModelRedirect(ch, group, params.Method, params.Path, params.ContentType, params.Body)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error())
+	}
+	return preview, nil
+}
+
+// GetKeyPoolStatus returns per-group key pool status built from key counters and hourly stats.
+func (s *GroupService) GetKeyPoolStatus(ctx context.Context) ([]KeyPoolStatus, error) {
+	var groups []models.Group
+	if err := s.db.WithContext(ctx).
+		Select("id, name, display_name").
+		Where("group_type != ? OR group_type IS NULL", "aggregate").
+		Order("sort ASC, id DESC").
+		Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	var keyRows []struct {
+		GroupID     uint
+		Status      string
+		KeyCount    int64
+		FailingKeys int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Select("group_id, status, COUNT(*) as key_count, SUM(CASE WHEN failure_count > 0 THEN 1 ELSE 0 END) as failing_keys").
+		Group("group_id, status").
+		Scan(&keyRows).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	stats1Hour, err := s.queryAllGroupsHourlyStats(ctx, 1)
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	stats24Hour, err := s.queryAllGroupsHourlyStats(ctx, 24)
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	statusMap := make(map[uint]*KeyPoolStatus, len(groups))
+	result := make([]KeyPoolStatus, len(groups))
+	for i, group := range groups {
+		result[i] = KeyPoolStatus{
+			GroupID:     group.ID,
+			GroupName:   group.Name,
+			DisplayName: group.DisplayName,
+			Stats1Hour:  stats1Hour[group.ID],
+			Stats24Hour: stats24Hour[group.ID],
+		}
+		statusMap[group.ID] = &result[i]
+	}
+
+	for _, row := range keyRows {
+		status, ok := statusMap[row.GroupID]
+		if !ok {
+			continue
+		}
+		status.TotalKeys += row.KeyCount
+		switch row.Status {
+		case models.KeyStatusActive:
+			status.ActiveKeys += row.KeyCount
+			// 活跃但已有失败计数的密钥，接近黑名单阈值
+			status.FailingKeys += row.FailingKeys
+		case models.KeyStatusDraining:
+			status.DrainingKeys += row.KeyCount
+		default:
+			status.InvalidKeys += row.KeyCount
+		}
+	}
+
+	if err := s.fillKeyPoolRuntimeStatus(ctx, statusMap); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// fillKeyPoolRuntimeStatus adds the cooldown and request slot state held by the key pool to each group's status.
+func (s *GroupService) fillKeyPoolRuntimeStatus(ctx context.Context, statusMap map[uint]*KeyPoolStatus) error {
+	provider := s.keyService.KeyProvider
+
+	for groupID, status := range statusMap {
+		cooldowns, err := provider.KeyCooldowns(groupID)
+		if err != nil {
+			return fmt.Errorf("failed to load key cooldowns of group %d: %w", groupID, err)
+		}
+		status.CoolingKeys = cooldowns
+
+		if group, err := s.groupManager.GetGroupByName(status.GroupName); err == nil {
+			status.KeyMaxConcurrency = group.EffectiveConfig.KeyMaxConcurrency
+		}
+	}
+
+	inFlight := provider.KeysInFlight()
+	if len(inFlight) == 0 {
+		return nil
+	}
+	keyIDs := make([]uint, 0, len(inFlight))
+	for keyID := range inFlight {
+		keyIDs = append(keyIDs, keyID)
+	}
+
+	var keys []models.APIKey
+	if err := s.db.WithContext(ctx).Select("id, group_id").Where("id IN ?", keyIDs).Find(&keys).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	for _, key := range keys {
+		status, ok := statusMap[key.GroupID]
+		if !ok {
+			continue
+		}
+		count := inFlight[key.ID]
+		status.InFlightRequests += count
+		status.BusyKeys++
+		if status.KeyMaxConcurrency > 0 && count >= status.KeyMaxConcurrency {
+			status.SaturatedKeys++
+		}
+	}
+	return nil
+}
+
+// queryAllGroupsHourlyStats aggregates hourly statistics of all groups for the last N hours.
+func (s *GroupService) queryAllGroupsHourlyStats(ctx context.Context, hours int) (map[uint]RequestStats, error) {
+	var rows []struct {
+		GroupID      uint
+		SuccessCount int64
+		FailureCount int64
+	}
+
+	endTime := time.Now().Truncate(time.Hour).Add(time.Hour)
+	startTime := endTime.Add(-time.Duration(hours) * time.Hour)
+
+	if err := s.db.WithContext(ctx).Model(&models.GroupHourlyStat{}).
+		Select("group_id, SUM(success_count) as success_count, SUM(failure_count) as failure_count").
+		Where("time >= ? AND time < ?", startTime, endTime).
+		Group("group_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]RequestStats, len(rows))
+	for _, row := range rows {
+		result[row.GroupID] = calculateRequestStats(row.SuccessCount+row.FailureCount, row.FailureCount)
+	}
+	return result, nil
+}
+
 // queryGroupHourlyStats queries aggregated hourly statistics from group_hourly_stats table
 func (s *GroupService) queryGroupHourlyStats(ctx context.Context, groupID uint, hours int) (RequestStats, error) {
 	var result struct {
@@ -841,11 +1089,18 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 			continue
 		}
 		canonicalKey := http.CanonicalHeaderKey(key)
-		if seenKeys[canonicalKey] {
-			return nil, NewI18nError(app_errors.ErrValidation, "validation.duplicate_header", map[string]any{"key": canonicalKey})
+		match, err := normalizeHeaderRuleMatch(rule.Match)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_header_rule_match", map[string]any{"key": canonicalKey, "error": err.Error()})
+		}
+		// Conditional rules may share a header, e.g. to set different values for different models.
+		if match == nil {
+			if seenKeys[canonicalKey] {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.duplicate_header", map[string]any{"key": canonicalKey})
+			}
+			seenKeys[canonicalKey] = true
 		}
-		seenKeys[canonicalKey] = true
-		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action})
+		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action, Match: match})
 	}
 
 	if len(normalized) == 0 {
@@ -860,6 +1115,56 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 	return datatypes.JSON(headerRulesBytes), nil
 }
 
+// normalizeHeaderRuleMatch trims a header rule condition and checks its model patterns. A condition
+// without any criteria is dropped.
+func normalizeHeaderRuleMatch(match *models.HeaderRuleMatch) (*models.HeaderRuleMatch, error) {
+	if match == nil {
+		return nil, nil
+	}
+
+	normalized := &models.HeaderRuleMatch{PathPrefix: strings.TrimSpace(match.PathPrefix), Stream: match.Stream}
+	for _, pattern := range match.Models {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid model pattern '%s'", pattern)
+		}
+		normalized.Models = append(normalized.Models, pattern)
+	}
+	if normalized.PathPrefix != "" && !strings.HasPrefix(normalized.PathPrefix, "/") {
+		normalized.PathPrefix = "/" + normalized.PathPrefix
+	}
+
+	if len(normalized.Models) == 0 && normalized.PathPrefix == "" && normalized.Stream == nil {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// normalizeBodyRules validates body rules, keeping their order since later rules see the effect of earlier ones.
+func (s *GroupService) normalizeBodyRules(rules []models.BodyRule) (datatypes.JSON, error) {
+	normalized := make([]models.BodyRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Path = strings.TrimSpace(rule.Path)
+		rule.Action = strings.TrimSpace(rule.Action)
+		if err := utils.ValidateBodyRule(rule); err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_body_rule", map[string]any{"error": err.Error()})
+		}
+		if rule.Action == utils.BodyRuleRemove {
+			rule.Value = nil
+		}
+		normalized = append(normalized, rule)
+	}
+
+	bodyRulesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrInternalServer, "error.process_body_rules", map[string]any{"error": err.Error()})
+	}
+	return datatypes.JSON(bodyRulesBytes), nil
+}
+
 // validateAndCleanUpstreams validates upstream definitions.
 func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error) {
 	if len(upstreams) == 0 {
@@ -979,7 +1284,7 @@ func (s *GroupService) isValidChannelType(channelType string) bool {
 }
 
 // convertToJSONMap converts a map[string]string to datatypes.JSONMap
-func convertToJSONMap(input map[string]string) datatypes.JSONMap {
+func convertToJSONMap[V any](input map[string]V) datatypes.JSONMap {
 	if len(input) == 0 {
 		return datatypes.JSONMap{}
 	}
@@ -992,16 +1297,48 @@ func convertToJSONMap(input map[string]string) datatypes.JSONMap {
 }
 
 // validateModelRedirectRules validates the format and content of model redirect rules
-func validateModelRedirectRules(rules map[string]string) error {
+func validateModelRedirectRules(rules map[string]any) error {
 	if len(rules) == 0 {
 		return nil
 	}
 
 	for key, value := range rules {
-		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+		if strings.TrimSpace(key) == "" {
 			return fmt.Errorf("model name cannot be empty")
 		}
+		if _, _, err := utils.ParseModelRedirectRule(value); err != nil {
+			return fmt.Errorf("rule for '%s': %w", key, err)
+		}
 	}
 
 	return nil
 }
+
+// validateVertexLocations validates the per-model Vertex location mapping.
+func validateVertexLocations(locations map[string]string) error {
+	for model, location := range locations {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("model name cannot be empty")
+		}
+		if match, _ := regexp.MatchString("^[a-z0-9-]{1,64}$", location); !match {
+			return fmt.Errorf("invalid location '%s' for model '%s'", location, model)
+		}
+	}
+	return nil
+}
+
+// normalizeModelCapabilities validates capability annotations and converts them to a JSON map.
+func normalizeModelCapabilities(capabilities map[string]models.ModelCapability) (datatypes.JSONMap, error) {
+	result := make(datatypes.JSONMap, len(capabilities))
+	for model, capability := range capabilities {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			return nil, fmt.Errorf("model name cannot be empty")
+		}
+		if capability.ContextWindow < 0 {
+			return nil, fmt.Errorf("context window for model '%s' cannot be negative", model)
+		}
+		result[model] = capability
+	}
+	return result, nil
+}
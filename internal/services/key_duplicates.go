@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 重复密钥的判定依据
+const (
+	DuplicateByClientEmail = "client_email"
+	DuplicateByKeyValue    = "key_value"
+)
+
+// DuplicateKeySet describes a set of keys in a group that share the same identity.
+type DuplicateKeySet struct {
+	Type     string `json:"type"`
+	Identity string `json:"identity"`
+	KeyIDs   []uint `json:"key_ids"`
+}
+
+// DuplicateKeysResult holds the duplicate key report of a group.
+type DuplicateKeysResult struct {
+	GroupID       uint              `json:"group_id"`
+	DuplicateSets []DuplicateKeySet `json:"duplicate_sets"`
+	RedundantKeys int               `json:"redundant_keys"`
+}
+
+// keyClientEmail returns the normalized client_email of a service account key, or "" for plain keys.
+func keyClientEmail(key string) string {
+	if !strings.HasPrefix(key, "{") {
+		return ""
+	}
+
+	var sa struct {
+		ClientEmail string `json:"client_email"`
+	}
+	if err := json.Unmarshal([]byte(key), &sa); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(sa.ClientEmail))
+}
+
+// loadClientEmails returns the client_email of every service account key stored in a group.
+func (s *KeyService) loadClientEmails(groupID uint) (map[string]bool, error) {
+	emails := make(map[string]bool)
+
+	var keys []models.APIKey
+	err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Select("id, key_value").
+		FindInBatches(&keys, chunkSize, func(tx *gorm.DB, batch int) error {
+			for _, key := range keys {
+				decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+				if err != nil {
+					logrus.WithError(err).WithField("key_id", key.ID).Warn("Failed to decrypt key for duplicate detection, skipping")
+					continue
+				}
+				if email := keyClientEmail(decryptedKey); email != "" {
+					emails[email] = true
+				}
+			}
+			return nil
+		}).Error
+
+	return emails, err
+}
+
+// FindDuplicateKeys reports keys in a group that share the same key value or service account client_email.
+func (s *KeyService) FindDuplicateKeys(groupID uint) (*DuplicateKeysResult, error) {
+	byHash := make(map[string][]uint)
+	byEmail := make(map[string][]uint)
+
+	var keys []models.APIKey
+	err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Select("id, key_value, key_hash").Order("id asc").
+		FindInBatches(&keys, chunkSize, func(tx *gorm.DB, batch int) error {
+			for _, key := range keys {
+				byHash[key.KeyHash] = append(byHash[key.KeyHash], key.ID)
+
+				decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+				if err != nil {
+					logrus.WithError(err).WithField("key_id", key.ID).Warn("Failed to decrypt key for duplicate detection, skipping")
+					continue
+				}
+				if email := keyClientEmail(decryptedKey); email != "" {
+					byEmail[email] = append(byEmail[email], key.ID)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DuplicateKeysResult{
+		GroupID:       groupID,
+		DuplicateSets: []DuplicateKeySet{},
+	}
+
+	// A key whose value is duplicated is also reported under its client_email, so count each key as redundant once.
+	redundant := make(map[uint]bool)
+	for hash, ids := range byHash {
+		if len(ids) < 2 {
+			continue
+		}
+		result.DuplicateSets = append(result.DuplicateSets, DuplicateKeySet{Type: DuplicateByKeyValue, Identity: hash, KeyIDs: ids})
+		for _, id := range ids[1:] {
+			redundant[id] = true
+		}
+	}
+	for email, ids := range byEmail {
+		if len(ids) < 2 {
+			continue
+		}
+		result.DuplicateSets = append(result.DuplicateSets, DuplicateKeySet{Type: DuplicateByClientEmail, Identity: email, KeyIDs: ids})
+		for _, id := range ids[1:] {
+			redundant[id] = true
+		}
+	}
+	result.RedundantKeys = len(redundant)
+
+	sort.Slice(result.DuplicateSets, func(i, j int) bool {
+		return result.DuplicateSets[i].KeyIDs[0] < result.DuplicateSets[j].KeyIDs[0]
+	})
+
+	return result, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"testing"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testSAKeyA  = `{"type":"service_account","client_email":"svc@p.iam.gserviceaccount.com","private_key_id":"a"}`
+	testSAKeyB  = `{"type":"service_account","client_email":" SVC@p.iam.gserviceaccount.com ","private_key_id":"b"}`
+	testSAOther = `{"type":"service_account","client_email":"other@p.iam.gserviceaccount.com","private_key_id":"c"}`
+)
+
+// newTestDB opens an in-memory SQLite database with the given tables.
+func newTestDB(t *testing.T, tables ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	// Every connection to ":memory:" opens a separate database, so keep to one.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newDuplicateTestService(t *testing.T, groupID uint, keys ...string) *KeyService {
+	t.Helper()
+	db := newTestDB(t, &models.APIKey{})
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+
+	for _, key := range keys {
+		apiKey := models.APIKey{GroupID: groupID, KeyValue: key, KeyHash: encSvc.Hash(key), Status: models.KeyStatusActive}
+		if err := db.Create(&apiKey).Error; err != nil {
+			t.Fatalf("create key: %v", err)
+		}
+	}
+	return &KeyService{DB: db, EncryptionSvc: encSvc}
+}
+
+func TestKeyClientEmail(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"sk-plain", ""},
+		{testSAKeyA, "svc@p.iam.gserviceaccount.com"},
+		{testSAKeyB, "svc@p.iam.gserviceaccount.com"},
+		{`{"type":"service_account"}`, ""},
+		{`{not json`, ""},
+	}
+	for _, tt := range tests {
+		if got := keyClientEmail(tt.key); got != tt.want {
+			t.Errorf("keyClientEmail(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		keys          []string
+		wantSets      []DuplicateKeySet
+		wantRedundant int
+	}{
+		{
+			name:     "no duplicates",
+			keys:     []string{"sk-a", "sk-b", testSAKeyA, testSAOther},
+			wantSets: []DuplicateKeySet{},
+		},
+		{
+			name:          "same key value",
+			keys:          []string{"sk-a", "sk-b", "sk-a", "sk-a"},
+			wantSets:      []DuplicateKeySet{{Type: DuplicateByKeyValue, KeyIDs: []uint{1, 3, 4}}},
+			wantRedundant: 2,
+		},
+		{
+			name:          "same client_email with different key material",
+			keys:          []string{testSAKeyA, testSAOther, testSAKeyB},
+			wantSets:      []DuplicateKeySet{{Type: DuplicateByClientEmail, Identity: "svc@p.iam.gserviceaccount.com", KeyIDs: []uint{1, 3}}},
+			wantRedundant: 1,
+		},
+		{
+			name: "identical service account key counted once",
+			keys: []string{testSAKeyA, testSAKeyA},
+			wantSets: []DuplicateKeySet{
+				{Type: DuplicateByKeyValue, KeyIDs: []uint{1, 2}},
+				{Type: DuplicateByClientEmail, Identity: "svc@p.iam.gserviceaccount.com", KeyIDs: []uint{1, 2}},
+			},
+			wantRedundant: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDuplicateTestService(t, 1, tt.keys...)
+			result, err := s.FindDuplicateKeys(1)
+			if err != nil {
+				t.Fatalf("FindDuplicateKeys: %v", err)
+			}
+			if result.RedundantKeys != tt.wantRedundant {
+				t.Errorf("RedundantKeys = %d, want %d", result.RedundantKeys, tt.wantRedundant)
+			}
+			if len(result.DuplicateSets) != len(tt.wantSets) {
+				t.Fatalf("DuplicateSets = %+v, want %+v", result.DuplicateSets, tt.wantSets)
+			}
+
+			byType := make(map[string]DuplicateKeySet)
+			for _, set := range result.DuplicateSets {
+				byType[set.Type] = set
+			}
+			for _, want := range tt.wantSets {
+				got, ok := byType[want.Type]
+				if !ok {
+					t.Errorf("missing %s duplicate set", want.Type)
+					continue
+				}
+				if want.Type == DuplicateByKeyValue {
+					// Key value sets are identified by the key hash, never the key itself.
+					want.Identity = s.EncryptionSvc.Hash(tt.keys[want.KeyIDs[0]-1])
+				}
+				if got.Identity != want.Identity {
+					t.Errorf("%s identity = %q, want %q", want.Type, got.Identity, want.Identity)
+				}
+				if !equalKeyIDs(got.KeyIDs, want.KeyIDs) {
+					t.Errorf("%s key ids = %v, want %v", want.Type, got.KeyIDs, want.KeyIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestFindDuplicateKeysIgnoresOtherGroups(t *testing.T) {
+	s := newDuplicateTestService(t, 1, "sk-a", testSAKeyA)
+	if err := s.DB.Create(&models.APIKey{GroupID: 2, KeyValue: "sk-a", KeyHash: s.EncryptionSvc.Hash("sk-a")}).Error; err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	if err := s.DB.Create(&models.APIKey{GroupID: 2, KeyValue: testSAKeyB, KeyHash: s.EncryptionSvc.Hash(testSAKeyB)}).Error; err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	result, err := s.FindDuplicateKeys(1)
+	if err != nil {
+		t.Fatalf("FindDuplicateKeys: %v", err)
+	}
+	if len(result.DuplicateSets) != 0 || result.RedundantKeys != 0 {
+		t.Errorf("result = %+v, want no duplicates", result)
+	}
+}
+
+func TestProcessAndCreateKeysSkipsDuplicates(t *testing.T) {
+	tests := []struct {
+		name          string
+		dedupe        bool
+		keys          []string
+		wantIgnored   int
+		wantDuplicate int
+	}{
+		{
+			name:        "same key value is always skipped",
+			keys:        []string{"sk-a", testSAKeyA},
+			wantIgnored: 2,
+		},
+		{
+			name:          "same client_email skipped when deduplicating",
+			dedupe:        true,
+			keys:          []string{testSAKeyB},
+			wantIgnored:   1,
+			wantDuplicate: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDuplicateTestService(t, 1, "sk-a", testSAKeyA)
+			group := &models.Group{ID: 1}
+			group.EffectiveConfig.DedupeKeysOnImport = tt.dedupe
+
+			added, ignored, duplicates, err := s.processAndCreateKeys(group, tt.keys, nil)
+			if err != nil {
+				t.Fatalf("processAndCreateKeys: %v", err)
+			}
+			if added != 0 || ignored != tt.wantIgnored || duplicates != tt.wantDuplicate {
+				t.Errorf("added, ignored, duplicates = %d, %d, %d, want 0, %d, %d", added, ignored, duplicates, tt.wantIgnored, tt.wantDuplicate)
+			}
+		})
+	}
+}
+
+func equalKeyIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -9,8 +9,9 @@ import (
 
 // KeyImportResult holds the result of an import task.
 type KeyImportResult struct {
-	AddedCount   int `json:"added_count"`
-	IgnoredCount int `json:"ignored_count"`
+	AddedCount     int `json:"added_count"`
+	IgnoredCount   int `json:"ignored_count"`
+	DuplicateCount int `json:"duplicate_count"`
 }
 
 // KeyImportService handles the asynchronous import of a large number of keys.
@@ -51,7 +52,7 @@ func (s *KeyImportService) runImport(group *models.Group, keys []string) {
 		}
 	}
 
-	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeys(group.ID, keys, progressCallback)
+	addedCount, ignoredCount, duplicateCount, err := s.KeyService.processAndCreateKeys(group, keys, progressCallback)
 	if err != nil {
 		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
 			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
@@ -60,8 +61,9 @@ func (s *KeyImportService) runImport(group *models.Group, keys []string) {
 	}
 
 	result := KeyImportResult{
-		AddedCount:   addedCount,
-		IgnoredCount: ignoredCount,
+		AddedCount:     addedCount,
+		IgnoredCount:   ignoredCount,
+		DuplicateCount: duplicateCount,
 	}
 
 	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"gorm.io/datatypes"
+)
+
+func TestGetKeyPoolStatus(t *testing.T) {
+	db := newTestDB(t, &models.Group{}, &models.APIKey{}, &models.GroupHourlyStat{})
+	encSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatalf("encryption service: %v", err)
+	}
+	provider := keypool.NewProvider(db, store.NewMemoryStore(), nil, encSvc, nil)
+	s := &GroupService{db: db, groupManager: &GroupManager{}, keyService: &KeyService{DB: db, KeyProvider: provider}}
+
+	groups := []models.Group{
+		{ID: 1, Name: "alpha", GroupType: "standard", ChannelType: "openai", Upstreams: datatypes.JSON(`[]`)},
+		{ID: 2, Name: "beta", GroupType: "standard", ChannelType: "openai", Upstreams: datatypes.JSON(`[]`)},
+		{ID: 3, Name: "mixed", GroupType: "aggregate", ChannelType: "openai", Upstreams: datatypes.JSON(`[]`)},
+	}
+	for i := range groups {
+		if err := db.Create(&groups[i]).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+	keys := []models.APIKey{
+		{ID: 1, GroupID: 1, KeyValue: "sk-1", Status: models.KeyStatusActive},
+		{ID: 2, GroupID: 1, KeyValue: "sk-2", Status: models.KeyStatusActive, FailureCount: 2},
+		{ID: 3, GroupID: 1, KeyValue: "sk-3", Status: models.KeyStatusActive},
+		{ID: 4, GroupID: 1, KeyValue: "sk-4", Status: models.KeyStatusInvalid},
+		{ID: 5, GroupID: 1, KeyValue: "sk-5", Status: models.KeyStatusDraining},
+		{ID: 6, GroupID: 2, KeyValue: "sk-6", Status: models.KeyStatusActive},
+	}
+	for i := range keys {
+		if err := db.Create(&keys[i]).Error; err != nil {
+			t.Fatalf("create key: %v", err)
+		}
+	}
+
+	provider.CooldownKey(&keys[0], &groups[0], time.Minute)
+	provider.CooldownKey(&keys[2], &groups[0], 30*time.Second)
+	waitForCooldowns(t, provider, 1, 2)
+
+	for _, keyID := range []uint{1, 1, 2, 5} {
+		release, ok := provider.TryAcquireKey(keyID, 0)
+		if !ok {
+			t.Fatalf("TryAcquireKey(%d) failed", keyID)
+		}
+		defer release()
+	}
+
+	statuses, err := s.GetKeyPoolStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetKeyPoolStatus: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2 standard groups", len(statuses))
+	}
+	byGroup := make(map[uint]KeyPoolStatus, len(statuses))
+	for _, status := range statuses {
+		byGroup[status.GroupID] = status
+	}
+
+	alpha := byGroup[1]
+	if alpha.TotalKeys != 5 || alpha.ActiveKeys != 3 || alpha.FailingKeys != 1 || alpha.InvalidKeys != 1 || alpha.DrainingKeys != 1 {
+		t.Errorf("alpha counts = total %d, active %d, failing %d, invalid %d, draining %d; want 5, 3, 1, 1, 1",
+			alpha.TotalKeys, alpha.ActiveKeys, alpha.FailingKeys, alpha.InvalidKeys, alpha.DrainingKeys)
+	}
+	if alpha.InFlightRequests != 4 || alpha.BusyKeys != 3 || alpha.SaturatedKeys != 0 {
+		t.Errorf("alpha slots = in flight %d, busy %d, saturated %d; want 4, 3, 0", alpha.InFlightRequests, alpha.BusyKeys, alpha.SaturatedKeys)
+	}
+	if len(alpha.CoolingKeys) != 2 {
+		t.Fatalf("alpha cooling keys = %+v, want 2", alpha.CoolingKeys)
+	}
+	// Soonest recovery first.
+	if alpha.CoolingKeys[0].KeyID != 3 || alpha.CoolingKeys[1].KeyID != 1 {
+		t.Errorf("alpha cooling key order = %d, %d, want 3, 1", alpha.CoolingKeys[0].KeyID, alpha.CoolingKeys[1].KeyID)
+	}
+	if until := alpha.CoolingKeys[1].CooldownUntil; until.Before(time.Now().Add(50*time.Second)) || until.After(time.Now().Add(time.Minute+time.Second)) {
+		t.Errorf("key 1 cooldown until %v, want about a minute from now", until)
+	}
+
+	beta := byGroup[2]
+	if beta.TotalKeys != 1 || beta.ActiveKeys != 1 || beta.InFlightRequests != 0 || len(beta.CoolingKeys) != 0 {
+		t.Errorf("beta status = %+v, want one idle active key", beta)
+	}
+}
+
+func waitForCooldowns(t *testing.T, provider *keypool.KeyProvider, groupID uint, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cooldowns, err := provider.KeyCooldowns(groupID)
+		if err != nil {
+			t.Fatalf("KeyCooldowns: %v", err)
+		}
+		if len(cooldowns) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d cooldowns, want %d", len(cooldowns), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
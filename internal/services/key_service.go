@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/encryption"
@@ -22,9 +23,10 @@ const (
 
 // AddKeysResult holds the result of adding multiple keys.
 type AddKeysResult struct {
-	AddedCount   int   `json:"added_count"`
-	IgnoredCount int   `json:"ignored_count"`
-	TotalInGroup int64 `json:"total_in_group"`
+	AddedCount     int   `json:"added_count"`
+	IgnoredCount   int   `json:"ignored_count"`
+	DuplicateCount int   `json:"duplicate_count"`
+	TotalInGroup   int64 `json:"total_in_group"`
 }
 
 // DeleteKeysResult holds the result of deleting multiple keys.
@@ -61,7 +63,7 @@ func NewKeyService(db *gorm.DB, keyProvider *keypool.KeyProvider, keyValidator *
 
 // AddMultipleKeys handles the business logic of creating new keys from a text block.
 // deprecated: use KeyImportService for large imports
-func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysResult, error) {
+func (s *KeyService) AddMultipleKeys(group *models.Group, keysText string) (*AddKeysResult, error) {
 	keys := s.ParseKeysFromText(keysText)
 	if len(keys) > maxRequestKeys {
 		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keys))
@@ -70,39 +72,56 @@ func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysRes
 		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	addedCount, ignoredCount, err := s.processAndCreateKeys(groupID, keys, nil)
+	addedCount, ignoredCount, duplicateCount, err := s.processAndCreateKeys(group, keys, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var totalInGroup int64
-	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Count(&totalInGroup).Error; err != nil {
+	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", group.ID).Count(&totalInGroup).Error; err != nil {
 		return nil, err
 	}
 
 	return &AddKeysResult{
-		AddedCount:   addedCount,
-		IgnoredCount: ignoredCount,
-		TotalInGroup: totalInGroup,
+		AddedCount:     addedCount,
+		IgnoredCount:   ignoredCount,
+		DuplicateCount: duplicateCount,
+		TotalInGroup:   totalInGroup,
 	}, nil
 }
 
 // processAndCreateKeys is the lowest-level reusable function for adding keys.
+// duplicateCount is the number of service account keys whose client_email already exists in the group;
+// they are skipped when the group enables dedupe_keys_on_import, otherwise added and reported.
 func (s *KeyService) processAndCreateKeys(
-	groupID uint,
+	group *models.Group,
 	keys []string,
 	progressCallback func(processed int),
-) (addedCount int, ignoredCount int, err error) {
+) (addedCount int, ignoredCount int, duplicateCount int, err error) {
+	groupID := group.ID
+
 	// 1. Get existing key hashes in the group for deduplication
 	var existingHashes []string
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &existingHashes).Error; err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 	existingHashMap := make(map[string]bool)
 	for _, h := range existingHashes {
 		existingHashMap[h] = true
 	}
 
+	// Service account keys are also deduplicated by client_email, as the same account may be pasted with different key material.
+	var existingEmails map[string]bool
+	for _, keyVal := range keys {
+		if keyClientEmail(strings.TrimSpace(keyVal)) != "" {
+			if existingEmails, err = s.loadClientEmails(groupID); err != nil {
+				return 0, 0, 0, err
+			}
+			break
+		}
+	}
+	dedupe := group.EffectiveConfig.DedupeKeysOnImport
+
 	// 2. Prepare new keys for creation
 	var newKeysToCreate []models.APIKey
 	uniqueNewKeys := make(map[string]bool)
@@ -119,6 +138,17 @@ func (s *KeyService) processAndCreateKeys(
 			continue
 		}
 
+		if email := keyClientEmail(trimmedKey); email != "" {
+			if existingEmails[email] {
+				duplicateCount++
+				if dedupe {
+					continue
+				}
+				logrus.WithFields(logrus.Fields{"group_id": groupID, "client_email": email}).Warn("Importing duplicate service account key")
+			}
+			existingEmails[email] = true
+		}
+
 		encryptedKey, err := s.EncryptionSvc.Encrypt(trimmedKey)
 		if err != nil {
 			logrus.WithError(err).WithField("key", trimmedKey).Error("Failed to encrypt key, skipping")
@@ -135,7 +165,7 @@ func (s *KeyService) processAndCreateKeys(
 	}
 
 	if len(newKeysToCreate) == 0 {
-		return 0, len(keys), nil
+		return 0, len(keys), duplicateCount, nil
 	}
 
 	// 3. Use KeyProvider to add keys in chunks
@@ -146,7 +176,7 @@ func (s *KeyService) processAndCreateKeys(
 		}
 		chunk := newKeysToCreate[i:end]
 		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
-			return addedCount, len(keys) - addedCount, err
+			return addedCount, len(keys) - addedCount, duplicateCount, err
 		}
 		addedCount += len(chunk)
 
@@ -155,7 +185,7 @@ func (s *KeyService) processAndCreateKeys(
 		}
 	}
 
-	return addedCount, len(keys) - addedCount, nil
+	return addedCount, len(keys) - addedCount, duplicateCount, nil
 }
 
 // ParseKeysFromText parses a string of keys from various formats into a string slice.
@@ -185,14 +215,15 @@ func (s *KeyService) ParseKeysFromText(text string) []string {
 		}
 	}
 
-	// Support importing a JSON array of objects, one object per key.
+	// Support importing a JSON array of objects, one object per key. A nested array is kept as a
+	// single key (e.g. several service accounts of one Vertex key).
 	if strings.HasPrefix(trimmedText, "[") {
 		var rawMessages []json.RawMessage
 		if json.Unmarshal([]byte(trimmedText), &rawMessages) == nil && len(rawMessages) > 0 {
 			objKeys := make([]string, 0, len(rawMessages))
 			for _, raw := range rawMessages {
 				rawTrimmed := bytes.TrimSpace(raw)
-				if len(rawTrimmed) == 0 || rawTrimmed[0] != '{' {
+				if len(rawTrimmed) == 0 || (rawTrimmed[0] != '{' && rawTrimmed[0] != '[') {
 					continue
 				}
 				var compacted bytes.Buffer
@@ -329,8 +360,9 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 	}, nil
 }
 
-// ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status.
-func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHash string) *gorm.DB {
+// ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status
+// and by tags, all of which a key must carry.
+func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHash string, tags []string) *gorm.DB {
 	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID)
 
 	if statusFilter != "" {
@@ -341,12 +373,21 @@ func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, sea
 		query = query.Where("key_hash = ?", searchHash)
 	}
 
+	for _, tag := range tags {
+		query = keypool.WhereKeyHasTag(query, tag)
+	}
+
 	query = query.Order("last_used_at desc, updated_at desc")
 
 	return query
 }
 
 // TestMultipleKeys handles a one-off validation test for multiple keys.
+// CheckLiveness runs a lightweight health probe for a group.
+func (s *KeyService) CheckLiveness(ctx context.Context, group *models.Group) (*keypool.LivenessResult, error) {
+	return s.KeyValidator.CheckLiveness(ctx, group)
+}
+
 func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]keypool.KeyTestResult, error) {
 	keysToTest := s.ParseKeysFromText(keysText)
 	if len(keysToTest) > maxRequestKeys {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"strconv"
 	"time"
@@ -153,3 +154,73 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 
 	return nil
 }
+
+// Usage summary groupings.
+const (
+	UsageGroupByGroup = "group"
+	UsageGroupByKey   = "key"
+	UsageGroupByModel = "model"
+)
+
+// UsageSummary is the token consumption and estimated cost of one group, key or model.
+type UsageSummary struct {
+	GroupName        string  `json:"group_name,omitempty"`
+	KeyHash          string  `json:"key_hash,omitempty"`
+	KeyValue         string  `json:"key_value,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// GetUsageSummary totals the tokens and estimated cost of final requests matching the log filters,
+// grouped by group, key or model. Keys are returned masked.
+func (s *LogService) GetUsageSummary(c *gin.Context, groupBy string) ([]UsageSummary, error) {
+	var column string
+	switch groupBy {
+	case UsageGroupByGroup:
+		column = "group_name"
+	case UsageGroupByKey:
+		column = "key_hash"
+	case UsageGroupByModel:
+		column = "model"
+	default:
+		return nil, fmt.Errorf("unsupported grouping '%s'", groupBy)
+	}
+
+	selects := column + `,
+		COUNT(*) as requests,
+		COALESCE(SUM(prompt_tokens), 0) as prompt_tokens,
+		COALESCE(SUM(completion_tokens), 0) as completion_tokens,
+		COALESCE(SUM(cost), 0) as cost`
+	if groupBy == UsageGroupByKey {
+		// Every log encrypts the key anew, so any one ciphertext of the hash identifies it.
+		selects += ", MAX(key_value) as key_value"
+	}
+
+	var results []UsageSummary
+	err := s.DB.Model(&models.RequestLog{}).
+		Scopes(s.logFiltersScope(c)).
+		Where("request_type = ?", models.RequestTypeFinal).
+		Select(selects).
+		Group(column).
+		Order("cost DESC, requests DESC").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].KeyValue == "" {
+			continue
+		}
+		if decrypted, err := s.EncryptionSvc.Decrypt(results[i].KeyValue); err != nil {
+			logrus.WithError(err).WithField("key_hash", results[i].KeyHash).Error("Failed to decrypt key for usage summary")
+			results[i].KeyValue = "failed-to-decrypt"
+		} else {
+			results[i].KeyValue = utils.MaskAPIKey(decrypted)
+		}
+	}
+	return results, nil
+}
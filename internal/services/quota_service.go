@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notify"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Quota windows. Windows are calendar days or months in UTC and reset at their boundary.
+const (
+	QuotaWindowNone    = "none"
+	QuotaWindowDaily   = "daily"
+	QuotaWindowMonthly = "monthly"
+)
+
+// QuotaUsage reports a group's consumption in the current quota window.
+type QuotaUsage struct {
+	Window       string    `json:"window"`
+	WindowStart  time.Time `json:"window_start"`
+	ResetsAt     time.Time `json:"resets_at"`
+	RequestsUsed int64     `json:"requests_used"`
+	MaxRequests  int       `json:"max_requests"`
+	TokensUsed   int64     `json:"tokens_used"`
+	MaxTokens    int       `json:"max_tokens"`
+}
+
+// QuotaService tracks per-group request and token quotas in the store.
+type QuotaService struct {
+	store    store.Store
+	notifier *notify.Notifier
+}
+
+// NewQuotaService creates a new QuotaService.
+func NewQuotaService(store store.Store, notifier *notify.Notifier) *QuotaService {
+	return &QuotaService{store: store, notifier: notifier}
+}
+
+// QuotaEnabled reports whether the group enforces a request or token quota.
+func QuotaEnabled(group *models.Group) bool {
+	cfg := group.EffectiveConfig
+	return cfg.QuotaWindow != "" && cfg.QuotaWindow != QuotaWindowNone && (cfg.QuotaMaxRequests > 0 || cfg.QuotaMaxTokens > 0)
+}
+
+// Usage returns the group's consumption in the current window.
+func (s *QuotaService) Usage(group *models.Group) (*QuotaUsage, error) {
+	cfg := group.EffectiveConfig
+	start, end := quotaWindowBounds(cfg.QuotaWindow, time.Now())
+	usage := &QuotaUsage{
+		Window:      cfg.QuotaWindow,
+		WindowStart: start,
+		ResetsAt:    end,
+		MaxRequests: cfg.QuotaMaxRequests,
+		MaxTokens:   cfg.QuotaMaxTokens,
+	}
+	if !QuotaEnabled(group) {
+		return usage, nil
+	}
+
+	counters, err := s.store.HGetAll(quotaStoreKey(group.ID, cfg.QuotaWindow, start))
+	if err != nil {
+		return nil, err
+	}
+	usage.RequestsUsed, _ = strconv.ParseInt(counters["requests"], 10, 64)
+	usage.TokensUsed, _ = strconv.ParseInt(counters["tokens"], 10, 64)
+	return usage, nil
+}
+
+// Admit counts a request against the group's quota, or rejects it with a 429 error once the request or
+// token quota of the current window is used up. Store errors fail open so an outage does not block traffic.
+func (s *QuotaService) Admit(group *models.Group) *app_errors.APIError {
+	if !QuotaEnabled(group) {
+		return nil
+	}
+	cfg := group.EffectiveConfig
+	start, end := quotaWindowBounds(cfg.QuotaWindow, time.Now())
+	key := quotaStoreKey(group.ID, cfg.QuotaWindow, start)
+
+	if cfg.QuotaMaxTokens > 0 {
+		counters, err := s.store.HGetAll(key)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to read group quota, admitting request")
+			return nil
+		}
+		if tokens, _ := strconv.ParseInt(counters["tokens"], 10, 64); tokens >= int64(cfg.QuotaMaxTokens) {
+			return quotaExceededError(group, "token", end)
+		}
+	}
+
+	requests, err := s.store.HIncrBy(key, "requests", 1)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to count request against group quota")
+		return nil
+	}
+	if requests == 1 {
+		// First request of a new window: the previous window's counters are no longer needed.
+		previousStart, _ := quotaWindowBounds(cfg.QuotaWindow, start.Add(-time.Nanosecond))
+		_ = s.store.Delete(quotaStoreKey(group.ID, cfg.QuotaWindow, previousStart))
+	}
+	if cfg.QuotaMaxRequests > 0 && requests > int64(cfg.QuotaMaxRequests) {
+		if requests == int64(cfg.QuotaMaxRequests)+1 {
+			s.notifyExhausted(group, "request", end)
+		}
+		return quotaExceededError(group, "request", end)
+	}
+	return nil
+}
+
+// Exhausted reports whether the group's request or token quota of the current window is used up, without
+// counting a request. Store errors report the quota as available, like Admit.
+func (s *QuotaService) Exhausted(group *models.Group) bool {
+	if !QuotaEnabled(group) {
+		return false
+	}
+	usage, err := s.Usage(group)
+	if err != nil {
+		return false
+	}
+	return (usage.MaxRequests > 0 && usage.RequestsUsed >= int64(usage.MaxRequests)) ||
+		(usage.MaxTokens > 0 && usage.TokensUsed >= int64(usage.MaxTokens))
+}
+
+// AddTokens counts consumed tokens against the group's quota.
+func (s *QuotaService) AddTokens(group *models.Group, tokens int64) {
+	if tokens <= 0 || !QuotaEnabled(group) {
+		return
+	}
+	cfg := group.EffectiveConfig
+	start, end := quotaWindowBounds(cfg.QuotaWindow, time.Now())
+	total, err := s.store.HIncrBy(quotaStoreKey(group.ID, cfg.QuotaWindow, start), "tokens", tokens)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to count tokens against group quota")
+		return
+	}
+	// Only the request crossing the limit reports it, so a window is reported once.
+	if limit := int64(cfg.QuotaMaxTokens); limit > 0 && total >= limit && total-tokens < limit {
+		s.notifyExhausted(group, "token", end)
+	}
+}
+
+// notifyExhausted reports through the webhook that a quota of the group has been used up.
+func (s *QuotaService) notifyExhausted(group *models.Group, kind string, resetsAt time.Time) {
+	s.notifier.Notify(notify.Event{
+		Type:     notify.EventQuotaExhausted,
+		Group:    group.Name,
+		Reason:   fmt.Sprintf("The %s quota of the %s window is used up", kind, group.EffectiveConfig.QuotaWindow),
+		ResetsAt: &resetsAt,
+	})
+}
+
+// QuotaResetsAt returns when the group's current quota window ends.
+func QuotaResetsAt(group *models.Group) time.Time {
+	_, end := quotaWindowBounds(group.EffectiveConfig.QuotaWindow, time.Now())
+	return end
+}
+
+func quotaExceededError(group *models.Group, kind string, resetsAt time.Time) *app_errors.APIError {
+	return app_errors.NewAPIError(app_errors.ErrQuotaExceeded,
+		fmt.Sprintf("The %s quota of group '%s' is exhausted until %s", kind, group.Name, resetsAt.Format(time.RFC3339)))
+}
+
+// quotaWindowBounds returns the UTC start and end of the window containing now.
+func quotaWindowBounds(window string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if window == QuotaWindowMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+func quotaStoreKey(groupID uint, window string, start time.Time) string {
+	return fmt.Sprintf("quota:%d:%s:%s", groupID, window, start.Format("20060102"))
+}
@@ -237,7 +237,10 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 		hourlyStats := make(map[struct {
 			Time    time.Time
 			GroupID uint
-		}]struct{ Success, Failure int64 })
+		}]struct {
+			Success, Failure, PromptTokens, CompletionTokens int64
+			Cost                                             float64
+		})
 		for _, log := range logs {
 			if log.RequestType == models.RequestTypeRetry {
 				continue
@@ -254,6 +257,9 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 			} else {
 				counts.Failure++
 			}
+			counts.PromptTokens += log.PromptTokens
+			counts.CompletionTokens += log.CompletionTokens
+			counts.Cost += log.Cost
 			hourlyStats[key] = counts
 
 			if log.ParentGroupID > 0 {
@@ -268,6 +274,9 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 				} else {
 					parentCounts.Failure++
 				}
+				parentCounts.PromptTokens += log.PromptTokens
+				parentCounts.CompletionTokens += log.CompletionTokens
+				parentCounts.Cost += log.Cost
 				hourlyStats[parentKey] = parentCounts
 			}
 		}
@@ -277,15 +286,21 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 				err := tx.Clauses(clause.OnConflict{
 					Columns: []clause.Column{{Name: "time"}, {Name: "group_id"}},
 					DoUpdates: clause.Assignments(map[string]any{
-						"success_count": gorm.Expr("group_hourly_stats.success_count + ?", counts.Success),
-						"failure_count": gorm.Expr("group_hourly_stats.failure_count + ?", counts.Failure),
-						"updated_at":    time.Now(),
+						"success_count":     gorm.Expr("group_hourly_stats.success_count + ?", counts.Success),
+						"failure_count":     gorm.Expr("group_hourly_stats.failure_count + ?", counts.Failure),
+						"prompt_tokens":     gorm.Expr("group_hourly_stats.prompt_tokens + ?", counts.PromptTokens),
+						"completion_tokens": gorm.Expr("group_hourly_stats.completion_tokens + ?", counts.CompletionTokens),
+						"cost":              gorm.Expr("group_hourly_stats.cost + ?", counts.Cost),
+						"updated_at":        time.Now(),
 					}),
 				}).Create(&models.GroupHourlyStat{
-					Time:         key.Time,
-					GroupID:      key.GroupID,
-					SuccessCount: counts.Success,
-					FailureCount: counts.Failure,
+					Time:             key.Time,
+					GroupID:          key.GroupID,
+					SuccessCount:     counts.Success,
+					FailureCount:     counts.Failure,
+					PromptTokens:     counts.PromptTokens,
+					CompletionTokens: counts.CompletionTokens,
+					Cost:             counts.Cost,
 				}).Error
 
 				if err != nil {
@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -193,6 +194,29 @@ func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
 	return result, nil
 }
 
+func (s *MemoryStore) HDel(key string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawHash, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+
+	hash, ok := rawHash.(map[string]string)
+	if !ok {
+		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	for _, field := range fields {
+		delete(hash, field)
+	}
+	if len(hash) == 0 {
+		delete(s.data, key)
+	}
+	return nil
+}
+
 func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -377,6 +401,108 @@ func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	return popped, nil
 }
 
+// --- SORTED SET operations ---
+
+// sortedSet returns the sorted set stored at key, creating it when create is set.
+func (s *MemoryStore) sortedSet(key string, create bool) (map[string]float64, error) {
+	rawSet, exists := s.data[key]
+	if !exists {
+		if !create {
+			return nil, nil
+		}
+		set := make(map[string]float64)
+		s.data[key] = set
+		return set, nil
+	}
+	set, ok := rawSet.(map[string]float64)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+	return set, nil
+}
+
+// sortedMembers orders the members of a sorted set by score, then lexicographically like Redis.
+func sortedMembers(set map[string]float64) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if set[members[i]] != set[members[j]] {
+			return set[members[i]] < set[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	return members
+}
+
+// ZAdd adds members to a sorted set, updating the scores of existing ones.
+func (s *MemoryStore) ZAdd(key string, members map[string]float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, true)
+	if err != nil {
+		return err
+	}
+	for member, score := range members {
+		set[member] = score
+	}
+	return nil
+}
+
+// ZRem removes members from a sorted set.
+func (s *MemoryStore) ZRem(key string, members ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil || set == nil {
+		return err
+	}
+	for _, member := range members {
+		delete(set, fmt.Sprint(member))
+	}
+	return nil
+}
+
+// ZRange returns all members of a sorted set, lowest score first.
+func (s *MemoryStore) ZRange(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+	return sortedMembers(set), nil
+}
+
+// ZRotateMin atomically takes the member of a sorted set with the lowest score, gives it the new score
+// and returns it.
+func (s *MemoryStore) ZRotateMin(key string, score float64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil {
+		return "", err
+	}
+	if len(set) == 0 {
+		return "", ErrNotFound
+	}
+
+	var minMember string
+	first := true
+	for member, memberScore := range set {
+		if first || memberScore < set[minMember] || (memberScore == set[minMember] && member < minMember) {
+			minMember, first = member, false
+		}
+	}
+	set[minMember] = score
+	return minMember, nil
+}
+
 // --- Pub/Sub operations ---
 
 // memorySubscription implements the Subscription interface for the in-memory store.
@@ -96,6 +96,10 @@ func (s *RedisStore) HGetAll(key string) (map[string]string, error) {
 	return s.client.HGetAll(context.Background(), s.prefixKey(key)).Result()
 }
 
+func (s *RedisStore) HDel(key string, fields ...string) error {
+	return s.client.HDel(context.Background(), s.prefixKey(key), fields...).Err()
+}
+
 func (s *RedisStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return s.client.HIncrBy(context.Background(), s.prefixKey(key), field, incr).Result()
 }
@@ -137,6 +141,51 @@ func (s *RedisStore) SPopN(key string, count int64) ([]string, error) {
 	return s.client.SPopN(context.Background(), s.prefixKey(key), count).Result()
 }
 
+// --- SORTED SET operations ---
+
+func (s *RedisStore) ZAdd(key string, members map[string]float64) error {
+	if len(members) == 0 {
+		return nil
+	}
+	zs := make([]redis.Z, 0, len(members))
+	for member, score := range members {
+		zs = append(zs, redis.Z{Score: score, Member: member})
+	}
+	return s.client.ZAdd(context.Background(), s.prefixKey(key), zs...).Err()
+}
+
+func (s *RedisStore) ZRem(key string, members ...any) error {
+	return s.client.ZRem(context.Background(), s.prefixKey(key), members...).Err()
+}
+
+// ZRange returns all members of a sorted set, lowest score first.
+func (s *RedisStore) ZRange(key string) ([]string, error) {
+	return s.client.ZRange(context.Background(), s.prefixKey(key), 0, -1).Result()
+}
+
+// zRotateMinScript re-scores the lowest-scored member of a sorted set and returns it.
+var zRotateMinScript = redis.NewScript(`
+local members = redis.call('ZRANGE', KEYS[1], 0, 0)
+if #members == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[1], ARGV[1], members[1])
+return members[1]
+`)
+
+// ZRotateMin atomically takes the member of a sorted set with the lowest score, gives it the new score
+// and returns it.
+func (s *RedisStore) ZRotateMin(key string, score float64) (string, error) {
+	val, err := zRotateMinScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, score).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return val, nil
+}
+
 // --- Pipeliner implementation ---
 
 type redisPipeliner struct {
@@ -43,6 +43,7 @@ type Store interface {
 	// HASH operations
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
+	HDel(key string, fields ...string) error
 	HIncrBy(key, field string, incr int64) (int64, error)
 
 	// LIST operations
@@ -55,6 +56,12 @@ type Store interface {
 	SAdd(key string, members ...any) error
 	SPopN(key string, count int64) ([]string, error)
 
+	// SORTED SET operations
+	ZAdd(key string, members map[string]float64) error
+	ZRem(key string, members ...any) error
+	ZRange(key string) ([]string, error)
+	ZRotateMin(key string, score float64) (string, error)
+
 	// Close closes the store and releases any underlying resources.
 	Close() error
 
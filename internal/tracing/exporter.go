@@ -0,0 +1,230 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	exportQueueSize    = 2048
+	exportBatchSize    = 512
+	exportInterval     = 5 * time.Second
+	exportTimeout      = 10 * time.Second
+	otlpTracesPath     = "/v1/traces"
+	otlpStatusCodeOK   = 1
+	otlpStatusCodeFail = 2
+)
+
+// exporter batches finished spans and posts them as OTLP/HTTP JSON. Spans are dropped, not blocked on,
+// when the queue is full so a slow collector never delays proxied requests.
+type exporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	queue chan *Span
+	done  chan struct{}
+	flush chan context.Context
+}
+
+func newExporter(cfg types.TracingConfig) *exporter {
+	url := strings.TrimRight(cfg.Endpoint, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+	return &exporter{
+		url:         url,
+		headers:     cfg.Headers,
+		serviceName: cfg.ServiceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, exportQueueSize),
+		done:        make(chan struct{}),
+		flush:       make(chan context.Context),
+	}
+}
+
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		logrus.Debug("Trace export queue is full, dropping span")
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				e.export(context.Background(), batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.export(context.Background(), batch)
+				batch = batch[:0]
+			}
+		case ctx := <-e.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			if len(batch) > 0 {
+				e.export(ctx, batch)
+			}
+			return
+		}
+	}
+}
+
+// stop exports the remaining spans and waits for the exporter to finish or ctx to expire.
+func (e *exporter) stop(ctx context.Context) {
+	select {
+	case e.flush <- ctx:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+}
+
+func (e *exporter) export(ctx context.Context, batch []*Span) {
+	body, err := json.Marshal(e.buildPayload(batch))
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to encode trace spans")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create trace export request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to export trace spans")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logrus.WithField("status", resp.StatusCode).Warn("Trace collector rejected spans")
+	}
+}
+
+// OTLP/JSON payload types. Ids are hex encoded and 64-bit integers are decimal strings.
+type otlpPayload struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (e *exporter) buildPayload(batch []*Span) otlpPayload {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		span.mu.Lock()
+		out := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        make([]otlpAttribute, 0, len(span.attrs)),
+			Status:            otlpStatus{Code: otlpStatusCodeOK},
+		}
+		if span.parentID != ([8]byte{}) {
+			out.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		for _, attr := range span.attrs {
+			out.Attributes = append(out.Attributes, toOTLPAttribute(attr))
+		}
+		if span.errorMsg != "" {
+			out.Status = otlpStatus{Code: otlpStatusCodeFail, Message: span.errorMsg}
+		}
+		span.mu.Unlock()
+		spans = append(spans, out)
+	}
+
+	return otlpPayload{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{toOTLPAttribute(String("service.name", e.serviceName))}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "gpt-load/proxy"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func toOTLPAttribute(attr Attribute) otlpAttribute {
+	if attr.isInt {
+		return otlpAttribute{Key: attr.Key, Value: map[string]any{"intValue": fmt.Sprint(attr.intValue)}}
+	}
+	return otlpAttribute{Key: attr.Key, Value: map[string]any{"stringValue": attr.strValue}}
+}
@@ -0,0 +1,192 @@
+// Package tracing records OpenTelemetry-compatible spans for proxied requests and exports them to an
+// OTLP/HTTP collector. Without a configured endpoint every call is a no-op: Start returns a nil span
+// and all span methods accept a nil receiver.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+)
+
+// Span kinds as defined by OTLP.
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+var activeExporter atomic.Pointer[exporter]
+
+// Attribute is a span attribute; only string and integer values are used by the proxy.
+type Attribute struct {
+	Key      string
+	strValue string
+	intValue int64
+	isInt    bool
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, strValue: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, intValue: int64(value), isInt: true}
+}
+
+// Span is one timed operation of a trace.
+type Span struct {
+	name     string
+	kind     int
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+
+	mu       sync.Mutex
+	end      time.Time
+	attrs    []Attribute
+	errorMsg string
+	ended    bool
+}
+
+type spanKey struct{}
+
+// Configure starts exporting spans when an endpoint is configured.
+func Configure(cfg types.TracingConfig) {
+	if cfg.Endpoint == "" {
+		return
+	}
+	exp := newExporter(cfg)
+	activeExporter.Store(exp)
+	go exp.run()
+}
+
+// Shutdown flushes the buffered spans and stops exporting.
+func Shutdown(ctx context.Context) {
+	if exp := activeExporter.Swap(nil); exp != nil {
+		exp.stop(ctx)
+	}
+}
+
+// Start begins a span as a child of the span in ctx. The root span of a request takes its trace id from
+// an incoming W3C traceparent header, if given, and otherwise from the request's log trace id so traces
+// and log lines can be correlated.
+func Start(ctx context.Context, name string, kind int, attrs ...Attribute) (context.Context, *Span) {
+	if activeExporter.Load() == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now(), attrs: attrs}
+	_, _ = rand.Read(span.spanID[:])
+	if parent := parentFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else if remote := remoteParentFromContext(ctx); remote != nil {
+		span.traceID = remote.traceID
+		span.parentID = remote.spanID
+	} else if id, err := hex.DecodeString(strings.ReplaceAll(utils.TraceIDFromContext(ctx), "-", "")); err == nil && len(id) == len(span.traceID) {
+		copy(span.traceID[:], id)
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errorMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if exp := activeExporter.Load(); exp != nil {
+		exp.enqueue(s)
+	}
+}
+
+// Inject writes the W3C traceparent of the span in ctx into an outgoing request's headers.
+func Inject(ctx context.Context, header http.Header) {
+	if span := parentFromContext(ctx); span != nil {
+		header.Set("traceparent", "00-"+hex.EncodeToString(span.traceID[:])+"-"+hex.EncodeToString(span.spanID[:])+"-01")
+	}
+}
+
+// Extract returns a context carrying the remote parent from an incoming W3C traceparent header, if valid.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if activeExporter.Load() == nil {
+		return ctx
+	}
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var remote Span
+	if _, err := hex.Decode(remote.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(remote.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	if remote.traceID == ([16]byte{}) || remote.spanID == ([8]byte{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, &remote)
+}
+
+// HashKeyID returns a short, stable digest of a key id so spans do not reveal the key pool layout.
+func HashKeyID(keyID uint) string {
+	sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(keyID), 10)))
+	return hex.EncodeToString(sum[:8])
+}
+
+type remoteParentKey struct{}
+
+func parentFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func remoteParentFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(remoteParentKey{}).(*Span)
+	return span
+}
@@ -7,6 +7,8 @@ type ConfigManager interface {
 	GetCORSConfig() CORSConfig
 	GetPerformanceConfig() PerformanceConfig
 	GetLogConfig() LogConfig
+	GetMetricsConfig() MetricsConfig
+	GetTracingConfig() TracingConfig
 	GetDatabaseConfig() DatabaseConfig
 	GetEncryptionKey() string
 	GetEffectiveServerConfig() ServerConfig
@@ -24,22 +26,87 @@ type SystemSettings struct {
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"config.log_retention_days" category:"config.category.basic" desc:"config.log_retention_days_desc" validate:"required,min=0"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"config.log_write_interval" category:"config.category.basic" desc:"config.log_write_interval_desc" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
+	RequestLifecycleLogLevel       string `json:"request_lifecycle_log_level" default:"off" name:"config.request_lifecycle_log_level" category:"config.category.basic" desc:"config.request_lifecycle_log_level_desc" validate:"required,oneof=off errors all"`
+	LogUpstreamHeaders             bool   `json:"log_upstream_headers" default:"false" name:"config.log_upstream_headers" category:"config.category.basic" desc:"config.log_upstream_headers_desc"`
+	RequestLogFields               string `json:"request_log_fields" name:"config.request_log_fields" category:"config.category.basic" desc:"config.request_log_fields_desc"`
+	ModelPrices                    string `json:"model_prices" name:"config.model_prices" category:"config.category.basic" desc:"config.model_prices_desc"`
+	WebhookURL                     string `json:"webhook_url" name:"config.webhook_url" category:"config.category.basic" desc:"config.webhook_url_desc"`
+	WebhookFormat                  string `json:"webhook_format" default:"raw" name:"config.webhook_format" category:"config.category.basic" desc:"config.webhook_format_desc" validate:"required,oneof=raw slack discord"`
+	WebhookBatchSeconds            int    `json:"webhook_batch_seconds" default:"30" name:"config.webhook_batch_seconds" category:"config.category.basic" desc:"config.webhook_batch_seconds_desc" validate:"required,min=0"`
 
 	// 请求设置
 	RequestTimeout        int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
+	MaxRequestTimeout     int    `json:"max_request_timeout" default:"0" name:"config.max_request_timeout" category:"config.category.request" desc:"config.max_request_timeout_desc" validate:"min=0"`
 	ConnectTimeout        int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
 	IdleConnTimeout       int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
 	ResponseHeaderTimeout int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
+	ForceHTTP2            bool   `json:"force_http2" default:"false" name:"config.force_http2" category:"config.category.request" desc:"config.force_http2_desc"`
+	TLSHandshakeTimeout   int    `json:"tls_handshake_timeout" default:"15" name:"config.tls_handshake_timeout" category:"config.category.request" desc:"config.tls_handshake_timeout_desc" validate:"required,min=1"`
 	MaxIdleConns          int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
 	MaxIdleConnsPerHost   int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
 	ProxyURL              string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	TLSMinVersion         string `json:"tls_min_version" default:"1.2" name:"config.tls_min_version" category:"config.category.request" desc:"config.tls_min_version_desc" validate:"required,oneof=1.0 1.1 1.2 1.3"`
+	TLSCipherSuites       string `json:"tls_cipher_suites" name:"config.tls_cipher_suites" category:"config.category.request" desc:"config.tls_cipher_suites_desc"`
+	AllowedContentTypes   string `json:"allowed_content_types" name:"config.allowed_content_types" category:"config.category.request" desc:"config.allowed_content_types_desc"`
+	PassthroughHeaders    string `json:"passthrough_headers" name:"config.passthrough_headers" category:"config.category.request" desc:"config.passthrough_headers_desc"`
+	GroupMaxConcurrency   int    `json:"group_max_concurrency" default:"0" name:"config.group_max_concurrency" category:"config.category.request" desc:"config.group_max_concurrency_desc" validate:"required,min=0"`
+	KeyMaxConcurrency     int    `json:"key_max_concurrency" default:"0" name:"config.key_max_concurrency" category:"config.category.request" desc:"config.key_max_concurrency_desc" validate:"required,min=0"`
+	AllowForceKey         bool   `json:"allow_force_key" default:"false" name:"config.allow_force_key" category:"config.category.request" desc:"config.allow_force_key_desc"`
+	RejectUnknownModels   bool   `json:"reject_unknown_models" default:"false" name:"config.reject_unknown_models" category:"config.category.request" desc:"config.reject_unknown_models_desc"`
+	ModelAliases          string `json:"model_aliases" name:"config.model_aliases" category:"config.category.request" desc:"config.model_aliases_desc"`
+	ModelListCacheTTL     int    `json:"model_list_cache_ttl" default:"0" name:"config.model_list_cache_ttl" category:"config.category.request" desc:"config.model_list_cache_ttl_desc" validate:"required,min=0"`
+	ModelAllowlist        string `json:"model_allowlist" name:"config.model_allowlist" category:"config.category.request" desc:"config.model_allowlist_desc"`
+	ModelDenylist         string `json:"model_denylist" name:"config.model_denylist" category:"config.category.request" desc:"config.model_denylist_desc"`
+	FairShareClientHeader string `json:"fair_share_client_header" name:"config.fair_share_client_header" category:"config.category.request" desc:"config.fair_share_client_header_desc"`
+	SessionAffinityHeader string `json:"session_affinity_header" name:"config.session_affinity_header" category:"config.category.request" desc:"config.session_affinity_header_desc"`
+	ResponseCacheTTL      int    `json:"response_cache_ttl" default:"0" name:"config.response_cache_ttl" category:"config.category.request" desc:"config.response_cache_ttl_desc" validate:"required,min=0"`
+	ResponseCacheMaxMB    int    `json:"response_cache_max_mb" default:"64" name:"config.response_cache_max_mb" category:"config.category.request" desc:"config.response_cache_max_mb_desc" validate:"required,min=1,max=4096"`
+	CoalesceRequests      bool   `json:"coalesce_requests" default:"false" name:"config.coalesce_requests" category:"config.category.request" desc:"config.coalesce_requests_desc"`
+	QuotaWindow           string `json:"quota_window" default:"none" name:"config.quota_window" category:"config.category.request" desc:"config.quota_window_desc" validate:"required,oneof=none daily monthly"`
+	QuotaMaxRequests      int    `json:"quota_max_requests" default:"0" name:"config.quota_max_requests" category:"config.category.request" desc:"config.quota_max_requests_desc" validate:"required,min=0"`
+	QuotaMaxTokens        int    `json:"quota_max_tokens" default:"0" name:"config.quota_max_tokens" category:"config.category.request" desc:"config.quota_max_tokens_desc" validate:"required,min=0"`
+	FallbackGroups        string `json:"fallback_groups" name:"config.fallback_groups" category:"config.category.request" desc:"config.fallback_groups_desc"`
+	ShadowGroup           string `json:"shadow_group" name:"config.shadow_group" category:"config.category.request" desc:"config.shadow_group_desc"`
+	ShadowPercentage      int    `json:"shadow_percentage" default:"0" name:"config.shadow_percentage" category:"config.category.request" desc:"config.shadow_percentage_desc" validate:"min=0,max=100"`
+	MaxRequestBodyMB      int    `json:"max_request_body_mb" default:"0" name:"config.max_request_body_mb" category:"config.category.request" desc:"config.max_request_body_mb_desc" validate:"required,min=0"`
+	MaxResponseBodyMB     int    `json:"max_response_body_mb" default:"0" name:"config.max_response_body_mb" category:"config.category.request" desc:"config.max_response_body_mb_desc" validate:"required,min=0"`
+	MaxInputTokens        int    `json:"max_input_tokens" default:"0" name:"config.max_input_tokens" category:"config.category.request" desc:"config.max_input_tokens_desc" validate:"required,min=0"`
+	StreamTokensPerSecond int    `json:"stream_tokens_per_second" default:"0" name:"config.stream_tokens_per_second" category:"config.category.request" desc:"config.stream_tokens_per_second_desc" validate:"required,min=0"`
+	InputTokenEstimation  string `json:"input_token_estimation" default:"heuristic" name:"config.input_token_estimation" category:"config.category.request" desc:"config.input_token_estimation_desc" validate:"required,oneof=heuristic count_tokens"`
+	VertexAutoRegion      bool   `json:"vertex_auto_region" default:"false" name:"config.vertex_auto_region" category:"config.category.request" desc:"config.vertex_auto_region_desc"`
+	VertexStrictProject   bool   `json:"vertex_strict_project" default:"false" name:"config.vertex_strict_project" category:"config.category.request" desc:"config.vertex_strict_project_desc"`
+	VertexOpenAIFallback  bool   `json:"vertex_openai_fallback" default:"false" name:"config.vertex_openai_fallback" category:"config.category.request" desc:"config.vertex_openai_fallback_desc"`
+	VertexTokenSkew       int    `json:"vertex_token_skew" default:"120" name:"config.vertex_token_skew" category:"config.category.request" desc:"config.vertex_token_skew_desc" validate:"required,min=0,max=3000"`
+	VertexMintTimeout     int    `json:"vertex_mint_timeout" default:"30" name:"config.vertex_mint_timeout" category:"config.category.request" desc:"config.vertex_mint_timeout_desc" validate:"required,min=1,max=600"`
+	VertexOAuthScopes     string `json:"vertex_oauth_scopes" name:"config.vertex_oauth_scopes" category:"config.category.request" desc:"config.vertex_oauth_scopes_desc"`
+	VertexAccountOrder    string `json:"vertex_account_order" default:"failover" name:"config.vertex_account_order" category:"config.category.request" desc:"config.vertex_account_order_desc" validate:"required,oneof=failover round_robin"`
+	VertexTokenMaxAge     int    `json:"vertex_token_max_age" default:"0" name:"config.vertex_token_max_age" category:"config.category.request" desc:"config.vertex_token_max_age_desc" validate:"required,min=0"`
+	VertexTokenURI        string `json:"vertex_token_uri" name:"config.vertex_token_uri" category:"config.category.request" desc:"config.vertex_token_uri_desc"`
+	VertexCachedContent   string `json:"vertex_cached_content" name:"config.vertex_cached_content" category:"config.category.request" desc:"config.vertex_cached_content_desc"`
+	VertexGCSBucket       string `json:"vertex_gcs_bucket" name:"config.vertex_gcs_bucket" category:"config.category.request" desc:"config.vertex_gcs_bucket_desc"`
+	VertexGCSThresholdKB  int    `json:"vertex_gcs_threshold_kb" default:"1024" name:"config.vertex_gcs_threshold_kb" category:"config.category.request" desc:"config.vertex_gcs_threshold_kb_desc" validate:"required,min=1"`
+	VertexTokenCache      string `json:"vertex_token_cache" default:"shared" name:"config.vertex_token_cache" category:"config.category.request" desc:"config.vertex_token_cache_desc" validate:"required,oneof=shared memory"`
+	StripReasoningContent bool   `json:"strip_reasoning_content" default:"false" name:"config.strip_reasoning_content" category:"config.category.request" desc:"config.strip_reasoning_content_desc"`
+	GroundingMetadataMode string `json:"grounding_metadata_mode" default:"passthrough" name:"config.grounding_metadata_mode" category:"config.category.request" desc:"config.grounding_metadata_mode_desc" validate:"required,oneof=passthrough strip redact"`
+	ClientAPIFormat       string `json:"client_api_format" default:"native" name:"config.client_api_format" category:"config.category.request" desc:"config.client_api_format_desc" validate:"required,oneof=native openai gemini"`
+	AzureAPIVersion       string `json:"azure_api_version" default:"2024-10-21" name:"config.azure_api_version" category:"config.category.request" desc:"config.azure_api_version_desc" validate:"required"`
+	UpstreamPathPrefix    string `json:"upstream_path_prefix" name:"config.upstream_path_prefix" category:"config.category.request" desc:"config.upstream_path_prefix_desc"`
 
 	// 密钥配置
-	MaxRetries                   int `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
-	BlacklistThreshold           int `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
-	KeyValidationIntervalMinutes int `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
-	KeyValidationConcurrency     int `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
-	KeyValidationTimeoutSeconds  int `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	MaxRetries                   int    `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
+	RetryStatusCodes             string `json:"retry_status_codes" name:"config.retry_status_codes" category:"config.category.key" desc:"config.retry_status_codes_desc"`
+	RetryStreamRequests          bool   `json:"retry_stream_requests" default:"true" name:"config.retry_stream_requests" category:"config.category.key" desc:"config.retry_stream_requests_desc"`
+	BlacklistThreshold           int    `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
+	KeyValidationIntervalMinutes int    `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
+	RevalidationBackoffBase      int    `json:"revalidation_backoff_base" default:"5" name:"config.revalidation_backoff_base" category:"config.category.key" desc:"config.revalidation_backoff_base_desc" validate:"required,min=1"`
+	RevalidationBackoffMax       int    `json:"revalidation_backoff_max" default:"360" name:"config.revalidation_backoff_max" category:"config.category.key" desc:"config.revalidation_backoff_max_desc" validate:"required,min=1"`
+	KeyValidationConcurrency     int    `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
+	KeyValidationTimeoutSeconds  int    `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	KeyValidationMaxInFlight     int    `json:"key_validation_max_inflight" default:"0" name:"config.key_validation_max_inflight" category:"config.category.key" desc:"config.key_validation_max_inflight_desc" validate:"required,min=0"`
+	DedupeKeysOnImport           bool   `json:"dedupe_keys_on_import" default:"false" name:"config.dedupe_keys_on_import" category:"config.category.key" desc:"config.dedupe_keys_on_import_desc"`
+	KeySelectionStrategy         string `json:"key_selection_strategy" default:"round_robin" name:"config.key_selection_strategy" category:"config.category.key" desc:"config.key_selection_strategy_desc" validate:"required,oneof=round_robin least_recently_used"`
+	RequiredKeyTags              string `json:"required_key_tags" name:"config.required_key_tags" category:"config.category.key" desc:"config.required_key_tags_desc"`
+	KeyExpiryWarningHours        int    `json:"key_expiry_warning_hours" default:"72" name:"config.key_expiry_warning_hours" category:"config.category.key" desc:"config.key_expiry_warning_hours_desc" validate:"required,min=0"`
 
 	// For cache
 	ProxyKeysMap map[string]struct{} `json:"-"`
@@ -81,6 +148,22 @@ type LogConfig struct {
 	Format     string `json:"format"`
 	EnableFile bool   `json:"enable_file"`
 	FilePath   string `json:"file_path"`
+
+	EnableAccessLog bool   `json:"enable_access_log"`
+	AccessLogPath   string `json:"access_log_path"`
+}
+
+// MetricsConfig represents metrics endpoint configuration
+type MetricsConfig struct {
+	Enabled  bool `json:"enabled"`
+	KeyLabel bool `json:"key_label"`
+}
+
+// TracingConfig represents OpenTelemetry trace export configuration
+type TracingConfig struct {
+	Endpoint    string            `json:"endpoint"`
+	Headers     map[string]string `json:"-"`
+	ServiceName string            `json:"service_name"`
 }
 
 // DatabaseConfig represents database configuration
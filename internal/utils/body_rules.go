@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/models"
+)
+
+// Body rule actions
+const (
+	BodyRuleSet     = "set"
+	BodyRuleRemove  = "remove"
+	BodyRuleDefault = "default"
+)
+
+// SplitBodyRulePath splits a body rule path such as "$.generationConfig.temperature" or
+// "messages.0.content" into its segments. Numeric segments index arrays.
+func SplitBodyRulePath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// ValidateBodyRule checks that a rule has a path, a known action and a value when the action needs one.
+func ValidateBodyRule(rule models.BodyRule) error {
+	segments := SplitBodyRulePath(rule.Path)
+	if len(segments) == 0 {
+		return fmt.Errorf("path cannot be empty")
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("path '%s' has an empty segment", rule.Path)
+		}
+	}
+
+	switch rule.Action {
+	case BodyRuleRemove:
+	case BodyRuleSet, BodyRuleDefault:
+		if len(rule.Value) == 0 || !json.Valid(rule.Value) {
+			return fmt.Errorf("rule for path '%s' needs a JSON value", rule.Path)
+		}
+	default:
+		return fmt.Errorf("unknown action '%s' for path '%s'", rule.Action, rule.Path)
+	}
+	return nil
+}
+
+// ApplyBodyRules applies body rules to a JSON object body in order. Bodies that are not JSON objects
+// are returned unchanged, as is the body when no rule changed anything.
+func ApplyBodyRules(body []byte, rules []models.BodyRule) ([]byte, error) {
+	if len(rules) == 0 || len(body) == 0 {
+		return body, nil
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, nil
+	}
+
+	modified := false
+	for _, rule := range rules {
+		segments := SplitBodyRulePath(rule.Path)
+		if len(segments) == 0 {
+			continue
+		}
+
+		switch rule.Action {
+		case BodyRuleRemove:
+			if removeBodyValue(payload, segments) {
+				modified = true
+			}
+		case BodyRuleSet, BodyRuleDefault:
+			var value any
+			if err := json.Unmarshal(rule.Value, &value); err != nil {
+				return nil, fmt.Errorf("invalid value for body rule '%s': %w", rule.Path, err)
+			}
+			if setBodyValue(payload, segments, value, rule.Action == BodyRuleDefault) {
+				modified = true
+			}
+		}
+	}
+
+	if !modified {
+		return body, nil
+	}
+	return json.Marshal(payload)
+}
+
+// lookupBodyValue returns the value at the path of a decoded JSON value.
+func lookupBodyValue(value any, segments []string) (any, bool) {
+	if len(segments) == 0 {
+		return nil, false
+	}
+	for _, segment := range segments {
+		switch v := value.(type) {
+		case map[string]any:
+			child, exists := v[segment]
+			if !exists {
+				return nil, false
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setBodyValue sets the value at the path, creating missing objects on the way. With onlyIfMissing the
+// value is set only when the path does not exist yet. Paths through arrays require the element to exist.
+func setBodyValue(container any, segments []string, value any, onlyIfMissing bool) bool {
+	key, rest := segments[0], segments[1:]
+
+	switch c := container.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			if _, exists := c[key]; exists && onlyIfMissing {
+				return false
+			}
+			c[key] = value
+			return true
+		}
+		child, exists := c[key]
+		if !exists || child == nil {
+			child = map[string]any{}
+			c[key] = child
+		}
+		return setBodyValue(child, rest, value, onlyIfMissing)
+	case []any:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(c) {
+			return false
+		}
+		if len(rest) == 0 {
+			if onlyIfMissing {
+				return false
+			}
+			c[index] = value
+			return true
+		}
+		return setBodyValue(c[index], rest, value, onlyIfMissing)
+	default:
+		return false
+	}
+}
+
+// removeBodyValue removes the value at the path. Array elements are removed, shifting later ones.
+func removeBodyValue(container any, segments []string) bool {
+	key, rest := segments[0], segments[1:]
+
+	switch c := container.(type) {
+	case map[string]any:
+		child, exists := c[key]
+		if !exists {
+			return false
+		}
+		if len(rest) == 0 {
+			delete(c, key)
+			return true
+		}
+		if array, ok := child.([]any); ok && len(rest) == 1 {
+			if index, err := strconv.Atoi(rest[0]); err == nil && index >= 0 && index < len(array) {
+				c[key] = append(array[:index:index], array[index+1:]...)
+				return true
+			}
+			return false
+		}
+		return removeBodyValue(child, rest)
+	case []any:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(c) || len(rest) == 0 {
+			return false
+		}
+		return removeBodyValue(c[index], rest)
+	default:
+		return false
+	}
+}
@@ -163,6 +163,18 @@ func ParseArray(value string, defaultValue []string) []string {
 	return result
 }
 
+// ParseKeyValuePairs parses a comma-separated list of key=value pairs; malformed entries are skipped
+func ParseKeyValuePairs(value string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range ParseArray(value, nil) {
+		key, val, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			result[key] = strings.TrimSpace(val)
+		}
+	}
+	return result
+}
+
 // GetEnvOrDefault gets environment variable or default value
 func GetEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
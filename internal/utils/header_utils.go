@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"encoding/json"
 	"gpt-load/internal/models"
 	"net/http"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -10,11 +13,56 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// sensitiveHeaders lists headers whose values carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+	"X-Goog-Api-Key":      true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// MaskHeaders returns a copy of the headers suitable for logging, with credential values masked.
+// For "Bearer"-style values only the credential part is masked.
+func MaskHeaders(headers http.Header) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			if scheme, credential, found := strings.Cut(value, " "); found {
+				value = scheme + " " + maskSecret(credential)
+			} else {
+				value = maskSecret(value)
+			}
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// maskSecret masks a secret, hiding short values entirely.
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return MaskAPIKey(secret)
+}
+
+// bodyVariablePattern matches ${BODY:path} variables, which take a field of the request body.
+var bodyVariablePattern = regexp.MustCompile(`\$\{BODY:([^}]*)\}`)
+
 // HeaderVariableContext holds context data for variable resolution
 type HeaderVariableContext struct {
 	ClientIP string
 	Group    *models.Group
 	APIKey   *models.APIKey
+	// Model is the model sent upstream and Body the request body, when the request has them.
+	Model string
+	Body  []byte
+	// Path is the request path below the group and Stream whether a streaming response was requested.
+	Path   string
+	Stream bool
 }
 
 // ResolveHeaderVariables resolves dynamic variables in header values
@@ -41,14 +89,49 @@ func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 		variables["${API_KEY}"] = ctx.APIKey.KeyValue
 	}
 
+	variables["${MODEL}"] = ctx.Model
+
 	// Replace variables in the value
 	for variable, replacement := range variables {
 		result = strings.ReplaceAll(result, variable, replacement)
 	}
 
+	if strings.Contains(result, "${BODY:") {
+		result = resolveBodyVariables(result, ctx.Body)
+	}
+
 	return result
 }
 
+// resolveBodyVariables replaces ${BODY:path} variables with fields of a JSON body, using the paths of body
+// rules. Strings are inserted as they are and other values as JSON; missing fields resolve to "".
+func resolveBodyVariables(value string, body []byte) string {
+	var payload any
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			payload = nil
+		}
+	}
+
+	return bodyVariablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		path := bodyVariablePattern.FindStringSubmatch(match)[1]
+		field, ok := lookupBodyValue(payload, SplitBodyRulePath(path))
+		if !ok || field == nil {
+			return ""
+		}
+		rendered, isString := field.(string)
+		if !isString {
+			encoded, err := json.Marshal(field)
+			if err != nil {
+				return ""
+			}
+			rendered = string(encoded)
+		}
+		// Line breaks are not allowed in header values.
+		return strings.NewReplacer("\r", " ", "\n", " ").Replace(rendered)
+	})
+}
+
 // ApplyHeaderRules applies header rules to the HTTP request
 func ApplyHeaderRules(req *http.Request, rules []models.HeaderRule, ctx *HeaderVariableContext) {
 	if req == nil || len(rules) == 0 {
@@ -56,6 +139,9 @@ func ApplyHeaderRules(req *http.Request, rules []models.HeaderRule, ctx *HeaderV
 	}
 
 	for _, rule := range rules {
+		if !HeaderRuleMatches(rule.Match, ctx) {
+			continue
+		}
 		canonicalKey := http.CanonicalHeaderKey(rule.Key)
 
 		switch rule.Action {
@@ -68,6 +154,33 @@ func ApplyHeaderRules(req *http.Request, rules []models.HeaderRule, ctx *HeaderV
 	}
 }
 
+// HeaderRuleMatches reports whether a request satisfies a header rule condition. Rules without a
+// condition always apply; conditional rules never apply without a request context.
+func HeaderRuleMatches(match *models.HeaderRuleMatch, ctx *HeaderVariableContext) bool {
+	if match == nil {
+		return true
+	}
+	if ctx == nil {
+		return false
+	}
+
+	if match.Stream != nil && *match.Stream != ctx.Stream {
+		return false
+	}
+	if match.PathPrefix != "" && !strings.HasPrefix(ctx.Path, match.PathPrefix) {
+		return false
+	}
+	if len(match.Models) > 0 {
+		for _, pattern := range match.Models {
+			if matched, _ := path.Match(pattern, ctx.Model); matched {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
 // NewHeaderVariableContextFromGin creates HeaderVariableContext from Gin context
 func NewHeaderVariableContextFromGin(c *gin.Context, group *models.Group, apiKey *models.APIKey) *HeaderVariableContext {
 	if c == nil {
@@ -83,9 +196,13 @@ func NewHeaderVariableContextFromGin(c *gin.Context, group *models.Group, apiKey
 
 // NewHeaderVariableContext creates HeaderVariableContext without Gin context
 func NewHeaderVariableContext(group *models.Group, apiKey *models.APIKey) *HeaderVariableContext {
-	return &HeaderVariableContext{
+	ctx := &HeaderVariableContext{
 		ClientIP: "127.0.0.1",
 		Group:    group,
 		APIKey:   apiKey,
 	}
+	if group != nil {
+		ctx.Model = group.TestModel
+	}
+	return ctx
 }
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 请求生命周期日志级别
+const (
+	LifecycleLogOff    = "off"
+	LifecycleLogErrors = "errors"
+	LifecycleLogAll    = "all"
+)
+
+// LogRequestLifecycle logs a request lifecycle event according to the group's verbosity setting,
+// tagged with the trace id carried by ctx.
+func LogRequestLifecycle(ctx context.Context, group *models.Group, isError bool, fields logrus.Fields, msg string) {
+	if group == nil {
+		return
+	}
+
+	switch group.EffectiveConfig.RequestLifecycleLogLevel {
+	case LifecycleLogAll:
+	case LifecycleLogErrors:
+		if !isError {
+			return
+		}
+	default:
+		return
+	}
+
+	entry := LoggerFromContext(ctx).WithField("group", group.Name).WithFields(fields)
+	if isError {
+		entry.Warn(msg)
+	} else {
+		entry.Info(msg)
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLogs sends the standard logger's output to a buffer as JSON lines for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	logger := logrus.StandardLogger()
+	out, formatter, level := logger.Out, logger.Formatter, logger.GetLevel()
+	t.Cleanup(func() {
+		logger.SetOutput(out)
+		logger.SetFormatter(formatter)
+		logger.SetLevel(level)
+	})
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.InfoLevel)
+	return &buf
+}
+
+func TestLogRequestLifecycleVerbosity(t *testing.T) {
+	// One failed attempt retried with another key.
+	events := []struct {
+		isError bool
+		msg     string
+	}{
+		{false, "Key selected"},
+		{false, "Upstream request prepared"},
+		{true, "Upstream request failed"},
+		{true, "Retrying request with another key"},
+		{false, "Key selected"},
+		{false, "Upstream responded"},
+	}
+
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{"", nil},
+		{LifecycleLogOff, nil},
+		{LifecycleLogErrors, []string{
+			"warning: Upstream request failed",
+			"warning: Retrying request with another key",
+		}},
+		{LifecycleLogAll, []string{
+			"info: Key selected",
+			"info: Upstream request prepared",
+			"warning: Upstream request failed",
+			"warning: Retrying request with another key",
+			"info: Key selected",
+			"info: Upstream responded",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("level "+tt.level, func(t *testing.T) {
+			buf := captureLogs(t)
+			group := &models.Group{Name: "g"}
+			group.EffectiveConfig.RequestLifecycleLogLevel = tt.level
+			ctx := WithTraceID(context.Background(), "trace-1")
+
+			for _, event := range events {
+				LogRequestLifecycle(ctx, group, event.isError, logrus.Fields{"attempt": 1}, event.msg)
+			}
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("log line %q: %v", line, err)
+				}
+				if record["group"] != "g" || record["trace_id"] != "trace-1" {
+					t.Errorf("log record %v is missing the group or trace id", record)
+				}
+				got = append(got, fmt.Sprintf("%v: %v", record["level"], record["msg"]))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("logged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogRequestLifecycleWithoutGroup(t *testing.T) {
+	buf := captureLogs(t)
+	LogRequestLifecycle(context.Background(), nil, true, nil, "Key selection failed")
+	if buf.Len() != 0 {
+		t.Errorf("logged %q without a group, want nothing", buf.String())
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseModelAliases parses a JSON alias map such as {"flash":"gemini-1.5-flash-002"} of friendly names to
+// canonical model ids. An empty value returns nil, which means no aliases.
+func ParseModelAliases(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var aliases map[string]string
+	if err := json.Unmarshal([]byte(value), &aliases); err != nil {
+		return nil, fmt.Errorf("model aliases must be a JSON object of aliases to model ids: %w", err)
+	}
+	for alias, model := range aliases {
+		if strings.TrimSpace(alias) == "" || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("alias and model id cannot be empty")
+		}
+		if alias == model {
+			return nil, fmt.Errorf("alias '%s' cannot point to itself", alias)
+		}
+		if _, chained := aliases[model]; chained {
+			return nil, fmt.Errorf("alias '%s' points to another alias '%s'", alias, model)
+		}
+	}
+	return aliases, nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseModelAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", value: "  "},
+		{name: "aliases", value: `{"fast":"gemini-2.0-flash","smart":"gpt-4o"}`, want: map[string]string{"fast": "gemini-2.0-flash", "smart": "gpt-4o"}},
+		{name: "not an object", value: `["fast"]`, wantErr: true},
+		{name: "empty alias", value: `{" ":"gpt-4o"}`, wantErr: true},
+		{name: "empty model", value: `{"smart":""}`, wantErr: true},
+		{name: "alias to itself", value: `{"gpt-4o":"gpt-4o"}`, wantErr: true},
+		{name: "chained aliases", value: `{"fast":"quick","quick":"gemini-2.0-flash"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseModelAliases(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseModelAliases error = %v, want error %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("ParseModelAliases = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
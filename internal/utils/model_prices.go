@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is the price of a model in currency units per 1K tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ModelPriceTable maps model names to their prices. A name ending in "*" prices every model with that prefix.
+type ModelPriceTable map[string]ModelPrice
+
+// ParseModelPrices parses a JSON price table such as {"gpt-4o":{"input":0.0025,"output":0.01}}.
+// An empty value returns nil, which means no costs are estimated.
+func ParseModelPrices(value string) (ModelPriceTable, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var table ModelPriceTable
+	if err := json.Unmarshal([]byte(value), &table); err != nil {
+		return nil, fmt.Errorf("model prices must be a JSON object of model names to {\"input\": price, \"output\": price}: %w", err)
+	}
+	for model, price := range table {
+		if strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("model name cannot be empty")
+		}
+		if price.Input < 0 || price.Output < 0 {
+			return nil, fmt.Errorf("prices of model '%s' cannot be negative", model)
+		}
+	}
+	return table, nil
+}
+
+// Lookup returns the price of a model: an exact entry wins, then the wildcard entry with the longest prefix.
+func (t ModelPriceTable) Lookup(model string) (ModelPrice, bool) {
+	model = strings.TrimPrefix(model, "models/")
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	var best ModelPrice
+	bestLen := -1
+	for name, price := range t {
+		prefix, ok := strings.CutSuffix(name, "*")
+		if ok && strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = price, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Cost returns the cost of the given token counts at this price.
+func (p ModelPrice) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1000
+}
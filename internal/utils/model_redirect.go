@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/models"
+)
+
+// ParseModelRedirectRule reads the value of a model redirect rule. It is either the target model, or a
+// canary object such as {"target": "gemini-2.0-pro", "percentage": 5} that sends the given percentage of
+// requests to the target while the rest keep the requested model.
+func ParseModelRedirectRule(value any) (string, *models.ModelRedirectCanary, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "", nil, fmt.Errorf("model name cannot be empty")
+		}
+		return v, nil, nil
+	case map[string]any:
+		target, _ := v["target"].(string)
+		if strings.TrimSpace(target) == "" {
+			return "", nil, fmt.Errorf("canary rule needs a target model")
+		}
+		percentage, ok := v["percentage"].(float64)
+		if !ok || percentage != float64(int(percentage)) || percentage < 0 || percentage > 100 {
+			return "", nil, fmt.Errorf("canary rule for '%s' needs an integer percentage between 0 and 100", target)
+		}
+		return target, &models.ModelRedirectCanary{Target: target, Percentage: int(percentage)}, nil
+	default:
+		return "", nil, fmt.Errorf("rule value must be a model name or a canary object, got %T", value)
+	}
+}
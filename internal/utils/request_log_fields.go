@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RequestLogSelectableFields are the request log fields a group can choose to record through request_log_fields.
+// Group, status code, success flag, request type and timestamp are always recorded because statistics depend on them.
+var RequestLogSelectableFields = []string{
+	"model",
+	"key",
+	"source_ip",
+	"request_path",
+	"duration",
+	"error_message",
+	"user_agent",
+	"upstream_addr",
+	"request_body",
+}
+
+// ParseRequestLogFields parses a comma-separated request log field selection.
+// An empty selection returns nil, which means every field is recorded.
+func ParseRequestLogFields(value string) (map[string]bool, error) {
+	names := SplitAndTrim(value, ",")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	fields := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !slices.Contains(RequestLogSelectableFields, name) {
+			return nil, fmt.Errorf("unknown request log field '%s', allowed fields: %s", name, strings.Join(RequestLogSelectableFields, ", "))
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
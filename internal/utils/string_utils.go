@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -54,3 +55,16 @@ func StringToSet(s string, sep string) map[string]struct{} {
 	}
 	return set
 }
+
+// ParseStatusCodes parses a comma-separated list of HTTP error status codes (400-599) into a set.
+func ParseStatusCodes(s string) (map[int]bool, error) {
+	codes := make(map[int]bool)
+	for _, part := range SplitAndTrim(s, ",") {
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 400 || code > 599 {
+			return nil, fmt.Errorf("'%s' is not an HTTP error status code", part)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type traceIDKey struct{}
+
+// NewTraceID returns a fresh id that correlates the log lines of one proxied request.
+func NewTraceID() string {
+	return uuid.NewString()
+}
+
+// WithTraceID returns a context carrying the given trace id.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace id stored in ctx, or "" if there is none.
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// LoggerFromContext returns a log entry tagged with the trace id of ctx, if any.
+// Background work such as scheduled key validation has no trace id and logs untagged.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		return logrus.WithField("trace_id", traceID)
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
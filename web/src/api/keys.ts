@@ -1,6 +1,7 @@
 import i18n from "@/locales";
 import type {
   APIKey,
+  GCPKeyImportPreview,
   Group,
   GroupConfigOption,
   GroupStatsResponse,
@@ -74,6 +75,7 @@ export const keysApi = {
     page_size: number;
     key_value?: string;
     status?: KeyStatus;
+    tags?: string;
   }): Promise<{
     items: APIKey[];
     pagination: {
@@ -116,11 +118,28 @@ export const keysApi = {
     return res.data;
   },
 
+  // 从 GCP 文件夹或组织导入服务账号密钥，confirm 与 parent 一致时才会创建密钥，否则仅返回预览
+  async importGcpKeys(params: {
+    group_id: number;
+    service_account: string;
+    parent: string;
+    account_id: string;
+    confirm?: string;
+  }): Promise<GCPKeyImportPreview | TaskInfo> {
+    const res = await http.post("/keys/import-gcp", params, { hideMessage: true });
+    return res.data;
+  },
+
   // 更新密钥备注
   async updateKeyNotes(keyId: number, notes: string): Promise<void> {
     await http.put(`/keys/${keyId}/notes`, { notes }, { hideMessage: true });
   },
 
+  // 更新密钥标签
+  async updateKeyTags(keyId: number, tags: string[]): Promise<void> {
+    await http.put(`/keys/${keyId}/tags`, { tags }, { hideMessage: true });
+  },
+
   // 测试密钥
   async testKeys(
     group_id: number,
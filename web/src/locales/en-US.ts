@@ -309,7 +309,7 @@ export default {
       "Determines display order in the list, smaller numbers appear first. Recommend using intervals like 10, 20, 30 for easy adjustment",
     sortValue: "Sort value",
     testModelTooltip:
-      "Model name for validating API key availability. System will use this model to send test requests to check if the key is working. Please use lightweight and fast models",
+      "Model name for validating API key availability. System will use this model to send test requests to check if the key is working. Please use lightweight and fast models. Vertex AI groups can use auto to pick a lightweight model available to the project",
     testPathTooltip1:
       "Custom API endpoint path for key validation. Default path will be used if not specified",
     testPathTooltip2: "If using non-standard path, please enter complete API path here",
@@ -343,6 +343,8 @@ export default {
     apiKeyVar: "Current API key",
     timestampMsVar: "Milliseconds timestamp",
     timestampSVar: "Seconds timestamp",
+    modelVar: "Model sent upstream",
+    bodyFieldVar: "Request body field by dotted path, e.g. generationConfig.temperature; empty if missing",
     header: "Header",
     headerTooltip:
       "Configure HTTP header name, value and operation type. Remove operation will delete the specified header",
@@ -366,11 +368,13 @@ export default {
       "In loose mode, models without redirect configuration will be passed directly to upstream service",
     modelRedirectRules: "Model Redirect Rules",
     modelRedirectRulesTooltip:
-      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream",
+      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream. A value may also be an object with target and percentage fields, sending that percentage of requests to the target as a canary while the rest keep the requested model",
     modelRedirectRulesDescription:
-      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream",
+      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream. A value may also be an object with target and percentage fields, sending that percentage of requests to the target as a canary while the rest keep the requested model",
     modelRedirectInvalidJson: "Invalid JSON format for model redirect rules",
-    modelRedirectInvalidFormat: "Model redirect rule keys and values must all be strings",
+    modelRedirectInvalidFormat:
+      "Model redirect rule values must be model names or objects with a target model",
+    modelRedirectInvalidPercentage: "Canary percentage must be an integer between 0 and 100",
     modelRedirectEmptyModel: "Model name cannot be empty",
     never: "Never",
     daysAgo: "{days} days ago",
@@ -452,6 +456,7 @@ export default {
     copyFailed: "Failed to copy {type}",
     retryRequest: "Retry Request",
     finalRequest: "Final Request",
+    shadowRequest: "Shadow Request",
     time: "Time",
     requestType: "Request Type",
     responseType: "Response Type",
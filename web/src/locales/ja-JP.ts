@@ -308,7 +308,7 @@ export default {
       "リスト内の表示順序を決定、数値が小さいほど前に表示されます。10、20、30のような間隔での設定を推奨",
     sortValue: "ソート値",
     testModelTooltip:
-      "APIキーの有効性を検証するためのモデル名。システムはこのモデルを使用してテストリクエストを送信し、キーが機能しているか確認します。軽量で高速なモデルを使用してください",
+      "APIキーの有効性を検証するためのモデル名。システムはこのモデルを使用してテストリクエストを送信し、キーが機能しているか確認します。軽量で高速なモデルを使用してください。Vertex AI グループでは auto を指定すると、プロジェクトで利用可能な軽量モデルを自動で選択します",
     testPathTooltip1:
       "キー検証用のカスタムAPIエンドポイントパス。指定しない場合はデフォルトパスが使用されます",
     testPathTooltip2: "非標準パスを使用する場合は、完全なAPIパスをここに入力してください",
@@ -342,6 +342,8 @@ export default {
     apiKeyVar: "現在のAPIキー",
     timestampMsVar: "ミリ秒タイムスタンプ",
     timestampSVar: "秒タイムスタンプ",
+    modelVar: "上流に送信するモデル",
+    bodyFieldVar: "ドット区切りパスで指定するリクエストボディのフィールド（例：generationConfig.temperature）。存在しない場合は空",
     header: "ヘッダー",
     headerTooltip:
       "HTTPヘッダー名、値、操作タイプを設定します。削除操作は指定されたヘッダーを削除します",
@@ -365,12 +367,13 @@ export default {
       "寛容モードでは、リダイレクト設定のないモデルはアップストリームサービスに直接パススルーされます",
     modelRedirectRules: "モデルリダイレクトルール",
     modelRedirectRulesTooltip:
-      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名",
+      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名。値には target と percentage フィールドを持つオブジェクトも指定でき、その割合のリクエストをカナリアとして target に送り、残りは元のモデルのままにします",
     modelRedirectRulesDescription:
-      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名",
+      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名。値には target と percentage フィールドを持つオブジェクトも指定でき、その割合のリクエストをカナリアとして target に送り、残りは元のモデルのままにします",
     modelRedirectInvalidJson: "モデルリダイレクトルールのJSON形式が無効です",
     modelRedirectInvalidFormat:
-      "モデルリダイレクトルールのキーと値はすべて文字列である必要があります",
+      "モデルリダイレクトルールの値はモデル名か target モデルを含むオブジェクトである必要があります",
+    modelRedirectInvalidPercentage: "カナリアの割合は 0 から 100 の整数である必要があります",
     modelRedirectEmptyModel: "モデル名を空にすることはできません",
     never: "使用なし",
     daysAgo: "{days}日前",
@@ -453,6 +456,7 @@ export default {
     copyFailed: "{type}のコピーに失敗しました",
     retryRequest: "リトライリクエスト",
     finalRequest: "最終リクエスト",
+    shadowRequest: "シャドウリクエスト",
     time: "時間",
     requestType: "リクエストタイプ",
     responseType: "レスポンスタイプ",
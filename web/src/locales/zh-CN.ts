@@ -304,7 +304,7 @@ export default {
       "决定分组在列表中的显示顺序，数字越小越靠前。建议使用10、20、30这样的间隔数字，便于后续调整",
     sortValue: "排序值",
     testModelTooltip:
-      "用于验证API密钥有效性的模型名称。系统会使用这个模型发送测试请求来检查密钥是否可用，请尽量使用轻量快速的模型",
+      "用于验证API密钥有效性的模型名称。系统会使用这个模型发送测试请求来检查密钥是否可用，请尽量使用轻量快速的模型。Vertex AI 分组可填写 auto，自动选择项目可用的轻量模型",
     testPathTooltip1: "自定义用于验证密钥的API端点路径。如果不填写，将使用默认路径",
     testPathTooltip2: "如需使用非标准路径，请在此填写完整的API路径",
     optionalCustomValidationPath: "可选，自定义用于验证key的API路径",
@@ -332,6 +332,8 @@ export default {
     apiKeyVar: "当前轮询的API密钥",
     timestampMsVar: "毫秒时间戳",
     timestampSVar: "秒时间戳",
+    modelVar: "发送给上游的模型",
+    bodyFieldVar: "按点分路径读取的请求体字段，例如 generationConfig.temperature，字段不存在时为空",
     header: "请求头",
     headerTooltip: "配置HTTP请求头的名称、值和操作类型。移除操作会删除指定的请求头",
     headerName: "Header名称",
@@ -350,11 +352,13 @@ export default {
       "严格模式下，只有在下方重定向规则中配置的模型才能被请求，其他模型将返回404错误",
     modelRedirectLooseInfo: "宽松模式下，未配置重定向的模型将直接透传给上游服务",
     modelRedirectRules: "模型重定向规则",
-    modelRedirectRulesTooltip: "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名",
+    modelRedirectRulesTooltip:
+      "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名。值也可以是包含 target 和 percentage 字段的对象，按该百分比将请求作为金丝雀发送到 target，其余请求保持原模型",
     modelRedirectRulesDescription:
-      "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名",
+      "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名。值也可以是包含 target 和 percentage 字段的对象，按该百分比将请求作为金丝雀发送到 target，其余请求保持原模型",
     modelRedirectInvalidJson: "模型重定向规则 JSON 格式错误",
-    modelRedirectInvalidFormat: "模型重定向规则的键值必须都是字符串",
+    modelRedirectInvalidFormat: "模型重定向规则的值必须是模型名或包含 target 模型的对象",
+    modelRedirectInvalidPercentage: "金丝雀百分比必须是 0 到 100 之间的整数",
     modelRedirectEmptyModel: "模型名称不能为空",
     never: "从未",
     daysAgo: "{days}天前",
@@ -436,6 +440,7 @@ export default {
     copyFailed: "复制{type}失败",
     retryRequest: "重试请求",
     finalRequest: "最终请求",
+    shadowRequest: "影子请求",
     time: "时间",
     requestType: "请求类型",
     responseType: "响应类型",
@@ -12,7 +12,7 @@ export type KeyStatus = "active" | "invalid" | undefined;
 export type GroupType = "standard" | "aggregate";
 
 // 渠道类型
-export type ChannelType = "openai" | "gemini" | "anthropic" | "vertex_gemini";
+export type ChannelType = "openai" | "gemini" | "anthropic" | "vertex_gemini" | "bedrock" | "azure_openai";
 
 // 数据模型定义
 export interface APIKey {
@@ -20,10 +20,12 @@ export interface APIKey {
   group_id: number;
   key_value: string;
   notes?: string;
+  tags?: string;
   status: KeyStatus;
   request_count: number;
   failure_count: number;
   last_used_at?: string;
+  expires_at?: string | null;
   created_at: string;
   updated_at: string;
 }
@@ -33,10 +35,23 @@ export interface UpstreamInfo {
   weight: number;
 }
 
+export interface HeaderRuleMatch {
+  models?: string[];
+  path_prefix?: string;
+  stream?: boolean;
+}
+
 export interface HeaderRule {
   key: string;
   value: string;
   action: "set" | "remove";
+  match?: HeaderRuleMatch;
+}
+
+export interface BodyRule {
+  path: string;
+  value?: unknown;
+  action: "set" | "remove" | "default";
 }
 
 // 子分组配置（创建/更新时使用）
@@ -62,6 +77,12 @@ export interface ParentAggregateGroup {
   weight: number;
 }
 
+// 金丝雀重定向：按百分比将请求发送到目标模型，其余请求保持原模型
+export interface ModelRedirectCanary {
+  target: string;
+  percentage: number;
+}
+
 export interface Group {
   id?: number;
   name: string;
@@ -76,9 +97,10 @@ export interface Group {
   api_keys?: APIKey[];
   endpoint?: string;
   param_overrides: Record<string, unknown>;
-  model_redirect_rules: Record<string, string>;
+  model_redirect_rules: Record<string, string | ModelRedirectCanary>;
   model_redirect_strict: boolean;
   header_rules?: HeaderRule[];
+  body_rules?: BodyRule[];
   proxy_keys: string;
   group_type?: GroupType;
   sub_groups?: SubGroupInfo[]; // 子分组列表（仅聚合分组）
@@ -116,7 +138,7 @@ export interface RequestStats {
   failure_rate: number;
 }
 
-export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE";
+export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE" | "GCP_KEY_IMPORT";
 
 export interface KeyValidationResult {
   invalid_keys: number;
@@ -129,6 +151,18 @@ export interface KeyImportResult {
   ignored_count: number;
 }
 
+export interface GCPImportProject {
+  project_id: string;
+  service_account: string;
+  exists: boolean;
+}
+
+export interface GCPKeyImportPreview {
+  import_account: string;
+  projects: GCPImportProject[];
+  to_import: number;
+}
+
 export interface KeyDeleteResult {
   deleted_count: number;
   ignored_count: number;
@@ -159,7 +193,7 @@ export interface RequestLog {
   duration_ms: number;
   error_message: string;
   user_agent: string;
-  request_type: "retry" | "final";
+  request_type: "retry" | "final" | "shadow";
   group_name?: string;
   parent_group_name?: string;
   key_value?: string;
@@ -194,7 +228,7 @@ export interface LogFilter {
   error_contains?: string;
   start_time?: string | null;
   end_time?: string | null;
-  request_type?: "retry" | "final";
+  request_type?: "retry" | "final" | "shadow";
 }
 
 export interface DashboardStats {